@@ -54,8 +54,28 @@ func start(errChan chan<- error) {
 	}
 
 	localAdapter := adapter.NewLocalAdapter(cache)
-	nodeRouter := router.NewNodeRouter(ctx, localAdapter)
-	cacheDistributor := router.NewDistributor(nodeRouter)
+	nodeRouter := router.NewNodeRouter(ctx, config, localAdapter)
+	cacheDistributor := router.NewDistributor(nodeRouter, config)
+	clusterManager := router.NewClusterManager(nodeRouter, config)
+	antiEntropy := router.NewAntiEntropy(nodeRouter, cache, config)
+	consensus, consensusErr := router.NewClusterConsensus(nodeRouter, clusterManager, config)
+	if consensusErr != nil {
+		log.Fatalf("Failed to create cluster consensus: %v", consensusErr)
+	}
+	if config.Cluster.Enabled {
+		if config.Cluster.Rebalance.Enabled {
+			// listen before the cluster manager adds the first peers
+			router.NewRebalancer(nodeRouter, cache, config).Start(ctx)
+		}
+		if err := clusterManager.Start(ctx); err != nil {
+			log.Fatalf("Failed to start cluster manager: %v", err)
+		}
+		consensus.Start(ctx)
+		cacheDistributor.StartHintedHandoff(ctx)
+		if config.Cluster.AntiEntropy.Enabled {
+			antiEntropy.Start(ctx)
+		}
+	}
 
 	// Start the API server
 	if config.HTTP.Enabled {
@@ -64,7 +84,7 @@ func start(errChan chan<- error) {
 			defer wg.Done()
 			addr := config.HTTP.Address
 			fmt.Println("Starting API server on", addr)
-			if err := api.StartAPIServer(ctx, addr, cacheDistributor); err != nil {
+			if err := api.StartAPIServer(ctx, addr, cacheDistributor, clusterManager, antiEntropy, consensus); err != nil {
 				errChan <- err
 			}
 		}()
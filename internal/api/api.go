@@ -16,13 +16,19 @@ type APIServer struct {
 	Addr        string
 	httpSever   *http.Server
 	Distributor router.DistributorInterface
+	Cluster     router.ClusterManagerInterface
+	AntiEntropy router.AntiEntropyInterface
+	Consensus   router.ConsensusInterface
 }
 
-func StartAPIServer(ctx context.Context, addr string, distributor router.DistributorInterface) error {
+func StartAPIServer(ctx context.Context, addr string, distributor router.DistributorInterface, cluster router.ClusterManagerInterface, antiEntropy router.AntiEntropyInterface, consensus router.ConsensusInterface) error {
 	// Implementation for starting the API server goes here
 	server := APIServer{
 		Addr:        addr,
 		Distributor: distributor,
+		Cluster:     cluster,
+		AntiEntropy: antiEntropy,
+		Consensus:   consensus,
 	}
 
 	httpServer := &http.Server{
@@ -63,29 +69,66 @@ func (server *APIServer) router() *gin.Engine {
 	// Middleware
 	r.Use(gin.Recovery())
 	r.Use(gin.Logger())
+	r.Use(handler.Deadline())
 	// Ping route for health check
 	server.ping(r)
-	// core API routes
+	// core API routes, routed to the node owning each key
+	server.cacheRoutes(r, server.Distributor)
+	// peer API routes, served by this node only so forwarded calls are not routed again
+	internalGroup := r.Group("/internal")
+	server.cacheRoutes(internalGroup, server.Distributor.Local())
+	server.entries(internalGroup, server.Distributor.Local())
+	server.merkle(internalGroup)
+	server.raftPeers(internalGroup)
+	// cluster API routes
+	server.clusterNodes(r)
+	server.antiEntropy(r)
+	server.raft(r)
+	return r
+}
+
+func (server *APIServer) cacheRoutes(r gin.IRouter, cache router.DistributorInterface) {
 	// read
-	server.get(r)
-	server.exists(r)
-	server.keys(r)
-	server.ttl(r)
+	server.get(r, cache)
+	server.exists(r, cache)
+	server.keys(r, cache)
+	server.ttl(r, cache)
 	// expire
-	server.expire(r)
+	server.expire(r, cache)
 	// write
-	server.set(r)
-	server.del(r)
-	server.flush(r)
-	server.persist(r)
-	server.incr(r)
-	server.decr(r)
+	server.set(r, cache)
+	server.del(r, cache)
+	server.flush(r, cache)
+	server.persist(r, cache)
+	server.incr(r, cache)
+	server.decr(r, cache)
 	// extra
-	server.setNX(r)
-	server.getSet(r)
-	server.mGet(r)
-	server.mSet(r)
-	return r
+	server.setNX(r, cache)
+	server.getSet(r, cache)
+	server.mGet(r, cache)
+	server.mSet(r, cache)
+	// list
+	server.list(r, cache)
+	// hash
+	server.hash(r, cache)
+	// set
+	server.members(r, cache)
+	// sorted set
+	server.sortedSet(r, cache)
+	// stream
+	server.stream(r, cache)
+	// bitmap
+	server.bitmap(r, cache)
+	// hyperloglog
+	server.hyperLogLog(r, cache)
+	// geo
+	server.geo(r, cache)
+	// json
+	server.json(r, cache)
+	// bloom
+	server.bloom(r, cache)
+	// cuckoo
+	server.cuckoo(r, cache)
 }
 
 func (server *APIServer) ping(r *gin.Engine) {
@@ -94,107 +137,305 @@ func (server *APIServer) ping(r *gin.Engine) {
 	})
 }
 
-func (server *APIServer) set(r *gin.Engine) {
+func (server *APIServer) set(r gin.IRouter, cache router.DistributorInterface) {
 	setHandler := handler.SetHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/set", setHandler.Set)
 }
 
-func (server *APIServer) get(r *gin.Engine) {
+func (server *APIServer) get(r gin.IRouter, cache router.DistributorInterface) {
 	getHandler := handler.GetHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.GET("/get", getHandler.Get)
 }
 
-func (server *APIServer) del(r *gin.Engine) {
+func (server *APIServer) del(r gin.IRouter, cache router.DistributorInterface) {
 	delHandler := handler.DelHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.DELETE("/del", delHandler.Del)
 }
 
-func (server *APIServer) exists(r *gin.Engine) {
+func (server *APIServer) exists(r gin.IRouter, cache router.DistributorInterface) {
 	existsHandler := handler.ExistsHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.GET("/exists", existsHandler.Exists)
 }
 
-func (server *APIServer) keys(r *gin.Engine) {
+func (server *APIServer) keys(r gin.IRouter, cache router.DistributorInterface) {
 	keysHandler := handler.KeysHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.GET("/keys", keysHandler.Keys)
 }
 
-func (server *APIServer) flush(r *gin.Engine) {
+func (server *APIServer) flush(r gin.IRouter, cache router.DistributorInterface) {
 	flushHandler := handler.FlushHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/flush", flushHandler.Flush)
 }
 
-func (server *APIServer) expire(r *gin.Engine) {
+func (server *APIServer) expire(r gin.IRouter, cache router.DistributorInterface) {
 	expireHandler := handler.ExpireHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/expire", expireHandler.Expire)
 }
 
-func (server *APIServer) ttl(r *gin.Engine) {
+func (server *APIServer) ttl(r gin.IRouter, cache router.DistributorInterface) {
 	ttlHandler := handler.TTLHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.GET("/ttl", ttlHandler.TTL)
 }
 
-func (server *APIServer) persist(r *gin.Engine) {
+func (server *APIServer) persist(r gin.IRouter, cache router.DistributorInterface) {
 	persistHandler := handler.PersistHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/persist", persistHandler.Persist)
 }
 
-func (server *APIServer) incr(r *gin.Engine) {
+func (server *APIServer) incr(r gin.IRouter, cache router.DistributorInterface) {
 	incrHandler := handler.IncrHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/incr", incrHandler.Incr)
 }
 
-func (server *APIServer) decr(r *gin.Engine) {
+func (server *APIServer) decr(r gin.IRouter, cache router.DistributorInterface) {
 	decrHandler := handler.DecrHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/decr", decrHandler.Decr)
 }
 
-func (server *APIServer) setNX(r *gin.Engine) {
+func (server *APIServer) setNX(r gin.IRouter, cache router.DistributorInterface) {
 	setNXHandler := handler.SetNXHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/setnx", setNXHandler.SetNX)
 }
 
-func (server *APIServer) getSet(r *gin.Engine) {
+func (server *APIServer) getSet(r gin.IRouter, cache router.DistributorInterface) {
 	getSetHandler := handler.GetSetHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/getset", getSetHandler.GetSet)
 }
 
-func (server *APIServer) mGet(r *gin.Engine) {
+func (server *APIServer) mGet(r gin.IRouter, cache router.DistributorInterface) {
 	mGetHandler := handler.MGetHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/mget", mGetHandler.MGet)
 }
 
-func (server *APIServer) mSet(r *gin.Engine) {
+func (server *APIServer) mSet(r gin.IRouter, cache router.DistributorInterface) {
 	mSetHandler := handler.MSetHandler{
-		Cache: server.Distributor,
+		Cache: cache,
 	}
 	r.POST("/mset", mSetHandler.MSet)
 }
+
+func (server *APIServer) list(r gin.IRouter, cache router.DistributorInterface) {
+	listHandler := handler.ListHandler{
+		Cache: cache,
+	}
+	r.POST("/lpush", listHandler.LPush)
+	r.POST("/rpush", listHandler.RPush)
+	r.POST("/lpop", listHandler.LPop)
+	r.POST("/rpop", listHandler.RPop)
+	r.GET("/lrange", listHandler.LRange)
+	r.GET("/llen", listHandler.LLen)
+	r.POST("/ltrim", listHandler.LTrim)
+	r.GET("/lindex", listHandler.LIndex)
+}
+
+func (server *APIServer) hash(r gin.IRouter, cache router.DistributorInterface) {
+	hashHandler := handler.HashHandler{
+		Cache: cache,
+	}
+	r.POST("/hset", hashHandler.HSet)
+	r.GET("/hget", hashHandler.HGet)
+	r.POST("/hmget", hashHandler.HMGet)
+	r.DELETE("/hdel", hashHandler.HDel)
+	r.GET("/hgetall", hashHandler.HGetAll)
+	r.POST("/hincrby", hashHandler.HIncrBy)
+	r.GET("/hexists", hashHandler.HExists)
+	r.GET("/hlen", hashHandler.HLen)
+}
+
+func (server *APIServer) members(r gin.IRouter, cache router.DistributorInterface) {
+	membersHandler := handler.MembersHandler{
+		Cache: cache,
+	}
+	r.POST("/sadd", membersHandler.SAdd)
+	r.DELETE("/srem", membersHandler.SRem)
+	r.GET("/sismember", membersHandler.SIsMember)
+	r.GET("/smembers", membersHandler.SMembers)
+	r.GET("/scard", membersHandler.SCard)
+	r.POST("/spop", membersHandler.SPop)
+	r.GET("/srandmember", membersHandler.SRandMember)
+	r.POST("/sunion", membersHandler.SUnion)
+	r.POST("/sinter", membersHandler.SInter)
+	r.POST("/sdiff", membersHandler.SDiff)
+}
+
+func (server *APIServer) sortedSet(r gin.IRouter, cache router.DistributorInterface) {
+	sortedSetHandler := handler.SortedSetHandler{
+		Cache: cache,
+	}
+	r.POST("/zadd", sortedSetHandler.ZAdd)
+	r.POST("/zincrby", sortedSetHandler.ZIncrBy)
+	r.GET("/zscore", sortedSetHandler.ZScore)
+	r.GET("/zrank", sortedSetHandler.ZRank)
+	r.GET("/zrange", sortedSetHandler.ZRange)
+	r.GET("/zcard", sortedSetHandler.ZCard)
+	r.DELETE("/zrem", sortedSetHandler.ZRem)
+	r.POST("/zremrangebyscore", sortedSetHandler.ZRemRangeByScore)
+	r.POST("/zpopmin", sortedSetHandler.ZPopMin)
+	r.POST("/zpopmax", sortedSetHandler.ZPopMax)
+}
+
+func (server *APIServer) stream(r gin.IRouter, cache router.DistributorInterface) {
+	streamHandler := handler.StreamHandler{
+		Cache: cache,
+	}
+	r.POST("/xadd", streamHandler.XAdd)
+	r.GET("/xrange", streamHandler.XRange)
+	r.GET("/xlen", streamHandler.XLen)
+	r.POST("/xtrim", streamHandler.XTrim)
+	r.POST("/xgroup/create", streamHandler.XGroupCreate)
+	r.POST("/xreadgroup", streamHandler.XReadGroup)
+	r.POST("/xack", streamHandler.XAck)
+	r.GET("/xpending", streamHandler.XPending)
+}
+
+func (server *APIServer) bitmap(r gin.IRouter, cache router.DistributorInterface) {
+	bitmapHandler := handler.BitmapHandler{
+		Cache: cache,
+	}
+	r.POST("/setraw", bitmapHandler.SetRaw)
+	r.GET("/getraw", bitmapHandler.GetRaw)
+	r.POST("/setbit", bitmapHandler.SetBit)
+	r.GET("/getbit", bitmapHandler.GetBit)
+	r.GET("/bitcount", bitmapHandler.BitCount)
+	r.GET("/bitpos", bitmapHandler.BitPos)
+	r.POST("/bitop", bitmapHandler.BitOp)
+	r.POST("/bitfield", bitmapHandler.BitField)
+}
+
+func (server *APIServer) hyperLogLog(r gin.IRouter, cache router.DistributorInterface) {
+	hllHandler := handler.HyperLogLogHandler{
+		Cache: cache,
+	}
+	r.POST("/pfadd", hllHandler.PFAdd)
+	r.GET("/pfcount", hllHandler.PFCount)
+	r.POST("/pfmerge", hllHandler.PFMerge)
+}
+
+func (server *APIServer) geo(r gin.IRouter, cache router.DistributorInterface) {
+	geoHandler := handler.GeoHandler{
+		Cache: cache,
+	}
+	r.POST("/geoadd", geoHandler.GeoAdd)
+	r.GET("/geopos", geoHandler.GeoPos)
+	r.GET("/geodist", geoHandler.GeoDist)
+	r.POST("/geosearch", geoHandler.GeoSearch)
+}
+
+func (server *APIServer) json(r gin.IRouter, cache router.DistributorInterface) {
+	jsonHandler := handler.JSONHandler{
+		Cache: cache,
+	}
+	r.GET("/json/get", jsonHandler.Get)
+	r.POST("/json/set", jsonHandler.Set)
+	r.DELETE("/json/del", jsonHandler.Del)
+	r.POST("/json/arrappend", jsonHandler.ArrAppend)
+	r.POST("/json/numincrby", jsonHandler.NumIncrBy)
+}
+
+func (server *APIServer) bloom(r gin.IRouter, cache router.DistributorInterface) {
+	bloomHandler := handler.BloomHandler{
+		Cache: cache,
+	}
+	r.POST("/bf/reserve", bloomHandler.Reserve)
+	r.POST("/bf/add", bloomHandler.Add)
+	r.POST("/bf/madd", bloomHandler.MAdd)
+	r.GET("/bf/exists", bloomHandler.Exists)
+	r.GET("/bf/mexists", bloomHandler.MExists)
+}
+
+func (server *APIServer) cuckoo(r gin.IRouter, cache router.DistributorInterface) {
+	cuckooHandler := handler.CuckooHandler{
+		Cache: cache,
+	}
+	r.POST("/cf/reserve", cuckooHandler.Reserve)
+	r.POST("/cf/add", cuckooHandler.Add)
+	r.POST("/cf/addnx", cuckooHandler.AddNX)
+	r.GET("/cf/exists", cuckooHandler.Exists)
+	r.GET("/cf/mexists", cuckooHandler.MExists)
+	r.GET("/cf/count", cuckooHandler.Count)
+	r.DELETE("/cf/del", cuckooHandler.Del)
+}
+
+// entries is only registered for peers, it copies items to backup nodes
+func (server *APIServer) entries(r gin.IRouter, cache router.DistributorInterface) {
+	entriesHandler := handler.EntriesHandler{
+		Cache: cache,
+	}
+	r.POST("/entries/get", entriesHandler.GetEntries)
+	r.POST("/entries/set", entriesHandler.SetEntries)
+	r.POST("/entries/del", entriesHandler.DeleteEntries)
+	r.POST("/entries/tombstones", entriesHandler.GetTombstones)
+}
+
+func (server *APIServer) clusterNodes(r *gin.Engine) {
+	clusterNodesHandler := handler.ClusterNodesHandler{
+		Cluster: server.Cluster,
+	}
+	r.GET("/cluster/nodes", clusterNodesHandler.Nodes)
+	r.GET("/cluster/distribution", clusterNodesHandler.Distribution)
+	r.GET("/cluster/slots", clusterNodesHandler.Slots)
+}
+
+// merkle is only registered for peers, it answers their anti-entropy rounds
+func (server *APIServer) merkle(r gin.IRouter) {
+	antiEntropyHandler := handler.AntiEntropyHandler{
+		AntiEntropy: server.AntiEntropy,
+	}
+	r.GET("/merkle/root", antiEntropyHandler.MerkleRoot)
+	r.GET("/merkle/leaves", antiEntropyHandler.MerkleLeaves)
+	r.GET("/merkle/bucket", antiEntropyHandler.MerkleBucket)
+}
+
+func (server *APIServer) antiEntropy(r *gin.Engine) {
+	antiEntropyHandler := handler.AntiEntropyHandler{
+		AntiEntropy: server.AntiEntropy,
+	}
+	r.GET("/cluster/anti-entropy", antiEntropyHandler.Metrics)
+	r.POST("/cluster/anti-entropy", antiEntropyHandler.Run)
+}
+
+// raftPeers is only registered for peers, it carries the raft RPCs
+func (server *APIServer) raftPeers(r gin.IRouter) {
+	raftHandler := handler.RaftHandler{
+		Consensus: server.Consensus,
+	}
+	r.POST("/raft/vote", raftHandler.Vote)
+	r.POST("/raft/append", raftHandler.Append)
+	r.POST("/raft/propose", raftHandler.Propose)
+}
+
+func (server *APIServer) raft(r *gin.Engine) {
+	raftHandler := handler.RaftHandler{
+		Consensus: server.Consensus,
+	}
+	r.GET("/cluster/raft", raftHandler.Status)
+	r.POST("/cluster/config", raftHandler.Configure)
+}
@@ -1,6 +1,9 @@
 package dto
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"go-cache-server-mini/internal/core/data"
+)
 
 type KeyRequest struct {
 	Key string `form:"key" binding:"required"`
@@ -38,3 +41,467 @@ type MSetRequest struct {
 	KV  map[string]json.RawMessage `json:"kv" binding:"required"`
 	TTL int64                      `json:"ttl" binding:"omitempty"`
 }
+
+// GetEntriesRequest, SetEntriesRequest, DeleteEntriesRequest and TombstonesResponse carry stored items
+// and delete versions between nodes, expiration included, for replication
+type GetEntriesRequest struct {
+	Keys []string `json:"keys" binding:"required"`
+}
+
+type EntriesResponse struct {
+	Entries map[string]data.CacheItem `json:"entries"`
+}
+
+type SetEntriesRequest struct {
+	Entries map[string]data.CacheItem `json:"entries" binding:"required"`
+}
+
+type DeleteEntriesRequest struct {
+	Versions map[string]uint64 `json:"versions" binding:"required"`
+}
+
+type TombstonesResponse struct {
+	Versions map[string]uint64 `json:"versions"`
+}
+
+// MerkleRequest names the asking node, a peer only hashes the keys both nodes replicate
+type MerkleRequest struct {
+	Node   string `form:"node" binding:"required"`
+	Bucket int    `form:"bucket"`
+}
+
+type MerkleRootResponse struct {
+	Root string `json:"root"`
+}
+
+type MerkleLeavesResponse struct {
+	Leaves []string `json:"leaves"`
+}
+
+type MerkleBucketResponse struct {
+	Versions map[string]uint64 `json:"versions"`
+	Deleted  map[string]uint64 `json:"deleted,omitempty"`
+}
+
+// ClusterConfigRequest changes the agreed ring, empty fields keep their current value
+type ClusterConfigRequest struct {
+	Placement    string         `json:"placement"`
+	VirtualNodes int            `json:"virtual_nodes"`
+	Weights      map[string]int `json:"weights"`
+}
+
+// FanOutRequest asks a call sent to every node to fail as a whole when any node fails
+type FanOutRequest struct {
+	Strict bool `form:"strict"`
+}
+
+// ListPushRequest carries the values of LPUSH and RPUSH, each one a JSON value
+type ListPushRequest struct {
+	Key    string            `json:"key" binding:"required"`
+	Values []json.RawMessage `json:"values" binding:"required,min=1"`
+}
+
+// KeyCountRequest asks for up to count values of a key, one by default
+type KeyCountRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Count int    `form:"count,default=1" binding:"min=1"`
+}
+
+// ListRangeRequest selects the values between start and stop, both included, negative indexes count from the tail
+type ListRangeRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Start int    `form:"start"`
+	Stop  int    `form:"stop,default=-1"`
+}
+
+type ListTrimRequest struct {
+	Key   string `json:"key" binding:"required"`
+	Start int    `json:"start"`
+	Stop  int    `json:"stop"`
+}
+
+type ListIndexRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Index int    `form:"index"`
+}
+
+type ListValuesResponse struct {
+	Values []json.RawMessage `json:"values"`
+}
+
+// LengthResponse carries the size of a list, hash or other collection
+type LengthResponse struct {
+	Length int `json:"length"`
+}
+
+// HashSetRequest carries the fields of HSET, each value a JSON value
+type HashSetRequest struct {
+	Key    string                     `json:"key" binding:"required"`
+	Fields map[string]json.RawMessage `json:"fields" binding:"required,min=1"`
+}
+
+type HashFieldRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Field string `form:"field" binding:"required"`
+}
+
+type HashMGetRequest struct {
+	Key    string   `json:"key" binding:"required"`
+	Fields []string `json:"fields" binding:"required,min=1"`
+}
+
+// HashDelRequest names the fields to delete with a repeated field query parameter
+type HashDelRequest struct {
+	Key    string   `form:"key" binding:"required"`
+	Fields []string `form:"field" binding:"required,min=1"`
+}
+
+type HashIncrByRequest struct {
+	Key       string `json:"key" binding:"required"`
+	Field     string `json:"field" binding:"required"`
+	Increment int64  `json:"increment"`
+}
+
+type HashFieldsResponse struct {
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+type SetAddRequest struct {
+	Key     string   `json:"key" binding:"required"`
+	Members []string `json:"members" binding:"required,min=1"`
+}
+
+// SetRemRequest names the members to remove with a repeated member query parameter
+type SetRemRequest struct {
+	Key     string   `form:"key" binding:"required"`
+	Members []string `form:"member" binding:"required,min=1"`
+}
+
+type SetMemberRequest struct {
+	Key    string `form:"key" binding:"required"`
+	Member string `form:"member" binding:"required"`
+}
+
+// SetAlgebraRequest lists the sets of SUNION, SINTER and SDIFF in order
+type SetAlgebraRequest struct {
+	Keys []string `json:"keys" binding:"required,min=1"`
+}
+
+type SetMembersResponse struct {
+	Members []string `json:"members"`
+}
+
+// SortedSetAddRequest carries ZADD, the embedded options are its NX, XX, GT and LT flags
+type SortedSetAddRequest struct {
+	Key     string              `json:"key" binding:"required"`
+	Members []data.ScoredMember `json:"members" binding:"required,min=1"`
+	data.ZAddOptions
+}
+
+type SortedSetIncrByRequest struct {
+	Key       string  `json:"key" binding:"required"`
+	Member    string  `json:"member" binding:"required"`
+	Increment float64 `json:"increment"`
+}
+
+// SortedSetMemberRequest names one member, Rev ranks it from the highest score
+type SortedSetMemberRequest struct {
+	Key    string `form:"key" binding:"required"`
+	Member string `form:"member" binding:"required"`
+	Rev    bool   `form:"rev"`
+}
+
+// SortedSetRangeRequest carries a data.ZRangeQuery, every member by default
+type SortedSetRangeRequest struct {
+	Key    string `form:"key" binding:"required"`
+	By     string `form:"by,default=rank" binding:"oneof=rank score lex"`
+	Start  string `form:"start"`
+	Stop   string `form:"stop"`
+	Rev    bool   `form:"rev"`
+	Offset int    `form:"offset"`
+	Count  int    `form:"count,default=-1"`
+}
+
+type SortedSetRemRequest struct {
+	Key     string   `form:"key" binding:"required"`
+	Members []string `form:"member" binding:"required"`
+}
+
+// SortedSetRemRangeRequest carries score bounds like "1", "(1", "-inf" or "+inf"
+type SortedSetRemRangeRequest struct {
+	Key string `json:"key" binding:"required"`
+	Min string `json:"min" binding:"required"`
+	Max string `json:"max" binding:"required"`
+}
+
+type ScoredMembersResponse struct {
+	Members []data.ScoredMember `json:"members"`
+}
+
+type ScoreResponse struct {
+	Score float64 `json:"score"`
+}
+
+type RankResponse struct {
+	Rank int `json:"rank"`
+}
+
+// StreamAddRequest carries XADD, each field value a JSON value. ID defaults to "*", the embedded
+// trim caps the stream after the entry is added.
+type StreamAddRequest struct {
+	Key    string                     `json:"key" binding:"required"`
+	ID     string                     `json:"id"`
+	Fields map[string]json.RawMessage `json:"fields" binding:"required,min=1"`
+	data.StreamTrim
+}
+
+// StreamRangeRequest selects the entries between start and end, the whole stream by default
+type StreamRangeRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Start string `form:"start,default=-"`
+	End   string `form:"end,default=+"`
+	Count int    `form:"count" binding:"min=0"`
+}
+
+type StreamTrimRequest struct {
+	Key string `json:"key" binding:"required"`
+	data.StreamTrim
+}
+
+// StreamGroupCreateRequest carries XGROUP CREATE, ID defaults to "$" (entries added from now on)
+type StreamGroupCreateRequest struct {
+	Key      string `json:"key" binding:"required"`
+	Group    string `json:"group" binding:"required"`
+	ID       string `json:"id"`
+	MkStream bool   `json:"mkstream"`
+}
+
+// StreamReadGroupRequest carries XREADGROUP for one stream, ID defaults to ">" (new entries)
+type StreamReadGroupRequest struct {
+	Key      string `json:"key" binding:"required"`
+	Group    string `json:"group" binding:"required"`
+	Consumer string `json:"consumer" binding:"required"`
+	ID       string `json:"id"`
+	Count    int    `json:"count" binding:"min=0"`
+}
+
+type StreamAckRequest struct {
+	Key   string   `json:"key" binding:"required"`
+	Group string   `json:"group" binding:"required"`
+	IDs   []string `json:"ids" binding:"required,min=1"`
+}
+
+type StreamGroupRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Group string `form:"group" binding:"required"`
+}
+
+// StreamEntry is a stream entry with JSON field values, fields are null for a trimmed entry
+type StreamEntry struct {
+	ID     string                     `json:"id"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+type StreamEntriesResponse struct {
+	Entries []StreamEntry `json:"entries"`
+}
+
+type StreamPendingResponse struct {
+	Pending []data.PendingEntry `json:"pending"`
+}
+
+// SetRawRequest and RawValueResponse carry a string value that need not be JSON, such as a bitmap,
+// as base64
+type SetRawRequest struct {
+	Key   string `json:"key" binding:"required"`
+	Value []byte `json:"value" binding:"required"`
+	TTL   int64  `json:"ttl" binding:"omitempty"`
+}
+
+type RawValueResponse struct {
+	Value []byte `json:"value"`
+}
+
+type SetBitRequest struct {
+	Key    string `json:"key" binding:"required"`
+	Offset int64  `json:"offset" binding:"min=0"`
+	Bit    int    `json:"bit" binding:"oneof=0 1"`
+}
+
+type GetBitRequest struct {
+	Key    string `form:"key" binding:"required"`
+	Offset int64  `form:"offset" binding:"min=0"`
+}
+
+// BitRangeRequest selects the bytes BITCOUNT and BITPOS look at, or the bits with unit=bit.
+// The whole value by default.
+type BitRangeRequest struct {
+	Key   string `form:"key" binding:"required"`
+	Start int64  `form:"start"`
+	End   *int64 `form:"end"`
+	Unit  string `form:"unit,default=byte" binding:"oneof=byte bit"`
+}
+
+type BitPosRequest struct {
+	BitRangeRequest
+	Bit int `form:"bit" binding:"oneof=0 1"`
+}
+
+type BitOpRequest struct {
+	Op   string   `json:"op" binding:"oneof=and or xor not"`
+	Dest string   `json:"dest" binding:"required"`
+	Keys []string `json:"keys" binding:"required,min=1"`
+}
+
+type BitFieldRequest struct {
+	Key string            `json:"key" binding:"required"`
+	Ops []data.BitFieldOp `json:"ops" binding:"required,min=1"`
+}
+
+type BitResponse struct {
+	Bit int `json:"bit"`
+}
+
+type BitCountResponse struct {
+	Count int64 `json:"count"`
+}
+
+type BitPosResponse struct {
+	Position int64 `json:"position"`
+}
+
+// BitFieldResponse holds one result per op, null where an overflow failed
+type BitFieldResponse struct {
+	Results []*int64 `json:"results"`
+}
+
+type HLLAddRequest struct {
+	Key      string   `json:"key" binding:"required"`
+	Elements []string `json:"elements"`
+}
+
+type HLLCountRequest struct {
+	Keys []string `form:"key" binding:"required,min=1"`
+}
+
+// HLLMergeRequest carries PFMERGE. Sources are HyperLogLogs to merge as they are, a node sends
+// the ones it read from other nodes when the keys do not share their replicas.
+type HLLMergeRequest struct {
+	Dest    string              `json:"dest" binding:"required"`
+	Keys    []string            `json:"keys"`
+	Sources []*data.HyperLogLog `json:"sources,omitempty"`
+}
+
+type HLLCountResponse struct {
+	Count int64 `json:"count"`
+}
+
+// GeoAddRequest adds members to a geospatial index, NX only adds new ones and XX only moves existing ones
+type GeoAddRequest struct {
+	Key     string           `json:"key" binding:"required"`
+	Members []data.GeoMember `json:"members" binding:"required,min=1"`
+	NX      bool             `json:"nx"`
+	XX      bool             `json:"xx"`
+}
+
+type GeoPosRequest struct {
+	Key     string   `form:"key" binding:"required"`
+	Members []string `form:"member" binding:"required"`
+}
+
+// GeoDistRequest measures from one member to another in unit, meters by default
+type GeoDistRequest struct {
+	Key  string `form:"key" binding:"required"`
+	From string `form:"from" binding:"required"`
+	To   string `form:"to" binding:"required"`
+	Unit string `form:"unit,default=m" binding:"oneof=m km mi ft"`
+}
+
+type GeoSearchRequest struct {
+	Key string `json:"key" binding:"required"`
+	data.GeoQuery
+}
+
+// GeoPositionsResponse holds a position per member asked for, null for a missing one
+type GeoPositionsResponse struct {
+	Positions []*data.GeoPoint `json:"positions"`
+}
+
+type GeoDistanceResponse struct {
+	Distance float64 `json:"distance"`
+}
+
+type GeoSearchResponse struct {
+	Results []data.GeoResult `json:"results"`
+}
+
+// JSONPathRequest names a value of a JSON document, the whole document by default
+type JSONPathRequest struct {
+	Key  string `form:"key" binding:"required"`
+	Path string `form:"path,default=$"`
+}
+
+// JSONSetRequest writes Value at Path, the root when Path is empty
+type JSONSetRequest struct {
+	Key   string          `json:"key" binding:"required"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value" binding:"required"`
+	data.JSONSetOptions
+}
+
+type JSONArrAppendRequest struct {
+	Key    string            `json:"key" binding:"required"`
+	Path   string            `json:"path"`
+	Values []json.RawMessage `json:"values" binding:"required,min=1"`
+}
+
+// JSONNumIncrByRequest adds Value, an integer or a float, to the number at Path
+type JSONNumIncrByRequest struct {
+	Key   string      `json:"key" binding:"required"`
+	Path  string      `json:"path"`
+	Value json.Number `json:"value" binding:"required"`
+}
+
+// BloomReserveRequest creates a Bloom filter, options left out take the defaults
+type BloomReserveRequest struct {
+	Key string `json:"key" binding:"required"`
+	data.BloomOptions
+}
+
+// FilterAddRequest adds one item to a Bloom or cuckoo filter
+type FilterAddRequest struct {
+	Key  string `json:"key" binding:"required"`
+	Item string `json:"item" binding:"required"`
+}
+
+type BloomMAddRequest struct {
+	Key   string   `json:"key" binding:"required"`
+	Items []string `json:"items" binding:"required,min=1"`
+}
+
+// FilterItemRequest names one item of a Bloom or cuckoo filter
+type FilterItemRequest struct {
+	Key  string `form:"key" binding:"required"`
+	Item string `form:"item" binding:"required"`
+}
+
+type FilterItemsRequest struct {
+	Key   string   `form:"key" binding:"required"`
+	Items []string `form:"item" binding:"required,min=1"`
+}
+
+// CuckooReserveRequest creates a cuckoo filter, options left out take the defaults
+type CuckooReserveRequest struct {
+	Key string `json:"key" binding:"required"`
+	data.CuckooOptions
+}
+
+// FilterAddedResponse tells for each item whether it was added, false when it may have been there already
+type FilterAddedResponse struct {
+	Added []bool `json:"added"`
+}
+
+// FilterExistsResponse tells for each item whether it may be in the filter
+type FilterExistsResponse struct {
+	Exists []bool `json:"exists"`
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AntiEntropyHandler serves the Merkle tree exchange between peers, the metrics and the manual trigger
+type AntiEntropyHandler struct {
+	AntiEntropy router.AntiEntropyInterface
+}
+
+func (h *AntiEntropyHandler) MerkleRoot(c *gin.Context) {
+	var req dto.MerkleRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	root, err := h.AntiEntropy.Root(req.Node)
+	if err != nil {
+		respondMerkleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MerkleRootResponse{Root: root})
+}
+
+func (h *AntiEntropyHandler) MerkleLeaves(c *gin.Context) {
+	var req dto.MerkleRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	leaves, err := h.AntiEntropy.Leaves(req.Node)
+	if err != nil {
+		respondMerkleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MerkleLeavesResponse{Leaves: leaves})
+}
+
+func (h *AntiEntropyHandler) MerkleBucket(c *gin.Context) {
+	var req dto.MerkleRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	versions, deleted, err := h.AntiEntropy.Bucket(req.Node, req.Bucket)
+	if err != nil {
+		respondMerkleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MerkleBucketResponse{Versions: versions, Deleted: deleted})
+}
+
+// Run starts an anti-entropy round right away and answers with its report
+func (h *AntiEntropyHandler) Run(c *gin.Context) {
+	c.JSON(http.StatusOK, h.AntiEntropy.Run(c.Request.Context()))
+}
+
+func (h *AntiEntropyHandler) Metrics(c *gin.Context) {
+	c.JSON(http.StatusOK, h.AntiEntropy.Metrics())
+}
+
+func respondMerkleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, internal.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error building merkle tree: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BitmapHandler serves the bit commands on string values. A bitmap is not JSON, so it is
+// written and read whole through SetRaw and GetRaw as base64.
+type BitmapHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *BitmapHandler) SetRaw(c *gin.Context) {
+	var req dto.SetRawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	ttl := time.Duration(req.TTL) * time.Second
+	if err := cache.SetRaw(c.Request.Context(), req.Key, req.Value, ttl); err != nil {
+		log.Printf("Error setting cache: %v", err.Error())
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *BitmapHandler) GetRaw(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	value, found, err := cache.GetRaw(c.Request.Context(), req.Key)
+	if err != nil {
+		log.Printf("Error getting cache : %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.RawValueResponse{Value: value})
+}
+
+func (h *BitmapHandler) SetBit(c *gin.Context) {
+	var req dto.SetBitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	previous, err := cache.SetBit(c.Request.Context(), req.Key, req.Offset, req.Bit)
+	if err != nil {
+		log.Printf("Error setting bit: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.BitResponse{Bit: previous})
+}
+
+func (h *BitmapHandler) GetBit(c *gin.Context) {
+	var req dto.GetBitRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	bit, err := cache.GetBit(c.Request.Context(), req.Key, req.Offset)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.BitResponse{Bit: bit})
+}
+
+func (h *BitmapHandler) BitCount(c *gin.Context) {
+	var req dto.BitRangeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	count, err := cache.BitCount(c.Request.Context(), req.Key, bitRange(req))
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.BitCountResponse{Count: count})
+}
+
+func (h *BitmapHandler) BitPos(c *gin.Context) {
+	var req dto.BitPosRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	position, err := cache.BitPos(c.Request.Context(), req.Key, req.Bit, bitRange(req.BitRangeRequest))
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.BitPosResponse{Position: position})
+}
+
+func (h *BitmapHandler) BitOp(c *gin.Context) {
+	var req dto.BitOpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.BitOp(c.Request.Context(), req.Op, req.Dest, req.Keys)
+	if err != nil {
+		log.Printf("Error running bitop: %v for key: %s", err.Error(), req.Dest)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *BitmapHandler) BitField(c *gin.Context) {
+	var req dto.BitFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	results, err := cache.BitField(c.Request.Context(), req.Key, req.Ops)
+	if err != nil {
+		log.Printf("Error running bitfield: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.BitFieldResponse{Results: results})
+}
+
+func bitRange(req dto.BitRangeRequest) data.BitRange {
+	return data.BitRange{Start: req.Start, End: req.End, Bit: req.Unit == "bit"}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BloomHandler serves BF.RESERVE, BF.ADD, BF.MADD, BF.EXISTS and BF.MEXISTS
+type BloomHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *BloomHandler) Reserve(c *gin.Context) {
+	var req dto.BloomReserveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	if err := cache.BFReserve(c.Request.Context(), req.Key, req.BloomOptions); err != nil {
+		log.Printf("Error reserving bloom filter: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *BloomHandler) Add(c *gin.Context) {
+	var req dto.FilterAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	added, ok := h.add(c, req.Key, []string{req.Item})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added[0]})
+}
+
+func (h *BloomHandler) MAdd(c *gin.Context) {
+	var req dto.BloomMAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	added, ok := h.add(c, req.Key, req.Items)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.FilterAddedResponse{Added: added})
+}
+
+func (h *BloomHandler) Exists(c *gin.Context) {
+	var req dto.FilterItemRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	exists, ok := h.exists(c, req.Key, []string{req.Item})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"exists": exists[0]})
+}
+
+func (h *BloomHandler) MExists(c *gin.Context) {
+	var req dto.FilterItemsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	exists, ok := h.exists(c, req.Key, req.Items)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, dto.FilterExistsResponse{Exists: exists})
+}
+
+// add adds items and reports whether it could, having answered the request when it could not
+func (h *BloomHandler) add(c *gin.Context, key string, items []string) ([]bool, bool) {
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return nil, false
+	}
+	added, err := cache.BFAdd(c.Request.Context(), key, items)
+	if err != nil {
+		log.Printf("Error adding to bloom filter: %v for key: %s", err.Error(), key)
+		respondCacheError(c, err)
+		return nil, false
+	}
+	return added, true
+}
+
+func (h *BloomHandler) exists(c *gin.Context, key string, items []string) ([]bool, bool) {
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return nil, false
+	}
+	exists, err := cache.BFExists(c.Request.Context(), key, items)
+	if err != nil {
+		respondCacheError(c, err)
+		return nil, false
+	}
+	return exists, true
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal/distributed/router"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClusterNodesHandler struct {
+	Cluster router.ClusterManagerInterface
+}
+
+func (h *ClusterNodesHandler) Nodes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"nodes": h.Cluster.Nodes()})
+}
+
+func (h *ClusterNodesHandler) Distribution(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Cluster.Distribution())
+}
+
+func (h *ClusterNodesHandler) Slots(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Cluster.Slots())
+}
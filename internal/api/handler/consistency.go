@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/distributed/router"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsistencyHeader picks the replicas a single request waits for: one, quorum or all
+const ConsistencyHeader = "X-Consistency"
+
+// withConsistency applies the consistency header of the request to cache.
+// It answers 400 itself and returns false when the level is unknown.
+func withConsistency(c *gin.Context, cache router.DistributorInterface) (router.DistributorInterface, bool) {
+	level := c.GetHeader(ConsistencyHeader)
+	if level == "" {
+		return cache, true
+	}
+	leveled, err := cache.WithConsistency(level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return leveled, true
+}
+
+// respondCacheError answers 307 to the owner of a key in redirect mode, 503 with the reason
+// when too few replicas answered, 409 when the key holds another type, 504 when the request
+// deadline passed, 500 otherwise
+func respondCacheError(c *gin.Context, err error) {
+	var moved *router.MovedError
+	if errors.As(err, &moved) {
+		c.Header("Location", moved.Address+c.Request.URL.RequestURI())
+		c.JSON(http.StatusTemporaryRedirect, gin.H{
+			"error":   "MOVED",
+			"key":     moved.Key,
+			"node":    moved.Node,
+			"address": moved.Address,
+			"epoch":   moved.Epoch,
+		})
+		return
+	}
+	if errors.Is(err, internal.ErrQuorum) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, internal.ErrWrongType) {
+		c.JSON(http.StatusConflict, gin.H{"error": internal.ErrWrongType.Error()})
+		return
+	}
+	if errors.Is(err, internal.ErrBadRequest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": context.DeadlineExceeded.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CuckooHandler serves CF.RESERVE, CF.ADD, CF.ADDNX, CF.EXISTS, CF.MEXISTS, CF.COUNT and CF.DEL
+type CuckooHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *CuckooHandler) Reserve(c *gin.Context) {
+	var req dto.CuckooReserveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	if err := cache.CFReserve(c.Request.Context(), req.Key, req.CuckooOptions); err != nil {
+		log.Printf("Error reserving cuckoo filter: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *CuckooHandler) Add(c *gin.Context) {
+	h.add(c, false)
+}
+
+// AddNX only adds an item the filter does not seem to hold
+func (h *CuckooHandler) AddNX(c *gin.Context) {
+	h.add(c, true)
+}
+
+func (h *CuckooHandler) add(c *gin.Context, nx bool) {
+	var req dto.FilterAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	added, err := cache.CFAdd(c.Request.Context(), req.Key, req.Item, nx)
+	if err != nil {
+		log.Printf("Error adding to cuckoo filter: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+func (h *CuckooHandler) Exists(c *gin.Context) {
+	var req dto.FilterItemRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	exists, err := cache.CFExists(c.Request.Context(), req.Key, []string{req.Item})
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"exists": exists[0]})
+}
+
+func (h *CuckooHandler) MExists(c *gin.Context) {
+	var req dto.FilterItemsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	exists, err := cache.CFExists(c.Request.Context(), req.Key, req.Items)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FilterExistsResponse{Exists: exists})
+}
+
+func (h *CuckooHandler) Count(c *gin.Context) {
+	var req dto.FilterItemRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	count, err := cache.CFCount(c.Request.Context(), req.Key, req.Item)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+func (h *CuckooHandler) Del(c *gin.Context) {
+	var req dto.FilterItemRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	deleted, err := cache.CFDel(c.Request.Context(), req.Key, req.Item)
+	if err != nil {
+		log.Printf("Error deleting from cuckoo filter: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline bounds the request context by the milliseconds in adapter.TimeoutHeader, sent by
+// peers forwarding a call and accepted from clients, so work stops once the caller gave up
+func Deadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(adapter.TimeoutHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+		milliseconds, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || milliseconds < 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(milliseconds)*time.Millisecond)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
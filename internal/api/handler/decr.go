@@ -23,14 +23,18 @@ func (h *DecrHandler) Decr(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	newValue, decrErr := h.Cache.Decr(req.Key)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	newValue, decrErr := cache.Decr(c.Request.Context(), req.Key)
 	if decrErr != nil {
 		if decrErr == internal.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
 			return
 		}
 		log.Printf("Error decrementing cache: %v for key: %s", decrErr.Error(), req.Key)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, decrErr)
 		return
 	}
 	var res dto.ValueResponse
@@ -19,9 +19,13 @@ func (h *DelHandler) Del(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	delErr := h.Cache.Del(req.Key)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	delErr := cache.Del(c.Request.Context(), req.Key)
 	if delErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, delErr)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
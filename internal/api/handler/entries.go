@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EntriesHandler moves stored items, expiration included, between nodes for replication
+type EntriesHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *EntriesHandler) GetEntries(c *gin.Context) {
+	var req dto.GetEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	entries, err := h.Cache.GetEntries(c.Request.Context(), req.Keys)
+	if err != nil {
+		log.Printf("Error getting entries: %v", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.EntriesResponse{Entries: entries})
+}
+
+func (h *EntriesHandler) SetEntries(c *gin.Context) {
+	var req dto.SetEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	if err := h.Cache.SetEntries(c.Request.Context(), req.Entries); err != nil {
+		log.Printf("Error setting entries: %v", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *EntriesHandler) DeleteEntries(c *gin.Context) {
+	var req dto.DeleteEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	if err := h.Cache.DeleteEntries(c.Request.Context(), req.Versions); err != nil {
+		log.Printf("Error deleting entries: %v", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *EntriesHandler) GetTombstones(c *gin.Context) {
+	var req dto.GetEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	versions, err := h.Cache.GetTombstones(c.Request.Context(), req.Keys)
+	if err != nil {
+		log.Printf("Error getting tombstones: %v", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.TombstonesResponse{Versions: versions})
+}
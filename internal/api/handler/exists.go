@@ -19,9 +19,13 @@ func (h *ExistsHandler) Exists(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	exists, err := h.Cache.Exists(req.Key)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	exists, err := cache.Exists(c.Request.Context(), req.Key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCacheError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"exists": exists})
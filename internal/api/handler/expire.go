@@ -21,13 +21,17 @@ func (h *ExpireHandler) Expire(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	expireErr := h.Cache.Expire(expireReq.Key, time.Duration(expireReq.TTL)*time.Second)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	expireErr := cache.Expire(c.Request.Context(), expireReq.Key, time.Duration(expireReq.TTL)*time.Second)
 	if expireErr != nil {
 		if errors.Is(expireErr, internal.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, expireErr)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
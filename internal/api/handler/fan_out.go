@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindFanOut reads the strict flag of a call sent to every node, answering 400 itself when it is not a bool
+func bindFanOut(c *gin.Context) (dto.FanOutRequest, bool) {
+	var req dto.FanOutRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// respondStrictFailure answers 503 with the node summary when strict is set and a node failed
+func respondStrictFailure(c *gin.Context, strict bool, summary router.FanOutSummary) bool {
+	err := summary.Err()
+	if !strict || err == nil {
+		return false
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "nodes": summary.Nodes, "failed": summary.Failed})
+	return true
+}
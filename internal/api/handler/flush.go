@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"go-cache-server-mini/internal"
 	"go-cache-server-mini/internal/distributed/router"
 	"net/http"
 
@@ -13,10 +12,17 @@ type FlushHandler struct {
 }
 
 func (h *FlushHandler) Flush(c *gin.Context) {
-	flushErr := h.Cache.Flush()
-	if flushErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+	req, ok := bindFanOut(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	summary, err := h.Cache.Flush(c.Request.Context())
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	if respondStrictFailure(c, req.Strict, summary) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "nodes": summary.Nodes, "failed": summary.Failed})
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeoHandler serves geospatial indexes, sorted sets scored by geohash that the sorted set routes read too
+type GeoHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *GeoHandler) GeoAdd(c *gin.Context) {
+	var req dto.GeoAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	added, err := cache.GeoAdd(c.Request.Context(), req.Key, req.Members, data.ZAddOptions{NX: req.NX, XX: req.XX})
+	if err != nil {
+		log.Printf("Error adding to geo index: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+func (h *GeoHandler) GeoPos(c *gin.Context) {
+	var req dto.GeoPosRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	positions, err := cache.GeoPos(c.Request.Context(), req.Key, req.Members)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.GeoPositionsResponse{Positions: positions})
+}
+
+func (h *GeoHandler) GeoDist(c *gin.Context) {
+	var req dto.GeoDistRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	distance, found, err := cache.GeoDist(c.Request.Context(), req.Key, req.From, req.To, req.Unit)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.GeoDistanceResponse{Distance: distance})
+}
+
+func (h *GeoHandler) GeoSearch(c *gin.Context) {
+	var req dto.GeoSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	results, err := cache.GeoSearch(c.Request.Context(), req.Key, req.GeoQuery)
+	if err != nil {
+		// the member searched from is missing
+		if errors.Is(err, internal.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.GeoSearchResponse{Results: results})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"go-cache-server-mini/internal"
 	"go-cache-server-mini/internal/api/dto"
 	"go-cache-server-mini/internal/distributed/router"
@@ -21,10 +22,14 @@ func (h *GetHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	value, ok, err := h.Cache.Get(req.Key)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	value, ok, err := cache.Get(c.Request.Context(), req.Key)
 	if err != nil {
 		log.Printf("Error getting cache : %v for key: %s", err.Error(), req.Key)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, err)
 		return
 	}
 	if !ok {
@@ -32,5 +37,10 @@ func (h *GetHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
 		return
 	}
+	// bits written by SETBIT or SETRAW are not JSON, they are read with /getraw
+	if !json.Valid(value) {
+		respondCacheError(c, internal.ErrWrongType)
+		return
+	}
 	c.JSON(http.StatusOK, dto.ValueResponse{Value: value})
 }
@@ -19,9 +19,13 @@ func (h *GetSetHandler) GetSet(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	oldValue, getSetErr := h.Cache.GetSet(req.Key, req.Value)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	oldValue, getSetErr := cache.GetSet(c.Request.Context(), req.Key, req.Value)
 	if getSetErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, getSetErr)
 		return
 	}
 	c.JSON(http.StatusOK, dto.ValueResponse{Value: oldValue})
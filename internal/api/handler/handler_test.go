@@ -5,17 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"slices"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"go-cache-server-mini/internal/api/dto"
 	"go-cache-server-mini/internal/config"
 	"go-cache-server-mini/internal/core"
+	"go-cache-server-mini/internal/core/data"
 	"go-cache-server-mini/internal/distributed/adapter"
 	"go-cache-server-mini/internal/distributed/router"
 )
@@ -34,8 +38,8 @@ func newHandlerTestCache(t *testing.T) router.DistributorInterface {
 	})
 	core, err := core.NewCache(ctx, config)
 	localAdapter := adapter.NewLocalAdapter(core)
-	nodeRouter := router.NewNodeRouter(ctx, localAdapter)
-	cacheDistributor := router.NewDistributor(nodeRouter)
+	nodeRouter := router.NewNodeRouter(ctx, config, localAdapter)
+	cacheDistributor := router.NewDistributor(nodeRouter, config)
 	if err != nil {
 		t.Fatalf("Failed to create cache: %v", err)
 	}
@@ -77,7 +81,7 @@ func TestSetHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
-	_, ok, err := cache.Get("foo")
+	_, ok, err := cache.Get(t.Context(), "foo")
 	if err != nil || !ok {
 		t.Fatalf("expected key to be set in cache, but got ok=%v, err=%v", ok, err)
 	}
@@ -85,7 +89,7 @@ func TestSetHandler(t *testing.T) {
 
 func TestGetHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("foo", []byte(`"bar"`), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "foo", []byte(`"bar"`), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -112,9 +116,41 @@ func TestGetHandler(t *testing.T) {
 	}
 }
 
+func TestDeadlineHeaderBoundsTheRequest(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	if err := cache.Set(t.Context(), "foo", []byte(`"bar"`), 5*time.Second); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	handler := GetHandler{Cache: cache}
+	engine := gin.New()
+	engine.Use(Deadline())
+	engine.GET("/get", handler.Get)
+
+	cases := []struct {
+		timeout string
+		status  int
+	}{
+		{"", http.StatusOK},
+		{"1000", http.StatusOK},
+		{"0", http.StatusGatewayTimeout}, // no time left, the cache is not touched
+		{"soon", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/get?key=foo", nil)
+		if tc.timeout != "" {
+			req.Header.Set(adapter.TimeoutHeader, tc.timeout)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("timeout %q: expected status %d, got %d: %s", tc.timeout, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
 func TestDelHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("foo", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "foo", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -125,7 +161,7 @@ func TestDelHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
-	exists, err := cache.Exists("foo")
+	exists, err := cache.Exists(t.Context(), "foo")
 	if exists || err != nil {
 		t.Fatalf("expected key to be deleted")
 	}
@@ -133,7 +169,7 @@ func TestDelHandler(t *testing.T) {
 
 func TestExistsHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("foo", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "foo", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -158,10 +194,10 @@ func TestExistsHandler(t *testing.T) {
 
 func TestKeysHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("a", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "a", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
-	if err := cache.Set("b", []byte("2"), 0); err != nil {
+	if err := cache.Set(t.Context(), "b", []byte("2"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -188,7 +224,7 @@ func TestKeysHandler(t *testing.T) {
 
 func TestFlushHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("a", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "a", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -199,7 +235,7 @@ func TestFlushHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
-	keys, err := cache.Keys()
+	keys, _, err := cache.Keys(t.Context())
 	if err != nil {
 		t.Fatalf("Keys returned error: %v", err)
 	}
@@ -208,9 +244,691 @@ func TestFlushHandler(t *testing.T) {
 	}
 }
 
+func TestKeysHandlerReportsFailedNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	config := config.LoadTestConfig()
+	config.Persistent.Type = "memory"
+	cache, err := core.NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	nodeRouter := router.NewNodeRouter(ctx, config, adapter.NewLocalAdapter(cache))
+	nodeRouter.AddAdapter("peer-node", adapter.NewRemoteAdapter("127.0.0.1:9", adapter.RemotePolicy{Timeout: time.Second, MaxAttempts: 1})) // nothing listens
+	handler := KeysHandler{Cache: router.NewDistributor(nodeRouter, config)}
+
+	c, w := newTestContext(http.MethodGet, "/keys", nil)
+	handler.Keys(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Error  string              `json:"error"`
+		Nodes  []router.NodeResult `json:"nodes"`
+		Failed []string            `json:"failed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Nodes) != 2 || !slices.Equal(resp.Failed, []string{"peer-node"}) {
+		t.Fatalf("expected peer-node to be reported as failed, got %+v", resp)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/keys?strict=true", nil)
+	handler.Keys(c)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 in strict mode, got %d", w.Code)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error == "" || !slices.Equal(resp.Failed, []string{"peer-node"}) {
+		t.Fatalf("expected the strict failure to name peer-node, got %+v", resp)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/keys?strict=maybe", nil)
+	handler.Keys(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a bad strict flag, got %d", w.Code)
+	}
+}
+
+func TestListHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := ListHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/rpush", mustJSON(t, map[string]any{"key": "queue", "values": []any{1, "two", map[string]int{"three": 3}}}))
+	handler.RPush(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"length":3}` {
+		t.Fatalf("unexpected rpush response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/lrange?key=queue&start=1", nil)
+	handler.LRange(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"values":["two",{"three":3}]}` {
+		t.Fatalf("unexpected lrange response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/lpop?key=queue", nil)
+	handler.LPop(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"values":[1]}` {
+		t.Fatalf("unexpected lpop response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/lindex?key=queue&index=5", nil)
+	handler.LIndex(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an index out of range, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/rpush", mustJSON(t, map[string]any{"key": "queue", "values": []any{}}))
+	handler.RPush(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an empty push, got %d", w.Code)
+	}
+
+	if err := cache.Set(t.Context(), "plain", []byte("1"), 0); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	c, w = newTestContext(http.MethodGet, "/llen?key=plain", nil)
+	handler.LLen(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for a string key, got %d", w.Code)
+	}
+}
+
+func TestHashHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := HashHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/hset", mustJSON(t, map[string]any{"key": "session", "fields": map[string]any{"user": "kim", "visits": 1, "tags": []string{"a"}}}))
+	handler.HSet(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":3}` {
+		t.Fatalf("unexpected hset response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/hincrby", mustJSON(t, map[string]any{"key": "session", "field": "visits", "increment": 2}))
+	handler.HIncrBy(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"value":3}` {
+		t.Fatalf("unexpected hincrby response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/hget?key=session&field=user", nil)
+	handler.HGet(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"value":"kim"}` {
+		t.Fatalf("unexpected hget response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodDelete, "/hdel?key=session&field=tags&field=missing", nil)
+	handler.HDel(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"deleted":1}` {
+		t.Fatalf("unexpected hdel response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/hgetall?key=session", nil)
+	handler.HGetAll(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"fields":{"user":"kim","visits":3}}` {
+		t.Fatalf("unexpected hgetall response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/hget?key=session&field=tags", nil)
+	handler.HGet(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a missing field, got %d", w.Code)
+	}
+
+	if err := cache.Set(t.Context(), "plain", []byte("1"), 0); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	c, w = newTestContext(http.MethodGet, "/hlen?key=plain", nil)
+	handler.HLen(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for a string key, got %d", w.Code)
+	}
+}
+
+func TestMembersHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := MembersHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/sadd", mustJSON(t, map[string]any{"key": "tags:a", "members": []string{"go", "redis", "go"}}))
+	handler.SAdd(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":2}` {
+		t.Fatalf("unexpected sadd response %d %s", w.Code, w.Body.String())
+	}
+	c, _ = newTestContext(http.MethodPost, "/sadd", mustJSON(t, map[string]any{"key": "tags:b", "members": []string{"redis", "rust"}}))
+	handler.SAdd(c)
+
+	c, w = newTestContext(http.MethodGet, "/sismember?key=tags:a&member=go", nil)
+	handler.SIsMember(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"exists":true}` {
+		t.Fatalf("unexpected sismember response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/sinter", mustJSON(t, map[string]any{"keys": []string{"tags:a", "tags:b"}}))
+	handler.SInter(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"members":["redis"]}` {
+		t.Fatalf("unexpected sinter response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/sunion", mustJSON(t, map[string]any{"keys": []string{"tags:a", "tags:b"}}))
+	handler.SUnion(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"members":["go","redis","rust"]}` {
+		t.Fatalf("unexpected sunion response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodDelete, "/srem?key=tags:a&member=go&member=missing", nil)
+	handler.SRem(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"removed":1}` {
+		t.Fatalf("unexpected srem response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/sdiff", mustJSON(t, map[string]any{"keys": []string{"tags:a", "tags:b"}}))
+	handler.SDiff(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"members":[]}` {
+		t.Fatalf("unexpected sdiff response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/spop?key=tags:a", nil)
+	handler.SPop(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"members":["redis"]}` {
+		t.Fatalf("unexpected spop response %d %s", w.Code, w.Body.String())
+	}
+	c, w = newTestContext(http.MethodPost, "/spop?key=tags:a", nil)
+	handler.SPop(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 popping a missing set, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/scard?key=tags:b", nil)
+	handler.SCard(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"length":2}` {
+		t.Fatalf("unexpected scard response %d %s", w.Code, w.Body.String())
+	}
+
+	if err := cache.Set(t.Context(), "plain", []byte("1"), 0); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	c, w = newTestContext(http.MethodGet, "/smembers?key=plain", nil)
+	handler.SMembers(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for a string key, got %d", w.Code)
+	}
+}
+
+func TestSortedSetHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := SortedSetHandler{Cache: cache}
+
+	body := mustJSON(t, map[string]any{"key": "board", "members": []map[string]any{{"member": "kim", "score": 30}, {"member": "lee", "score": 10}, {"member": "park", "score": 20}}})
+	c, w := newTestContext(http.MethodPost, "/zadd", body)
+	handler.ZAdd(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":3}` {
+		t.Fatalf("unexpected zadd response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/zadd", mustJSON(t, map[string]any{"key": "board", "members": []map[string]any{{"member": "kim", "score": 1}}, "nx": true, "xx": true}))
+	handler.ZAdd(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for nx with xx, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/zincrby", mustJSON(t, map[string]any{"key": "board", "member": "lee", "increment": 25.5}))
+	handler.ZIncrBy(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"score":35.5}` {
+		t.Fatalf("unexpected zincrby response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/zrank?key=board&member=lee&rev=true", nil)
+	handler.ZRank(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"rank":0}` {
+		t.Fatalf("unexpected zrank response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/zrange?key=board&by=score&start=%2Binf&stop=(20&rev=true&count=1", nil)
+	handler.ZRange(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"members":[{"member":"lee","score":35.5}]}` {
+		t.Fatalf("unexpected zrange response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/zrange?key=board&by=score&start=low", nil)
+	handler.ZRange(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a bad score bound, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/zpopmin?key=board", nil)
+	handler.ZPopMin(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"members":[{"member":"park","score":20}]}` {
+		t.Fatalf("unexpected zpopmin response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/zremrangebyscore", mustJSON(t, map[string]any{"key": "board", "min": "30", "max": "+inf"}))
+	handler.ZRemRangeByScore(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"removed":2}` {
+		t.Fatalf("unexpected zremrangebyscore response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/zscore?key=board&member=kim", nil)
+	handler.ZScore(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a missing member, got %d", w.Code)
+	}
+}
+
+func TestStreamHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := StreamHandler{Cache: cache}
+
+	for i, id := range []string{"1-1", "1-2", "2-1"} {
+		c, w := newTestContext(http.MethodPost, "/xadd", mustJSON(t, map[string]any{"key": "events", "id": id, "fields": map[string]any{"n": i}}))
+		handler.XAdd(c)
+		if w.Code != http.StatusOK || w.Body.String() != `{"id":"`+id+`"}` {
+			t.Fatalf("unexpected xadd response %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	c, w := newTestContext(http.MethodGet, "/xrange?key=events&start=1&end=1", nil)
+	handler.XRange(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"entries":[{"id":"1-1","fields":{"n":0}},{"id":"1-2","fields":{"n":1}}]}` {
+		t.Fatalf("unexpected xrange response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/xreadgroup", mustJSON(t, map[string]any{"key": "events", "group": "workers", "consumer": "w1"}))
+	handler.XReadGroup(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a missing group, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/xgroup/create", mustJSON(t, map[string]any{"key": "events", "group": "workers", "id": "1-1"}))
+	handler.XGroupCreate(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected xgroup create response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/xreadgroup", mustJSON(t, map[string]any{"key": "events", "group": "workers", "consumer": "w1", "count": 1}))
+	handler.XReadGroup(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"entries":[{"id":"1-2","fields":{"n":1}}]}` {
+		t.Fatalf("unexpected xreadgroup response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/xpending?key=events&group=workers", nil)
+	handler.XPending(c)
+	var pending dto.StreamPendingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &pending); err != nil || w.Code != http.StatusOK || len(pending.Pending) != 1 || pending.Pending[0].Consumer != "w1" {
+		t.Fatalf("unexpected xpending response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/xack", mustJSON(t, map[string]any{"key": "events", "group": "workers", "ids": []string{"1-2"}}))
+	handler.XAck(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"acked":1}` {
+		t.Fatalf("unexpected xack response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/xtrim", mustJSON(t, map[string]any{"key": "events", "minid": "2"}))
+	handler.XTrim(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"trimmed":2}` {
+		t.Fatalf("unexpected xtrim response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/xadd", mustJSON(t, map[string]any{"key": "events", "id": "1-5", "fields": map[string]any{"n": 9}}))
+	handler.XAdd(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an ID below the last one, got %d", w.Code)
+	}
+}
+
+func TestBitmapHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := BitmapHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/setbit", mustJSON(t, map[string]any{"key": "visits", "offset": 0, "bit": 1}))
+	handler.SetBit(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"bit":0}` {
+		t.Fatalf("unexpected setbit response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/setbit", mustJSON(t, map[string]any{"key": "visits", "offset": 0, "bit": 2}))
+	handler.SetBit(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a bit other than 0 or 1, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/getraw?key=visits", nil)
+	handler.GetRaw(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"value":"gA=="}` {
+		t.Fatalf("unexpected getraw response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/get?key=visits", nil)
+	(&GetHandler{Cache: cache}).Get(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 getting a bitmap as JSON, got %d %s", w.Code, w.Body.String())
+	}
+	cache.Set(t.Context(), "name", []byte(`"kim"`), 0)
+	c, w = newTestContext(http.MethodPost, "/mget", mustJSON(t, map[string]any{"keys": []string{"visits", "name"}}))
+	(&MGetHandler{Cache: cache}).MGet(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"kv":{"name":"kim"}}` {
+		t.Fatalf("expected MGET to leave the bitmap out, got %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/setraw", mustJSON(t, map[string]any{"key": "bits", "value": []byte("\x00\xff\x80")}))
+	handler.SetRaw(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected setraw response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/getbit?key=bits&offset=8", nil)
+	handler.GetBit(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"bit":1}` {
+		t.Fatalf("unexpected getbit response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/bitcount?key=bits&start=12&end=16&unit=bit", nil)
+	handler.BitCount(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"count":5}` {
+		t.Fatalf("unexpected bitcount response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/bitpos?key=bits&bit=1&start=2", nil)
+	handler.BitPos(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"position":16}` {
+		t.Fatalf("unexpected bitpos response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bitop", mustJSON(t, map[string]any{"op": "or", "dest": "both", "keys": []string{"visits", "bits"}}))
+	handler.BitOp(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"length":3}` {
+		t.Fatalf("unexpected bitop response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bitfield", mustJSON(t, map[string]any{"key": "both", "ops": []map[string]any{
+		{"op": "get", "type": "u8", "offset": "#0"},
+		{"op": "incrby", "type": "u8", "offset": 8, "value": 1, "overflow": "fail"},
+		{"op": "incrby", "type": "i8", "offset": "#2", "value": -1},
+	}}))
+	handler.BitField(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"results":[128,null,127]}` {
+		t.Fatalf("unexpected bitfield response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bitfield", mustJSON(t, map[string]any{"key": "both", "ops": []map[string]any{{"op": "get", "type": "u64", "offset": 0}}}))
+	handler.BitField(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a u64 field, got %d", w.Code)
+	}
+}
+
+func TestHyperLogLogHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := HyperLogLogHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/pfadd", mustJSON(t, map[string]any{"key": "home", "elements": []string{"kim", "lee", "kim"}}))
+	handler.PFAdd(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"changed":true}` {
+		t.Fatalf("unexpected pfadd response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/pfadd", mustJSON(t, map[string]any{"key": "pricing", "elements": []string{"lee", "park"}}))
+	handler.PFAdd(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected pfadd response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/pfcount?key=home&key=pricing", nil)
+	handler.PFCount(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"count":3}` {
+		t.Fatalf("unexpected pfcount response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/pfmerge", mustJSON(t, map[string]any{"dest": "site", "keys": []string{"home", "pricing"}}))
+	handler.PFMerge(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected pfmerge response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/pfcount?key=site", nil)
+	handler.PFCount(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"count":3}` {
+		t.Fatalf("unexpected pfcount response after pfmerge %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/pfmerge", mustJSON(t, map[string]any{"dest": "site", "sources": []map[string]any{{"encoding": "dense", "registers": "AAAA"}}}))
+	handler.PFMerge(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a truncated dense source, got %d", w.Code)
+	}
+}
+
+func TestGeoHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := GeoHandler{Cache: cache}
+
+	members := []map[string]any{
+		{"member": "Palermo", "longitude": 13.361389, "latitude": 38.115556},
+		{"member": "Catania", "longitude": 15.087269, "latitude": 37.502669},
+	}
+	c, w := newTestContext(http.MethodPost, "/geoadd", mustJSON(t, map[string]any{"key": "Sicily", "members": members}))
+	handler.GeoAdd(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":2}` {
+		t.Fatalf("unexpected geoadd response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/geoadd", mustJSON(t, map[string]any{"key": "Sicily", "members": []map[string]any{{"member": "x", "longitude": 200, "latitude": 0}}}))
+	handler.GeoAdd(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a longitude past 180, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/geopos?key=Sicily&member=Palermo&member=missing", nil)
+	handler.GeoPos(c)
+	var positions dto.GeoPositionsResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &positions) != nil || len(positions.Positions) != 2 ||
+		positions.Positions[1] != nil || math.Abs(positions.Positions[0].Longitude-13.361389) > 1e-5 {
+		t.Fatalf("unexpected geopos response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/geodist?key=Sicily&from=Palermo&to=Catania&unit=km", nil)
+	handler.GeoDist(c)
+	var distance dto.GeoDistanceResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &distance) != nil || math.Abs(distance.Distance-166.2742) > 0.001 {
+		t.Fatalf("unexpected geodist response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/geodist?key=Sicily&from=Palermo&to=missing", nil)
+	handler.GeoDist(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a missing member, got %d", w.Code)
+	}
+
+	search := map[string]any{"key": "Sicily", "from": map[string]any{"longitude": 15, "latitude": 37}, "radius": 200, "unit": "km", "count": 1}
+	c, w = newTestContext(http.MethodPost, "/geosearch", mustJSON(t, search))
+	handler.GeoSearch(c)
+	var results dto.GeoSearchResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &results) != nil || len(results.Results) != 1 ||
+		results.Results[0].Member != "Catania" || math.Abs(results.Results[0].Distance-56.4413) > 0.001 {
+		t.Fatalf("unexpected geosearch response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/geosearch", mustJSON(t, map[string]any{"key": "Sicily", "member": "missing", "radius": 1}))
+	handler.GeoSearch(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 searching from a missing member, got %d", w.Code)
+	}
+}
+
+func TestJSONHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := JSONHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/json/set", mustJSON(t, map[string]any{"key": "user", "value": map[string]any{"name": "kim", "tags": []string{}, "visits": 1}}))
+	handler.Set(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"set":true}` {
+		t.Fatalf("unexpected json/set response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/json/set", mustJSON(t, map[string]any{"key": "user", "path": "$.name", "value": "lee", "nx": true}))
+	handler.Set(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"set":false}` {
+		t.Fatalf("unexpected json/set response with nx %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/json/arrappend", mustJSON(t, map[string]any{"key": "user", "path": "$.tags", "values": []string{"vip", "new"}}))
+	handler.ArrAppend(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"length":2}` {
+		t.Fatalf("unexpected json/arrappend response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/json/numincrby", mustJSON(t, map[string]any{"key": "user", "path": "$.visits", "value": 2}))
+	handler.NumIncrBy(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"value":3}` {
+		t.Fatalf("unexpected json/numincrby response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/json/get?key=user&path=$.tags[-1]", nil)
+	handler.Get(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"value":"new"}` {
+		t.Fatalf("unexpected json/get response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodDelete, "/json/del?key=user&path=$.tags", nil)
+	handler.Del(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"deleted":1}` {
+		t.Fatalf("unexpected json/del response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/json/get?key=user", nil)
+	handler.Get(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"value":{"name":"kim","visits":3}}` {
+		t.Fatalf("unexpected json/get response for the whole document %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/json/get?key=user&path=$.tags", nil)
+	handler.Get(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a deleted path, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/json/numincrby", mustJSON(t, map[string]any{"key": "user", "path": "$.missing", "value": 1}))
+	handler.NumIncrBy(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 incrementing a missing path, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/json/numincrby", mustJSON(t, map[string]any{"key": "user", "path": "$.name", "value": 1}))
+	handler.NumIncrBy(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 incrementing a string, got %d", w.Code)
+	}
+}
+
+func TestBloomHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := BloomHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/bf/reserve", mustJSON(t, map[string]any{"key": "seen", "error_rate": 0.001, "capacity": 500}))
+	handler.Reserve(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected bf/reserve response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bf/reserve", mustJSON(t, map[string]any{"key": "seen"}))
+	handler.Reserve(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 reserving an existing key, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bf/add", mustJSON(t, map[string]any{"key": "seen", "item": "event-1"}))
+	handler.Add(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":true}` {
+		t.Fatalf("unexpected bf/add response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bf/madd", mustJSON(t, map[string]any{"key": "seen", "items": []string{"event-1", "event-2"}}))
+	handler.MAdd(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":[false,true]}` {
+		t.Fatalf("unexpected bf/madd response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/bf/exists?key=seen&item=event-2", nil)
+	handler.Exists(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"exists":true}` {
+		t.Fatalf("unexpected bf/exists response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/bf/mexists?key=seen&item=event-1&item=event-3", nil)
+	handler.MExists(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"exists":[true,false]}` {
+		t.Fatalf("unexpected bf/mexists response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/bf/reserve", mustJSON(t, map[string]any{"key": "bad", "error_rate": 2}))
+	handler.Reserve(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an error rate above 1, got %d", w.Code)
+	}
+}
+
+func TestCuckooHandlers(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	handler := CuckooHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodPost, "/cf/reserve", mustJSON(t, map[string]any{"key": "dedup", "capacity": 1000, "bucket_size": 4}))
+	handler.Reserve(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected cf/reserve response %d %s", w.Code, w.Body.String())
+	}
+
+	for range 2 {
+		c, w = newTestContext(http.MethodPost, "/cf/add", mustJSON(t, map[string]any{"key": "dedup", "item": "event-1"}))
+		handler.Add(c)
+		if w.Code != http.StatusOK || w.Body.String() != `{"added":true}` {
+			t.Fatalf("unexpected cf/add response %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	c, w = newTestContext(http.MethodPost, "/cf/addnx", mustJSON(t, map[string]any{"key": "dedup", "item": "event-1"}))
+	handler.AddNX(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"added":false}` {
+		t.Fatalf("unexpected cf/addnx response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/cf/count?key=dedup&item=event-1", nil)
+	handler.Count(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"count":2}` {
+		t.Fatalf("unexpected cf/count response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodDelete, "/cf/del?key=dedup&item=event-1", nil)
+	handler.Del(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"deleted":true}` {
+		t.Fatalf("unexpected cf/del response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodGet, "/cf/exists?key=dedup&item=event-1", nil)
+	handler.Exists(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"exists":true}` {
+		t.Fatalf("unexpected cf/exists response %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodDelete, "/cf/del?key=dedup&item=event-1", nil)
+	handler.Del(c)
+	c, w = newTestContext(http.MethodGet, "/cf/mexists?key=dedup&item=event-1&item=event-2", nil)
+	handler.MExists(c)
+	if w.Code != http.StatusOK || w.Body.String() != `{"exists":[false,false]}` {
+		t.Fatalf("unexpected cf/mexists response after deleting both copies %d %s", w.Code, w.Body.String())
+	}
+
+	c, w = newTestContext(http.MethodPost, "/cf/reserve", mustJSON(t, map[string]any{"key": "bad", "bucket_size": 300}))
+	handler.Reserve(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a bucket size above 255, got %d", w.Code)
+	}
+}
+
 func TestExpireHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("ttl", []byte("1"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "ttl", []byte("1"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -222,7 +940,7 @@ func TestExpireHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
-	ttl, ok, err := cache.TTL("ttl")
+	ttl, ok, err := cache.TTL(t.Context(), "ttl")
 	if err != nil {
 		t.Fatalf("TTL returned error: %v", err)
 	}
@@ -233,10 +951,10 @@ func TestExpireHandler(t *testing.T) {
 
 func TestTTLHandlerReturnsPersistentTTL(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("persist-key", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "persist-key", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
-	if err := cache.Persist("persist-key"); err != nil {
+	if err := cache.Persist(t.Context(), "persist-key"); err != nil {
 		t.Fatalf("Persist returned error: %v", err)
 	}
 
@@ -259,7 +977,7 @@ func TestTTLHandlerReturnsPersistentTTL(t *testing.T) {
 
 func TestPersistHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("persist", []byte("1"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "persist", []byte("1"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -270,7 +988,7 @@ func TestPersistHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
-	ttl, ok, err := cache.TTL("persist")
+	ttl, ok, err := cache.TTL(t.Context(), "persist")
 	if err != nil {
 		t.Fatalf("TTL returned error: %v", err)
 	}
@@ -281,7 +999,7 @@ func TestPersistHandler(t *testing.T) {
 
 func TestIncrHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("count", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "count", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -310,7 +1028,7 @@ func TestIncrHandler(t *testing.T) {
 
 func TestDecrHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("count", []byte("2"), 0); err != nil {
+	if err := cache.Set(t.Context(), "count", []byte("2"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -364,7 +1082,7 @@ func TestSetNXHandler(t *testing.T) {
 
 func TestGetSetHandler(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("swap", []byte(`"old"`), 0); err != nil {
+	if err := cache.Set(t.Context(), "swap", []byte(`"old"`), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -387,7 +1105,7 @@ func TestGetSetHandler(t *testing.T) {
 		t.Fatalf("expected old value old, got %s", old)
 	}
 
-	value, _, err := cache.Get("swap")
+	value, _, err := cache.Get(t.Context(), "swap")
 	if err != nil {
 		t.Fatalf("failed to get value from cache: %v", err)
 	}
@@ -398,10 +1116,10 @@ func TestGetSetHandler(t *testing.T) {
 
 func TestMGetHandlerReturnsValues(t *testing.T) {
 	cache := newHandlerTestCache(t)
-	if err := cache.Set("a", []byte("1"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "a", []byte("1"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
-	if err := cache.Set("b", []byte("2"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "b", []byte("2"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
@@ -446,10 +1164,264 @@ func TestMSetHandler(t *testing.T) {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
 
-	if value, ok, err := cache.Get("a"); !ok || err != nil || string(value) != `"1"` {
+	if value, ok, err := cache.Get(t.Context(), "a"); !ok || err != nil || string(value) != `"1"` {
 		t.Fatalf("expected key a to be set, got %s ok=%v err=%v", value, ok, err)
 	}
-	if value, ok, err := cache.Get("b"); !ok || err != nil || string(value) != `"2"` {
+	if value, ok, err := cache.Get(t.Context(), "b"); !ok || err != nil || string(value) != `"2"` {
 		t.Fatalf("expected key b to be set, got %s ok=%v err=%v", value, ok, err)
 	}
 }
+
+func TestConsistencyHeader(t *testing.T) {
+	cache := newHandlerTestCache(t)
+	if err := cache.Set(t.Context(), "foo", []byte(`"bar"`), 5*time.Second); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	handler := GetHandler{Cache: cache}
+
+	c, w := newTestContext(http.MethodGet, "/get?key=foo", nil)
+	c.Request.Header.Set(ConsistencyHeader, "all")
+	handler.Get(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/get?key=foo", nil)
+	c.Request.Header.Set(ConsistencyHeader, "most")
+	handler.Get(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an unknown level, got %d", w.Code)
+	}
+}
+
+func TestGetHandlerRedirectsToOwner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	config := config.LoadTestConfig()
+	config.Persistent.Type = "memory"
+	config.Cluster.Routing = router.RoutingRedirect
+	cache, err := core.NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	nodeRouter := router.NewNodeRouter(ctx, config, adapter.NewLocalAdapter(cache))
+	nodeRouter.AddAdapter("peer-node", adapter.NewRemoteAdapter("127.0.0.1:9", adapter.RemotePolicy{Timeout: time.Second})) // never called
+	handler := GetHandler{Cache: router.NewDistributor(nodeRouter, config)}
+
+	key := ""
+	for i := 0; key == ""; i++ {
+		if candidate := "key-" + strconv.Itoa(i); nodeRouter.Moved(candidate) != nil {
+			key = candidate
+		}
+	}
+	c, w := newTestContext(http.MethodGet, "/get?key="+key, nil)
+	handler.Get(c)
+
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("expected status 307, got %d", w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "http://127.0.0.1:9/get?key="+key {
+		t.Fatalf("unexpected Location header %q", location)
+	}
+	var resp struct {
+		Error string `json:"error"`
+		Node  string `json:"node"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "MOVED" || resp.Node != "peer-node" {
+		t.Fatalf("expected a MOVED body naming peer-node, got %+v", resp)
+	}
+}
+
+func TestEntriesHandlerCopiesExpiration(t *testing.T) {
+	source := newHandlerTestCache(t)
+	if err := source.Set(t.Context(), "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	handler := EntriesHandler{Cache: source}
+	c, w := newTestContext(http.MethodPost, "/internal/entries/get", mustJSON(t, map[string]any{"keys": []string{"a", "missing"}}))
+	handler.GetEntries(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp dto.EntriesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Entries) != 1 || string(resp.Entries["a"].Value) != "1" {
+		t.Fatalf("unexpected entries: %+v", resp.Entries)
+	}
+
+	// store the copied entry under another key
+	entries := map[string]data.CacheItem{"b": resp.Entries["a"]}
+	c, w = newTestContext(http.MethodPost, "/internal/entries/set", mustJSON(t, dto.SetEntriesRequest{Entries: entries}))
+	handler.SetEntries(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	copied, err := source.GetEntries(t.Context(), []string{"b"})
+	if err != nil || !copied["b"].Expiration.Equal(resp.Entries["a"].Expiration) {
+		t.Fatalf("expected expiration to be kept, got %+v err=%v", copied, err)
+	}
+
+	if err := source.Del(t.Context(), "a"); err != nil {
+		t.Fatalf("Del returned error: %v", err)
+	}
+	c, w = newTestContext(http.MethodPost, "/internal/entries/tombstones", mustJSON(t, map[string]any{"keys": []string{"a", "b"}}))
+	handler.GetTombstones(c)
+	var tombstones dto.TombstonesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &tombstones); err != nil || len(tombstones.Versions) != 1 || tombstones.Versions["a"] <= resp.Entries["a"].Version {
+		t.Fatalf("expected the delete of a to be reported, got %s", w.Body.String())
+	}
+}
+
+func TestClusterNodesHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	config := config.LoadTestConfig()
+	config.Persistent.Type = "memory"
+	cache, err := core.NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	nodeRouter := router.NewNodeRouter(ctx, config, adapter.NewLocalAdapter(cache))
+	handler := ClusterNodesHandler{Cluster: router.NewClusterManager(nodeRouter, config)}
+
+	c, w := newTestContext(http.MethodGet, "/cluster/nodes", nil)
+	handler.Nodes(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Nodes []router.NodeStatus `json:"nodes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Nodes) != 1 || resp.Nodes[0].Name != config.Cluster.NodeName || !resp.Nodes[0].Local {
+		t.Fatalf("expected only the local node, got %+v", resp.Nodes)
+	}
+	if resp.Nodes[0].State != router.NodeAlive {
+		t.Fatalf("expected local node to be alive, got %s", resp.Nodes[0].State)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/cluster/distribution", nil)
+	handler.Distribution(c)
+
+	var distribution router.KeyspaceDistribution
+	if err := json.Unmarshal(w.Body.Bytes(), &distribution); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(distribution.Nodes) != 1 || distribution.Nodes[0].Primary != 1 {
+		t.Fatalf("expected the local node to own the whole keyspace, got %+v", distribution)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/cluster/slots", nil)
+	handler.Slots(c)
+
+	var slots router.ClusterSlots
+	if err := json.Unmarshal(w.Body.Bytes(), &slots); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(slots.Nodes) != 1 || slots.Nodes[0].Address != "http://"+config.Cluster.Address || len(slots.Ranges) != 1 {
+		t.Fatalf("expected one range held by the local node, got %+v", slots)
+	}
+}
+
+func TestAntiEntropyHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	config := config.LoadTestConfig()
+	config.Persistent.Type = "memory"
+	cache, err := core.NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	nodeRouter := router.NewNodeRouter(ctx, config, adapter.NewLocalAdapter(cache))
+	handler := AntiEntropyHandler{AntiEntropy: router.NewAntiEntropy(nodeRouter, cache, config)}
+
+	c, w := newTestContext(http.MethodPost, "/cluster/anti-entropy", nil)
+	handler.Run(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/cluster/anti-entropy", nil)
+	handler.Metrics(c)
+	var metrics router.AntiEntropyMetrics
+	if err := json.Unmarshal(w.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if metrics.Rounds != 1 {
+		t.Fatalf("expected one round, got %+v", metrics)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/internal/merkle/root?node="+config.Cluster.NodeName, nil)
+	handler.MerkleRoot(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	c, w = newTestContext(http.MethodGet, "/internal/merkle/root?node=unknown", nil)
+	handler.MerkleRoot(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a node outside the ring, got %d", w.Code)
+	}
+}
+
+func TestRaftHandlerConfiguresRing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	config := config.LoadTestConfig()
+	config.Persistent.Type = "memory"
+	cache, err := core.NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	nodeRouter := router.NewNodeRouter(ctx, config, adapter.NewLocalAdapter(cache))
+	disabled, err := router.NewClusterConsensus(nodeRouter, router.NewClusterManager(nodeRouter, config), config)
+	if err != nil {
+		t.Fatalf("NewClusterConsensus returned error: %v", err)
+	}
+	handler := RaftHandler{Consensus: disabled}
+	c, w := newTestContext(http.MethodPost, "/cluster/config", mustJSON(t, dto.ClusterConfigRequest{Placement: "jump"}))
+	handler.Configure(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without raft, got %d", w.Code)
+	}
+
+	// a single voter is its own quorum
+	config.Cluster.Enabled = true
+	config.Cluster.Raft.Enabled = true
+	config.Cluster.Raft.ElectionTimeout = 20
+	consensus, err := router.NewClusterConsensus(nodeRouter, router.NewClusterManager(nodeRouter, config), config)
+	if err != nil {
+		t.Fatalf("NewClusterConsensus returned error: %v", err)
+	}
+	consensus.Start(ctx)
+	handler = RaftHandler{Consensus: consensus}
+
+	c, w = newTestContext(http.MethodPost, "/cluster/config", mustJSON(t, dto.ClusterConfigRequest{Placement: "jump"}))
+	handler.Configure(c)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 before a leader is elected, got %d", w.Code)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for consensus.Status().Raft.Role != "leader" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c, w = newTestContext(http.MethodPost, "/cluster/config", mustJSON(t, dto.ClusterConfigRequest{Placement: "jump"}))
+	handler.Configure(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var status router.ConsensusStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if status.Metadata.Placement != "jump" || status.Raft.Leader != config.Cluster.NodeName {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
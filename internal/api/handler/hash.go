@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"go-cache-server-mini/internal/util"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HashHandler serves the hash commands, every field value is a JSON value like the value of /set
+type HashHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *HashHandler) HSet(c *gin.Context) {
+	var req dto.HashSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	fields := make(map[string][]byte, len(req.Fields))
+	for field, value := range req.Fields {
+		fields[field] = value
+	}
+	added, err := cache.HSet(c.Request.Context(), req.Key, fields)
+	if err != nil {
+		log.Printf("Error setting hash fields: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+func (h *HashHandler) HGet(c *gin.Context) {
+	var req dto.HashFieldRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	value, exists, err := cache.HGet(c.Request.Context(), req.Key, req.Field)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ValueResponse{Value: value})
+}
+
+func (h *HashHandler) HMGet(c *gin.Context) {
+	var req dto.HashMGetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	fields, err := cache.HMGet(c.Request.Context(), req.Key, req.Fields)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, hashResponse(fields))
+}
+
+func (h *HashHandler) HDel(c *gin.Context) {
+	var req dto.HashDelRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	deleted, err := cache.HDel(c.Request.Context(), req.Key, req.Fields)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+func (h *HashHandler) HGetAll(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	fields, err := cache.HGetAll(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, hashResponse(fields))
+}
+
+func (h *HashHandler) HIncrBy(c *gin.Context) {
+	var req dto.HashIncrByRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	value, err := cache.HIncrBy(c.Request.Context(), req.Key, req.Field, req.Increment)
+	if err != nil {
+		log.Printf("Error incrementing hash field: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.ValueResponse{Value: json.RawMessage(util.Int64ToBytes(value))})
+}
+
+func (h *HashHandler) HExists(c *gin.Context) {
+	var req dto.HashFieldRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	exists, err := cache.HExists(c.Request.Context(), req.Key, req.Field)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"exists": exists})
+}
+
+func (h *HashHandler) HLen(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.HLen(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func hashResponse(fields map[string][]byte) dto.HashFieldsResponse {
+	res := dto.HashFieldsResponse{Fields: make(map[string]json.RawMessage, len(fields))}
+	for field, value := range fields {
+		res.Fields[field] = value
+	}
+	return res
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HyperLogLogHandler serves PFADD, PFCOUNT and PFMERGE
+type HyperLogLogHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *HyperLogLogHandler) PFAdd(c *gin.Context) {
+	var req dto.HLLAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	changed, err := cache.PFAdd(c.Request.Context(), req.Key, req.Elements)
+	if err != nil {
+		log.Printf("Error adding to hyperloglog: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changed": changed})
+}
+
+func (h *HyperLogLogHandler) PFCount(c *gin.Context) {
+	var req dto.HLLCountRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	count, err := cache.PFCount(c.Request.Context(), req.Keys)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.HLLCountResponse{Count: count})
+}
+
+func (h *HyperLogLogHandler) PFMerge(c *gin.Context) {
+	var req dto.HLLMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	if err := cache.PFMerge(c.Request.Context(), req.Dest, req.Keys, req.Sources); err != nil {
+		log.Printf("Error merging hyperloglogs: %v for key: %s", err.Error(), req.Dest)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -23,14 +23,18 @@ func (h *IncrHandler) Incr(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	newValue, incrErr := h.Cache.Incr(req.Key)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	newValue, incrErr := cache.Incr(c.Request.Context(), req.Key)
 	if incrErr != nil {
 		if incrErr == internal.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
 			return
 		}
 		log.Printf("Error incrementing cache: %v for key: %s", incrErr.Error(), req.Key)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, incrErr)
 		return
 	}
 	var res dto.ValueResponse
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONHandler reads and changes JSON documents, the values /set stores, by path under the shard lock
+type JSONHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *JSONHandler) Get(c *gin.Context) {
+	var req dto.JSONPathRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	value, found, err := cache.JSONGet(c.Request.Context(), req.Key, req.Path)
+	if err != nil {
+		log.Printf("Error getting JSON path: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ValueResponse{Value: value})
+}
+
+func (h *JSONHandler) Set(c *gin.Context) {
+	var req dto.JSONSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	written, err := cache.JSONSet(c.Request.Context(), req.Key, req.Path, req.Value, req.JSONSetOptions)
+	if err != nil {
+		log.Printf("Error setting JSON path: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"set": written})
+}
+
+func (h *JSONHandler) Del(c *gin.Context) {
+	var req dto.JSONPathRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	deleted, err := cache.JSONDel(c.Request.Context(), req.Key, req.Path)
+	if err != nil {
+		log.Printf("Error deleting JSON path: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+func (h *JSONHandler) ArrAppend(c *gin.Context) {
+	var req dto.JSONArrAppendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.JSONArrAppend(c.Request.Context(), req.Key, req.Path, req.Values)
+	if err != nil {
+		log.Printf("Error appending to JSON array: %v for key: %s", err.Error(), req.Key)
+		respondJSONError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *JSONHandler) NumIncrBy(c *gin.Context) {
+	var req dto.JSONNumIncrByRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	number, err := cache.JSONNumIncrBy(c.Request.Context(), req.Key, req.Path, req.Value)
+	if err != nil {
+		log.Printf("Error incrementing JSON number: %v for key: %s", err.Error(), req.Key)
+		respondJSONError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.ValueResponse{Value: number})
+}
+
+// respondJSONError answers 404 for a missing document or path, other errors as respondCacheError
+func respondJSONError(c *gin.Context, err error) {
+	if errors.Is(err, internal.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	respondCacheError(c, err)
+}
@@ -12,10 +12,17 @@ type KeysHandler struct {
 }
 
 func (h *KeysHandler) Keys(c *gin.Context) {
-	keys, err := h.Cache.Keys()
+	req, ok := bindFanOut(c)
+	if !ok {
+		return
+	}
+	keys, summary, err := h.Cache.Keys(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCacheError(c, err)
+		return
+	}
+	if respondStrictFailure(c, req.Strict, summary) {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"keys": keys})
+	c.JSON(http.StatusOK, gin.H{"keys": keys, "nodes": summary.Nodes, "failed": summary.Failed})
 }
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListHandler serves the list commands, every element is a JSON value like the value of /set
+type ListHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *ListHandler) LPush(c *gin.Context) {
+	h.push(c, true)
+}
+
+func (h *ListHandler) RPush(c *gin.Context) {
+	h.push(c, false)
+}
+
+func (h *ListHandler) LPop(c *gin.Context) {
+	h.pop(c, true)
+}
+
+func (h *ListHandler) RPop(c *gin.Context) {
+	h.pop(c, false)
+}
+
+func (h *ListHandler) LRange(c *gin.Context) {
+	var req dto.ListRangeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	values, err := cache.LRange(c.Request.Context(), req.Key, req.Start, req.Stop)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, listResponse(values))
+}
+
+func (h *ListHandler) LLen(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.LLen(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *ListHandler) LTrim(c *gin.Context) {
+	var req dto.ListTrimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	if err := cache.LTrim(c.Request.Context(), req.Key, req.Start, req.Stop); err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *ListHandler) LIndex(c *gin.Context) {
+	var req dto.ListIndexRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	value, found, err := cache.LIndex(c.Request.Context(), req.Key, req.Index)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ValueResponse{Value: value})
+}
+
+func (h *ListHandler) push(c *gin.Context, head bool) {
+	var req dto.ListPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	values := make([][]byte, len(req.Values))
+	for i, value := range req.Values {
+		values[i] = value
+	}
+	var length int
+	var err error
+	if head {
+		length, err = cache.LPush(c.Request.Context(), req.Key, values)
+	} else {
+		length, err = cache.RPush(c.Request.Context(), req.Key, values)
+	}
+	if err != nil {
+		log.Printf("Error pushing to list: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *ListHandler) pop(c *gin.Context, head bool) {
+	var req dto.KeyCountRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	var values [][]byte
+	var err error
+	if head {
+		values, err = cache.LPop(c.Request.Context(), req.Key, req.Count)
+	} else {
+		values, err = cache.RPop(c.Request.Context(), req.Key, req.Count)
+	}
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+			return
+		}
+		log.Printf("Error popping from list: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, listResponse(values))
+}
+
+func listResponse(values [][]byte) dto.ListValuesResponse {
+	res := dto.ListValuesResponse{Values: make([]json.RawMessage, len(values))}
+	for i, value := range values {
+		res.Values[i] = value
+	}
+	return res
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MembersHandler serves the commands of the set type, members are strings returned as JSON arrays
+type MembersHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *MembersHandler) SAdd(c *gin.Context) {
+	var req dto.SetAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	added, err := cache.SAdd(c.Request.Context(), req.Key, req.Members)
+	if err != nil {
+		log.Printf("Error adding set members: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+func (h *MembersHandler) SRem(c *gin.Context) {
+	var req dto.SetRemRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	removed, err := cache.SRem(c.Request.Context(), req.Key, req.Members)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+func (h *MembersHandler) SIsMember(c *gin.Context) {
+	var req dto.SetMemberRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	exists, err := cache.SIsMember(c.Request.Context(), req.Key, req.Member)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"exists": exists})
+}
+
+func (h *MembersHandler) SMembers(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	members, err := cache.SMembers(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, membersResponse(members))
+}
+
+func (h *MembersHandler) SCard(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.SCard(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *MembersHandler) SPop(c *gin.Context) {
+	var req dto.KeyCountRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	members, err := cache.SPop(c.Request.Context(), req.Key, req.Count)
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+			return
+		}
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, membersResponse(members))
+}
+
+func (h *MembersHandler) SRandMember(c *gin.Context) {
+	var req dto.KeyCountRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	members, err := cache.SRandMember(c.Request.Context(), req.Key, req.Count)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, membersResponse(members))
+}
+
+func (h *MembersHandler) SUnion(c *gin.Context) {
+	h.combine(c, router.DistributorInterface.SUnion)
+}
+
+func (h *MembersHandler) SInter(c *gin.Context) {
+	h.combine(c, router.DistributorInterface.SInter)
+}
+
+func (h *MembersHandler) SDiff(c *gin.Context) {
+	h.combine(c, router.DistributorInterface.SDiff)
+}
+
+func (h *MembersHandler) combine(c *gin.Context, op func(router.DistributorInterface, context.Context, []string) ([]string, error)) {
+	var req dto.SetAlgebraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	members, err := op(cache, c.Request.Context(), req.Keys)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, membersResponse(members))
+}
+
+func membersResponse(members []string) dto.SetMembersResponse {
+	if members == nil {
+		members = []string{}
+	}
+	return dto.SetMembersResponse{Members: members}
+}
@@ -20,14 +20,21 @@ func (h *MGetHandler) MGet(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	kv, err := h.Cache.MGet(req.Keys)
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	kv, err := cache.MGet(c.Request.Context(), req.Keys)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCacheError(c, err)
 		return
 	}
 	var res dto.MGetResponse = dto.MGetResponse{KV: make(map[string]json.RawMessage, len(kv))}
 	for key, value := range kv {
-		res.KV[key] = json.RawMessage(value)
+		// raw bits are left out like a key of another type, a bitmap is read with /getraw
+		if json.Valid(value) {
+			res.KV[key] = json.RawMessage(value)
+		}
 	}
 	c.JSON(http.StatusOK, res)
 }
@@ -20,14 +20,18 @@ func (h *MSetHandler) MSet(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
 	ttl := time.Duration(req.TTL) * time.Second
 	kv := make(map[string][]byte)
 	for key, value := range req.KV {
 		kv[key] = value
 	}
-	setErr := h.Cache.MSet(kv, ttl)
+	setErr := cache.MSet(c.Request.Context(), kv, ttl)
 	if setErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, setErr)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -22,13 +22,17 @@ func (h *PersistHandler) Persist(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
-	if err := h.Cache.Persist(req.Key); err != nil {
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	if err := cache.Persist(c.Request.Context(), req.Key); err != nil {
 		if errors.Is(err, internal.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
 			return
 		}
 		log.Printf("Error persisting cache: %v for key: %s", err.Error(), req.Key)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
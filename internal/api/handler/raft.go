@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/distributed/raft"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RaftHandler serves the raft RPCs between voters, the consensus status and ring configuration changes
+type RaftHandler struct {
+	Consensus router.ConsensusInterface
+}
+
+func (h *RaftHandler) Vote(c *gin.Context) {
+	var req raft.VoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	res, err := h.Consensus.RequestVote(req)
+	if err != nil {
+		respondConsensusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *RaftHandler) Append(c *gin.Context) {
+	var req raft.AppendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	res, err := h.Consensus.AppendEntries(req)
+	if err != nil {
+		respondConsensusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *RaftHandler) Propose(c *gin.Context) {
+	var req raft.ProposeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	if err := h.Consensus.Propose(c.Request.Context(), req.Command); err != nil {
+		respondConsensusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *RaftHandler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Consensus.Status())
+}
+
+func (h *RaftHandler) Configure(c *gin.Context) {
+	var req dto.ClusterConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	if err := h.Consensus.Configure(c.Request.Context(), req.Placement, req.VirtualNodes, req.Weights); err != nil {
+		respondConsensusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.Consensus.Status())
+}
+
+// respondConsensusError answers 503 when the change could not reach a quorum, so it was not applied
+func respondConsensusError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, internal.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, raft.ErrNoQuorum), errors.Is(err, raft.ErrNotLeader):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error in raft request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+	}
+}
@@ -22,12 +22,16 @@ func (h *SetHandler) Set(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
 
 	ttl := time.Duration(req.TTL) * time.Second
-	setErr := h.Cache.Set(req.Key, req.Value, ttl)
+	setErr := cache.Set(c.Request.Context(), req.Key, req.Value, ttl)
 	if setErr != nil {
 		log.Printf("Error setting cache: %v", setErr.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, setErr)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
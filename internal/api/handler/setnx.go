@@ -20,10 +20,14 @@ func (h *SetNXHandler) SetNX(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
 	ttl := time.Duration(req.TTL) * time.Second
-	success, setErr := h.Cache.SetNX(req.Key, req.Value, ttl)
+	success, setErr := cache.SetNX(c.Request.Context(), req.Key, req.Value, ttl)
 	if setErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": internal.ErrServer.Error()})
+		respondCacheError(c, setErr)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": success})
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SortedSetHandler serves the commands of the sorted set type, members come back with their scores
+type SortedSetHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *SortedSetHandler) ZAdd(c *gin.Context) {
+	var req dto.SortedSetAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	added, err := cache.ZAdd(c.Request.Context(), req.Key, req.Members, req.ZAddOptions)
+	if err != nil {
+		log.Printf("Error adding sorted set members: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+func (h *SortedSetHandler) ZIncrBy(c *gin.Context) {
+	var req dto.SortedSetIncrByRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	score, err := cache.ZIncrBy(c.Request.Context(), req.Key, req.Member, req.Increment)
+	if err != nil {
+		log.Printf("Error incrementing sorted set member: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.ScoreResponse{Score: score})
+}
+
+func (h *SortedSetHandler) ZScore(c *gin.Context) {
+	var req dto.SortedSetMemberRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	score, exists, err := cache.ZScore(c.Request.Context(), req.Key, req.Member)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ScoreResponse{Score: score})
+}
+
+func (h *SortedSetHandler) ZRank(c *gin.Context) {
+	var req dto.SortedSetMemberRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	rank, exists, err := cache.ZRank(c.Request.Context(), req.Key, req.Member, req.Rev)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.RankResponse{Rank: rank})
+}
+
+func (h *SortedSetHandler) ZRange(c *gin.Context) {
+	var req dto.SortedSetRangeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	query := data.ZRangeQuery{By: req.By, Start: req.Start, Stop: req.Stop, Rev: req.Rev, Offset: req.Offset, Count: req.Count}
+	members, err := cache.ZRange(c.Request.Context(), req.Key, query)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, scoredMembersResponse(members))
+}
+
+func (h *SortedSetHandler) ZCard(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.ZCard(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *SortedSetHandler) ZRem(c *gin.Context) {
+	var req dto.SortedSetRemRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	removed, err := cache.ZRem(c.Request.Context(), req.Key, req.Members)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+func (h *SortedSetHandler) ZRemRangeByScore(c *gin.Context) {
+	var req dto.SortedSetRemRangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	removed, err := cache.ZRemRangeByScore(c.Request.Context(), req.Key, req.Min, req.Max)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+func (h *SortedSetHandler) ZPopMin(c *gin.Context) {
+	h.pop(c, router.DistributorInterface.ZPopMin)
+}
+
+func (h *SortedSetHandler) ZPopMax(c *gin.Context) {
+	h.pop(c, router.DistributorInterface.ZPopMax)
+}
+
+func (h *SortedSetHandler) pop(c *gin.Context, op func(router.DistributorInterface, context.Context, string, int) ([]data.ScoredMember, error)) {
+	var req dto.KeyCountRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	members, err := op(cache, c.Request.Context(), req.Key, req.Count)
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": internal.ErrNotFound.Error()})
+			return
+		}
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, scoredMembersResponse(members))
+}
+
+func scoredMembersResponse(members []data.ScoredMember) dto.ScoredMembersResponse {
+	if members == nil {
+		members = []data.ScoredMember{}
+	}
+	return dto.ScoredMembersResponse{Members: members}
+}
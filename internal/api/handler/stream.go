@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/router"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamHandler serves the commands of the stream type and its consumer groups
+type StreamHandler struct {
+	Cache router.DistributorInterface
+}
+
+func (h *StreamHandler) XAdd(c *gin.Context) {
+	var req dto.StreamAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	fields := make(map[string][]byte, len(req.Fields))
+	for field, value := range req.Fields {
+		fields[field] = value
+	}
+	id, err := cache.XAdd(c.Request.Context(), req.Key, cmp.Or(req.ID, "*"), fields, req.StreamTrim)
+	if err != nil {
+		log.Printf("Error adding stream entry: %v for key: %s", err.Error(), req.Key)
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+func (h *StreamHandler) XRange(c *gin.Context) {
+	var req dto.StreamRangeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	entries, err := cache.XRange(c.Request.Context(), req.Key, req.Start, req.End, req.Count)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, streamEntriesResponse(entries))
+}
+
+func (h *StreamHandler) XLen(c *gin.Context) {
+	var req dto.KeyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	length, err := cache.XLen(c.Request.Context(), req.Key)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LengthResponse{Length: length})
+}
+
+func (h *StreamHandler) XTrim(c *gin.Context) {
+	var req dto.StreamTrimRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.StreamTrim.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	trimmed, err := cache.XTrim(c.Request.Context(), req.Key, req.StreamTrim)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trimmed": trimmed})
+}
+
+func (h *StreamHandler) XGroupCreate(c *gin.Context) {
+	var req dto.StreamGroupCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	if err := cache.XGroupCreate(c.Request.Context(), req.Key, req.Group, cmp.Or(req.ID, "$"), req.MkStream); err != nil {
+		respondStreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *StreamHandler) XReadGroup(c *gin.Context) {
+	var req dto.StreamReadGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	entries, err := cache.XReadGroup(c.Request.Context(), req.Key, req.Group, req.Consumer, cmp.Or(req.ID, ">"), req.Count)
+	if err != nil {
+		log.Printf("Error reading stream group: %v for key: %s", err.Error(), req.Key)
+		respondStreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, streamEntriesResponse(entries))
+}
+
+func (h *StreamHandler) XAck(c *gin.Context) {
+	var req dto.StreamAckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	acked, err := cache.XAck(c.Request.Context(), req.Key, req.Group, req.IDs)
+	if err != nil {
+		respondCacheError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"acked": acked})
+}
+
+func (h *StreamHandler) XPending(c *gin.Context) {
+	var req dto.StreamGroupRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
+		return
+	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
+	pending, err := cache.XPending(c.Request.Context(), req.Key, req.Group)
+	if err != nil {
+		respondStreamError(c, err)
+		return
+	}
+	if pending == nil {
+		pending = []data.PendingEntry{}
+	}
+	c.JSON(http.StatusOK, dto.StreamPendingResponse{Pending: pending})
+}
+
+// respondStreamError answers 404 for a missing stream or consumer group, other errors as respondCacheError
+func respondStreamError(c *gin.Context, err error) {
+	if errors.Is(err, internal.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	respondCacheError(c, err)
+}
+
+func streamEntriesResponse(entries []data.StreamEntry) dto.StreamEntriesResponse {
+	res := dto.StreamEntriesResponse{Entries: make([]dto.StreamEntry, len(entries))}
+	for i, entry := range entries {
+		res.Entries[i] = dto.StreamEntry{ID: entry.ID.String()}
+		if entry.Fields != nil {
+			res.Entries[i].Fields = make(map[string]json.RawMessage, len(entry.Fields))
+			for field, value := range entry.Fields {
+				res.Entries[i].Fields[field] = value
+			}
+		}
+	}
+	return res
+}
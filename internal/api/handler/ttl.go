@@ -19,10 +19,14 @@ func (h *TTLHandler) TTL(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": internal.ErrBadRequest.Error()})
 		return
 	}
+	cache, ok := withConsistency(c, h.Cache)
+	if !ok {
+		return
+	}
 
-	ttl, exists, err := h.Cache.TTL(req.Key)
+	ttl, exists, err := cache.TTL(c.Request.Context(), req.Key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCacheError(c, err)
 		return
 	}
 	if !exists {
@@ -22,10 +22,89 @@ type HTTPConfig struct {
 	Address string `yaml:"address"`
 }
 
+type PeerConfig struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"`
+}
+
+type GossipConfig struct {
+	BindAddress      string   `yaml:"bind_address"`      // UDP address to listen on
+	AdvertiseAddress string   `yaml:"advertise_address"` // UDP address other nodes use to reach this node
+	Seeds            []string `yaml:"seeds"`             // gossip addresses to join through
+	ProbeInterval    int64    `yaml:"probe_interval"`    // milliseconds
+	ProbeTimeout     int64    `yaml:"probe_timeout"`     // milliseconds
+	SuspicionTimeout int64    `yaml:"suspicion_timeout"` // milliseconds
+	IndirectChecks   int      `yaml:"indirect_checks"`   // members asked to probe a node that missed its ack
+}
+
+type ReplicationConfig struct {
+	BackupNodes        int    `yaml:"backup_nodes"`         // copies kept on the next nodes of the ring
+	Mode               string `yaml:"mode"`                 // options: sync, async
+	ReadConsistency    string `yaml:"read_consistency"`     // options: one, quorum, all
+	WriteConsistency   string `yaml:"write_consistency"`    // options: one, quorum, all (default follows mode)
+	HintLimit          int    `yaml:"hint_limit"`           // writes kept per unreachable node
+	HintReplayInterval int64  `yaml:"hint_replay_interval"` // milliseconds
+}
+
+type AntiEntropyConfig struct {
+	Enabled  bool  `yaml:"enabled"`
+	Interval int64 `yaml:"interval"` // milliseconds between rounds
+}
+
+type RebalanceConfig struct {
+	Enabled        bool  `yaml:"enabled"`
+	BatchSize      int   `yaml:"batch_size"`      // keys sent per request
+	Rate           int   `yaml:"rate"`            // keys sent per second
+	FallbackWindow int64 `yaml:"fallback_window"` // milliseconds reads still try the old owners after a move
+}
+
+type RaftConfig struct {
+	Enabled           bool   `yaml:"enabled"`
+	ElectionTimeout   int64  `yaml:"election_timeout"`   // milliseconds
+	HeartbeatInterval int64  `yaml:"heartbeat_interval"` // milliseconds
+	ProposeTimeout    int64  `yaml:"propose_timeout"`    // milliseconds a ring change waits for a quorum
+	StatePath         string `yaml:"state_path"`         // file keeping the raft log, memory only when empty
+}
+
+type RetryConfig struct {
+	MaxAttempts int   `yaml:"max_attempts"` // tries of a peer call that is safe to repeat
+	BaseBackoff int64 `yaml:"base_backoff"` // milliseconds, doubled on each retry with full jitter
+	MaxBackoff  int64 `yaml:"max_backoff"`  // milliseconds
+}
+
+type CircuitBreakerConfig struct {
+	FailureThreshold int   `yaml:"failure_threshold"` // consecutive failed calls that open the breaker of a peer
+	OpenTimeout      int64 `yaml:"open_timeout"`      // milliseconds calls fail at once before a trial call
+	HalfOpenProbes   int   `yaml:"half_open_probes"`  // trial calls that must succeed to close the breaker
+}
+
+type ClusterConfig struct {
+	Enabled             bool                 `yaml:"enabled"`
+	NodeName            string               `yaml:"node_name"`
+	Address             string               `yaml:"address"`               // address other nodes use to reach this node
+	Membership          string               `yaml:"membership"`            // options: static, gossip
+	HealthCheckInterval int64                `yaml:"health_check_interval"` // milliseconds
+	FailureThreshold    int                  `yaml:"failure_threshold"`     // failed checks before a peer is removed
+	RequestTimeout      int64                `yaml:"request_timeout"`       // milliseconds
+	Routing             string               `yaml:"routing"`               // options: proxy, redirect
+	Placement           string               `yaml:"placement"`             // options: consistent_hash, rendezvous, jump
+	VirtualNodes        int                  `yaml:"virtual_nodes"`         // virtual nodes per unit of weight (consistent_hash)
+	Weights             map[string]int       `yaml:"weights"`               // node name to relative share of the keyspace, default 1
+	Retry               RetryConfig          `yaml:"retry"`
+	CircuitBreaker      CircuitBreakerConfig `yaml:"circuit_breaker"`
+	Peers               []PeerConfig         `yaml:"peers"`
+	Gossip              GossipConfig         `yaml:"gossip"`
+	Replication         ReplicationConfig    `yaml:"replication"`
+	AntiEntropy         AntiEntropyConfig    `yaml:"anti_entropy"`
+	Rebalance           RebalanceConfig      `yaml:"rebalance"`
+	Raft                RaftConfig           `yaml:"raft"`
+}
+
 type Config struct {
 	Persistent PersistentConfig `yaml:"persistent"`
 	TTL        TTLConfig        `yaml:"ttl"`
 	HTTP       HTTPConfig       `yaml:"http"`
+	Cluster    ClusterConfig    `yaml:"cluster"`
 }
 
 func LoadConfig(configFilePath string) (*Config, error) {
@@ -58,5 +137,50 @@ func LoadTestConfig() *Config {
 			Enabled: true,
 			Address: ":8080",
 		},
+		Cluster: ClusterConfig{
+			Enabled:             false,
+			NodeName:            "local-node",
+			Address:             "127.0.0.1:8080",
+			Membership:          "static",
+			HealthCheckInterval: 1000,
+			FailureThreshold:    3,
+			RequestTimeout:      2000,
+			Routing:             "proxy",
+			Placement:           "consistent_hash",
+			VirtualNodes:        128,
+			Retry: RetryConfig{
+				MaxAttempts: 3,
+				BaseBackoff: 50,
+				MaxBackoff:  500,
+			},
+			CircuitBreaker: CircuitBreakerConfig{
+				FailureThreshold: 5,
+				OpenTimeout:      5000,
+				HalfOpenProbes:   1,
+			},
+			Replication: ReplicationConfig{
+				BackupNodes:        0,
+				Mode:               "sync",
+				ReadConsistency:    "one",
+				HintLimit:          10000,
+				HintReplayInterval: 1000,
+			},
+			AntiEntropy: AntiEntropyConfig{
+				Enabled:  false,
+				Interval: 60000,
+			},
+			Rebalance: RebalanceConfig{
+				Enabled:        true,
+				BatchSize:      100,
+				Rate:           1000,
+				FallbackWindow: 10000,
+			},
+			Raft: RaftConfig{
+				Enabled:           false,
+				ElectionTimeout:   1000,
+				HeartbeatInterval: 150,
+				ProposeTimeout:    3000,
+			},
+		},
 	}
 }
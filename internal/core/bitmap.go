@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/util"
+	"time"
+)
+
+// SetBit sets or clears the bit at offset of the string at key and returns the bit it replaced.
+// A missing key starts as an empty string.
+func (c *Cache) SetBit(ctx context.Context, key string, offset int64, bit int) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	value, err := c.stringLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	updated, previous, err := data.SetBit(value, offset, bit)
+	if err != nil {
+		return 0, err
+	}
+	c.storeTypedLocked(index, key, data.CacheItem{Value: updated}, false)
+	return previous, nil
+}
+
+// GetBit returns the bit at offset, 0 past the end of the string or for a missing key
+func (c *Cache) GetBit(ctx context.Context, key string, offset int64) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	value, err := c.stringLocked(index, key)
+	return data.GetBit(value, offset), err
+}
+
+func (c *Cache) BitCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	value, err := c.stringLocked(index, key)
+	return data.BitCount(value, bitRange), err
+}
+
+// BitPos returns the offset of the first bit equal to bit, see data.BitPos
+func (c *Cache) BitPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	value, err := c.stringLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	return data.BitPos(value, bit, bitRange)
+}
+
+// BitOp stores op over the strings at keys in dest and returns its length. dest is replaced like SET,
+// whatever it held, and an empty result deletes it. Every shard involved is locked at once.
+func (c *Cache) BitOp(ctx context.Context, op, dest string, keys []string) (int, error) {
+	indexList := util.GetIndexListNoDup(append([]string{dest}, keys...), c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.Lock()
+	}
+	defer func() {
+		for j := len(indexList) - 1; j >= 0; j-- {
+			c.shardedMap[indexList[j]].lock.Unlock()
+		}
+	}()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := c.stringLocked(c.getShardedIndex(key), key)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = value
+	}
+	result, err := data.BitOp(op, values)
+	if err != nil {
+		return 0, err
+	}
+	index := c.getShardedIndex(dest)
+	if len(result) == 0 {
+		c.deleteLocked(index, dest)
+		return 0, nil
+	}
+	expiration, persistent := util.SetExpiration(c.defaultTTL, c.maxTTL, 0)
+	c.shardedMap[index].kvmap[dest] = data.CacheItem{
+		Value:      result,
+		Expiration: time.Now().Add(expiration),
+		Persistent: persistent,
+		Version:    nextVersion(c.shardedMap[index].kvmap[dest].Version),
+	}
+	// Write to AOF
+	c.setItemLog(dest, c.shardedMap[index].kvmap[dest])
+	return len(result), nil
+}
+
+// BitField runs ops on the string at key in one step, see data.BitField. Only ops that write take the write lock.
+func (c *Cache) BitField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error) {
+	index := c.getShardedIndex(key)
+	if data.BitFieldReadOnly(ops) {
+		c.shardedMap[index].lock.RLock()
+		defer c.shardedMap[index].lock.RUnlock()
+	} else {
+		c.shardedMap[index].lock.Lock()
+		defer c.shardedMap[index].lock.Unlock()
+	}
+	value, err := c.stringLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	updated, results, err := data.BitField(value, ops)
+	if err != nil {
+		return nil, err
+	}
+	if updated != nil {
+		c.storeTypedLocked(index, key, data.CacheItem{Value: updated}, false)
+	}
+	return results, nil
+}
+
+// stringLocked returns the string at key, nil when the key is missing or expired. The shard lock must be held.
+func (c *Cache) stringLocked(index int, key string) ([]byte, error) {
+	item, _, err := c.loadTypedLocked(index, key, data.TypeString)
+	return item.Value, err
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"slices"
+)
+
+// BFReserve creates an empty Bloom filter at key. A key that exists is not replaced.
+func (c *Cache) BFReserve(ctx context.Context, key string, options data.BloomOptions) error {
+	filter, err := data.NewBloomFilter(options)
+	if err != nil {
+		return err
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	_, found, err := c.loadTypedLocked(index, key, data.TypeBloom)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%w: key %q already exists", internal.ErrBadRequest, key)
+	}
+	c.storeBloomLocked(index, key, filter)
+	return nil
+}
+
+// BFAdd adds items to the Bloom filter at key and reports for each whether it is new. A missing key
+// is created with the default error rate and capacity.
+func (c *Cache) BFAdd(ctx context.Context, key string, items []string) ([]bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	item, found, err := c.loadTypedLocked(index, key, data.TypeBloom)
+	if err != nil {
+		return nil, err
+	}
+	var filter *data.BloomFilter
+	if found {
+		filter = item.Bloom.Clone()
+	} else if filter, err = data.NewBloomFilter(data.BloomOptions{}); err != nil {
+		return nil, err
+	}
+	added, err := filter.Add(items...)
+	if err != nil {
+		return nil, err
+	}
+	if found && !slices.Contains(added, true) {
+		return added, nil
+	}
+	c.storeBloomLocked(index, key, filter)
+	return added, nil
+}
+
+// BFExists reports for each of items whether it may have been added, false for all when the key is missing
+func (c *Cache) BFExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	item, found, err := c.loadTypedLocked(index, key, data.TypeBloom)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return make([]bool, len(items)), nil
+	}
+	return item.Bloom.Exists(items...), nil
+}
+
+// storeBloomLocked writes filter back to key. The shard lock must be held.
+func (c *Cache) storeBloomLocked(index int, key string, filter *data.BloomFilter) {
+	c.storeTypedLocked(index, key, data.CacheItem{Bloom: filter, Type: data.TypeBloom}, false)
+}
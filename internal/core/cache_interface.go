@@ -1,21 +1,105 @@
 package core
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"go-cache-server-mini/internal/core/data"
+	"time"
+)
 
+// CacheInterface is the local cache. ctx stops the scans over every shard (Keys, Flush),
+// operations on a few keys are short enough to always finish.
 type CacheInterface interface {
-	Set(key string, value []byte, expiration time.Duration) error           // expiration of -1 means no expiration
-	Get(key string) ([]byte, bool)                                          // returns value and whether the key exists
-	Del(key string) error                                                   // deletes a key
-	Exists(key string) bool                                                 // checks if a key exists
-	Keys() []string                                                         // returns all keys
-	Flush() error                                                           // clears the cache
-	TTL(key string) (time.Duration, bool)                                   // returns remaining TTL and whether the key exists
-	Expire(key string, expiration time.Duration) error                      // updates the TTL of a key
-	Persist(key string) error                                               // removes the expiration from a key
-	Incr(key string) (int64, error)                                         // increments an integer value plus one
-	Decr(key string) (int64, error)                                         // decrements an integer value minus one
-	SetNX(key string, value []byte, expiration time.Duration) (bool, error) // sets the value only if the key does not exist
-	GetSet(key string, value []byte) ([]byte, error)                        // sets a new value and returns the old value
-	MGet(keys []string) map[string][]byte                                   // retrieves multiple keys at once
-	MSet(kv map[string][]byte, expiration time.Duration) error              // sets multiple key-value pairs at once
+	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error                        // expiration of -1 means no expiration
+	Get(ctx context.Context, key string) ([]byte, bool)                                                       // returns value and whether the key exists as a string
+	Del(ctx context.Context, key string) error                                                                // deletes a key
+	Exists(ctx context.Context, key string) bool                                                              // checks if a key exists
+	Keys(ctx context.Context) ([]string, error)                                                               // returns all keys
+	Flush(ctx context.Context) error                                                                          // clears the cache
+	TTL(ctx context.Context, key string) (time.Duration, bool)                                                // returns remaining TTL and whether the key exists
+	Expire(ctx context.Context, key string, expiration time.Duration) error                                   // updates the TTL of a key
+	Persist(ctx context.Context, key string) error                                                            // removes the expiration from a key
+	Incr(ctx context.Context, key string) (int64, error)                                                      // increments an integer value plus one
+	Decr(ctx context.Context, key string) (int64, error)                                                      // decrements an integer value minus one
+	SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)              // sets the value only if the key does not exist
+	GetSet(ctx context.Context, key string, value []byte) ([]byte, error)                                     // sets a new value and returns the old value
+	MGet(ctx context.Context, keys []string) map[string][]byte                                                // retrieves multiple keys at once
+	MSet(ctx context.Context, kv map[string][]byte, expiration time.Duration) error                           // sets multiple key-value pairs at once
+	GetEntries(ctx context.Context, keys []string) map[string]data.CacheItem                                  // returns stored items with their expiration
+	SetEntries(ctx context.Context, entries map[string]data.CacheItem) error                                  // stores items as they are (replication)
+	Versions() map[string]uint64                                                                              // returns the version stamp of every key
+	DeleteEntries(ctx context.Context, versions map[string]uint64) error                                      // deletes keys older than their delete version
+	DropEntries(ctx context.Context, keys []string) error                                                     // removes handed-off keys without a tombstone
+	GetTombstones(ctx context.Context, keys []string) map[string]uint64                                       // returns the delete version of each deleted key of keys
+	Tombstones() map[string]uint64                                                                            // returns the delete version of every deleted key
+	LPush(ctx context.Context, key string, values [][]byte) (int, error)                                      // prepends values, returns the new length
+	RPush(ctx context.Context, key string, values [][]byte) (int, error)                                      // appends values, returns the new length
+	LPop(ctx context.Context, key string, count int) ([][]byte, error)                                        // removes and returns up to count values from the head
+	RPop(ctx context.Context, key string, count int) ([][]byte, error)                                        // removes and returns up to count values from the tail
+	LRange(ctx context.Context, key string, start, stop int) ([][]byte, error)                                // returns the values between start and stop, both included
+	LLen(ctx context.Context, key string) (int, error)                                                        // returns the length of a list, 0 when missing
+	LTrim(ctx context.Context, key string, start, stop int) error                                             // keeps only the values between start and stop
+	LIndex(ctx context.Context, key string, index int) ([]byte, bool, error)                                  // returns the value at index and whether it exists
+	HSet(ctx context.Context, key string, fields map[string][]byte) (int, error)                              // sets fields of a hash, returns how many were new
+	HGet(ctx context.Context, key, field string) ([]byte, bool, error)                                        // returns the value of a field and whether it exists
+	HMGet(ctx context.Context, key string, fields []string) (map[string][]byte, error)                        // returns the fields that exist
+	HDel(ctx context.Context, key string, fields []string) (int, error)                                       // removes fields, returns how many existed
+	HGetAll(ctx context.Context, key string) (map[string][]byte, error)                                       // returns every field of a hash
+	HIncrBy(ctx context.Context, key, field string, delta int64) (int64, error)                               // adds delta to an integer field
+	HExists(ctx context.Context, key, field string) (bool, error)                                             // checks if a field exists
+	HLen(ctx context.Context, key string) (int, error)                                                        // returns the number of fields
+	SAdd(ctx context.Context, key string, members []string) (int, error)                                      // adds members to a set, returns how many were new
+	SRem(ctx context.Context, key string, members []string) (int, error)                                      // removes members, returns how many existed
+	SIsMember(ctx context.Context, key, member string) (bool, error)                                          // checks if member is in a set
+	SMembers(ctx context.Context, key string) ([]string, error)                                               // returns the members in order
+	SCard(ctx context.Context, key string) (int, error)                                                       // returns the number of members
+	SPop(ctx context.Context, key string, count int) ([]string, error)                                        // removes and returns up to count random members
+	SRandMember(ctx context.Context, key string, count int) ([]string, error)                                 // returns up to count random members
+	SUnion(ctx context.Context, keys []string) ([]string, error)                                              // members of any of the sets
+	SInter(ctx context.Context, keys []string) ([]string, error)                                              // members of every set
+	SDiff(ctx context.Context, keys []string) ([]string, error)                                               // members of the first set only
+	ZAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error) // sets scores, returns how many members were new
+	ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error)                          // adds delta to the score of a member
+	ZScore(ctx context.Context, key, member string) (float64, bool, error)                                    // returns the score of a member and whether it exists
+	ZRank(ctx context.Context, key, member string, rev bool) (int, bool, error)                               // returns the rank of a member, from the highest score when rev
+	ZRange(ctx context.Context, key string, query data.ZRangeQuery) ([]data.ScoredMember, error)              // returns the members selected by rank, score or lex
+	ZCard(ctx context.Context, key string) (int, error)                                                       // returns the number of members
+	ZRem(ctx context.Context, key string, members []string) (int, error)                                      // removes members, returns how many existed
+	ZRemRangeByScore(ctx context.Context, key, min, max string) (int, error)                                  // removes the members scored between min and max
+	ZPopMin(ctx context.Context, key string, count int) ([]data.ScoredMember, error)                          // removes and returns up to count lowest scored members
+	ZPopMax(ctx context.Context, key string, count int) ([]data.ScoredMember, error)                          // removes and returns up to count highest scored members
+	XAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error) // appends a stream entry, returns its ID
+	XRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error)                // returns up to count entries between start and end
+	XLen(ctx context.Context, key string) (int, error)                                                        // returns the number of entries
+	XTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error)                                 // drops the oldest entries, returns how many went
+	XGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error                             // adds a consumer group reading after id
+	XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error)   // delivers new entries, or rereads pending ones
+	XAck(ctx context.Context, key, group string, ids []string) (int, error)                                   // acknowledges entries, returns how many were pending
+	XPending(ctx context.Context, key, group string) ([]data.PendingEntry, error)                             // lists the unacknowledged entries of a group
+	SetBit(ctx context.Context, key string, offset int64, bit int) (int, error)                               // sets or clears a bit of a string, returns the bit it replaced
+	GetBit(ctx context.Context, key string, offset int64) (int, error)                                        // returns a bit of a string, 0 past its end
+	BitCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error)                          // counts the set bits in a range
+	BitPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error)                   // returns the first bit equal to bit in a range
+	BitOp(ctx context.Context, op, dest string, keys []string) (int, error)                                   // stores and, or, xor or not of strings in dest, returns its length
+	BitField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error)                        // reads, sets and increments packed integers
+	PFAdd(ctx context.Context, key string, elements []string) (bool, error)                                   // adds elements to a HyperLogLog, reports whether a register changed
+	PFCount(ctx context.Context, keys []string) (int64, error)                                                // estimates the distinct elements of the union of HyperLogLogs
+	PFMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error               // stores the union of dest, keys and sources in dest
+	GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error)  // adds members to a geospatial index, returns how many were new
+	GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error)                       // returns the position of members, nil for a missing one
+	GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error)                           // returns the distance between two members
+	GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error)                 // returns the members within a radius or a box, nearest first
+	JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error)                             // returns the value at a path of a JSON document
+	JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error)   // writes the value at a path, reports whether options allowed it
+	JSONDel(ctx context.Context, key, path string) (int, error)                                               // removes the value at a path, returns how many were removed
+	JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error)               // appends to the array at a path, returns its length
+	JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error)          // adds to the number at a path, returns the new number
+	BFReserve(ctx context.Context, key string, options data.BloomOptions) error                               // creates an empty Bloom filter
+	BFAdd(ctx context.Context, key string, items []string) ([]bool, error)                                    // adds items to a Bloom filter, reports which are new
+	BFExists(ctx context.Context, key string, items []string) ([]bool, error)                                 // reports which items may have been added
+	CFReserve(ctx context.Context, key string, options data.CuckooOptions) error                              // creates an empty cuckoo filter
+	CFAdd(ctx context.Context, key, item string, nx bool) (bool, error)                                       // adds an item to a cuckoo filter, with nx only when it is not there
+	CFExists(ctx context.Context, key string, items []string) ([]bool, error)                                 // reports which items may have been added
+	CFCount(ctx context.Context, key, item string) (int, error)                                               // returns how many times an item may have been added
+	CFDel(ctx context.Context, key, item string) (bool, error)                                                // removes one copy of an item
 }
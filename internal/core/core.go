@@ -13,10 +13,14 @@ import (
 
 const shardCount = 256          // number of shards for sharded locks
 const sampleDeleteKeyCount = 20 // randomly check 20 keys for expiration each second
+// tombstoneTTL is how long a delete is remembered for anti-entropy. A replica that comes back
+// after longer than that may bring the keys deleted meanwhile back.
+const tombstoneTTL = 24 * time.Hour
 
 type cacheShard struct {
-	lock  sync.RWMutex
-	kvmap map[string]data.CacheItem
+	lock    sync.RWMutex
+	kvmap   map[string]data.CacheItem
+	deleted map[string]uint64 // tombstones: the version of the last delete of each key, kept in memory only
 }
 
 type Cache struct {
@@ -56,8 +60,9 @@ func initShardedMap() [shardCount]*cacheShard {
 	shardedMap := [shardCount]*cacheShard{}
 	for i := 0; i < shardCount; i++ {
 		shardedMap[i] = &cacheShard{
-			lock:  sync.RWMutex{},
-			kvmap: make(map[string]data.CacheItem),
+			lock:    sync.RWMutex{},
+			kvmap:   make(map[string]data.CacheItem),
+			deleted: make(map[string]uint64),
 		}
 	}
 	return shardedMap
@@ -84,6 +89,8 @@ func (c *Cache) Load() error {
 func (c *Cache) daemon(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
+	tombstoneTicker := time.NewTicker(time.Minute)
+	defer tombstoneTicker.Stop()
 
 	var snapTickerChan <-chan time.Time
 	if c.persistentType == "file" {
@@ -100,6 +107,8 @@ func (c *Cache) daemon(ctx context.Context) {
 			return
 		case <-ticker.C:
 			c.expireSampling()
+		case <-tombstoneTicker.C:
+			c.purgeTombstones()
 		case <-snapTickerChan: // trigger snapshot every 60 seconds
 			if c.persistentLogger != nil {
 				c.snapMap()
@@ -108,7 +117,7 @@ func (c *Cache) daemon(ctx context.Context) {
 	}
 }
 
-func (c *Cache) Set(key string, value []byte, expiration time.Duration) error {
+func (c *Cache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
@@ -118,38 +127,34 @@ func (c *Cache) Set(key string, value []byte, expiration time.Duration) error {
 		Value:      value,
 		Expiration: time.Now().Add(expiration),
 		Persistent: persistent,
+		Version:    nextVersion(c.shardedMap[index].kvmap[key].Version),
 	}
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return nil
 }
 
-func (c *Cache) Get(key string) ([]byte, bool) {
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.RLock()
 	defer c.shardedMap[index].lock.RUnlock()
 
 	item, exists := c.shardedMap[index].kvmap[key]
-	if exists {
-		if isExpired(item) {
-			return nil, false
-		}
-		return item.Value, true
+	if !exists || isExpired(item) || item.Type != data.TypeString {
+		return nil, false
 	}
-	return nil, false
+	return item.Value, true
 }
 
-func (c *Cache) Del(key string) error {
+func (c *Cache) Del(ctx context.Context, key string) error {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
-	delete(c.shardedMap[index].kvmap, key)
-	// Write to AOF
-	c.delItemLog(key)
+	c.deleteLocked(index, key)
 	return nil
 }
 
-func (c *Cache) Exists(key string) bool {
+func (c *Cache) Exists(ctx context.Context, key string) bool {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.RLock()
 	defer c.shardedMap[index].lock.RUnlock()
@@ -160,9 +165,12 @@ func (c *Cache) Exists(key string) bool {
 	return false
 }
 
-func (c *Cache) Keys() []string {
+func (c *Cache) Keys(ctx context.Context) ([]string, error) {
 	var keys []string
 	for i := 0; i < shardCount; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		c.shardedMap[i].lock.RLock()
 		for key, item := range c.shardedMap[i].kvmap {
 			if !isExpired(item) {
@@ -172,23 +180,24 @@ func (c *Cache) Keys() []string {
 		c.shardedMap[i].lock.RUnlock()
 	}
 
-	return keys
+	return keys, nil
 }
 
-func (c *Cache) Flush() error {
+func (c *Cache) Flush(ctx context.Context) error {
 	for i := 0; i < shardCount; i++ {
+		if err := ctx.Err(); err != nil {
+			return err // shards already cleared stay cleared
+		}
 		c.shardedMap[i].lock.Lock()
 		for key := range c.shardedMap[i].kvmap {
-			// Write to AOF
-			c.delItemLog(key)
+			c.deleteLocked(i, key)
 		}
-		c.shardedMap[i].kvmap = make(map[string]data.CacheItem)
 		c.shardedMap[i].lock.Unlock()
 	}
 	return nil
 }
 
-func (c *Cache) TTL(key string) (time.Duration, bool) {
+func (c *Cache) TTL(ctx context.Context, key string) (time.Duration, bool) {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.RLock()
 	defer c.shardedMap[index].lock.RUnlock()
@@ -206,14 +215,12 @@ func (c *Cache) TTL(key string) (time.Duration, bool) {
 	return remaining, true
 }
 
-func (c *Cache) Expire(key string, expiration time.Duration) error {
+func (c *Cache) Expire(ctx context.Context, key string, expiration time.Duration) error {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
 	if expiration <= 0 {
-		delete(c.shardedMap[index].kvmap, key)
-		// Write to AOF
-		c.delItemLog(key)
+		c.deleteLocked(index, key)
 		return nil
 	}
 
@@ -225,17 +232,16 @@ func (c *Cache) Expire(key string, expiration time.Duration) error {
 	if isExpired(item) {
 		return internal.ErrNotFound
 	}
-	c.shardedMap[index].kvmap[key] = data.CacheItem{
-		Value:      item.Value,
-		Expiration: time.Now().Add(expiration),
-		Persistent: persistent,
-	}
+	item.Expiration = time.Now().Add(expiration)
+	item.Persistent = persistent
+	item.Version = nextVersion(item.Version)
+	c.shardedMap[index].kvmap[key] = item
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return nil
 }
 
-func (c *Cache) Persist(key string) error {
+func (c *Cache) Persist(ctx context.Context, key string) error {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
@@ -243,17 +249,16 @@ func (c *Cache) Persist(key string) error {
 	if !exists || isExpired(item) {
 		return internal.ErrNotFound
 	}
-	c.shardedMap[index].kvmap[key] = data.CacheItem{
-		Value:      item.Value,
-		Expiration: time.Time{},
-		Persistent: true,
-	}
+	item.Expiration = time.Time{}
+	item.Persistent = true
+	item.Version = nextVersion(item.Version)
+	c.shardedMap[index].kvmap[key] = item
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return nil
 }
 
-func (c *Cache) Incr(key string) (int64, error) {
+func (c *Cache) Incr(ctx context.Context, key string) (int64, error) {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
@@ -261,6 +266,9 @@ func (c *Cache) Incr(key string) (int64, error) {
 	if !exists || isExpired(item) {
 		return 0, internal.ErrNotFound
 	}
+	if item.Type != data.TypeString {
+		return 0, internal.ErrWrongType
+	}
 	value, err := util.BytesToInt64(item.Value)
 	if err != nil {
 		return 0, err
@@ -270,13 +278,14 @@ func (c *Cache) Incr(key string) (int64, error) {
 		Value:      util.Int64ToBytes(value),
 		Expiration: item.Expiration,
 		Persistent: item.Persistent,
+		Version:    nextVersion(item.Version),
 	}
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return value, nil
 }
 
-func (c *Cache) Decr(key string) (int64, error) {
+func (c *Cache) Decr(ctx context.Context, key string) (int64, error) {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
@@ -284,6 +293,9 @@ func (c *Cache) Decr(key string) (int64, error) {
 	if !exists || isExpired(item) {
 		return 0, internal.ErrNotFound
 	}
+	if item.Type != data.TypeString {
+		return 0, internal.ErrWrongType
+	}
 	value, err := util.BytesToInt64(item.Value)
 	if err != nil {
 		return 0, err
@@ -293,13 +305,14 @@ func (c *Cache) Decr(key string) (int64, error) {
 		Value:      util.Int64ToBytes(value),
 		Expiration: item.Expiration,
 		Persistent: item.Persistent,
+		Version:    nextVersion(item.Version),
 	}
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return value, nil
 }
 
-func (c *Cache) SetNX(key string, value []byte, expiration time.Duration) (bool, error) {
+func (c *Cache) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
@@ -312,13 +325,14 @@ func (c *Cache) SetNX(key string, value []byte, expiration time.Duration) (bool,
 		Value:      value,
 		Expiration: time.Now().Add(expiration),
 		Persistent: persistent,
+		Version:    nextVersion(item.Version),
 	}
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return true, nil
 }
 
-func (c *Cache) GetSet(key string, value []byte) ([]byte, error) {
+func (c *Cache) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
 	index := c.getShardedIndex(key)
 	c.shardedMap[index].lock.Lock()
 	defer c.shardedMap[index].lock.Unlock()
@@ -327,6 +341,9 @@ func (c *Cache) GetSet(key string, value []byte) ([]byte, error) {
 	var expiration time.Time = time.Now().Add(time.Duration(c.defaultTTL) * time.Second)
 	var persistent bool = false
 	if exists && !isExpired(item) {
+		if item.Type != data.TypeString {
+			return nil, internal.ErrWrongType
+		}
 		oldValue = item.Value
 		persistent = item.Persistent
 		expiration = item.Expiration
@@ -335,13 +352,14 @@ func (c *Cache) GetSet(key string, value []byte) ([]byte, error) {
 		Value:      value,
 		Expiration: expiration,
 		Persistent: persistent,
+		Version:    nextVersion(item.Version),
 	}
 	// Write to AOF
 	c.setItemLog(key, c.shardedMap[index].kvmap[key])
 	return oldValue, nil
 }
 
-func (c *Cache) MGet(keys []string) map[string][]byte {
+func (c *Cache) MGet(ctx context.Context, keys []string) map[string][]byte {
 	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
 	for _, index := range indexList {
 		c.shardedMap[index].lock.RLock()
@@ -350,7 +368,7 @@ func (c *Cache) MGet(keys []string) map[string][]byte {
 	for _, key := range keys {
 		index := c.getShardedIndex(key)
 		item, exists := c.shardedMap[index].kvmap[key]
-		if exists && !isExpired(item) {
+		if exists && !isExpired(item) && item.Type == data.TypeString {
 			result[key] = item.Value
 		}
 	}
@@ -360,7 +378,7 @@ func (c *Cache) MGet(keys []string) map[string][]byte {
 	return result
 }
 
-func (c *Cache) MSet(kv map[string][]byte, expiration time.Duration) error {
+func (c *Cache) MSet(ctx context.Context, kv map[string][]byte, expiration time.Duration) error {
 	keys := make([]string, 0, len(kv))
 	for key := range kv {
 		keys = append(keys, key)
@@ -377,6 +395,7 @@ func (c *Cache) MSet(kv map[string][]byte, expiration time.Duration) error {
 			Value:      value,
 			Expiration: expirationTime,
 			Persistent: persistent,
+			Version:    nextVersion(c.shardedMap[index].kvmap[key].Version),
 		}
 		// Write to AOF
 		c.setItemLog(key, c.shardedMap[index].kvmap[key])
@@ -387,6 +406,168 @@ func (c *Cache) MSet(kv map[string][]byte, expiration time.Duration) error {
 	return nil
 }
 
+// GetEntries returns the stored items of keys, with their expiration, for replication
+func (c *Cache) GetEntries(ctx context.Context, keys []string) map[string]data.CacheItem {
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.RLock()
+	}
+	result := make(map[string]data.CacheItem)
+	for _, key := range keys {
+		index := c.getShardedIndex(key)
+		item, exists := c.shardedMap[index].kvmap[key]
+		if exists && !isExpired(item) {
+			result[key] = item.Clone()
+		}
+	}
+	for j := len(indexList) - 1; j >= 0; j-- {
+		c.shardedMap[indexList[j]].lock.RUnlock()
+	}
+	return result
+}
+
+// SetEntries stores items as they are, keeping the expiration decided by the node that wrote them.
+// An item older than the stored one (lower Version) is ignored, so is one written before the key was deleted.
+func (c *Cache) SetEntries(ctx context.Context, entries map[string]data.CacheItem) error {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.Lock()
+	}
+	for key, item := range entries {
+		index := c.getShardedIndex(key)
+		current, exists := c.shardedMap[index].kvmap[key]
+		if exists && !isExpired(current) && current.Version >= item.Version {
+			continue
+		}
+		if deleted, ok := c.shardedMap[index].deleted[key]; ok && deleted >= item.Version {
+			continue
+		}
+		item = item.Clone() // changed in place from now on, the caller may still hold it
+		c.shardedMap[index].kvmap[key] = item
+		// Write to AOF
+		c.setItemLog(key, item)
+	}
+	for j := len(indexList) - 1; j >= 0; j-- {
+		c.shardedMap[indexList[j]].lock.Unlock()
+	}
+	return nil
+}
+
+// DeleteEntries deletes each key whose stored item is older than its delete version, and remembers
+// the delete like Del does. A key written after the delete is kept.
+func (c *Cache) DeleteEntries(ctx context.Context, versions map[string]uint64) error {
+	keys := make([]string, 0, len(versions))
+	for key := range versions {
+		keys = append(keys, key)
+	}
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.Lock()
+	}
+	for key, version := range versions {
+		index := c.getShardedIndex(key)
+		if item, exists := c.shardedMap[index].kvmap[key]; exists && item.Version >= version {
+			continue
+		}
+		if _, exists := c.shardedMap[index].kvmap[key]; exists {
+			delete(c.shardedMap[index].kvmap, key)
+			// Write to AOF
+			c.delItemLog(key)
+		}
+		c.shardedMap[index].deleted[key] = max(c.shardedMap[index].deleted[key], version)
+	}
+	for j := len(indexList) - 1; j >= 0; j-- {
+		c.shardedMap[indexList[j]].lock.Unlock()
+	}
+	return nil
+}
+
+// DropEntries removes keys handed off to the nodes that replicate them now. Unlike Del it leaves no
+// tombstone, and forgets an older one, so anti-entropy does not take the hand-off for a delete.
+func (c *Cache) DropEntries(ctx context.Context, keys []string) error {
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.Lock()
+	}
+	for _, key := range keys {
+		index := c.getShardedIndex(key)
+		delete(c.shardedMap[index].deleted, key)
+		if _, exists := c.shardedMap[index].kvmap[key]; exists {
+			delete(c.shardedMap[index].kvmap, key)
+			// Write to AOF
+			c.delItemLog(key)
+		}
+	}
+	for j := len(indexList) - 1; j >= 0; j-- {
+		c.shardedMap[indexList[j]].lock.Unlock()
+	}
+	return nil
+}
+
+// GetTombstones returns the delete version of each of keys deleted and not written since
+func (c *Cache) GetTombstones(ctx context.Context, keys []string) map[string]uint64 {
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.RLock()
+	}
+	tombstones := make(map[string]uint64)
+	for _, key := range keys {
+		index := c.getShardedIndex(key)
+		version, deleted := c.shardedMap[index].deleted[key]
+		if item, exists := c.shardedMap[index].kvmap[key]; deleted && (!exists || isExpired(item)) {
+			tombstones[key] = version
+		}
+	}
+	for j := len(indexList) - 1; j >= 0; j-- {
+		c.shardedMap[indexList[j]].lock.RUnlock()
+	}
+	return tombstones
+}
+
+// Tombstones returns the delete version of every key deleted and not written since, for comparing replicas
+func (c *Cache) Tombstones() map[string]uint64 {
+	tombstones := make(map[string]uint64)
+	for i := 0; i < shardCount; i++ {
+		c.shardedMap[i].lock.RLock()
+		for key, version := range c.shardedMap[i].deleted {
+			if item, exists := c.shardedMap[i].kvmap[key]; !exists || isExpired(item) {
+				tombstones[key] = version
+			}
+		}
+		c.shardedMap[i].lock.RUnlock()
+	}
+	return tombstones
+}
+
+// Versions returns the version stamp of every live key, for comparing replicas
+func (c *Cache) Versions() map[string]uint64 {
+	versions := make(map[string]uint64)
+	for i := 0; i < shardCount; i++ {
+		c.shardedMap[i].lock.RLock()
+		for key, item := range c.shardedMap[i].kvmap {
+			if !isExpired(item) {
+				versions[key] = item.Version
+			}
+		}
+		c.shardedMap[i].lock.RUnlock()
+	}
+	return versions
+}
+
+// nextVersion stamps a write with the wall clock in nanoseconds, kept above the previous
+// stamp of the key so a later write on the same node always wins
+func nextVersion(previous uint64) uint64 {
+	now := uint64(time.Now().UnixNano())
+	if now <= previous {
+		return previous + 1
+	}
+	return now
+}
+
 func isExpired(item data.CacheItem) bool {
 	if time.Now().After(item.Expiration) && !item.Persistent {
 		return true
@@ -405,6 +586,19 @@ func (c *Cache) setItemLog(key string, item data.CacheItem) {
 	}
 }
 
+// changeItemLog logs change alone, with the type, expiration and version of the item it was made to
+func (c *Cache) changeItemLog(key string, item data.CacheItem, change data.Change) {
+	if c.persistentType == "file" {
+		// Write to AOF
+		c.persistentLogger.WriteAOF(persistentLogger.Command{
+			Action: "CHANGE",
+			Key:    key,
+			Item:   data.CacheItem{Type: item.Type, Expiration: item.Expiration, Persistent: item.Persistent, Version: item.Version},
+			Change: &change,
+		})
+	}
+}
+
 func (c *Cache) delItemLog(key string) {
 	if c.persistentType == "file" {
 		// Write to AOF
@@ -415,6 +609,30 @@ func (c *Cache) delItemLog(key string) {
 	}
 }
 
+// deleteLocked removes key and leaves a tombstone versioned after the removed item, so a replica that
+// missed the delete is told to drop its copy instead of handing it back. The shard lock must be held.
+func (c *Cache) deleteLocked(index int, key string) {
+	shard := c.shardedMap[index]
+	shard.deleted[key] = nextVersion(max(shard.kvmap[key].Version, shard.deleted[key]))
+	delete(shard.kvmap, key)
+	// Write to AOF
+	c.delItemLog(key)
+}
+
+// purgeTombstones forgets the deletes older than tombstoneTTL
+func (c *Cache) purgeTombstones() {
+	cutoff := uint64(time.Now().Add(-tombstoneTTL).UnixNano())
+	for i := 0; i < shardCount; i++ {
+		c.shardedMap[i].lock.Lock()
+		for key, version := range c.shardedMap[i].deleted {
+			if version < cutoff {
+				delete(c.shardedMap[i].deleted, key)
+			}
+		}
+		c.shardedMap[i].lock.Unlock()
+	}
+}
+
 func (c *Cache) expireSampling() {
 	indexList := util.GetRandomShardIndex(shardCount, sampleDeleteKeyCount)
 	for _, index := range indexList {
@@ -444,13 +662,18 @@ func (c *Cache) expireSampling() {
 }
 
 func (c *Cache) snapMap() {
+	c.persistentLogger.TriggerSnap(c.snapItems)
+}
+
+// snapItems copies every item for a snapshot, the snapshot is written after the locks are released
+func (c *Cache) snapItems() map[string]data.CacheItem {
 	c.KVMap = make(map[string]data.CacheItem)
 	for i := 0; i < shardCount; i++ {
 		c.shardedMap[i].lock.RLock()
 		for key, item := range c.shardedMap[i].kvmap {
-			c.KVMap[key] = item
+			c.KVMap[key] = item.Clone()
 		}
 		c.shardedMap[i].lock.RUnlock()
 	}
-	c.persistentLogger.TriggerSnap(c.KVMap)
+	return c.KVMap
 }
@@ -1,13 +1,21 @@
 package core
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
 	"os"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
 	"go-cache-server-mini/internal"
 	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core/data"
 )
 
 func newTestCache(t *testing.T) *Cache {
@@ -28,20 +36,20 @@ func newTestCache(t *testing.T) *Cache {
 func TestCacheBasicOperations(t *testing.T) {
 	cache := newTestCache(t)
 
-	if err := cache.Set("foo", []byte("bar"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "foo", []byte("bar"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
-	value, ok := cache.Get("foo")
+	value, ok := cache.Get(t.Context(), "foo")
 	if !ok || string(value) != "bar" {
 		t.Fatalf("Get returned unexpected result, ok=%v value=%s", ok, value)
 	}
 
-	if err := cache.Del("foo"); err != nil {
+	if err := cache.Del(t.Context(), "foo"); err != nil {
 		t.Fatalf("Del returned error: %v", err)
 	}
 
-	if _, ok := cache.Get("foo"); ok {
+	if _, ok := cache.Get(t.Context(), "foo"); ok {
 		t.Fatalf("expected key to be deleted")
 	}
 }
@@ -49,68 +57,71 @@ func TestCacheBasicOperations(t *testing.T) {
 func TestCacheExistsKeysAndFlush(t *testing.T) {
 	cache := newTestCache(t)
 
-	if cache.Exists("missing") {
+	if cache.Exists(t.Context(), "missing") {
 		t.Fatalf("missing key should not exist")
 	}
 
-	if err := cache.Set("a", []byte("1"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "a", []byte("1"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
-	if err := cache.Set("b", []byte("2"), 5*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "b", []byte("2"), 5*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
-	if !cache.Exists("a") || !cache.Exists("b") {
+	if !cache.Exists(t.Context(), "a") || !cache.Exists(t.Context(), "b") {
 		t.Fatalf("expected keys to exist")
 	}
 
-	keys := cache.Keys()
+	keys, err := cache.Keys(t.Context())
+	if err != nil {
+		t.Fatalf("Keys returned error: %v", err)
+	}
 	if len(keys) != 2 {
 		t.Fatalf("expected 2 keys, got %d", len(keys))
 	}
 
-	if err := cache.Flush(); err != nil {
+	if err := cache.Flush(t.Context()); err != nil {
 		t.Fatalf("Flush returned error: %v", err)
 	}
 
-	if cache.Exists("a") || cache.Exists("b") {
+	if cache.Exists(t.Context(), "a") || cache.Exists(t.Context(), "b") {
 		t.Fatalf("keys should be removed after flush")
 	}
 }
 
 func TestCacheTTLExpireAndPersist(t *testing.T) {
 	cache := newTestCache(t)
-	if err := cache.Set("ttl", []byte("value"), 2*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "ttl", []byte("value"), 2*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
-	ttl, ok := cache.TTL("ttl")
+	ttl, ok := cache.TTL(t.Context(), "ttl")
 	if !ok || ttl <= 0 {
 		t.Fatalf("TTL should return positive value, got %v (ok=%v)", ttl, ok)
 	}
 
-	if err := cache.Expire("ttl", 5*time.Second); err != nil {
+	if err := cache.Expire(t.Context(), "ttl", 5*time.Second); err != nil {
 		t.Fatalf("Expire returned error: %v", err)
 	}
 
-	ttl, ok = cache.TTL("ttl")
+	ttl, ok = cache.TTL(t.Context(), "ttl")
 	if !ok || ttl <= 0 || ttl > 5*time.Second {
 		t.Fatalf("Expire should update TTL close to requested duration, got %v", ttl)
 	}
 
-	if err := cache.Persist("ttl"); err != nil {
+	if err := cache.Persist(t.Context(), "ttl"); err != nil {
 		t.Fatalf("Persist returned error: %v", err)
 	}
 
-	ttl, ok = cache.TTL("ttl")
+	ttl, ok = cache.TTL(t.Context(), "ttl")
 	if !ok || ttl != -1 {
 		t.Fatalf("Persisted key should have ttl -1, got %v", ttl)
 	}
 
-	if err := cache.Del("ttl"); err != nil {
+	if err := cache.Del(t.Context(), "ttl"); err != nil {
 		t.Fatalf("Del returned error: %v", err)
 	}
-	if err := cache.Expire("ttl", time.Second); err != internal.ErrNotFound {
+	if err := cache.Expire(t.Context(), "ttl", time.Second); err != internal.ErrNotFound {
 		t.Fatalf("Expire should return ErrNotFound for missing key, got %v", err)
 	}
 }
@@ -118,19 +129,19 @@ func TestCacheTTLExpireAndPersist(t *testing.T) {
 func TestCacheIncrDecr(t *testing.T) {
 	cache := newTestCache(t)
 
-	if err := cache.Set("counter", []byte("1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "counter", []byte("1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
-	if value, err := cache.Incr("counter"); err != nil || value != 2 {
+	if value, err := cache.Incr(t.Context(), "counter"); err != nil || value != 2 {
 		t.Fatalf("Incr expected 2, got value=%d err=%v", value, err)
 	}
 
-	if value, err := cache.Decr("counter"); err != nil || value != 1 {
+	if value, err := cache.Decr(t.Context(), "counter"); err != nil || value != 1 {
 		t.Fatalf("Decr expected 1, got value=%d err=%v", value, err)
 	}
 
-	if _, err := cache.Incr("missing"); err != internal.ErrNotFound {
+	if _, err := cache.Incr(t.Context(), "missing"); err != internal.ErrNotFound {
 		t.Fatalf("Incr should fail with ErrNotFound for missing key, got %v", err)
 	}
 }
@@ -138,12 +149,12 @@ func TestCacheIncrDecr(t *testing.T) {
 func TestCacheSetNX(t *testing.T) {
 	cache := newTestCache(t)
 
-	ok, err := cache.SetNX("nx", []byte("first"), 0)
+	ok, err := cache.SetNX(t.Context(), "nx", []byte("first"), 0)
 	if err != nil || !ok {
 		t.Fatalf("SetNX first call expected true, got ok=%v err=%v", ok, err)
 	}
 
-	ok, err = cache.SetNX("nx", []byte("second"), 0)
+	ok, err = cache.SetNX(t.Context(), "nx", []byte("second"), 0)
 	if err != nil {
 		t.Fatalf("SetNX second call returned error: %v", err)
 	}
@@ -151,7 +162,7 @@ func TestCacheSetNX(t *testing.T) {
 		t.Fatalf("SetNX should return false when key exists")
 	}
 
-	val, exists := cache.Get("nx")
+	val, exists := cache.Get(t.Context(), "nx")
 	if !exists || string(val) != "first" {
 		t.Fatalf("SetNX should not overwrite existing value, got %s", val)
 	}
@@ -164,11 +175,11 @@ func TestCacheMGetMSet(t *testing.T) {
 		"b": []byte("2"),
 	}
 
-	if err := cache.MSet(payload, 0); err != nil {
+	if err := cache.MSet(t.Context(), payload, 0); err != nil {
 		t.Fatalf("MSet returned error: %v", err)
 	}
 
-	result := cache.MGet([]string{"a", "b", "c"})
+	result := cache.MGet(t.Context(), []string{"a", "b", "c"})
 	if len(result) != 2 {
 		t.Fatalf("expected 2 keys, got %d", len(result))
 	}
@@ -183,13 +194,13 @@ func TestCacheMGetMSet(t *testing.T) {
 func TestCacheGetSetPreservesTTL(t *testing.T) {
 	cache := newTestCache(t)
 
-	if err := cache.Set("ttl-key", []byte("v1"), 2*time.Second); err != nil {
+	if err := cache.Set(t.Context(), "ttl-key", []byte("v1"), 2*time.Second); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
 
 	time.Sleep(50 * time.Millisecond)
 
-	before, ok := cache.TTL("ttl-key")
+	before, ok := cache.TTL(t.Context(), "ttl-key")
 	if !ok {
 		t.Fatalf("TTL reported missing key before GetSet")
 	}
@@ -197,11 +208,11 @@ func TestCacheGetSetPreservesTTL(t *testing.T) {
 		t.Fatalf("unexpected TTL before GetSet: %v", before)
 	}
 
-	if _, err := cache.GetSet("ttl-key", []byte("v2")); err != nil {
+	if _, err := cache.GetSet(t.Context(), "ttl-key", []byte("v2")); err != nil {
 		t.Fatalf("GetSet returned error: %v", err)
 	}
 
-	after, ok := cache.TTL("ttl-key")
+	after, ok := cache.TTL(t.Context(), "ttl-key")
 	if !ok {
 		t.Fatalf("TTL reported missing key after GetSet")
 	}
@@ -216,18 +227,18 @@ func TestCacheGetSetPreservesTTL(t *testing.T) {
 func TestCacheGetSetRespectsPersistence(t *testing.T) {
 	cache := newTestCache(t)
 
-	if err := cache.Set("persist-key", []byte("v1"), 0); err != nil {
+	if err := cache.Set(t.Context(), "persist-key", []byte("v1"), 0); err != nil {
 		t.Fatalf("Set returned error: %v", err)
 	}
-	if err := cache.Persist("persist-key"); err != nil {
+	if err := cache.Persist(t.Context(), "persist-key"); err != nil {
 		t.Fatalf("Persist returned error: %v", err)
 	}
 
-	if _, err := cache.GetSet("persist-key", []byte("v2")); err != nil {
+	if _, err := cache.GetSet(t.Context(), "persist-key", []byte("v2")); err != nil {
 		t.Fatalf("GetSet returned error: %v", err)
 	}
 
-	ttl, ok := cache.TTL("persist-key")
+	ttl, ok := cache.TTL(t.Context(), "persist-key")
 	if !ok {
 		t.Fatalf("TTL reported missing persistent key")
 	}
@@ -235,3 +246,1149 @@ func TestCacheGetSetRespectsPersistence(t *testing.T) {
 		t.Fatalf("persistent key TTL should be -1, got %v", ttl)
 	}
 }
+
+func TestCacheGetEntriesSetEntries(t *testing.T) {
+	cache := newTestCache(t)
+	if err := cache.Set(t.Context(), "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := cache.Set(t.Context(), "b", []byte("2"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := cache.Persist(t.Context(), "b"); err != nil {
+		t.Fatalf("Persist returned error: %v", err)
+	}
+
+	entries := cache.GetEntries(t.Context(), []string{"a", "b", "missing"})
+	if len(entries) != 2 || !entries["b"].Persistent {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	if err := cache.Flush(t.Context()); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	// a copy written before its key was deleted does not bring it back
+	if err := cache.SetEntries(t.Context(), entries); err != nil {
+		t.Fatalf("SetEntries returned error: %v", err)
+	}
+	if cache.Exists(t.Context(), "a") || cache.Exists(t.Context(), "b") {
+		t.Fatal("expected copies older than the delete to be ignored")
+	}
+	if tombstones := cache.GetTombstones(t.Context(), []string{"a", "b", "missing"}); len(tombstones) != 2 || tombstones["a"] <= entries["a"].Version {
+		t.Fatalf("expected the deletes of a and b to be reported, got %v", tombstones)
+	}
+
+	if err := cache.SetEntries(t.Context(), map[string]data.CacheItem{"copy-a": entries["a"], "copy-b": entries["b"]}); err != nil {
+		t.Fatalf("SetEntries returned error: %v", err)
+	}
+	restored := cache.GetEntries(t.Context(), []string{"copy-a", "copy-b"})
+	if !restored["copy-a"].Expiration.Equal(entries["a"].Expiration) || string(restored["copy-a"].Value) != "1" {
+		t.Fatalf("expected entry a to keep its expiration, got %+v", restored["copy-a"])
+	}
+	if ttl, ok := cache.TTL(t.Context(), "copy-b"); !ok || ttl != -1 {
+		t.Fatalf("expected b to stay persistent, got ttl=%v ok=%v", ttl, ok)
+	}
+}
+
+func listStrings(values [][]byte) []string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = string(value)
+	}
+	return result
+}
+
+func TestCacheListOperations(t *testing.T) {
+	cache := newTestCache(t)
+
+	if length, err := cache.RPush(t.Context(), "queue", [][]byte{[]byte("b"), []byte("c")}); err != nil || length != 2 {
+		t.Fatalf("RPush returned length=%d err=%v", length, err)
+	}
+	if length, err := cache.LPush(t.Context(), "queue", [][]byte{[]byte("a"), []byte("z")}); err != nil || length != 4 {
+		t.Fatalf("LPush returned length=%d err=%v", length, err)
+	}
+	values, err := cache.LRange(t.Context(), "queue", 0, -1)
+	if err != nil || !slices.Equal(listStrings(values), []string{"z", "a", "b", "c"}) {
+		t.Fatalf("LRange returned %q err=%v", listStrings(values), err)
+	}
+	if value, ok, err := cache.LIndex(t.Context(), "queue", -1); err != nil || !ok || string(value) != "c" {
+		t.Fatalf("LIndex(-1) returned %s ok=%v err=%v", value, ok, err)
+	}
+	if _, ok, _ := cache.LIndex(t.Context(), "queue", 4); ok {
+		t.Fatal("expected an index past the tail to be missing")
+	}
+
+	if values, err := cache.LPop(t.Context(), "queue", 1); err != nil || !slices.Equal(listStrings(values), []string{"z"}) {
+		t.Fatalf("LPop returned %q err=%v", listStrings(values), err)
+	}
+	if values, err := cache.RPop(t.Context(), "queue", 2); err != nil || !slices.Equal(listStrings(values), []string{"c", "b"}) {
+		t.Fatalf("RPop returned %q err=%v", listStrings(values), err)
+	}
+	if length, err := cache.LLen(t.Context(), "queue"); err != nil || length != 1 {
+		t.Fatalf("LLen returned %d err=%v", length, err)
+	}
+
+	// an emptied list is deleted
+	if err := cache.LTrim(t.Context(), "queue", 1, -1); err != nil {
+		t.Fatalf("LTrim returned error: %v", err)
+	}
+	if cache.Exists(t.Context(), "queue") {
+		t.Fatal("expected the emptied list to be deleted")
+	}
+	if _, err := cache.LPop(t.Context(), "queue", 1); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound popping a missing list, got %v", err)
+	}
+
+	if err := cache.Set(t.Context(), "plain", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if _, err := cache.RPush(t.Context(), "plain", [][]byte{[]byte("x")}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType pushing to a string, got %v", err)
+	}
+	cache.RPush(t.Context(), "list", [][]byte{[]byte("1")})
+	if _, err := cache.Incr(t.Context(), "list"); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType incrementing a list, got %v", err)
+	}
+	if _, ok := cache.Get(t.Context(), "list"); ok {
+		t.Fatal("expected Get to skip a list")
+	}
+	if err := cache.Expire(t.Context(), "list", time.Minute); err != nil {
+		t.Fatalf("Expire returned error: %v", err)
+	}
+	if length, _ := cache.LLen(t.Context(), "list"); length != 1 {
+		t.Fatalf("expected Expire to keep the list, got length %d", length)
+	}
+}
+
+func TestCacheHashOperations(t *testing.T) {
+	cache := newTestCache(t)
+
+	added, err := cache.HSet(t.Context(), "session", map[string][]byte{"user": []byte(`"kim"`), "visits": []byte("1")})
+	if err != nil || added != 2 {
+		t.Fatalf("HSet returned added=%d err=%v", added, err)
+	}
+	if added, _ := cache.HSet(t.Context(), "session", map[string][]byte{"user": []byte(`"lee"`), "theme": []byte(`"dark"`)}); added != 1 {
+		t.Fatalf("expected only theme to be new, got %d", added)
+	}
+	if value, ok, err := cache.HGet(t.Context(), "session", "user"); err != nil || !ok || string(value) != `"lee"` {
+		t.Fatalf("HGet returned %s ok=%v err=%v", value, ok, err)
+	}
+	if value, err := cache.HIncrBy(t.Context(), "session", "visits", 4); err != nil || value != 5 {
+		t.Fatalf("HIncrBy returned %d err=%v", value, err)
+	}
+	if value, err := cache.HIncrBy(t.Context(), "session", "logins", -1); err != nil || value != -1 {
+		t.Fatalf("HIncrBy on a new field returned %d err=%v", value, err)
+	}
+	if _, err := cache.HIncrBy(t.Context(), "session", "user", 1); err == nil {
+		t.Fatal("expected HIncrBy to fail on a non-integer field")
+	}
+	fields, err := cache.HMGet(t.Context(), "session", []string{"theme", "missing"})
+	if err != nil || len(fields) != 1 || string(fields["theme"]) != `"dark"` {
+		t.Fatalf("HMGet returned %v err=%v", fields, err)
+	}
+	if length, _ := cache.HLen(t.Context(), "session"); length != 4 {
+		t.Fatalf("expected 4 fields, got %d", length)
+	}
+
+	if deleted, err := cache.HDel(t.Context(), "session", []string{"theme", "missing"}); err != nil || deleted != 1 {
+		t.Fatalf("HDel returned deleted=%d err=%v", deleted, err)
+	}
+	if exists, _ := cache.HExists(t.Context(), "session", "theme"); exists {
+		t.Fatal("expected theme to be deleted")
+	}
+	cache.HDel(t.Context(), "session", []string{"user", "visits", "logins"})
+	if cache.Exists(t.Context(), "session") {
+		t.Fatal("expected the hash to be deleted with its last field")
+	}
+	if all, err := cache.HGetAll(t.Context(), "session"); err != nil || len(all) != 0 {
+		t.Fatalf("expected an empty hash, got %v err=%v", all, err)
+	}
+
+	cache.RPush(t.Context(), "list", [][]byte{[]byte("1")})
+	if _, err := cache.HSet(t.Context(), "list", map[string][]byte{"a": []byte("1")}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType setting a field of a list, got %v", err)
+	}
+}
+
+func TestCacheSetOperations(t *testing.T) {
+	cache := newTestCache(t)
+
+	added, err := cache.SAdd(t.Context(), "tags:a", []string{"go", "redis", "go"})
+	if err != nil || added != 2 {
+		t.Fatalf("SAdd returned added=%d err=%v", added, err)
+	}
+	cache.SAdd(t.Context(), "tags:b", []string{"redis", "rust"})
+	if ok, err := cache.SIsMember(t.Context(), "tags:a", "go"); err != nil || !ok {
+		t.Fatalf("SIsMember returned %v err=%v", ok, err)
+	}
+	if members, _ := cache.SMembers(t.Context(), "tags:a"); !slices.Equal(members, []string{"go", "redis"}) {
+		t.Fatalf("SMembers returned %v", members)
+	}
+	if union, _ := cache.SUnion(t.Context(), []string{"tags:a", "tags:b", "missing"}); !slices.Equal(union, []string{"go", "redis", "rust"}) {
+		t.Fatalf("SUnion returned %v", union)
+	}
+	if inter, _ := cache.SInter(t.Context(), []string{"tags:a", "tags:b"}); !slices.Equal(inter, []string{"redis"}) {
+		t.Fatalf("SInter returned %v", inter)
+	}
+	if diff, _ := cache.SDiff(t.Context(), []string{"tags:a", "tags:b"}); !slices.Equal(diff, []string{"go"}) {
+		t.Fatalf("SDiff returned %v", diff)
+	}
+	if random, _ := cache.SRandMember(t.Context(), "tags:b", 5); len(random) != 2 {
+		t.Fatalf("expected SRandMember to return both members, got %v", random)
+	}
+	if card, _ := cache.SCard(t.Context(), "tags:b"); card != 2 {
+		t.Fatalf("expected SRandMember to keep the members, got %d", card)
+	}
+
+	popped, err := cache.SPop(t.Context(), "tags:b", 1)
+	if err != nil || len(popped) != 1 {
+		t.Fatalf("SPop returned %v err=%v", popped, err)
+	}
+	if removed, _ := cache.SRem(t.Context(), "tags:b", []string{"redis", "rust"}); removed != 1 {
+		t.Fatalf("expected SRem to remove the member left, got %d", removed)
+	}
+	if cache.Exists(t.Context(), "tags:b") {
+		t.Fatal("expected the set to be deleted with its last member")
+	}
+	if _, err := cache.SPop(t.Context(), "tags:b", 1); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound popping a missing set, got %v", err)
+	}
+
+	cache.RPush(t.Context(), "list", [][]byte{[]byte("1")})
+	if _, err := cache.SAdd(t.Context(), "list", []string{"a"}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType adding to a list, got %v", err)
+	}
+	if _, err := cache.SUnion(t.Context(), []string{"tags:a", "list"}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType combining a list, got %v", err)
+	}
+}
+
+func TestCacheSortedSetOperations(t *testing.T) {
+	cache := newTestCache(t)
+
+	board := []data.ScoredMember{{Member: "kim", Score: 30}, {Member: "lee", Score: 10}, {Member: "park", Score: 20}, {Member: "choi", Score: 20}}
+	if added, err := cache.ZAdd(t.Context(), "board", board, data.ZAddOptions{}); err != nil || added != 4 {
+		t.Fatalf("ZAdd returned added=%d err=%v", added, err)
+	}
+	if added, _ := cache.ZAdd(t.Context(), "board", []data.ScoredMember{{Member: "kim", Score: 5}, {Member: "jung", Score: 1}}, data.ZAddOptions{GT: true}); added != 1 {
+		t.Fatalf("expected GT to only add jung, got %d", added)
+	}
+	if score, _, _ := cache.ZScore(t.Context(), "board", "kim"); score != 30 {
+		t.Fatalf("expected GT to keep kim at 30, got %v", score)
+	}
+	if added, _ := cache.ZAdd(t.Context(), "board", []data.ScoredMember{{Member: "lee", Score: 40}, {Member: "yoon", Score: 1}}, data.ZAddOptions{XX: true}); added != 0 {
+		t.Fatalf("expected XX to add nothing, got %d", added)
+	}
+	if _, err := cache.ZAdd(t.Context(), "board", board, data.ZAddOptions{NX: true, GT: true}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for nx with gt, got %v", err)
+	}
+	if score, err := cache.ZIncrBy(t.Context(), "board", "jung", 2.5); err != nil || score != 3.5 {
+		t.Fatalf("ZIncrBy returned %v err=%v", score, err)
+	}
+
+	// jung 3.5, choi 20, park 20, kim 30, lee 40
+	if rank, ok, _ := cache.ZRank(t.Context(), "board", "park", false); !ok || rank != 2 {
+		t.Fatalf("ZRank returned %d ok=%v", rank, ok)
+	}
+	if rank, ok, _ := cache.ZRank(t.Context(), "board", "lee", true); !ok || rank != 0 {
+		t.Fatalf("ZRank rev returned %d ok=%v", rank, ok)
+	}
+	queries := []struct {
+		query data.ZRangeQuery
+		want  []string
+	}{
+		{data.ZRangeQuery{Start: "0", Stop: "1", Count: -1}, []string{"jung", "choi"}},
+		{data.ZRangeQuery{Start: "0", Stop: "1", Rev: true, Count: -1}, []string{"lee", "kim"}},
+		{data.ZRangeQuery{By: data.ZRangeByScore, Start: "(3.5", Stop: "30", Count: -1}, []string{"choi", "park", "kim"}},
+		{data.ZRangeQuery{By: data.ZRangeByScore, Start: "+inf", Stop: "20", Rev: true, Offset: 1, Count: 2}, []string{"kim", "park"}},
+	}
+	for _, tc := range queries {
+		members, err := cache.ZRange(t.Context(), "board", tc.query)
+		if err != nil || !slices.Equal(memberNames(members), tc.want) {
+			t.Fatalf("ZRange %+v returned %v err=%v, want %v", tc.query, memberNames(members), err, tc.want)
+		}
+	}
+	cache.ZAdd(t.Context(), "names", []data.ScoredMember{{Member: "b"}, {Member: "a"}, {Member: "c"}, {Member: "d"}}, data.ZAddOptions{})
+	if members, _ := cache.ZRange(t.Context(), "names", data.ZRangeQuery{By: data.ZRangeByLex, Start: "(a", Stop: "[c", Count: -1}); !slices.Equal(memberNames(members), []string{"b", "c"}) {
+		t.Fatalf("ZRange by lex returned %v", memberNames(members))
+	}
+	if _, err := cache.ZRange(t.Context(), "board", data.ZRangeQuery{By: data.ZRangeByScore, Start: "low", Count: -1}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for a bad score bound, got %v", err)
+	}
+
+	if removed, err := cache.ZRemRangeByScore(t.Context(), "board", "-inf", "(20"); err != nil || removed != 1 {
+		t.Fatalf("ZRemRangeByScore returned %d err=%v", removed, err)
+	}
+	popped, err := cache.ZPopMax(t.Context(), "board", 2)
+	if err != nil || !slices.Equal(memberNames(popped), []string{"lee", "kim"}) {
+		t.Fatalf("ZPopMax returned %v err=%v", popped, err)
+	}
+	if popped, _ := cache.ZPopMin(t.Context(), "board", 1); !slices.Equal(memberNames(popped), []string{"choi"}) {
+		t.Fatalf("ZPopMin returned %v", popped)
+	}
+	if removed, _ := cache.ZRem(t.Context(), "board", []string{"park", "missing"}); removed != 1 {
+		t.Fatalf("expected ZRem to remove park, got %d", removed)
+	}
+	if cache.Exists(t.Context(), "board") {
+		t.Fatal("expected the sorted set to be deleted with its last member")
+	}
+	if _, err := cache.ZPopMin(t.Context(), "board", 1); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound popping a missing sorted set, got %v", err)
+	}
+
+	cache.SAdd(t.Context(), "set", []string{"a"})
+	if _, err := cache.ZCard(t.Context(), "set"); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType reading a set as a sorted set, got %v", err)
+	}
+}
+
+func TestSortedSetMatchesSortedSlice(t *testing.T) {
+	zset := data.NewSortedSet()
+	scores := make(map[string]float64)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		member := strconv.Itoa(rng.Intn(300))
+		if rng.Intn(3) == 0 {
+			zset.Remove(member)
+			delete(scores, member)
+		} else {
+			score := float64(rng.Intn(50))
+			zset.Add(member, score)
+			scores[member] = score
+		}
+		if i%100 == 0 {
+			zset = zset.Clone()
+		}
+	}
+	want := make([]data.ScoredMember, 0, len(scores))
+	for member, score := range scores {
+		want = append(want, data.ScoredMember{Member: member, Score: score})
+	}
+	slices.SortFunc(want, func(a, b data.ScoredMember) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Member, b.Member))
+	})
+	if got := zset.Range(0, zset.Len()); !slices.Equal(got, want) {
+		t.Fatalf("expected the skiplist to hold %d members in order, got %d", len(want), len(got))
+	}
+	for rank, member := range want {
+		if got, ok := zset.Rank(member.Member, false); !ok || got != rank {
+			t.Fatalf("expected %s at rank %d, got %d", member.Member, rank, got)
+		}
+		if got := zset.Range(rank, rank+1); got[0] != member {
+			t.Fatalf("expected rank %d to hold %v, got %v", rank, member, got)
+		}
+	}
+	from, to := zset.ScoreRanks(data.ScoreBound{Score: 10}, data.ScoreBound{Score: 20, Exclusive: true})
+	for rank, member := range want {
+		if inside := member.Score >= 10 && member.Score < 20; inside != (rank >= from && rank < to) {
+			t.Fatalf("score range [10, 20) returned ranks %d..%d, wrong for %v at %d", from, to, member, rank)
+		}
+	}
+}
+
+func TestCacheStreamOperations(t *testing.T) {
+	cache := newTestCache(t)
+	event := func(value string) map[string][]byte {
+		return map[string][]byte{"event": []byte(value)}
+	}
+
+	first, err := cache.XAdd(t.Context(), "events", "*", event(`"a"`), data.StreamTrim{})
+	if err != nil {
+		t.Fatalf("XAdd returned error: %v", err)
+	}
+	second, _ := cache.XAdd(t.Context(), "events", "*", event(`"b"`), data.StreamTrim{})
+	if id, _ := data.ParseStreamID(second); id.Compare(mustStreamID(t, first)) <= 0 {
+		t.Fatalf("expected generated IDs to grow, got %s then %s", first, second)
+	}
+	if _, err := cache.XAdd(t.Context(), "events", "1-1", event(`"old"`), data.StreamTrim{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for an ID below the last one, got %v", err)
+	}
+	for _, value := range []string{`"c"`, `"d"`} {
+		cache.XAdd(t.Context(), "events", "*", event(value), data.StreamTrim{})
+	}
+
+	entries, err := cache.XRange(t.Context(), "events", "("+first, "+", 2)
+	if err != nil || len(entries) != 2 || entries[0].ID.String() != second || string(entries[1].Fields["event"]) != `"c"` {
+		t.Fatalf("XRange returned %v err=%v", entries, err)
+	}
+
+	if err := cache.XGroupCreate(t.Context(), "events", "workers", "0", false); err != nil {
+		t.Fatalf("XGroupCreate returned error: %v", err)
+	}
+	if err := cache.XGroupCreate(t.Context(), "events", "workers", "0", false); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for an existing group, got %v", err)
+	}
+	if err := cache.XGroupCreate(t.Context(), "missing", "workers", "$", false); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound without mkstream, got %v", err)
+	}
+	delivered, err := cache.XReadGroup(t.Context(), "events", "workers", "w1", ">", 3)
+	if err != nil || len(delivered) != 3 || delivered[0].ID.String() != first {
+		t.Fatalf("XReadGroup returned %v err=%v", delivered, err)
+	}
+	if next, _ := cache.XReadGroup(t.Context(), "events", "workers", "w2", ">", 0); len(next) != 1 || string(next[0].Fields["event"]) != `"d"` {
+		t.Fatalf("expected w2 to get the entry left, got %v", next)
+	}
+	if acked, err := cache.XAck(t.Context(), "events", "workers", []string{first, first}); err != nil || acked != 1 {
+		t.Fatalf("XAck returned %d err=%v", acked, err)
+	}
+	pending, err := cache.XPending(t.Context(), "events", "workers")
+	if err != nil || len(pending) != 3 || pending[0].ID.String() != second || pending[0].Consumer != "w1" || pending[2].Consumer != "w2" {
+		t.Fatalf("XPending returned %+v err=%v", pending, err)
+	}
+
+	if trimmed, err := cache.XTrim(t.Context(), "events", data.StreamTrim{MaxLen: new(int)}); err != nil || trimmed != 4 {
+		t.Fatalf("XTrim returned %d err=%v", trimmed, err)
+	}
+	if length, _ := cache.XLen(t.Context(), "events"); length != 0 || !cache.Exists(t.Context(), "events") {
+		t.Fatalf("expected an empty stream to keep its key, got length %d", length)
+	}
+	history, err := cache.XReadGroup(t.Context(), "events", "workers", "w1", "0", 0)
+	if err != nil || len(history) != 2 || history[0].Fields != nil {
+		t.Fatalf("expected w1 to reread its trimmed pending entries without fields, got %v err=%v", history, err)
+	}
+	if _, err := cache.XAdd(t.Context(), "events", second, event(`"again"`), data.StreamTrim{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected a trimmed stream to keep its last ID, got %v", err)
+	}
+	if _, err := cache.XReadGroup(t.Context(), "events", "nobody", "w1", ">", 0); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing group, got %v", err)
+	}
+}
+
+func mustStreamID(t *testing.T, id string) data.StreamID {
+	t.Helper()
+	parsed, err := data.ParseStreamID(id)
+	if err != nil {
+		t.Fatalf("ParseStreamID(%q) returned error: %v", id, err)
+	}
+	return parsed
+}
+
+func TestCacheBitmapOperations(t *testing.T) {
+	cache := newTestCache(t)
+
+	if previous, err := cache.SetBit(t.Context(), "visits", 7, 1); err != nil || previous != 0 {
+		t.Fatalf("SetBit returned %d err=%v", previous, err)
+	}
+	if previous, _ := cache.SetBit(t.Context(), "visits", 7, 1); previous != 1 {
+		t.Fatalf("expected SetBit to return the replaced bit 1, got %d", previous)
+	}
+	if value, _ := cache.Get(t.Context(), "visits"); string(value) != "\x01" {
+		t.Fatalf("expected bit 7 to be the low bit of the first byte, got %q", value)
+	}
+	if bit, err := cache.GetBit(t.Context(), "visits", 100); err != nil || bit != 0 {
+		t.Fatalf("expected 0 past the end, got %d err=%v", bit, err)
+	}
+	if _, err := cache.SetBit(t.Context(), "visits", data.MaxBitOffset+1, 1); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for an offset out of range, got %v", err)
+	}
+
+	cache.Set(t.Context(), "bits", []byte("\xff\xf0\x00"), 0)
+	first, last := int64(1), int64(11)
+	for _, tc := range []struct {
+		bitRange data.BitRange
+		want     int64
+	}{
+		{data.BitRange{}, 12},
+		{data.BitRange{Start: 1, End: &first}, 4},
+		{data.BitRange{Start: 4, End: &last, Bit: true}, 8},
+		{data.BitRange{Start: -1}, 0},
+	} {
+		if count, err := cache.BitCount(t.Context(), "bits", tc.bitRange); err != nil || count != tc.want {
+			t.Fatalf("BitCount(%+v) returned %d err=%v, want %d", tc.bitRange, count, err, tc.want)
+		}
+	}
+	if position, _ := cache.BitPos(t.Context(), "bits", 0, data.BitRange{}); position != 12 {
+		t.Fatalf("expected the first clear bit at 12, got %d", position)
+	}
+	if position, _ := cache.BitPos(t.Context(), "bits", 1, data.BitRange{Start: 2}); position != -1 {
+		t.Fatalf("expected no set bit in the last byte, got %d", position)
+	}
+	cache.Set(t.Context(), "full", []byte("\xff"), 0)
+	if position, _ := cache.BitPos(t.Context(), "full", 0, data.BitRange{}); position != 8 {
+		t.Fatalf("expected a clear bit right past the value, got %d", position)
+	}
+	if position, _ := cache.BitPos(t.Context(), "full", 0, data.BitRange{End: new(int64)}); position != -1 {
+		t.Fatalf("expected no clear bit with an explicit end, got %d", position)
+	}
+
+	cache.Set(t.Context(), "a", []byte("\x0f"), 0)
+	cache.Set(t.Context(), "b", []byte("\xff\x01"), 0)
+	for _, tc := range []struct{ op, want string }{
+		{data.BitAnd, "\x0f\x00"},
+		{data.BitOr, "\xff\x01"},
+		{data.BitXor, "\xf0\x01"},
+	} {
+		length, err := cache.BitOp(t.Context(), tc.op, "dest", []string{"a", "b"})
+		if value, _ := cache.Get(t.Context(), "dest"); err != nil || length != 2 || string(value) != tc.want {
+			t.Fatalf("BitOp(%s) stored %q length %d err=%v, want %q", tc.op, value, length, err, tc.want)
+		}
+	}
+	if _, err := cache.BitOp(t.Context(), data.BitNot, "dest", []string{"a"}); err != nil {
+		t.Fatalf("BitOp(not) returned error: %v", err)
+	}
+	if value, _ := cache.Get(t.Context(), "dest"); string(value) != "\xf0" {
+		t.Fatalf("expected not of a, got %q", value)
+	}
+	if _, err := cache.BitOp(t.Context(), data.BitNot, "dest", []string{"a", "b"}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for not over two keys, got %v", err)
+	}
+	if length, err := cache.BitOp(t.Context(), data.BitOr, "dest", []string{"missing"}); err != nil || length != 0 || cache.Exists(t.Context(), "dest") {
+		t.Fatalf("expected an empty result to delete dest, got length %d err=%v", length, err)
+	}
+
+	cache.RPush(t.Context(), "queue", [][]byte{[]byte("a")})
+	if _, err := cache.SetBit(t.Context(), "queue", 0, 1); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a list, got %v", err)
+	}
+	if _, err := cache.BitOp(t.Context(), data.BitAnd, "dest", []string{"a", "queue"}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a list source, got %v", err)
+	}
+}
+
+func TestCacheBitField(t *testing.T) {
+	cache := newTestCache(t)
+	op := func(name, fieldType, offset string, value int64, overflow string) data.BitFieldOp {
+		return data.BitFieldOp{Op: name, Type: fieldType, Offset: data.BitFieldOffset(offset), Value: value, Overflow: overflow}
+	}
+
+	results, err := cache.BitField(t.Context(), "counters", []data.BitFieldOp{
+		op("set", "u8", "#0", 255, ""),
+		op("incrby", "u8", "0", 1, ""),
+		op("incrby", "u8", "#1", 300, "sat"),
+		op("set", "i8", "#2", -128, ""),
+		op("incrby", "i8", "#2", -1, "fail"),
+		op("get", "i8", "16", 0, ""),
+		op("get", "u4", "8", 0, ""),
+	})
+	if err != nil {
+		t.Fatalf("BitField returned error: %v", err)
+	}
+	want := []any{int64(0), int64(0), int64(255), int64(0), nil, int64(-128), int64(15)}
+	for i, result := range results {
+		if (result == nil) != (want[i] == nil) || (result != nil && *result != want[i]) {
+			t.Fatalf("result %d of BitField is %v, want %v", i, result, want[i])
+		}
+	}
+	if value, _ := cache.Get(t.Context(), "counters"); string(value) != "\x00\xff\x80" {
+		t.Fatalf("expected the fields packed as \\x00\\xff\\x80, got %q", value)
+	}
+
+	results, err = cache.BitField(t.Context(), "counters", []data.BitFieldOp{
+		op("set", "i64", "#1", math.MaxInt64, ""),
+		op("incrby", "i64", "#1", 1, "wrap"),
+		op("incrby", "u2", "0", -5, "sat"),
+	})
+	if err != nil || *results[1] != math.MinInt64 || *results[2] != 0 {
+		t.Fatalf("expected i64 to wrap and u2 to saturate at 0, got %v err=%v", results, err)
+	}
+
+	for _, bad := range []data.BitFieldOp{
+		op("get", "u64", "0", 0, ""),
+		op("get", "x8", "0", 0, ""),
+		op("get", "i8", "#-1", 0, ""),
+		op("incrby", "i8", "0", 1, "clamp"),
+		op("append", "i8", "0", 1, ""),
+	} {
+		if _, err := cache.BitField(t.Context(), "other", []data.BitFieldOp{op("set", "u8", "0", 1, ""), bad}); !errors.Is(err, internal.ErrBadRequest) {
+			t.Fatalf("expected ErrBadRequest for %+v, got %v", bad, err)
+		}
+	}
+	if cache.Exists(t.Context(), "other") {
+		t.Fatal("expected a BitField with an invalid op to write nothing")
+	}
+	if results, err := cache.BitField(t.Context(), "other", []data.BitFieldOp{op("get", "i16", "0", 0, "")}); err != nil || *results[0] != 0 {
+		t.Fatalf("expected a get on a missing key to read 0, got %v err=%v", results, err)
+	}
+}
+
+func TestCacheHyperLogLogOperations(t *testing.T) {
+	cache := newTestCache(t)
+	elements := func(from, to int) []string {
+		names := make([]string, 0, to-from)
+		for i := from; i < to; i++ {
+			names = append(names, "user-"+strconv.Itoa(i))
+		}
+		return names
+	}
+	near := func(count, want int64) bool {
+		return math.Abs(float64(count-want)) <= float64(want)*0.02
+	}
+
+	if changed, err := cache.PFAdd(t.Context(), "empty", nil); err != nil || !changed || !cache.Exists(t.Context(), "empty") {
+		t.Fatalf("expected PFAdd without elements to create the key, got %v err=%v", changed, err)
+	}
+	if changed, _ := cache.PFAdd(t.Context(), "empty", nil); changed {
+		t.Fatal("expected PFAdd without elements on an existing key to change nothing")
+	}
+	if changed, err := cache.PFAdd(t.Context(), "home", elements(0, 100)); err != nil || !changed {
+		t.Fatalf("PFAdd returned %v err=%v", changed, err)
+	}
+	if changed, _ := cache.PFAdd(t.Context(), "home", elements(0, 100)); changed {
+		t.Fatal("expected adding the same elements again to change nothing")
+	}
+	if count, err := cache.PFCount(t.Context(), []string{"home"}); err != nil || !near(count, 100) {
+		t.Fatalf("expected an estimate within 2%% of 100, got %d err=%v", count, err)
+	}
+
+	cache.PFAdd(t.Context(), "pricing", elements(50, 150))
+	union, err := cache.PFCount(t.Context(), []string{"home", "pricing", "missing"})
+	if err != nil || !near(union, 150) {
+		t.Fatalf("expected the union of both pages to count about 150, got %d err=%v", union, err)
+	}
+	if err := cache.PFMerge(t.Context(), "site", []string{"home", "pricing"}, nil); err != nil {
+		t.Fatalf("PFMerge returned error: %v", err)
+	}
+	if count, _ := cache.PFCount(t.Context(), []string{"site"}); count != union {
+		t.Fatalf("expected the merged HyperLogLog to count like the union, %d, got %d", union, count)
+	}
+
+	cache.PFAdd(t.Context(), "big", elements(0, 50000))
+	if encoding := cache.GetEntries(t.Context(), []string{"big"})["big"].HLL.Encoding(); encoding != data.HLLDense {
+		t.Fatalf("expected 50000 elements to turn the HyperLogLog dense, got %s", encoding)
+	}
+	if count, _ := cache.PFCount(t.Context(), []string{"big"}); !near(count, 50000) {
+		t.Fatalf("expected an estimate within 2%% of 50000, got %d", count)
+	}
+	if err := cache.PFMerge(t.Context(), "site", []string{"big"}, nil); err != nil {
+		t.Fatalf("PFMerge returned error: %v", err)
+	}
+	if count, _ := cache.PFCount(t.Context(), []string{"site"}); !near(count, 50000) {
+		t.Fatalf("expected merging into dest to keep its own registers, got %d", count)
+	}
+
+	cache.Set(t.Context(), "plain", []byte(`"x"`), 0)
+	if _, err := cache.PFAdd(t.Context(), "plain", []string{"a"}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a string, got %v", err)
+	}
+	if _, err := cache.PFCount(t.Context(), []string{"home", "plain"}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a string among the keys, got %v", err)
+	}
+}
+
+func TestCacheGeoOperations(t *testing.T) {
+	cache := newTestCache(t)
+	sicily := []data.GeoMember{
+		{Member: "Palermo", GeoPoint: data.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+		{Member: "Catania", GeoPoint: data.GeoPoint{Longitude: 15.087269, Latitude: 37.502669}},
+	}
+	if added, err := cache.GeoAdd(t.Context(), "Sicily", sicily, data.ZAddOptions{}); err != nil || added != 2 {
+		t.Fatalf("GeoAdd returned %d err=%v", added, err)
+	}
+	bad := []data.GeoMember{{Member: "pole", GeoPoint: data.GeoPoint{Longitude: 0, Latitude: 90}}}
+	if _, err := cache.GeoAdd(t.Context(), "Sicily", bad, data.ZAddOptions{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for a latitude past 85.05, got %v", err)
+	}
+	if _, err := cache.GeoAdd(t.Context(), "Sicily", sicily, data.ZAddOptions{GT: true}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for gt, got %v", err)
+	}
+	// the scores are the geohashes Redis stores
+	if score, _, _ := cache.ZScore(t.Context(), "Sicily", "Palermo"); score != 3479099956230698 {
+		t.Fatalf("expected the geohash of Palermo as its score, got %f", score)
+	}
+
+	positions, err := cache.GeoPos(t.Context(), "Sicily", []string{"Palermo", "missing"})
+	if err != nil || len(positions) != 2 || positions[1] != nil {
+		t.Fatalf("GeoPos returned %v err=%v", positions, err)
+	}
+	if d := data.GeoDistance(*positions[0], sicily[0].GeoPoint); d > 1 {
+		t.Fatalf("expected the position of Palermo within a meter, it is %f m off", d)
+	}
+	if distance, found, err := cache.GeoDist(t.Context(), "Sicily", "Palermo", "Catania", "km"); err != nil || !found || math.Abs(distance-166.2742) > 0.001 {
+		t.Fatalf("expected Palermo and Catania 166.2742 km apart, got %f found=%v err=%v", distance, found, err)
+	}
+	if _, found, _ := cache.GeoDist(t.Context(), "Sicily", "Palermo", "missing", ""); found {
+		t.Fatal("expected GeoDist to a missing member to find nothing")
+	}
+	if _, _, err := cache.GeoDist(t.Context(), "Sicily", "Palermo", "Catania", "yd"); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for an unknown unit, got %v", err)
+	}
+
+	from := &data.GeoPoint{Longitude: 15, Latitude: 37}
+	results, err := cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{From: from, Radius: 200, Unit: "km"})
+	if err != nil || len(results) != 2 || results[0].Member != "Catania" || results[1].Member != "Palermo" ||
+		math.Abs(results[0].Distance-56.4413) > 0.001 || math.Abs(results[1].Distance-190.4424) > 0.001 {
+		t.Fatalf("expected Catania at 56.4413 km then Palermo at 190.4424 km, got %v err=%v", results, err)
+	}
+	results, _ = cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{From: from, Radius: 200, Unit: "km", Desc: true, Count: 1})
+	if len(results) != 1 || results[0].Member != "Palermo" {
+		t.Fatalf("expected the farthest member only, got %v", results)
+	}
+	results, _ = cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{From: from, Radius: 100, Unit: "km"})
+	if len(results) != 1 || results[0].Member != "Catania" {
+		t.Fatalf("expected only Catania within 100 km, got %v", results)
+	}
+	results, _ = cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{Member: "Palermo", Box: &data.GeoBox{Width: 400, Height: 400}, Unit: "km"})
+	if len(results) != 2 || results[0].Member != "Palermo" || results[0].Distance != 0 {
+		t.Fatalf("expected both members in a 400 km box around Palermo, itself first, got %v", results)
+	}
+	results, _ = cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{Member: "Palermo", Box: &data.GeoBox{Width: 400, Height: 150}, Unit: "km"})
+	if len(results) != 2 {
+		t.Fatalf("expected Catania, 68 km south of Palermo, inside a box 150 km high, reaching 75 km each way, got %v", results)
+	}
+	results, _ = cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{Member: "Palermo", Box: &data.GeoBox{Width: 100, Height: 400}, Unit: "km"})
+	if len(results) != 1 {
+		t.Fatalf("expected Catania, 150 km east of Palermo, outside a box 100 km wide, reaching 50 km each way, got %v", results)
+	}
+	if _, err := cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{Member: "missing", Radius: 1}); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound searching from a missing member, got %v", err)
+	}
+	if _, err := cache.GeoSearch(t.Context(), "Sicily", data.GeoQuery{Member: "Palermo", From: from, Radius: 1}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest searching from both a member and a point, got %v", err)
+	}
+	if results, err := cache.GeoSearch(t.Context(), "missing", data.GeoQuery{From: from, Radius: 1}); err != nil || len(results) != 0 {
+		t.Fatalf("expected no results for a missing key, got %v err=%v", results, err)
+	}
+
+	cache.Set(t.Context(), "plain", []byte(`"x"`), 0)
+	if _, err := cache.GeoSearch(t.Context(), "plain", data.GeoQuery{From: from, Radius: 1}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a string, got %v", err)
+	}
+}
+
+func TestGeoSearchMatchesFullScan(t *testing.T) {
+	zset := data.NewSortedSet()
+	rng := rand.New(rand.NewSource(1))
+	points := make(map[string]data.GeoPoint)
+	for i := 0; i < 3000; i++ {
+		// crowd the points around a few centers, one near the antimeridian and one far north
+		center := []data.GeoPoint{{Longitude: 2.35, Latitude: 48.85}, {Longitude: 179.9, Latitude: -16.5}, {Longitude: 25, Latitude: 80}}[i%3]
+		point := data.GeoPoint{
+			Longitude: math.Mod(center.Longitude+rng.NormFloat64()+540, 360) - 180,
+			Latitude:  max(min(center.Latitude+rng.NormFloat64(), data.GeoLatMax), data.GeoLatMin),
+		}
+		member := strconv.Itoa(i)
+		zset.Add(member, data.GeoScore(point))
+		points[member] = data.GeoPointOf(data.GeoScore(point))
+	}
+	for i := 0; i < 60; i++ {
+		member := strconv.Itoa(rng.Intn(3000))
+		query := data.GeoQuery{Member: member, Radius: rng.Float64() * 300, Unit: "km"}
+		if i%2 == 1 {
+			query = data.GeoQuery{Member: member, Box: &data.GeoBox{Width: rng.Float64() * 600, Height: rng.Float64() * 600}, Unit: "km"}
+		}
+		results, err := data.GeoSearch(zset, query)
+		if err != nil {
+			t.Fatalf("GeoSearch returned error: %v", err)
+		}
+		center, want := points[member], 0
+		for _, point := range points {
+			if query.Box == nil {
+				if data.GeoDistance(center, point) <= query.Radius*1000 {
+					want++
+				}
+				continue
+			}
+			north := data.GeoDistance(data.GeoPoint{Longitude: center.Longitude, Latitude: point.Latitude}, center)
+			east := data.GeoDistance(data.GeoPoint{Longitude: center.Longitude, Latitude: point.Latitude}, point)
+			if north <= query.Box.Height*500 && east <= query.Box.Width*500 {
+				want++
+			}
+		}
+		if len(results) != want {
+			t.Fatalf("expected %d members for %+v, got %d", want, query, len(results))
+		}
+		if !slices.IsSortedFunc(results, func(a, b data.GeoResult) int { return cmp.Compare(a.Distance, b.Distance) }) {
+			t.Fatalf("expected the results of %+v sorted by distance", query)
+		}
+	}
+}
+
+func TestCacheJSONOperations(t *testing.T) {
+	cache := newTestCache(t)
+	doc := `{"name": "kim", "tags": ["a"], "address": {"city": "Seoul", "zip": 4524}, "visits": 9007199254740993}`
+	if err := cache.Set(t.Context(), "user", []byte(doc), 0); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	for path, want := range map[string]string{
+		"$":                `{"name": "kim", "tags": ["a"], "address": {"city": "Seoul", "zip": 4524}, "visits": 9007199254740993}`,
+		"$.address.city":   `"Seoul"`,
+		"address['zip']":   `4524`,
+		"$.tags[-1]":       `"a"`,
+		`$["address"].zip`: `4524`,
+	} {
+		if value, found, err := cache.JSONGet(t.Context(), "user", path); err != nil || !found || string(value) != want {
+			t.Fatalf("expected %s at %s, got %s found=%v err=%v", want, path, value, found, err)
+		}
+	}
+	for _, path := range []string{"$.missing", "$.tags[1]", "$.name.first", "$.tags.a"} {
+		if _, found, err := cache.JSONGet(t.Context(), "user", path); err != nil || found {
+			t.Fatalf("expected nothing at %s, got found=%v err=%v", path, found, err)
+		}
+	}
+	if _, _, err := cache.JSONGet(t.Context(), "user", "$.tags[x]"); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for an invalid path, got %v", err)
+	}
+
+	if written, err := cache.JSONSet(t.Context(), "user", "$.address.city", []byte(`"Busan"`), data.JSONSetOptions{}); err != nil || !written {
+		t.Fatalf("JSONSet returned %v err=%v", written, err)
+	}
+	if written, _ := cache.JSONSet(t.Context(), "user", "$.address.city", []byte(`"Daegu"`), data.JSONSetOptions{NX: true}); written {
+		t.Fatal("expected JSONSet with nx to leave an existing value")
+	}
+	if written, _ := cache.JSONSet(t.Context(), "user", "$.age", []byte(`30`), data.JSONSetOptions{XX: true}); written {
+		t.Fatal("expected JSONSet with xx to add nothing")
+	}
+	if written, err := cache.JSONSet(t.Context(), "user", "$.age", []byte(`30`), data.JSONSetOptions{}); err != nil || !written {
+		t.Fatalf("expected JSONSet to add a member, got %v err=%v", written, err)
+	}
+	if _, err := cache.JSONSet(t.Context(), "user", "$.missing.field", []byte(`1`), data.JSONSetOptions{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest setting under a missing object, got %v", err)
+	}
+	if _, err := cache.JSONSet(t.Context(), "new", "$.field", []byte(`1`), data.JSONSetOptions{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest creating a document below the root, got %v", err)
+	}
+
+	if length, err := cache.JSONArrAppend(t.Context(), "user", "$.tags", []json.RawMessage{[]byte(`"b"`), []byte(`{"c": 1}`)}); err != nil || length != 3 {
+		t.Fatalf("JSONArrAppend returned %d err=%v", length, err)
+	}
+	if _, err := cache.JSONArrAppend(t.Context(), "user", "$.name", []json.RawMessage{[]byte(`1`)}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest appending to a string, got %v", err)
+	}
+	if _, err := cache.JSONArrAppend(t.Context(), "missing", "$", []json.RawMessage{[]byte(`1`)}); !errors.Is(err, internal.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound appending to a missing key, got %v", err)
+	}
+
+	// integers stay exact past 2^53
+	if number, err := cache.JSONNumIncrBy(t.Context(), "user", "$.visits", "1"); err != nil || string(number) != "9007199254740994" {
+		t.Fatalf("expected 9007199254740994, got %s err=%v", number, err)
+	}
+	if number, err := cache.JSONNumIncrBy(t.Context(), "user", "$.address.zip", "0.5"); err != nil || string(number) != "4524.5" {
+		t.Fatalf("expected 4524.5, got %s err=%v", number, err)
+	}
+	if _, err := cache.JSONNumIncrBy(t.Context(), "user", "$.name", "1"); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest incrementing a string, got %v", err)
+	}
+
+	if deleted, err := cache.JSONDel(t.Context(), "user", "$.tags[0]"); err != nil || deleted != 1 {
+		t.Fatalf("JSONDel returned %d err=%v", deleted, err)
+	}
+	if deleted, _ := cache.JSONDel(t.Context(), "user", "$.name"); deleted != 1 {
+		t.Fatalf("expected the first member to be deleted, got %d", deleted)
+	}
+	if deleted, _ := cache.JSONDel(t.Context(), "user", "$.missing"); deleted != 0 {
+		t.Fatalf("expected nothing to delete at a missing path, got %d", deleted)
+	}
+	if deleted, err := cache.JSONDel(t.Context(), "user", "$.age.x"); err != nil || deleted != 0 {
+		t.Fatalf("expected nothing to delete under a number, got %d err=%v", deleted, err)
+	}
+	want := `{"tags": ["b",{"c": 1}], "address": {"city": "Busan", "zip": 4524.5}, "visits": 9007199254740994,"age":30}`
+	if value, _, _ := cache.JSONGet(t.Context(), "user", "$"); string(value) != want {
+		t.Fatalf("expected the edits spliced into the document, got %s", value)
+	}
+	if value, found := cache.Get(t.Context(), "user"); !found || string(value) != want {
+		t.Fatalf("expected Get to return the edited document, got %s", value)
+	}
+
+	if written, err := cache.JSONSet(t.Context(), "doc", "$", []byte(` {"a": []} `), data.JSONSetOptions{}); err != nil || !written {
+		t.Fatalf("expected JSONSet to create a document at the root, got %v err=%v", written, err)
+	}
+	if deleted, _ := cache.JSONDel(t.Context(), "doc", "$"); deleted != 1 || cache.Exists(t.Context(), "doc") {
+		t.Fatal("expected deleting the root to delete the key")
+	}
+	for _, scalar := range []string{`1`, `"abc"`, `true`} {
+		cache.JSONSet(t.Context(), "scalar", "$", []byte(scalar), data.JSONSetOptions{})
+		if deleted, err := cache.JSONDel(t.Context(), "scalar", "$.x"); err != nil || deleted != 0 {
+			t.Fatalf("expected nothing to delete under a %s root, got %d err=%v", scalar, deleted, err)
+		}
+		if deleted, err := cache.JSONDel(t.Context(), "scalar", "$[0]"); err != nil || deleted != 0 {
+			t.Fatalf("expected nothing to delete under a %s root, got %d err=%v", scalar, deleted, err)
+		}
+		if value, _, _ := cache.JSONGet(t.Context(), "scalar", "$"); string(value) != scalar {
+			t.Fatalf("expected the %s root to be kept, got %s", scalar, value)
+		}
+	}
+
+	cache.SetBit(t.Context(), "bits", 7, 1)
+	if _, _, err := cache.JSONGet(t.Context(), "bits", "$"); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a value that is not JSON, got %v", err)
+	}
+	cache.PFAdd(t.Context(), "hll", []string{"a"})
+	if _, _, err := cache.JSONGet(t.Context(), "hll", "$"); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a HyperLogLog, got %v", err)
+	}
+}
+
+func TestCacheBloomOperations(t *testing.T) {
+	cache := newTestCache(t)
+	items := func(prefix string, n int) []string {
+		names := make([]string, n)
+		for i := range names {
+			names[i] = prefix + strconv.Itoa(i)
+		}
+		return names
+	}
+	falsePositives := func(key string) float64 {
+		exists, err := cache.BFExists(t.Context(), key, items("other-", 10000))
+		if err != nil {
+			t.Fatalf("BFExists returned error: %v", err)
+		}
+		found := 0
+		for _, e := range exists {
+			if e {
+				found++
+			}
+		}
+		return float64(found) / float64(len(exists))
+	}
+
+	if err := cache.BFReserve(t.Context(), "seen", data.BloomOptions{ErrorRate: 0.01, Capacity: 1000}); err != nil {
+		t.Fatalf("BFReserve returned error: %v", err)
+	}
+	if err := cache.BFReserve(t.Context(), "seen", data.BloomOptions{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest reserving an existing key, got %v", err)
+	}
+	if _, err := cache.BFAdd(t.Context(), "seen", items("user-", 1000)); err != nil {
+		t.Fatalf("BFAdd returned error: %v", err)
+	}
+	if added, _ := cache.BFAdd(t.Context(), "seen", []string{"user-7"}); added[0] {
+		t.Fatal("expected adding an item again to report it as not new")
+	}
+	if exists, _ := cache.BFExists(t.Context(), "seen", items("user-", 1000)); slices.Contains(exists, false) {
+		t.Fatal("expected every added item to be found")
+	}
+	if rate := falsePositives("seen"); rate > 0.015 {
+		t.Fatalf("expected about 1%% false positives at capacity, got %.4f", rate)
+	}
+
+	// a filter made by its first add scales past its capacity of 100 and keeps its error rate bounded
+	if _, err := cache.BFAdd(t.Context(), "scaling", items("user-", 2000)); err != nil {
+		t.Fatalf("BFAdd returned error: %v", err)
+	}
+	if exists, _ := cache.BFExists(t.Context(), "scaling", items("user-", 2000)); slices.Contains(exists, false) {
+		t.Fatal("expected every added item to be found after scaling")
+	}
+	if rate := falsePositives("scaling"); rate > 0.025 {
+		t.Fatalf("expected the scaled filter to stay under 2%% false positives, got %.4f", rate)
+	}
+
+	cache.BFReserve(t.Context(), "fixed", data.BloomOptions{Capacity: 10, NonScaling: true})
+	if _, err := cache.BFAdd(t.Context(), "fixed", items("user-", 10)); err != nil {
+		t.Fatalf("BFAdd returned error: %v", err)
+	}
+	if _, err := cache.BFAdd(t.Context(), "fixed", []string{"one-more"}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected a full non-scaling filter to refuse an add, got %v", err)
+	}
+	if err := cache.BFReserve(t.Context(), "bad", data.BloomOptions{ErrorRate: 1.5}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for an error rate above 1, got %v", err)
+	}
+	if err := cache.BFReserve(t.Context(), "huge", data.BloomOptions{Capacity: 1 << 34}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for a filter past 512MB, got %v", err)
+	}
+	cache.BFReserve(t.Context(), "steep", data.BloomOptions{Capacity: 10, Expansion: 1 << 40})
+	if _, err := cache.BFAdd(t.Context(), "steep", items("user-", 11)); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected a layer past 512MB to be refused, got %v", err)
+	}
+
+	if exists, err := cache.BFExists(t.Context(), "missing", []string{"a", "b"}); err != nil || !slices.Equal(exists, []bool{false, false}) {
+		t.Fatalf("expected nothing to exist in a missing filter, got %v err=%v", exists, err)
+	}
+	cache.Set(t.Context(), "plain", []byte(`"x"`), 0)
+	if _, err := cache.BFAdd(t.Context(), "plain", []string{"a"}); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a string, got %v", err)
+	}
+}
+
+func TestCacheCuckooOperations(t *testing.T) {
+	cache := newTestCache(t)
+
+	if added, err := cache.CFAdd(t.Context(), "dedup", "event-1", false); err != nil || !added {
+		t.Fatalf("CFAdd returned %v err=%v", added, err)
+	}
+	cache.CFAdd(t.Context(), "dedup", "event-1", false)
+	if added, _ := cache.CFAdd(t.Context(), "dedup", "event-1", true); added {
+		t.Fatal("expected CFAdd with nx to skip an item that is there")
+	}
+	if count, err := cache.CFCount(t.Context(), "dedup", "event-1"); err != nil || count != 2 {
+		t.Fatalf("expected an item added twice to count 2, got %d err=%v", count, err)
+	}
+	if deleted, err := cache.CFDel(t.Context(), "dedup", "event-1"); err != nil || !deleted {
+		t.Fatalf("CFDel returned %v err=%v", deleted, err)
+	}
+	if exists, _ := cache.CFExists(t.Context(), "dedup", []string{"event-1", "event-2"}); !slices.Equal(exists, []bool{true, false}) {
+		t.Fatalf("expected one copy to remain after a delete, got %v", exists)
+	}
+	cache.CFDel(t.Context(), "dedup", "event-1")
+	if deleted, _ := cache.CFDel(t.Context(), "dedup", "event-1"); deleted {
+		t.Fatal("expected nothing left to delete")
+	}
+	if !cache.Exists(t.Context(), "dedup") {
+		t.Fatal("expected an emptied filter to keep its key")
+	}
+
+	// a filter grows layers past its capacity, and every item can be deleted again
+	if err := cache.CFReserve(t.Context(), "small", data.CuckooOptions{Capacity: 64, Expansion: 2}); err != nil {
+		t.Fatalf("CFReserve returned error: %v", err)
+	}
+	if err := cache.CFReserve(t.Context(), "small", data.CuckooOptions{}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest reserving an existing key, got %v", err)
+	}
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = "event-" + strconv.Itoa(i)
+		if _, err := cache.CFAdd(t.Context(), "small", items[i], false); err != nil {
+			t.Fatalf("CFAdd of item %d returned error: %v", i, err)
+		}
+	}
+	if exists, _ := cache.CFExists(t.Context(), "small", items); slices.Contains(exists, false) {
+		t.Fatal("expected every added item to be found after growing")
+	}
+	for _, item := range items {
+		if deleted, _ := cache.CFDel(t.Context(), "small", item); !deleted {
+			t.Fatalf("expected %s to be deleted", item)
+		}
+	}
+	if exists, _ := cache.CFExists(t.Context(), "small", items); slices.Contains(exists, true) {
+		t.Fatal("expected no item to be found once all were deleted")
+	}
+
+	if err := cache.CFReserve(t.Context(), "huge", data.CuckooOptions{Capacity: 1 << 34}); !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest for a filter past 512MB, got %v", err)
+	}
+	cache.CFReserve(t.Context(), "steep", data.CuckooOptions{Capacity: 64, Expansion: 1 << 40})
+	var err error
+	for i := 0; err == nil && i < 1000; i++ {
+		_, err = cache.CFAdd(t.Context(), "steep", "event-"+strconv.Itoa(i), false)
+	}
+	if !errors.Is(err, internal.ErrBadRequest) {
+		t.Fatalf("expected a layer past 512MB to be refused, got %v", err)
+	}
+
+	if count, err := cache.CFCount(t.Context(), "missing", "a"); err != nil || count != 0 {
+		t.Fatalf("expected a missing filter to count 0, got %d err=%v", count, err)
+	}
+	cache.BFAdd(t.Context(), "bloom", []string{"a"})
+	if _, err := cache.CFAdd(t.Context(), "bloom", "a", false); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType for a Bloom filter, got %v", err)
+	}
+}
+
+// newRestartableCache builds a cache logged to a directory of its own. restart flushes its AOF and
+// loads a new cache from it.
+func newRestartableCache(t *testing.T) (cache *Cache, restart func() *Cache) {
+	t.Helper()
+	config := config.LoadTestConfig()
+	path, err := os.MkdirTemp("", "restart")
+	if err != nil {
+		t.Fatalf("Failed to create the AOF directory: %v", err)
+	}
+	config.Persistent.Path = path
+	// not t.TempDir, the AOF of the reloaded cache may still be written to while the test ends
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		os.RemoveAll(path)
+	})
+	cache, err = NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	return cache, func() *Cache {
+		cache.persistentLogger.Close() // flushes the AOF
+		restarted, err := NewCache(ctx, config)
+		if err != nil {
+			t.Fatalf("Failed to reload cache: %v", err)
+		}
+		return restarted
+	}
+}
+
+func TestCacheTypedItemsSurviveRestart(t *testing.T) {
+	cache, restart := newRestartableCache(t)
+	cache.RPush(t.Context(), "queue", [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	cache.LPop(t.Context(), "queue", 1)
+	cache.HSet(t.Context(), "session", map[string][]byte{"user": []byte(`"kim"`), "visits": []byte("1"), "tmp": []byte("1")})
+	cache.HIncrBy(t.Context(), "session", "visits", 1)
+	cache.HDel(t.Context(), "session", []string{"tmp"})
+	cache.SAdd(t.Context(), "tags", []string{"go", "redis", "tmp"})
+	cache.SRem(t.Context(), "tags", []string{"tmp"})
+	cache.ZAdd(t.Context(), "board", []data.ScoredMember{{Member: "kim", Score: 2}, {Member: "lee", Score: 1}, {Member: "tmp", Score: 3}}, data.ZAddOptions{})
+	cache.ZIncrBy(t.Context(), "board", "lee", 5)
+	cache.ZRem(t.Context(), "board", []string{"tmp"})
+	cache.XAdd(t.Context(), "events", "1-1", map[string][]byte{"n": []byte("1")}, data.StreamTrim{})
+	cache.XAdd(t.Context(), "events", "1-2", map[string][]byte{"n": []byte("2")}, data.StreamTrim{})
+	cache.XGroupCreate(t.Context(), "events", "workers", "0", false)
+	cache.XReadGroup(t.Context(), "events", "workers", "w1", ">", 1)
+	maxLen := 3
+	cache.XAdd(t.Context(), "events", "1-3", map[string][]byte{"n": []byte("3")}, data.StreamTrim{MaxLen: &maxLen})
+	cache.XReadGroup(t.Context(), "events", "workers", "w2", ">", 2)
+	cache.XAck(t.Context(), "events", "workers", []string{"1-2"})
+	cache.XTrim(t.Context(), "events", data.StreamTrim{MinID: "1-2"})
+	cache.PFAdd(t.Context(), "visitors", []string{"kim", "lee", "park"})
+	cache.PFAdd(t.Context(), "visitors", []string{"kim", "choi"})
+	cache.BFAdd(t.Context(), "seen", []string{"kim", "lee"})
+	cache.CFAdd(t.Context(), "dedup", "kim", false)
+	cache.CFAdd(t.Context(), "dedup", "kim", false)
+
+	restarted := restart()
+	values, err := restarted.LRange(t.Context(), "queue", 0, -1)
+	if err != nil || !slices.Equal(listStrings(values), []string{"b", "c"}) {
+		t.Fatalf("expected the list to be reloaded from the AOF, got %q err=%v", listStrings(values), err)
+	}
+	fields, err := restarted.HGetAll(t.Context(), "session")
+	if err != nil || len(fields) != 2 || string(fields["visits"]) != "2" {
+		t.Fatalf("expected the hash to be reloaded from the AOF, got %v err=%v", fields, err)
+	}
+	members, err := restarted.SMembers(t.Context(), "tags")
+	if err != nil || !slices.Equal(members, []string{"go", "redis"}) {
+		t.Fatalf("expected the set to be reloaded from the AOF, got %v err=%v", members, err)
+	}
+	board, err := restarted.ZRange(t.Context(), "board", data.ZRangeQuery{Count: -1})
+	if err != nil || !slices.Equal(board, []data.ScoredMember{{Member: "kim", Score: 2}, {Member: "lee", Score: 6}}) {
+		t.Fatalf("expected the sorted set to be reloaded from the AOF, got %v err=%v", board, err)
+	}
+	pending, err := restarted.XPending(t.Context(), "events", "workers")
+	if err != nil || len(pending) != 2 || pending[0].ID != (data.StreamID{Ms: 1, Seq: 1}) || pending[1].Consumer != "w2" {
+		t.Fatalf("expected the pending list to be reloaded from the AOF, got %+v err=%v", pending, err)
+	}
+	if entries, err := restarted.XRange(t.Context(), "events", "-", "+", 0); err != nil || len(entries) != 2 || string(entries[0].Fields["n"]) != "2" {
+		t.Fatalf("expected the trimmed stream to be reloaded from the AOF, got %v err=%v", entries, err)
+	}
+	restarted.XAdd(t.Context(), "events", "1-4", map[string][]byte{"n": []byte("4")}, data.StreamTrim{})
+	if next, err := restarted.XReadGroup(t.Context(), "events", "workers", "w1", ">", 0); err != nil || len(next) != 1 || string(next[0].Fields["n"]) != "4" {
+		t.Fatalf("expected the group to resume after its last delivered entry, got %v err=%v", next, err)
+	}
+	if count, err := restarted.PFCount(t.Context(), []string{"visitors"}); err != nil || count != 4 {
+		t.Fatalf("expected the HyperLogLog registers to be reloaded from the AOF, got %d err=%v", count, err)
+	}
+	if exists, err := restarted.BFExists(t.Context(), "seen", []string{"kim", "lee", "park"}); err != nil || !slices.Equal(exists, []bool{true, true, false}) {
+		t.Fatalf("expected the Bloom filter to be reloaded from the AOF, got %v err=%v", exists, err)
+	}
+	if count, err := restarted.CFCount(t.Context(), "dedup", "kim"); err != nil || count != 2 {
+		t.Fatalf("expected the cuckoo filter to be reloaded from the AOF, got %d err=%v", count, err)
+	}
+	entries := restarted.GetEntries(t.Context(), []string{"queue", "session", "tags", "board", "events", "visitors", "seen", "dedup"})
+	if entries["queue"].Type != data.TypeList || entries["session"].Type != data.TypeHash || entries["tags"].Type != data.TypeSet ||
+		entries["board"].Type != data.TypeZSet || entries["events"].Type != data.TypeStream || entries["visitors"].Type != data.TypeHLL ||
+		entries["seen"].Type != data.TypeBloom || entries["dedup"].Type != data.TypeCuckoo {
+		t.Fatalf("expected the reloaded items to keep their type, got %+v", entries)
+	}
+}
+
+func TestCacheListChangesReplayOverSnapshot(t *testing.T) {
+	cache, restart := newRestartableCache(t)
+	for i := range 10 {
+		cache.RPush(t.Context(), "queue", [][]byte{[]byte(strconv.Itoa(i))})
+	}
+	cache.LPush(t.Context(), "queue", [][]byte{[]byte("b"), []byte("a")})
+	cache.LPop(t.Context(), "queue", 3)
+	cache.RPop(t.Context(), "queue", 1)
+	cache.LTrim(t.Context(), "queue", 0, -2)
+	time.Sleep(200 * time.Millisecond) // the AOF writes its batch, so the snapshot's AOF starts after it
+	// a push logged after the AOF is paused is in the snapshot and in the AOF that follows it
+	cache.persistentLogger.TriggerSnap(func() map[string]data.CacheItem {
+		cache.RPush(t.Context(), "queue", [][]byte{[]byte("8")})
+		return cache.snapItems()
+	})
+	cache.LPush(t.Context(), "queue", [][]byte{[]byte("head")})
+	cache.RPush(t.Context(), "queue", [][]byte{[]byte("tail")})
+	want := []string{"head", "1", "2", "3", "4", "5", "6", "7", "8", "tail"}
+	if values, _ := cache.LRange(t.Context(), "queue", 0, -1); !slices.Equal(listStrings(values), want) {
+		t.Fatalf("expected %q, got %q", want, listStrings(values))
+	}
+
+	restarted := restart()
+	if values, err := restarted.LRange(t.Context(), "queue", 0, -1); err != nil || !slices.Equal(listStrings(values), want) {
+		t.Fatalf("expected the changes after the snapshot to be replayed once, got %q err=%v", listStrings(values), err)
+	}
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+)
+
+// CFReserve creates an empty cuckoo filter at key. A key that exists is not replaced.
+func (c *Cache) CFReserve(ctx context.Context, key string, options data.CuckooOptions) error {
+	filter, err := data.NewCuckooFilter(options)
+	if err != nil {
+		return err
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	_, found, err := c.loadTypedLocked(index, key, data.TypeCuckoo)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%w: key %q already exists", internal.ErrBadRequest, key)
+	}
+	c.storeCuckooLocked(index, key, filter)
+	return nil
+}
+
+// CFAdd adds item to the cuckoo filter at key and reports whether it did. With nx, like CF.ADDNX, an item
+// that may be there already is not added again. A missing key is created with the default capacity.
+func (c *Cache) CFAdd(ctx context.Context, key, item string, nx bool) (bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stored, found, err := c.loadTypedLocked(index, key, data.TypeCuckoo)
+	if err != nil {
+		return false, err
+	}
+	var filter *data.CuckooFilter
+	if found {
+		if nx && stored.Cuckoo.Count(item) > 0 {
+			return false, nil
+		}
+		filter = stored.Cuckoo.Clone()
+	} else if filter, err = data.NewCuckooFilter(data.CuckooOptions{}); err != nil {
+		return false, err
+	}
+	if err := filter.Add(item); err != nil {
+		return false, err
+	}
+	c.storeCuckooLocked(index, key, filter)
+	return true, nil
+}
+
+// CFExists reports for each of items whether it may have been added, false for all when the key is missing
+func (c *Cache) CFExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	stored, found, err := c.loadTypedLocked(index, key, data.TypeCuckoo)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return make([]bool, len(items)), nil
+	}
+	return stored.Cuckoo.Exists(items...), nil
+}
+
+// CFCount returns how many times item may have been added, 0 when the key is missing
+func (c *Cache) CFCount(ctx context.Context, key, item string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	stored, found, err := c.loadTypedLocked(index, key, data.TypeCuckoo)
+	if err != nil || !found {
+		return 0, err
+	}
+	return stored.Cuckoo.Count(item), nil
+}
+
+// CFDel removes one copy of item and reports whether there was one. The filter is kept when it empties.
+func (c *Cache) CFDel(ctx context.Context, key, item string) (bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stored, found, err := c.loadTypedLocked(index, key, data.TypeCuckoo)
+	if err != nil || !found {
+		return false, err
+	}
+	filter := stored.Cuckoo.Clone()
+	if !filter.Del(item) {
+		return false, nil
+	}
+	c.storeCuckooLocked(index, key, filter)
+	return true, nil
+}
+
+// storeCuckooLocked writes filter back to key. The shard lock must be held.
+func (c *Cache) storeCuckooLocked(index int, key string, filter *data.CuckooFilter) {
+	c.storeTypedLocked(index, key, data.CacheItem{Cuckoo: filter, Type: data.TypeCuckoo}, false)
+}
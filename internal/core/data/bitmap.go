@@ -0,0 +1,398 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Bitmaps are string values read bit by bit. Bit 0 is the most significant bit of the first byte,
+// like in Redis, and a bitmap grows with zero bytes when a bit past its end is set.
+
+// MaxBitOffset is the highest bit a bitmap can address, which caps a bitmap at 512MB like Redis
+const MaxBitOffset = 1<<32 - 1
+
+// Bit operations across keys
+const (
+	BitAnd = "and"
+	BitOr  = "or"
+	BitXor = "xor"
+	BitNot = "not" // takes a single key
+)
+
+// GetBit returns the bit at offset, 0 past the end of value
+func GetBit(value []byte, offset int64) int {
+	if offset < 0 || offset >= int64(len(value))*8 {
+		return 0
+	}
+	return int(value[offset/8]>>(7-offset%8)) & 1
+}
+
+// SetBit returns a copy of value with the bit at offset set to bit, and the bit it replaced
+func SetBit(value []byte, offset int64, bit int) ([]byte, int, error) {
+	if err := validBitOffset(offset); err != nil {
+		return nil, 0, err
+	}
+	if bit != 0 && bit != 1 {
+		return nil, 0, fmt.Errorf("%w: bit must be 0 or 1", internal.ErrBadRequest)
+	}
+	previous := GetBit(value, offset)
+	updated := grow(value, offset+1)
+	mask := byte(1) << (7 - offset%8)
+	if bit == 1 {
+		updated[offset/8] |= mask
+	} else {
+		updated[offset/8] &^= mask
+	}
+	return updated, previous, nil
+}
+
+// BitRange picks the part of a bitmap BITCOUNT and BITPOS look at. Start and End are both included
+// and count from the end when negative, a nil End is the last one. They index bytes unless Bit is set.
+type BitRange struct {
+	Start int64
+	End   *int64
+	Bit   bool
+}
+
+// bits resolves r against value to a range of bit offsets, ok is false when it holds no bit
+func (r BitRange) bits(value []byte) (from, to int64, ok bool) {
+	length := int64(len(value))
+	if r.Bit {
+		length *= 8
+	}
+	start, end := r.Start, int64(-1)
+	if r.End != nil {
+		end = *r.End
+	}
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if end < 0 {
+		end = length + end
+	}
+	end = min(end, length-1)
+	if start > end || start >= length {
+		return 0, 0, false
+	}
+	if r.Bit {
+		return start, end + 1, true
+	}
+	return start * 8, (end + 1) * 8, true
+}
+
+// BitCount counts the bits set in the range r of value
+func BitCount(value []byte, r BitRange) int64 {
+	from, to, ok := r.bits(value)
+	if !ok {
+		return 0
+	}
+	var count int64
+	for ; from < to && from%8 != 0; from++ {
+		count += int64(GetBit(value, from))
+	}
+	for ; from+8 <= to; from += 8 {
+		count += int64(bits.OnesCount8(value[from/8]))
+	}
+	for ; from < to; from++ {
+		count += int64(GetBit(value, from))
+	}
+	return count
+}
+
+// BitPos returns the offset of the first bit equal to bit in the range r of value, -1 when there is none.
+// Looking for a 0 without an End finds the first bit past the value, as the value is padded with zeros.
+func BitPos(value []byte, bit int, r BitRange) (int64, error) {
+	if bit != 0 && bit != 1 {
+		return 0, fmt.Errorf("%w: bit must be 0 or 1", internal.ErrBadRequest)
+	}
+	if len(value) == 0 {
+		if bit == 0 {
+			return 0, nil
+		}
+		return -1, nil
+	}
+	from, to, ok := r.bits(value)
+	if !ok {
+		return -1, nil
+	}
+	skip := byte(0) // a byte holding no wanted bit
+	if bit == 0 {
+		skip = 0xff
+	}
+	for offset := from; offset < to; {
+		if offset%8 == 0 && offset+8 <= to && value[offset/8] == skip {
+			offset += 8
+			continue
+		}
+		if GetBit(value, offset) == bit {
+			return offset, nil
+		}
+		offset++
+	}
+	if bit == 0 && r.End == nil {
+		return to, nil
+	}
+	return -1, nil
+}
+
+// BitOp applies op to values byte by byte and returns the result, as long as the longest value.
+// Shorter values are padded with zero bytes, NOT takes exactly one value.
+func BitOp(op string, values [][]byte) ([]byte, error) {
+	switch op {
+	case BitAnd, BitOr, BitXor:
+	case BitNot:
+		if len(values) != 1 {
+			return nil, fmt.Errorf("%w: bitop not takes a single key", internal.ErrBadRequest)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown bitop %q", internal.ErrBadRequest, op)
+	}
+	length := 0
+	for _, value := range values {
+		length = max(length, len(value))
+	}
+	result := make([]byte, length)
+	for i := range result {
+		for j, value := range values {
+			var b byte
+			if i < len(value) {
+				b = value[i]
+			}
+			switch {
+			case op == BitNot:
+				result[i] = ^b
+			case j == 0:
+				result[i] = b
+			case op == BitAnd:
+				result[i] &= b
+			case op == BitOr:
+				result[i] |= b
+			case op == BitXor:
+				result[i] ^= b
+			}
+		}
+	}
+	return result, nil
+}
+
+// BitFieldOp is one step of BITFIELD. Type is "i" (signed) or "u" (unsigned) and a width, i1 to i64 or u1 to u63.
+// Overflow tells set and incrby what to do with a result out of range: wrap around (default), saturate
+// at the limit it crossed, or fail, which skips the write and returns null.
+type BitFieldOp struct {
+	Op       string         `json:"op"` // get, set or incrby
+	Type     string         `json:"type"`
+	Offset   BitFieldOffset `json:"offset"`
+	Value    int64          `json:"value,omitempty"` // the value of set, the increment of incrby
+	Overflow string         `json:"overflow,omitempty"`
+}
+
+// BitFieldOffset is the bit a field starts at, or "#n" for the n-th field of its width.
+// It is written as a JSON number or string.
+type BitFieldOffset string
+
+func (o *BitFieldOffset) UnmarshalJSON(b []byte) error {
+	var offset string
+	if err := json.Unmarshal(b, &offset); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(b, &number); err != nil {
+			return err
+		}
+		offset = number.String()
+	}
+	*o = BitFieldOffset(offset)
+	return nil
+}
+
+// BitFieldReadOnly reports whether ops only read, like BITFIELD_RO
+func BitFieldReadOnly(ops []BitFieldOp) bool {
+	for _, op := range ops {
+		if op.Op != "get" {
+			return false
+		}
+	}
+	return true
+}
+
+// bitField is a parsed BitFieldOp
+type bitField struct {
+	BitFieldOp
+	signed bool
+	width  int
+	offset int64
+}
+
+// BitField runs ops on value in order and returns one result per op: the value read by get, the value
+// replaced by set and the new value of incrby, nil where overflow failed. updated is nil when nothing
+// was written. Every op is checked before any runs.
+func BitField(value []byte, ops []BitFieldOp) (updated []byte, results []*int64, err error) {
+	fields := make([]bitField, len(ops))
+	for i, op := range ops {
+		if fields[i], err = parseBitField(op); err != nil {
+			return nil, nil, err
+		}
+	}
+	results = make([]*int64, len(fields))
+	current := value
+	for i, field := range fields {
+		old := field.get(current)
+		if field.Op == "get" {
+			results[i] = &old
+			continue
+		}
+		next, ok := field.limit(old, field.Value)
+		if !ok {
+			continue
+		}
+		if updated == nil {
+			updated = grow(current, field.offset+int64(field.width))
+		} else {
+			updated = growInPlace(updated, field.offset+int64(field.width))
+		}
+		current = updated
+		field.set(updated, next)
+		if field.Op == "set" {
+			results[i] = &old
+		} else {
+			results[i] = &next
+		}
+	}
+	return updated, results, nil
+}
+
+func parseBitField(op BitFieldOp) (bitField, error) {
+	field := bitField{BitFieldOp: op}
+	switch op.Op {
+	case "get", "set", "incrby":
+	default:
+		return field, fmt.Errorf("%w: unknown bitfield op %q", internal.ErrBadRequest, op.Op)
+	}
+	switch op.Overflow {
+	case "", "wrap", "sat", "fail":
+	default:
+		return field, fmt.Errorf("%w: unknown bitfield overflow %q", internal.ErrBadRequest, op.Overflow)
+	}
+	sign, width := "", ""
+	if op.Type != "" {
+		sign, width = op.Type[:1], op.Type[1:]
+	}
+	field.signed = sign == "i"
+	maxWidth := 63
+	if field.signed {
+		maxWidth = 64
+	}
+	var err error
+	field.width, err = strconv.Atoi(width)
+	if err != nil || (sign != "i" && sign != "u") || field.width < 1 || field.width > maxWidth {
+		return field, fmt.Errorf("%w: invalid bitfield type %q", internal.ErrBadRequest, op.Type)
+	}
+	offset, multiplied := strings.CutPrefix(string(op.Offset), "#")
+	if field.offset, err = strconv.ParseInt(offset, 10, 64); err != nil || field.offset < 0 {
+		return field, fmt.Errorf("%w: invalid bitfield offset %q", internal.ErrBadRequest, op.Offset)
+	}
+	if multiplied {
+		if field.offset > MaxBitOffset/int64(field.width) {
+			return field, fmt.Errorf("%w: bit offset out of range", internal.ErrBadRequest)
+		}
+		field.offset *= int64(field.width)
+	}
+	if err := validBitOffset(field.offset + int64(field.width) - 1); err != nil {
+		return field, err
+	}
+	return field, nil
+}
+
+func (f bitField) get(value []byte) int64 {
+	var raw uint64
+	for i := range int64(f.width) {
+		raw = raw<<1 | uint64(GetBit(value, f.offset+i))
+	}
+	return f.wrap(raw)
+}
+
+// set writes v to value, which must hold the field
+func (f bitField) set(value []byte, v int64) {
+	for i := range int64(f.width) {
+		offset := f.offset + i
+		mask := byte(1) << (7 - offset%8)
+		if uint64(v)>>(int64(f.width)-1-i)&1 == 1 {
+			value[offset/8] |= mask
+		} else {
+			value[offset/8] &^= mask
+		}
+	}
+}
+
+// wrap keeps the low width bits of raw, sign extended for a signed field
+func (f bitField) wrap(raw uint64) int64 {
+	if f.width == 64 {
+		return int64(raw)
+	}
+	raw &= 1<<f.width - 1
+	if f.signed && raw>>(f.width-1) == 1 {
+		return int64(raw) - 1<<f.width
+	}
+	return int64(raw)
+}
+
+func (f bitField) bounds() (low, high int64) {
+	switch {
+	case !f.signed:
+		return 0, 1<<f.width - 1
+	case f.width == 64:
+		return math.MinInt64, math.MaxInt64
+	default:
+		return -1 << (f.width - 1), 1<<(f.width-1) - 1
+	}
+}
+
+// limit returns the value set or incrby writes, ok is false when it overflows and overflow is fail
+func (f bitField) limit(old, value int64) (int64, bool) {
+	low, high := f.bounds()
+	next, raw := value, uint64(value)
+	above, below := value > high, value < low
+	if f.Op == "incrby" {
+		next, raw = old+value, uint64(old)+uint64(value)
+		above = value > 0 && old > high-value
+		below = value < 0 && (low-value < low || old < low-value) // low-value only wraps for u63 and math.MinInt64
+	}
+	if !above && !below {
+		return next, true
+	}
+	switch f.Overflow {
+	case "sat":
+		if above {
+			return high, true
+		}
+		return low, true
+	case "fail":
+		return 0, false
+	}
+	return f.wrap(raw), true
+}
+
+func validBitOffset(offset int64) error {
+	if offset < 0 || offset > MaxBitOffset {
+		return fmt.Errorf("%w: bit offset out of range", internal.ErrBadRequest)
+	}
+	return nil
+}
+
+// grow returns a copy of value long enough to hold bits bits
+func grow(value []byte, bits int64) []byte {
+	updated := make([]byte, max(int64(len(value)), (bits+7)/8))
+	copy(updated, value)
+	return updated
+}
+
+// growInPlace extends value, already a copy, to hold bits bits
+func growInPlace(value []byte, bits int64) []byte {
+	if need := (bits + 7) / 8; need > int64(len(value)) {
+		return append(value, make([]byte, need-int64(len(value)))...)
+	}
+	return value
+}
@@ -0,0 +1,219 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"slices"
+)
+
+// Defaults of a filter created by its first add, like RedisBloom
+const (
+	BloomDefaultErrorRate = 0.01
+	BloomDefaultCapacity  = 100
+	BloomDefaultExpansion = 2
+)
+
+// BloomMaxBytes caps the bits of all the layers of a filter at 512MB, like a bitmap
+const BloomMaxBytes = 512 << 20
+
+// BloomOptions are the arguments of BF.RESERVE, zero values take the defaults
+type BloomOptions struct {
+	ErrorRate  float64 `json:"error_rate"`
+	Capacity   int     `json:"capacity"`
+	Expansion  int     `json:"expansion"`  // how much larger each new layer is
+	NonScaling bool    `json:"nonscaling"` // refuse adds past the capacity instead of growing
+}
+
+// BloomFilter tells whether an item may have been added, never missing one that was and wrongly finding
+// one that was not at most at its error rate. It grows like a RedisBloom scalable filter: once the last
+// layer holds its capacity, a layer Expansion times larger is added with half its error rate, so the
+// rate of the whole filter stays under twice ErrorRate however much it grows.
+type BloomFilter struct {
+	errorRate float64
+	expansion int // 0 when the filter does not scale
+	layers    []*bloomLayer
+	owned     []bool // the layers this filter may write, the others are shared with the filter it was cloned from
+}
+
+type bloomLayer struct {
+	bits     []byte
+	hashes   int
+	capacity int
+	count    int
+}
+
+func NewBloomFilter(options BloomOptions) (*BloomFilter, error) {
+	if options.ErrorRate == 0 {
+		options.ErrorRate = BloomDefaultErrorRate
+	}
+	if options.Capacity == 0 {
+		options.Capacity = BloomDefaultCapacity
+	}
+	if options.Expansion == 0 {
+		options.Expansion = BloomDefaultExpansion
+	}
+	if !(options.ErrorRate > 0 && options.ErrorRate < 1) || options.Capacity < 1 || options.Expansion < 1 {
+		return nil, fmt.Errorf("%w: error rate must be between 0 and 1, capacity and expansion positive", internal.ErrBadRequest)
+	}
+	if bloomLayerBits(float64(options.Capacity), options.ErrorRate) > BloomMaxBytes*8 {
+		return nil, fmt.Errorf("%w: a filter of that capacity and error rate takes more than 512MB", internal.ErrBadRequest)
+	}
+	b := &BloomFilter{errorRate: options.ErrorRate, expansion: options.Expansion}
+	if options.NonScaling {
+		b.expansion = 0
+	}
+	b.grow(options.Capacity, options.ErrorRate)
+	return b, nil
+}
+
+// Clone returns a copy sharing the layers of b until it writes them
+func (b *BloomFilter) Clone() *BloomFilter {
+	return &BloomFilter{errorRate: b.errorRate, expansion: b.expansion, layers: slices.Clone(b.layers), owned: make([]bool, len(b.layers))}
+}
+
+// Add adds items and reports for each whether it is new, false when it may have been added before.
+// b is modified, clone a stored filter first.
+func (b *BloomFilter) Add(items ...string) ([]bool, error) {
+	added := make([]bool, len(items))
+	for i, item := range items {
+		h1, h2 := bloomHashes(item)
+		if b.contains(h1, h2) {
+			continue
+		}
+		last := len(b.layers) - 1
+		if layer := b.layers[last]; layer.count >= layer.capacity {
+			if b.expansion == 0 {
+				return nil, fmt.Errorf("%w: the filter is full", internal.ErrBadRequest)
+			}
+			// sized in floats so a growth past the cap is refused before it overflows
+			capacity, errorRate := float64(layer.capacity)*float64(b.expansion), b.errorRate*math.Pow(0.5, float64(len(b.layers)))
+			if float64(b.size())*8+bloomLayerBits(capacity, errorRate) > BloomMaxBytes*8 {
+				return nil, fmt.Errorf("%w: the filter is full, another layer would take it past 512MB", internal.ErrBadRequest)
+			}
+			b.grow(int(capacity), errorRate)
+			last++
+		}
+		layer := b.writable(last)
+		for _, bit := range layer.positions(h1, h2) {
+			layer.bits[bit/8] |= 1 << (bit % 8)
+		}
+		layer.count++
+		added[i] = true
+	}
+	return added, nil
+}
+
+// Exists reports for each of items whether it may have been added
+func (b *BloomFilter) Exists(items ...string) []bool {
+	exists := make([]bool, len(items))
+	for i, item := range items {
+		exists[i] = b.contains(bloomHashes(item))
+	}
+	return exists
+}
+
+func (b *BloomFilter) contains(h1, h2 uint64) bool {
+	for _, layer := range b.layers {
+		found := true
+		for _, bit := range layer.positions(h1, h2) {
+			if layer.bits[bit/8]&(1<<(bit%8)) == 0 {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// grow adds a layer sized for capacity items at errorRate, see bloomLayerBits, with log2(1/errorRate) hashes per item
+func (b *BloomFilter) grow(capacity int, errorRate float64) {
+	bits := int(bloomLayerBits(float64(capacity), errorRate))
+	hashes := int(math.Ceil(-math.Log2(errorRate)))
+	b.layers = append(b.layers, &bloomLayer{bits: make([]byte, (bits+7)/8), hashes: hashes, capacity: capacity})
+	b.owned = append(b.owned, true)
+}
+
+// size returns the bytes of all the layers
+func (b *BloomFilter) size() int {
+	size := 0
+	for _, layer := range b.layers {
+		size += len(layer.bits)
+	}
+	return size
+}
+
+// bloomLayerBits returns the bits of a layer for capacity items at errorRate, -ln(errorRate)/ln(2)^2 per item
+func bloomLayerBits(capacity, errorRate float64) float64 {
+	return max(math.Ceil(capacity*-math.Log(errorRate)/(math.Ln2*math.Ln2)), 8)
+}
+
+func (b *BloomFilter) writable(i int) *bloomLayer {
+	if !b.owned[i] {
+		layer := *b.layers[i]
+		layer.bits = slices.Clone(layer.bits)
+		b.layers[i], b.owned[i] = &layer, true
+	}
+	return b.layers[i]
+}
+
+// positions returns the bits of an item, combining its two hashes like Kirsch and Mitzenmacher
+func (l *bloomLayer) positions(h1, h2 uint64) []uint64 {
+	size := uint64(len(l.bits)) * 8
+	positions := make([]uint64, l.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % size
+	}
+	return positions
+}
+
+func bloomHashes(item string) (uint64, uint64) {
+	h1 := murmurHash64A([]byte(item), 0xc6a4a7935bd1e995)
+	return h1, murmurHash64A([]byte(item), h1)
+}
+
+// bloomJSON is how a BloomFilter is stored and sent, each layer's bits as base64
+type bloomJSON struct {
+	ErrorRate float64          `json:"error_rate"`
+	Expansion int              `json:"expansion"`
+	Layers    []bloomLayerJSON `json:"layers"`
+}
+
+type bloomLayerJSON struct {
+	Bits     []byte `json:"bits"`
+	Hashes   int    `json:"hashes"`
+	Capacity int    `json:"capacity"`
+	Count    int    `json:"count"`
+}
+
+func (b *BloomFilter) MarshalJSON() ([]byte, error) {
+	raw := bloomJSON{ErrorRate: b.errorRate, Expansion: b.expansion, Layers: make([]bloomLayerJSON, len(b.layers))}
+	for i, layer := range b.layers {
+		raw.Layers[i] = bloomLayerJSON{Bits: layer.bits, Hashes: layer.hashes, Capacity: layer.capacity, Count: layer.count}
+	}
+	return json.Marshal(raw)
+}
+
+func (b *BloomFilter) UnmarshalJSON(data []byte) error {
+	var raw bloomJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if !(raw.ErrorRate > 0 && raw.ErrorRate < 1) || raw.Expansion < 0 || len(raw.Layers) == 0 {
+		return fmt.Errorf("%w: invalid bloom filter", internal.ErrBadRequest)
+	}
+	parsed := BloomFilter{errorRate: raw.ErrorRate, expansion: raw.Expansion}
+	for _, layer := range raw.Layers {
+		if len(layer.Bits) == 0 || layer.Hashes < 1 || layer.Hashes > 64 || layer.Capacity < 1 || layer.Count < 0 {
+			return fmt.Errorf("%w: invalid bloom filter", internal.ErrBadRequest)
+		}
+		parsed.layers = append(parsed.layers, &bloomLayer{bits: layer.Bits, hashes: layer.Hashes, capacity: layer.Capacity, count: layer.Count})
+		parsed.owned = append(parsed.owned, true)
+	}
+	*b = parsed
+	return nil
+}
@@ -1,9 +1,101 @@
 package data
 
-import "time"
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// ItemType tags what a CacheItem holds, so it is read back as the same type from AOF, snapshot and peers
+type ItemType string
+
+const (
+	TypeString ItemType = ""       // Value holds the value
+	TypeList   ItemType = "list"   // List holds the elements, head first
+	TypeHash   ItemType = "hash"   // Hash holds the fields
+	TypeSet    ItemType = "set"    // Set holds the members
+	TypeZSet   ItemType = "zset"   // ZSet holds the members with their scores
+	TypeStream ItemType = "stream" // Stream holds the entries and consumer groups
+	TypeHLL    ItemType = "hll"    // HLL holds the HyperLogLog registers
+	TypeBloom  ItemType = "bloom"  // Bloom holds the Bloom filter
+	TypeCuckoo ItemType = "cuckoo" // Cuckoo holds the cuckoo filter
+)
 
 type CacheItem struct {
 	Value      []byte
+	List       [][]byte            `json:",omitempty"`
+	Hash       map[string][]byte   `json:",omitempty"`
+	Set        map[string]struct{} `json:",omitempty"`
+	ZSet       *SortedSet          `json:",omitempty"`
+	Stream     *Stream             `json:",omitempty"`
+	HLL        *HyperLogLog        `json:",omitempty"`
+	Bloom      *BloomFilter        `json:",omitempty"`
+	Cuckoo     *CuckooFilter       `json:",omitempty"`
+	Type       ItemType            `json:",omitempty"`
 	Expiration time.Time
 	Persistent bool
+	Version    uint64 // write stamp, the highest one wins between replicas
+
+	listBase [][]byte // the array List is kept at the end of, the room before it takes pushes at the head
+}
+
+// ListRange resolves start and stop like LRANGE, negative indexes count from the tail.
+// ok is false when the range holds no element.
+func ListRange(length, start, stop int) (from, to int, ok bool) {
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	stop = min(stop, length-1)
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+	return start, stop + 1, true
+}
+
+// ListIndex resolves index like LINDEX, ok is false when it is out of range
+func ListIndex(length, index int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0 && index < length
+}
+
+// Set operations across keys
+const (
+	SetUnion        = "union"
+	SetIntersection = "inter"
+	SetDifference   = "diff" // members of the first set missing from the others
+)
+
+// CombineSets applies op to sets in order and returns the sorted members of the result.
+// A missing key counts as an empty set.
+func CombineSets(op string, sets []map[string]struct{}) []string {
+	result := make(map[string]struct{})
+	for i, set := range sets {
+		switch {
+		case i == 0 || op == SetUnion:
+			maps.Copy(result, set)
+		case op == SetIntersection:
+			maps.DeleteFunc(result, func(member string, _ struct{}) bool {
+				_, ok := set[member]
+				return !ok
+			})
+		case op == SetDifference:
+			maps.DeleteFunc(result, func(member string, _ struct{}) bool {
+				_, ok := set[member]
+				return ok
+			})
+		}
+	}
+	return SetMembers(result)
+}
+
+// SetMembers returns the members of set in order, an empty slice for an empty set
+func SetMembers(set map[string]struct{}) []string {
+	members := slices.AppendSeq(make([]string, 0, len(set)), maps.Keys(set))
+	slices.Sort(members)
+	return members
 }
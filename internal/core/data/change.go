@@ -0,0 +1,178 @@
+package data
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// ChangeOp names a write that changes a typed item in place, see Change
+type ChangeOp string
+
+const (
+	ChangeListPush ChangeOp = "list_push" // Values go to the tail, or in front one by one when Head is set
+	ChangeListTrim ChangeOp = "list_trim" // keeps the elements from Start to Stop, Stop excluded
+	ChangeHashSet  ChangeOp = "hash_set"  // Fields are set
+	ChangeHashDel  ChangeOp = "hash_del"  // Members are the fields removed
+	ChangeSetAdd   ChangeOp = "set_add"   // Members are added
+	ChangeSetRem   ChangeOp = "set_rem"   // Members are removed
+	ChangeZSetAdd  ChangeOp = "zset_add"  // Scores are set
+	ChangeZSetRem  ChangeOp = "zset_rem"  // Members are removed
+
+	ChangeStreamAdd     ChangeOp = "stream_add"     // Entry is appended, then the stream is trimmed as Trim asks
+	ChangeStreamTrim    ChangeOp = "stream_trim"    // the oldest entries are dropped as Trim asks
+	ChangeStreamGroup   ChangeOp = "stream_group"   // Group is created delivering the entries after ID
+	ChangeStreamDeliver ChangeOp = "stream_deliver" // IDs were handed to Consumer of Group at Time
+	ChangeStreamAck     ChangeOp = "stream_ack"     // IDs are acknowledged in Group
+	ChangeHLLAdd        ChangeOp = "hll_add"        // Members are added to the HyperLogLog
+)
+
+// Change is one write made in place to a typed item. The cache applies it under the shard lock and
+// logs it to the AOF instead of the whole item, so a write costs what it changes rather than the size
+// of the item. Loading the AOF applies it again.
+type Change struct {
+	Op      ChangeOp
+	Values  [][]byte          `json:",omitempty"`
+	Head    bool              `json:",omitempty"`
+	Start   int               `json:",omitempty"`
+	Stop    int               `json:",omitempty"`
+	Fields  map[string][]byte `json:",omitempty"`
+	Members []string          `json:",omitempty"`
+	Scores  []ScoredMember    `json:",omitempty"`
+
+	Entry    *StreamEntry `json:",omitempty"`
+	Trim     StreamTrim   `json:",omitzero"`
+	Group    string       `json:",omitempty"`
+	Consumer string       `json:",omitempty"`
+	ID       StreamID     `json:",omitzero"`
+	IDs      []StreamID   `json:",omitempty"`
+	Time     time.Time    `json:",omitzero"`
+}
+
+// Apply makes change to item in place
+func (item *CacheItem) Apply(change Change) {
+	switch change.Op {
+	case ChangeListPush:
+		if !change.Head {
+			item.List = append(item.List, change.Values...)
+			return
+		}
+		// like LPUSH, each value goes in front of the previous one
+		values := slices.Clone(change.Values)
+		slices.Reverse(values)
+		item.pushFront(values)
+	case ChangeListTrim:
+		// popped elements are cleared so the room they leave does not keep them alive
+		clear(item.List[:change.Start])
+		clear(item.List[change.Stop:])
+		item.List = item.List[change.Start:change.Stop]
+	case ChangeHashSet:
+		if item.Hash == nil {
+			item.Hash = make(map[string][]byte, len(change.Fields))
+		}
+		maps.Copy(item.Hash, change.Fields)
+	case ChangeHashDel:
+		for _, field := range change.Members {
+			delete(item.Hash, field)
+		}
+	case ChangeSetAdd:
+		if item.Set == nil {
+			item.Set = make(map[string]struct{}, len(change.Members))
+		}
+		for _, member := range change.Members {
+			item.Set[member] = struct{}{}
+		}
+	case ChangeSetRem:
+		for _, member := range change.Members {
+			delete(item.Set, member)
+		}
+	case ChangeZSetAdd:
+		if item.ZSet == nil {
+			item.ZSet = NewSortedSet()
+		}
+		for _, member := range change.Scores {
+			item.ZSet.Add(member.Member, member.Score)
+		}
+	case ChangeZSetRem:
+		for _, member := range change.Members {
+			item.ZSet.Remove(member)
+		}
+	case ChangeStreamAdd:
+		item.stream().add(*change.Entry, change.Trim)
+	case ChangeStreamTrim:
+		item.stream().trim(change.Trim)
+	case ChangeStreamGroup:
+		item.stream().createGroup(change.Group, change.ID)
+	case ChangeStreamDeliver:
+		item.stream().deliver(change.Group, change.Consumer, change.IDs, change.Time)
+	case ChangeStreamAck:
+		item.stream().ack(change.Group, change.IDs)
+	case ChangeHLLAdd:
+		if item.HLL == nil {
+			item.HLL = NewHyperLogLog()
+		}
+		item.HLL.Add(change.Members...)
+	}
+}
+
+// Empty reports whether a list, a hash, a set or a sorted set has nothing left, which deletes its key.
+// Other types are kept.
+func (item CacheItem) Empty() bool {
+	switch item.Type {
+	case TypeList:
+		return len(item.List) == 0
+	case TypeHash:
+		return len(item.Hash) == 0
+	case TypeSet:
+		return len(item.Set) == 0
+	case TypeZSet:
+		return item.ZSet.Len() == 0
+	}
+	return false
+}
+
+// Clone copies the containers that writes change in place, so the copy can be read once the
+// shard lock is released
+func (item CacheItem) Clone() CacheItem {
+	item.List = slices.Clone(item.List)
+	item.listBase = nil
+	item.Hash = maps.Clone(item.Hash)
+	item.Set = maps.Clone(item.Set)
+	if item.ZSet != nil {
+		item.ZSet = item.ZSet.Clone()
+	}
+	if item.Stream != nil {
+		item.Stream = item.Stream.Clone()
+	}
+	if item.HLL != nil {
+		item.HLL = item.HLL.Clone()
+	}
+	return item
+}
+
+// stream returns the stream of item, made when a write creates the key
+func (item *CacheItem) stream() *Stream {
+	if item.Stream == nil {
+		item.Stream = &Stream{}
+	}
+	return item.Stream
+}
+
+// pushFront puts values in front of the list, in their order. The list lives at the end of listBase,
+// so the room a pop or an earlier push left before it is reused and a push at either end is
+// amortized O(1).
+func (item *CacheItem) pushFront(values [][]byte) {
+	if len(values) == 0 {
+		return
+	}
+	room := cap(item.listBase) - cap(item.List)
+	if room < len(values) || len(item.List) == 0 || &item.listBase[room] != &item.List[0] {
+		room = len(item.List) + len(values)
+		grown := make([][]byte, 2*room)
+		copy(grown[room:], item.List)
+		item.listBase = grown
+	}
+	start := room - len(values)
+	copy(item.listBase[start:room], values)
+	item.List = item.listBase[start : room+len(item.List)]
+}
@@ -0,0 +1,279 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+)
+
+// Defaults of a filter created by its first add, like RedisBloom
+const (
+	CuckooDefaultCapacity      = 1024
+	CuckooDefaultBucketSize    = 2
+	CuckooDefaultMaxIterations = 20
+	CuckooDefaultExpansion     = 1
+
+	cuckooMaxLayers = 32
+)
+
+// CuckooMaxBytes caps the fingerprints of all the layers of a filter at 512MB, like a bitmap
+const CuckooMaxBytes = 512 << 20
+
+// CuckooOptions are the arguments of CF.RESERVE, zero values take the defaults
+type CuckooOptions struct {
+	Capacity      int `json:"capacity"`
+	BucketSize    int `json:"bucket_size"`    // fingerprints per bucket, 1 to 255
+	MaxIterations int `json:"max_iterations"` // fingerprints moved to make room before the filter grows
+	Expansion     int `json:"expansion"`      // how much larger each new layer is
+}
+
+// CuckooFilter tells whether an item may have been added, like a Bloom filter, and can also delete
+// one. It keeps an 8-bit fingerprint of each item in one of two buckets; when both are full it moves
+// fingerprints to their other bucket to make room, and past MaxIterations moves it adds a layer
+// Expansion times larger, up to 32 layers. An item added twice is held twice and deleted once at a time.
+type CuckooFilter struct {
+	bucketSize    int
+	maxIterations int
+	expansion     int
+	layers        []*cuckooLayer
+	owned         []bool // the layers this filter may write, the others are shared with the filter it was cloned from
+}
+
+type cuckooLayer struct {
+	buckets uint64 // a power of two
+	slots   []byte // bucketSize fingerprints per bucket, 0 is a free slot
+	count   int
+}
+
+func NewCuckooFilter(options CuckooOptions) (*CuckooFilter, error) {
+	if options.Capacity == 0 {
+		options.Capacity = CuckooDefaultCapacity
+	}
+	if options.BucketSize == 0 {
+		options.BucketSize = CuckooDefaultBucketSize
+	}
+	if options.MaxIterations == 0 {
+		options.MaxIterations = CuckooDefaultMaxIterations
+	}
+	if options.Expansion == 0 {
+		options.Expansion = CuckooDefaultExpansion
+	}
+	if options.Capacity < 1 || options.BucketSize < 1 || options.BucketSize > 255 || options.MaxIterations < 1 || options.Expansion < 1 {
+		return nil, fmt.Errorf("%w: capacity, max iterations and expansion must be positive, bucket size 1 to 255", internal.ErrBadRequest)
+	}
+	f := &CuckooFilter{bucketSize: options.BucketSize, maxIterations: options.MaxIterations, expansion: options.Expansion}
+	if options.Capacity > CuckooMaxBytes || f.layerSlots(uint64(options.Capacity)) > CuckooMaxBytes {
+		return nil, fmt.Errorf("%w: a filter of that capacity takes more than 512MB", internal.ErrBadRequest)
+	}
+	f.grow(uint64(options.Capacity))
+	return f, nil
+}
+
+// Clone returns a copy sharing the layers of f until it writes them
+func (f *CuckooFilter) Clone() *CuckooFilter {
+	clone := *f
+	clone.layers, clone.owned = slices.Clone(f.layers), make([]bool, len(f.layers))
+	return &clone
+}
+
+// Add adds item, even when it may be there already. f is modified, clone a stored filter first.
+func (f *CuckooFilter) Add(item string) error {
+	fp, hash := cuckooHash(item)
+	last := len(f.layers) - 1
+	if f.insert(last, fp, hash) {
+		return nil
+	}
+	if len(f.layers) == cuckooMaxLayers {
+		return fmt.Errorf("%w: the filter is full", internal.ErrBadRequest)
+	}
+	slots := f.layers[last].buckets * uint64(f.bucketSize)
+	if uint64(f.expansion) > CuckooMaxBytes/slots || f.size()+f.layerSlots(slots*uint64(f.expansion)) > CuckooMaxBytes {
+		return fmt.Errorf("%w: the filter is full, another layer would take it past 512MB", internal.ErrBadRequest)
+	}
+	f.grow(slots * uint64(f.expansion))
+	f.insert(last+1, fp, hash)
+	return nil
+}
+
+// Exists reports for each of items whether it may have been added
+func (f *CuckooFilter) Exists(items ...string) []bool {
+	exists := make([]bool, len(items))
+	for i, item := range items {
+		exists[i] = f.Count(item) > 0
+	}
+	return exists
+}
+
+// Count returns how many times item may have been added, more when other items share its fingerprint
+func (f *CuckooFilter) Count(item string) int {
+	fp, hash := cuckooHash(item)
+	count := 0
+	for _, layer := range f.layers {
+		i1, i2 := layer.indexes(fp, hash)
+		count += layer.countIn(i1, fp, f.bucketSize)
+		if i2 != i1 {
+			count += layer.countIn(i2, fp, f.bucketSize)
+		}
+	}
+	return count
+}
+
+// Del removes one copy of item, newest layer first, and reports whether there was one
+func (f *CuckooFilter) Del(item string) bool {
+	fp, hash := cuckooHash(item)
+	for l := len(f.layers) - 1; l >= 0; l-- {
+		i1, i2 := f.layers[l].indexes(fp, hash)
+		for _, bucket := range []uint64{i1, i2} {
+			if slot := f.layers[l].find(bucket, fp, f.bucketSize); slot >= 0 {
+				layer := f.writable(l)
+				layer.slots[slot] = 0
+				layer.count--
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insert puts fp in layer l, moving fingerprints to their other bucket when both of its are full.
+// When that fails the moves are undone, so no fingerprint is lost.
+func (f *CuckooFilter) insert(l int, fp byte, hash uint64) bool {
+	layer := f.writable(l)
+	i1, i2 := layer.indexes(fp, hash)
+	for _, bucket := range []uint64{i1, i2} {
+		if slot := layer.find(bucket, 0, f.bucketSize); slot >= 0 {
+			layer.slots[slot] = fp
+			layer.count++
+			return true
+		}
+	}
+	bucket := []uint64{i1, i2}[rand.IntN(2)]
+	var moved []int // the slots swapped, to undo them
+	for range f.maxIterations {
+		slot := int(bucket)*f.bucketSize + rand.IntN(f.bucketSize)
+		fp, layer.slots[slot] = layer.slots[slot], fp
+		moved = append(moved, slot)
+		bucket = layer.alternate(bucket, fp)
+		if free := layer.find(bucket, 0, f.bucketSize); free >= 0 {
+			layer.slots[free] = fp
+			layer.count++
+			return true
+		}
+	}
+	for i := len(moved) - 1; i >= 0; i-- {
+		fp, layer.slots[moved[i]] = layer.slots[moved[i]], fp
+	}
+	return false
+}
+
+// grow adds a layer of at least capacity slots
+func (f *CuckooFilter) grow(capacity uint64) {
+	slots := f.layerSlots(capacity)
+	f.layers = append(f.layers, &cuckooLayer{buckets: slots / uint64(f.bucketSize), slots: make([]byte, slots)})
+	f.owned = append(f.owned, true)
+}
+
+// layerSlots returns the slots of a layer of at least capacity, its buckets rounded up to a power of two
+func (f *CuckooFilter) layerSlots(capacity uint64) uint64 {
+	buckets := uint64(1) << bits.Len64((capacity+uint64(f.bucketSize)-1)/uint64(f.bucketSize)-1)
+	return buckets * uint64(f.bucketSize)
+}
+
+// size returns the slots of all the layers, a byte each
+func (f *CuckooFilter) size() uint64 {
+	var size uint64
+	for _, layer := range f.layers {
+		size += uint64(len(layer.slots))
+	}
+	return size
+}
+
+func (f *CuckooFilter) writable(l int) *cuckooLayer {
+	if !f.owned[l] {
+		layer := *f.layers[l]
+		layer.slots = slices.Clone(layer.slots)
+		f.layers[l], f.owned[l] = &layer, true
+	}
+	return f.layers[l]
+}
+
+func (l *cuckooLayer) indexes(fp byte, hash uint64) (uint64, uint64) {
+	i1 := hash & (l.buckets - 1)
+	return i1, l.alternate(i1, fp)
+}
+
+// alternate returns the other bucket of fp, found again from either one since it is a xor
+func (l *cuckooLayer) alternate(bucket uint64, fp byte) uint64 {
+	return (bucket ^ uint64(fp)*0x5bd1e995) & (l.buckets - 1)
+}
+
+// find returns the slot of bucket holding fp, -1 when none does
+func (l *cuckooLayer) find(bucket uint64, fp byte, bucketSize int) int {
+	start := int(bucket) * bucketSize
+	if i := slices.Index(l.slots[start:start+bucketSize], fp); i >= 0 {
+		return start + i
+	}
+	return -1
+}
+
+func (l *cuckooLayer) countIn(bucket uint64, fp byte, bucketSize int) int {
+	count := 0
+	for _, slot := range l.slots[int(bucket)*bucketSize : int(bucket+1)*bucketSize] {
+		if slot == fp {
+			count++
+		}
+	}
+	return count
+}
+
+// cuckooHash returns the fingerprint of item, never 0 which marks a free slot, and the hash its buckets come from
+func cuckooHash(item string) (byte, uint64) {
+	hash := murmurHash64A([]byte(item), 0xc6a4a7935bd1e995)
+	return byte(hash>>56%255 + 1), hash
+}
+
+// cuckooJSON is how a CuckooFilter is stored and sent, each layer's slots as base64
+type cuckooJSON struct {
+	BucketSize    int               `json:"bucket_size"`
+	MaxIterations int               `json:"max_iterations"`
+	Expansion     int               `json:"expansion"`
+	Layers        []cuckooLayerJSON `json:"layers"`
+}
+
+type cuckooLayerJSON struct {
+	Buckets uint64 `json:"buckets"`
+	Slots   []byte `json:"slots"`
+	Count   int    `json:"count"`
+}
+
+func (f *CuckooFilter) MarshalJSON() ([]byte, error) {
+	raw := cuckooJSON{BucketSize: f.bucketSize, MaxIterations: f.maxIterations, Expansion: f.expansion, Layers: make([]cuckooLayerJSON, len(f.layers))}
+	for i, layer := range f.layers {
+		raw.Layers[i] = cuckooLayerJSON{Buckets: layer.buckets, Slots: layer.slots, Count: layer.count}
+	}
+	return json.Marshal(raw)
+}
+
+func (f *CuckooFilter) UnmarshalJSON(data []byte) error {
+	var raw cuckooJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	invalid := fmt.Errorf("%w: invalid cuckoo filter", internal.ErrBadRequest)
+	if raw.BucketSize < 1 || raw.BucketSize > 255 || raw.MaxIterations < 1 || raw.Expansion < 1 || len(raw.Layers) == 0 || len(raw.Layers) > cuckooMaxLayers {
+		return invalid
+	}
+	parsed := CuckooFilter{bucketSize: raw.BucketSize, maxIterations: raw.MaxIterations, expansion: raw.Expansion}
+	for _, layer := range raw.Layers {
+		if layer.Buckets == 0 || layer.Buckets&(layer.Buckets-1) != 0 || uint64(len(layer.Slots)) != layer.Buckets*uint64(raw.BucketSize) || layer.Count < 0 {
+			return invalid
+		}
+		parsed.layers = append(parsed.layers, &cuckooLayer{buckets: layer.Buckets, slots: layer.Slots, count: layer.Count})
+		parsed.owned = append(parsed.owned, true)
+	}
+	*f = parsed
+	return nil
+}
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"cmp"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"slices"
+)
+
+// A geospatial index is a sorted set whose scores are 52-bit geohashes, like in Redis, so every sorted set
+// command works on it too. A geohash interleaves 26 bits of latitude and 26 bits of longitude, which
+// puts nearby points at nearby scores: the points of one geohash cell form one range of scores.
+
+const (
+	GeoLatMin = -85.05112878 // the latitudes Web Mercator covers
+	GeoLatMax = 85.05112878
+	GeoLonMin = -180.0
+	GeoLonMax = 180.0
+
+	geoSteps = 26 // bits of latitude and of longitude in a geohash
+	// earthRadius in meters is the one Redis measures distances with
+	earthRadius = 6372797.560856
+)
+
+// geoUnits are the units distances are given in, as meters
+var geoUnits = map[string]float64{"m": 1, "km": 1000, "mi": 1609.34, "ft": 0.3048}
+
+type GeoPoint struct {
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// Validate rejects a point outside the area a geohash covers
+func (p GeoPoint) Validate() error {
+	if !(p.Longitude >= GeoLonMin && p.Longitude <= GeoLonMax && p.Latitude >= GeoLatMin && p.Latitude <= GeoLatMax) {
+		return fmt.Errorf("%w: invalid longitude,latitude pair %f,%f", internal.ErrBadRequest, p.Longitude, p.Latitude)
+	}
+	return nil
+}
+
+// GeoMember is a member of a geospatial index with its position
+type GeoMember struct {
+	Member string `json:"member"`
+	GeoPoint
+}
+
+// GeoResult is a member GeoSearch found, Distance from the center of the search in the unit of the query
+type GeoResult struct {
+	Member   string  `json:"member"`
+	Distance float64 `json:"distance"`
+	GeoPoint
+}
+
+// GeoBox is the size of a search box, centered on the center of the search
+type GeoBox struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// GeoQuery is a GEOSEARCH: the members within Radius of a center, or within Box when it is set. The center is
+// the position of Member or From, exactly one of them. Results are sorted by distance, the farthest first
+// when Desc is set, and only the first Count are kept unless it is 0.
+type GeoQuery struct {
+	Member string    `json:"member,omitempty"`
+	From   *GeoPoint `json:"from,omitempty"`
+	Radius float64   `json:"radius,omitempty"`
+	Box    *GeoBox   `json:"box,omitempty"`
+	Unit   string    `json:"unit,omitempty"` // of Radius, Box and the distances returned: m (default), km, mi or ft
+	Count  int       `json:"count,omitempty"`
+	Desc   bool      `json:"desc,omitempty"`
+}
+
+// GeoScore returns the geohash point is stored with
+func GeoScore(p GeoPoint) float64 {
+	lat, lon := geoCell(p, geoSteps)
+	return float64(interleave(lat, lon))
+}
+
+// GeoPointOf returns the center of the geohash cell of score, which is within a meter of the point it was made from
+func GeoPointOf(score float64) GeoPoint {
+	hash := uint64(score)
+	lat, lon := deinterleave(hash)
+	cells := float64(uint64(1) << geoSteps)
+	latWidth, lonWidth := (GeoLatMax-GeoLatMin)/cells, (GeoLonMax-GeoLonMin)/cells
+	return GeoPoint{
+		Longitude: min(GeoLonMin+(float64(lon)+0.5)*lonWidth, GeoLonMax),
+		Latitude:  min(GeoLatMin+(float64(lat)+0.5)*latWidth, GeoLatMax),
+	}
+}
+
+// GeoDistance returns the great-circle distance between a and b in meters
+func GeoDistance(a, b GeoPoint) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	u := math.Sin((lat2 - lat1) / 2)
+	v := math.Sin(radians(b.Longitude-a.Longitude) / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1)*math.Cos(lat2)*v*v))
+}
+
+// GeoUnit returns the size of unit in meters, "" is meters
+func GeoUnit(unit string) (float64, error) {
+	if unit == "" {
+		return 1, nil
+	}
+	meters, ok := geoUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown unit %q, use m, km, mi or ft", internal.ErrBadRequest, unit)
+	}
+	return meters, nil
+}
+
+// GeoPositions returns the position of each of members in zset, nil for a missing one
+func GeoPositions(zset *SortedSet, members []string) []*GeoPoint {
+	positions := make([]*GeoPoint, len(members))
+	for i, member := range members {
+		if score, exists := zset.Score(member); exists {
+			point := GeoPointOf(score)
+			positions[i] = &point
+		}
+	}
+	return positions
+}
+
+// GeoDist returns the distance between members from and to of zset in unit, found is false when either is missing
+func GeoDist(zset *SortedSet, from, to, unit string) (distance float64, found bool, err error) {
+	meters, err := GeoUnit(unit)
+	if err != nil {
+		return 0, false, err
+	}
+	a, fromExists := zset.Score(from)
+	b, toExists := zset.Score(to)
+	if !fromExists || !toExists {
+		return 0, false, nil
+	}
+	return GeoDistance(GeoPointOf(a), GeoPointOf(b)) / meters, true, nil
+}
+
+// GeoSearch returns the members of zset q selects. It only looks at the geohash cell of the center and the
+// eight around it, at the finest precision where those cells still hold the whole search area.
+func GeoSearch(zset *SortedSet, q GeoQuery) ([]GeoResult, error) {
+	meters, err := q.validate()
+	if err != nil {
+		return nil, err
+	}
+	var center GeoPoint
+	if q.From != nil {
+		center = *q.From
+	} else {
+		score, exists := zset.Score(q.Member)
+		if !exists {
+			return nil, fmt.Errorf("%w: member %q is not in the index", internal.ErrNotFound, q.Member)
+		}
+		center = GeoPointOf(score)
+	}
+	halfWidth, halfHeight := q.Radius*meters, q.Radius*meters
+	if q.Box != nil {
+		halfWidth, halfHeight = q.Box.Width*meters/2, q.Box.Height*meters/2
+	}
+	results := []GeoResult{}
+	for _, cells := range geoSearchRanges(center, halfWidth, halfHeight) {
+		from, to := zset.ScoreRanks(ScoreBound{Score: cells[0]}, ScoreBound{Score: cells[1], Exclusive: true})
+		for _, member := range zset.Range(from, to) {
+			point := GeoPointOf(member.Score)
+			distance := GeoDistance(center, point)
+			if q.Box != nil {
+				// like Redis, the box spans halfHeight along the meridian and halfWidth along the parallel of the point
+				if earthRadius*math.Abs(radians(point.Latitude-center.Latitude)) > halfHeight ||
+					GeoDistance(GeoPoint{Longitude: center.Longitude, Latitude: point.Latitude}, point) > halfWidth {
+					continue
+				}
+			} else if distance > halfWidth {
+				continue
+			}
+			results = append(results, GeoResult{Member: member.Member, Distance: distance / meters, GeoPoint: point})
+		}
+	}
+	slices.SortFunc(results, func(a, b GeoResult) int {
+		if q.Desc {
+			a, b = b, a
+		}
+		return cmp.Or(cmp.Compare(a.Distance, b.Distance), cmp.Compare(a.Member, b.Member))
+	})
+	if q.Count > 0 && len(results) > q.Count {
+		results = results[:q.Count]
+	}
+	return results, nil
+}
+
+// validate checks q and returns its unit in meters
+func (q GeoQuery) validate() (float64, error) {
+	if (q.Member == "") == (q.From == nil) {
+		return 0, fmt.Errorf("%w: search from either a member or a point", internal.ErrBadRequest)
+	}
+	if q.From != nil {
+		if err := q.From.Validate(); err != nil {
+			return 0, err
+		}
+	}
+	if q.Box != nil && q.Radius != 0 {
+		return 0, fmt.Errorf("%w: search either a radius or a box", internal.ErrBadRequest)
+	}
+	if q.Radius < 0 || (q.Box != nil && (q.Box.Width < 0 || q.Box.Height < 0)) || q.Count < 0 {
+		return 0, fmt.Errorf("%w: radius, box and count cannot be negative", internal.ErrBadRequest)
+	}
+	return GeoUnit(q.Unit)
+}
+
+// geoSearchRanges returns the score ranges, low included and high excluded, of the nine geohash cells around
+// center, at the most steps where a cell is still at least halfWidth wide and halfHeight high. A point within
+// that of center, which is somewhere in the middle cell, then falls in one of them.
+func geoSearchRanges(center GeoPoint, halfWidth, halfHeight float64) [][2]float64 {
+	step := geoSteps
+	for ; step > 1; step-- {
+		cells := float64(uint64(1) << step)
+		height := earthRadius * radians((GeoLatMax-GeoLatMin)/cells)
+		// the box is widest in degrees where it is nearest to a pole
+		farthest := math.Abs(center.Latitude) + halfHeight/earthRadius*180/math.Pi
+		width := earthRadius * radians((GeoLonMax-GeoLonMin)/cells) * math.Cos(radians(min(farthest, 90)))
+		if height >= halfHeight && width >= halfWidth {
+			break
+		}
+	}
+	lat, lon := geoCell(center, step)
+	cells := uint32(1) << step
+	shift := 2 * (geoSteps - step)
+	seen := make(map[uint64]bool)
+	var ranges [][2]float64
+	for _, dLat := range []int{-1, 0, 1} {
+		cellLat := int64(lat) + int64(dLat)
+		if cellLat < 0 || cellLat >= int64(cells) {
+			continue
+		}
+		for _, dLon := range []int{-1, 0, 1} {
+			cellLon := (int64(lon) + int64(dLon) + int64(cells)) % int64(cells) // longitude wraps around
+			hash := interleave(uint32(cellLat), uint32(cellLon))
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			ranges = append(ranges, [2]float64{float64(hash << shift), float64((hash + 1) << shift)})
+		}
+	}
+	return ranges
+}
+
+// geoCell returns the cell of p in a grid of 2^step by 2^step
+func geoCell(p GeoPoint, step int) (lat, lon uint32) {
+	cells := float64(uint64(1) << step)
+	lat = uint32(min((p.Latitude-GeoLatMin)/(GeoLatMax-GeoLatMin)*cells, cells-1))
+	lon = uint32(min((p.Longitude-GeoLonMin)/(GeoLonMax-GeoLonMin)*cells, cells-1))
+	return lat, lon
+}
+
+// interleave puts the bits of lat at the even positions of a geohash and those of lon at the odd ones
+func interleave(lat, lon uint32) uint64 {
+	var hash uint64
+	for i := range 32 {
+		hash |= uint64(lat>>i&1)<<(2*i) | uint64(lon>>i&1)<<(2*i+1)
+	}
+	return hash
+}
+
+func deinterleave(hash uint64) (lat, lon uint32) {
+	for i := range 32 {
+		lat |= uint32(hash>>(2*i)&1) << i
+		lon |= uint32(hash>>(2*i+1)&1) << i
+	}
+	return lat, lon
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
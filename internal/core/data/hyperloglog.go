@@ -0,0 +1,314 @@
+package data
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	hllP         = 14
+	hllQ         = 64 - hllP // hash bits left to rank an element with
+	hllRegisters = 1 << hllP
+	hllMaxRank   = hllQ + 1
+	hllAlphaInf  = 0.721347520444481703680 // 1 / (2 ln 2)
+
+	// HLLDenseSize is the size of the dense encoding, 16384 registers of 6 bits
+	HLLDenseSize = hllRegisters * 6 / 8
+	// hllSparseMax is the number of registers a sparse HyperLogLog holds before it turns dense,
+	// 3000 bytes of them like the default hll-sparse-max-bytes of Redis
+	hllSparseMax = 3000 / 4
+
+	HLLSparse = "sparse"
+	HLLDense  = "dense"
+)
+
+// HyperLogLog estimates how many distinct elements were added to it, with a standard error of 0.81%,
+// in at most 12KB. It starts sparse, an ordered list of the registers that are not zero, and turns
+// into the dense array of 16384 6-bit registers once that list outgrows 3000 bytes. Elements are
+// hashed and ranked like in Redis, so the estimates match its PFCOUNT.
+type HyperLogLog struct {
+	sparse []uint32 // index<<8 | rank, ordered by index, used while dense is nil
+	dense  []byte
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{}
+}
+
+// Encoding returns HLLSparse or HLLDense
+func (h *HyperLogLog) Encoding() string {
+	if h != nil && h.dense != nil {
+		return HLLDense
+	}
+	return HLLSparse
+}
+
+func (h *HyperLogLog) Clone() *HyperLogLog {
+	if h == nil {
+		return NewHyperLogLog()
+	}
+	return &HyperLogLog{sparse: slices.Clone(h.sparse), dense: slices.Clone(h.dense)}
+}
+
+// Raising returns the elements whose rank is above their register, the ones Add would change.
+// Every element raises a nil HyperLogLog.
+func (h *HyperLogLog) Raising(elements []string) []string {
+	if h == nil {
+		return slices.Clone(elements)
+	}
+	var raising []string
+	for _, element := range elements {
+		if index, rank := hllPosition(element); rank > h.register(index) {
+			raising = append(raising, element)
+		}
+	}
+	return raising
+}
+
+// Add adds elements and reports whether a register changed, which is when the estimate may have.
+// h is modified in place.
+func (h *HyperLogLog) Add(elements ...string) bool {
+	changed := false
+	for _, element := range elements {
+		index, rank := hllPosition(element)
+		if rank > h.register(index) {
+			h.setRegister(index, rank)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Count returns the estimated number of distinct elements, 0 for nil
+func (h *HyperLogLog) Count() int64 {
+	return CountHyperLogLogs([]*HyperLogLog{h})
+}
+
+// CountHyperLogLogs estimates the number of distinct elements added to any of hlls, nil ones count as empty
+func CountHyperLogLogs(hlls []*HyperLogLog) int64 {
+	registers := mergeRegisters(hlls)
+	var histogram [hllQ + 2]int
+	for _, rank := range registers {
+		histogram[rank]++
+	}
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return int64(math.Round(hllAlphaInf * m * m / z))
+}
+
+// MergeHyperLogLogs returns a HyperLogLog holding the highest of each register of hlls, as if every
+// element of each had been added to it. nil ones count as empty.
+func MergeHyperLogLogs(hlls []*HyperLogLog) *HyperLogLog {
+	registers := mergeRegisters(hlls)
+	merged := NewHyperLogLog()
+	for index, rank := range registers {
+		if rank > 0 {
+			merged.setRegister(index, rank)
+		}
+	}
+	return merged
+}
+
+func mergeRegisters(hlls []*HyperLogLog) []uint8 {
+	registers := make([]uint8, hllRegisters)
+	for _, h := range hlls {
+		switch {
+		case h == nil:
+		case h.dense != nil:
+			for index := range registers {
+				registers[index] = max(registers[index], h.register(index))
+			}
+		default:
+			for _, entry := range h.sparse {
+				index, rank := int(entry>>8), uint8(entry)
+				registers[index] = max(registers[index], rank)
+			}
+		}
+	}
+	return registers
+}
+
+func (h *HyperLogLog) register(index int) uint8 {
+	if h.dense == nil {
+		i, found := h.sparseSearch(index)
+		if !found {
+			return 0
+		}
+		return uint8(h.sparse[i])
+	}
+	// registers are packed from the low bits of each byte up, like the dense encoding of Redis
+	bit := index * 6
+	b, shift := bit/8, uint(bit%8)
+	value := h.dense[b] >> shift
+	if shift > 2 {
+		value |= h.dense[b+1] << (8 - shift)
+	}
+	return value & 63
+}
+
+func (h *HyperLogLog) setRegister(index int, rank uint8) {
+	if h.dense == nil {
+		entry := uint32(index)<<8 | uint32(rank)
+		if i, found := h.sparseSearch(index); found {
+			h.sparse[i] = entry
+			return
+		} else if len(h.sparse) < hllSparseMax {
+			h.sparse = slices.Insert(h.sparse, i, entry)
+			return
+		}
+		h.toDense()
+	}
+	bit := index * 6
+	b, shift := bit/8, uint(bit%8)
+	h.dense[b] = h.dense[b]&^(63<<shift) | rank<<shift
+	if shift > 2 {
+		h.dense[b+1] = h.dense[b+1]&^(63>>(8-shift)) | rank>>(8-shift)
+	}
+}
+
+func (h *HyperLogLog) sparseSearch(index int) (int, bool) {
+	return slices.BinarySearchFunc(h.sparse, index, func(entry uint32, index int) int {
+		return int(entry>>8) - index
+	})
+}
+
+func (h *HyperLogLog) toDense() {
+	sparse := h.sparse
+	h.sparse, h.dense = nil, make([]byte, HLLDenseSize)
+	for _, entry := range sparse {
+		h.setRegister(int(entry>>8), uint8(entry))
+	}
+}
+
+// hyperLogLogJSON is how a HyperLogLog is stored and sent: the dense registers as they are, or
+// the sparse ones as 2 bytes of index and 1 byte of rank each
+type hyperLogLogJSON struct {
+	Encoding  string `json:"encoding"`
+	Registers []byte `json:"registers"`
+}
+
+func (h *HyperLogLog) MarshalJSON() ([]byte, error) {
+	if h.dense != nil {
+		return json.Marshal(hyperLogLogJSON{Encoding: HLLDense, Registers: h.dense})
+	}
+	registers := make([]byte, 0, len(h.sparse)*3)
+	for _, entry := range h.sparse {
+		registers = binary.BigEndian.AppendUint16(registers, uint16(entry>>8))
+		registers = append(registers, uint8(entry))
+	}
+	return json.Marshal(hyperLogLogJSON{Encoding: HLLSparse, Registers: registers})
+}
+
+func (h *HyperLogLog) UnmarshalJSON(b []byte) error {
+	var raw hyperLogLogJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	invalid := fmt.Errorf("%w: invalid %s hyperloglog", internal.ErrBadRequest, raw.Encoding)
+	switch raw.Encoding {
+	case HLLDense:
+		if len(raw.Registers) != HLLDenseSize {
+			return invalid
+		}
+		parsed := HyperLogLog{dense: raw.Registers}
+		for index := range hllRegisters {
+			if parsed.register(index) > hllMaxRank {
+				return invalid
+			}
+		}
+		*h = parsed
+	case HLLSparse:
+		if len(raw.Registers)%3 != 0 || len(raw.Registers)/3 > hllSparseMax {
+			return invalid
+		}
+		parsed := HyperLogLog{sparse: make([]uint32, 0, len(raw.Registers)/3)}
+		for i := 0; i < len(raw.Registers); i += 3 {
+			index, rank := int(binary.BigEndian.Uint16(raw.Registers[i:])), raw.Registers[i+2]
+			if index >= hllRegisters || rank == 0 || rank > hllMaxRank || (i > 0 && index <= int(parsed.sparse[i/3-1]>>8)) {
+				return invalid
+			}
+			parsed.sparse = append(parsed.sparse, uint32(index)<<8|uint32(rank))
+		}
+		*h = parsed
+	default:
+		return fmt.Errorf("%w: unknown hyperloglog encoding %q", internal.ErrBadRequest, raw.Encoding)
+	}
+	return nil
+}
+
+// hllPosition returns the register element falls in and its rank: the position of the lowest set
+// bit in the rest of its hash, from 1
+func hllPosition(element string) (int, uint8) {
+	hash := murmurHash64A([]byte(element), 0xadc83b19)
+	index := int(hash & (hllRegisters - 1))
+	hash = hash>>hllP | 1<<hllQ // stop the rank at hllMaxRank
+	return index, uint8(bits.TrailingZeros64(hash) + 1)
+}
+
+// murmurHash64A is the hash Redis ranks HyperLogLog elements with
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m, r = 0xc6a4a7935bd1e995, 47
+	h := seed ^ uint64(len(key))*m
+	for ; len(key) >= 8; key = key[8:] {
+		k := binary.LittleEndian.Uint64(key)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if len(key) > 0 {
+		for i := len(key) - 1; i >= 0; i-- {
+			h ^= uint64(key[i]) << (8 * i)
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllSigma and hllTau correct the estimate for empty and saturated registers, from
+// "New cardinality estimation algorithms for HyperLogLog sketches" by Otmar Ertl
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		previous := z
+		z += x * y
+		y += y
+		if z == previous {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		previous := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if z == previous {
+			return z / 3
+		}
+	}
+}
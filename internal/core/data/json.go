@@ -0,0 +1,368 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSON documents are string values holding JSON, changed in place by path. A path is the part of JSONPath
+// that names a single value: "$" is the whole document, ".name" or "['name']" a member of an object and
+// "[n]" an element of an array, counted from the end when n is negative. The leading "$" may be left out.
+// Edits are spliced into the stored bytes, so the rest of a document keeps its key order and formatting.
+
+// JSONSetOptions are the conditions of JSON.SET
+type JSONSetOptions struct {
+	NX bool `json:"nx"` // only set a value that does not exist
+	XX bool `json:"xx"` // only replace a value that exists
+}
+
+type jsonStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonEntry is a member of an object or an element of an array, doc[start:end] including the key of a member
+type jsonEntry struct {
+	key                  string
+	start, end           int
+	valueStart, valueEnd int
+}
+
+// JSONGet returns the value at path in doc, found is false when nothing is there
+func JSONGet(doc []byte, path string) (json.RawMessage, bool, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validDocument(doc); err != nil {
+		return nil, false, err
+	}
+	start, end, found := locate(doc, steps)
+	if !found {
+		return nil, false, nil
+	}
+	return slices.Clone(doc[start:end]), true, nil
+}
+
+// JSONSet returns doc with value at path, and whether options allowed the write. A missing member is added
+// to its object, but the object itself must exist. A nil doc only takes the root path.
+func JSONSet(doc []byte, path string, value []byte, options JSONSetOptions) ([]byte, bool, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if options.NX && options.XX {
+		return nil, false, fmt.Errorf("%w: nx and xx cannot be combined", internal.ErrBadRequest)
+	}
+	if !json.Valid(value) {
+		return nil, false, fmt.Errorf("%w: value is not JSON", internal.ErrBadRequest)
+	}
+	if doc == nil {
+		if len(steps) > 0 {
+			return nil, false, fmt.Errorf("%w: a new document must be set at the root path", internal.ErrBadRequest)
+		}
+		if options.XX {
+			return nil, false, nil
+		}
+		return bytes.TrimSpace(value), true, nil
+	}
+	if err := validDocument(doc); err != nil {
+		return nil, false, err
+	}
+	if start, end, found := locate(doc, steps); found {
+		if options.NX {
+			return nil, false, nil
+		}
+		return splice(doc, start, end, value), true, nil
+	}
+	parentStart, _, found := locate(doc, steps[:len(steps)-1])
+	last := steps[len(steps)-1]
+	if !found || doc[parentStart] != '{' || last.isIndex {
+		return nil, false, fmt.Errorf("%w: path %q does not exist", internal.ErrBadRequest, path)
+	}
+	if options.XX {
+		return nil, false, nil
+	}
+	key, _ := json.Marshal(last.key)
+	member := append(append(key, ':'), value...)
+	return insert(doc, parentStart, member), true, nil
+}
+
+// JSONDel returns doc without the value at path and whether there was one. Deleting the root
+// returns nil, the key is then deleted.
+func JSONDel(doc []byte, path string) ([]byte, bool, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validDocument(doc); err != nil {
+		return nil, false, err
+	}
+	if len(steps) == 0 {
+		return nil, true, nil
+	}
+	// a scalar has nothing under it to delete
+	parentStart, _, found := locate(doc, steps[:len(steps)-1])
+	if !found || doc[parentStart] != '{' && doc[parentStart] != '[' {
+		return doc, false, nil
+	}
+	entries := jsonEntries(doc, parentStart)
+	i, found := find(doc[parentStart], entries, steps[len(steps)-1])
+	if !found {
+		return doc, false, nil
+	}
+	// take the comma before the entry, or after it when it is the first
+	from, to := entries[i].start, entries[i].end
+	switch {
+	case i > 0:
+		from = entries[i-1].end
+	case len(entries) > 1:
+		to = entries[1].start
+	}
+	return splice(doc, from, to, nil), true, nil
+}
+
+// JSONArrAppend returns doc with values appended to the array at path and its new length
+func JSONArrAppend(doc []byte, path string, values []json.RawMessage) ([]byte, int, error) {
+	start, _, err := target(doc, path, '[', "an array")
+	if err != nil {
+		return nil, 0, err
+	}
+	length := len(jsonEntries(doc, start))
+	for _, value := range values {
+		if !json.Valid(value) {
+			return nil, 0, fmt.Errorf("%w: value is not JSON", internal.ErrBadRequest)
+		}
+		doc = insert(doc, start, value)
+		length++
+	}
+	return doc, length, nil
+}
+
+// JSONNumIncrBy returns doc with delta added to the number at path, and that number. Integers stay
+// integers while the sum fits in 64 bits.
+func JSONNumIncrBy(doc []byte, path string, delta json.Number) ([]byte, json.RawMessage, error) {
+	start, end, err := target(doc, path, '-', "a number")
+	if err != nil {
+		return nil, nil, err
+	}
+	current := json.Number(doc[start:end])
+	var sum json.RawMessage
+	a, aErr := current.Int64()
+	b, bErr := delta.Int64()
+	if aErr == nil && bErr == nil && (b >= 0 && a <= math.MaxInt64-b || b < 0 && a >= math.MinInt64-b) {
+		sum = strconv.AppendInt(nil, a+b, 10)
+	} else {
+		x, _ := current.Float64()
+		y, err := delta.Float64()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: increment %q is not a number", internal.ErrBadRequest, delta)
+		}
+		if math.IsInf(x+y, 0) || math.IsNaN(x+y) {
+			return nil, nil, fmt.Errorf("%w: increment would overflow", internal.ErrBadRequest)
+		}
+		sum = strconv.AppendFloat(nil, x+y, 'g', -1, 64)
+	}
+	return splice(doc, start, end, sum), sum, nil
+}
+
+// target returns where the value at path is, after checking it opens with kind, '-' standing for any number.
+// A nil doc or a missing path is ErrNotFound.
+func target(doc []byte, path string, kind byte, name string) (start, end int, err error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if doc == nil {
+		return 0, 0, internal.ErrNotFound
+	}
+	if err := validDocument(doc); err != nil {
+		return 0, 0, err
+	}
+	start, end, found := locate(doc, steps)
+	if !found {
+		return 0, 0, fmt.Errorf("%w: path %q does not exist", internal.ErrNotFound, path)
+	}
+	if c := doc[start]; c != kind && (kind != '-' || c < '0' || c > '9') {
+		return 0, 0, fmt.Errorf("%w: the value at %q is not %s", internal.ErrBadRequest, path, name)
+	}
+	return start, end, nil
+}
+
+func validDocument(doc []byte) error {
+	if !json.Valid(doc) {
+		return fmt.Errorf("%w: the value is not a JSON document", internal.ErrWrongType)
+	}
+	return nil
+}
+
+func parseJSONPath(path string) ([]jsonStep, error) {
+	rest := strings.TrimPrefix(path, "$")
+	if rest != "" && rest == path && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	invalid := fmt.Errorf("%w: invalid JSON path %q", internal.ErrBadRequest, path)
+	var steps []jsonStep
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			name := rest[1:]
+			if i := strings.IndexAny(name, ".["); i >= 0 {
+				name = name[:i]
+			}
+			if name == "" {
+				return nil, invalid
+			}
+			steps = append(steps, jsonStep{key: name})
+			rest = rest[1+len(name):]
+		case strings.HasPrefix(rest, "['") || strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest[2:], string(rest[1])+"]")
+			if end < 0 {
+				return nil, invalid
+			}
+			steps = append(steps, jsonStep{key: rest[2 : 2+end]})
+			rest = rest[2+end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, invalid
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, invalid
+			}
+			steps = append(steps, jsonStep{index: index, isIndex: true})
+			rest = rest[end+1:]
+		default:
+			return nil, invalid
+		}
+	}
+	return steps, nil
+}
+
+// locate returns where the value at steps is in doc, which must be valid JSON
+func locate(doc []byte, steps []jsonStep) (start, end int, found bool) {
+	start = skipSpace(doc, 0)
+	end = skipValue(doc, start)
+	for _, step := range steps {
+		if doc[start] != '{' && doc[start] != '[' {
+			return 0, 0, false
+		}
+		entries := jsonEntries(doc, start)
+		i, ok := find(doc[start], entries, step)
+		if !ok {
+			return 0, 0, false
+		}
+		start, end = entries[i].valueStart, entries[i].valueEnd
+	}
+	return start, end, true
+}
+
+// find returns which of the entries of an object or array, opened by bracket, step names
+func find(bracket byte, entries []jsonEntry, step jsonStep) (int, bool) {
+	if bracket == '[' {
+		if !step.isIndex {
+			return 0, false
+		}
+		i := step.index
+		if i < 0 {
+			i += len(entries)
+		}
+		return i, i >= 0 && i < len(entries)
+	}
+	if bracket != '{' || step.isIndex {
+		return 0, false
+	}
+	// the last of duplicate keys wins, like when the document is decoded
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].key == step.key {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// jsonEntries lists the members or elements of the object or array at doc[start]
+func jsonEntries(doc []byte, start int) []jsonEntry {
+	var entries []jsonEntry
+	object := doc[start] == '{'
+	i := skipSpace(doc, start+1)
+	for doc[i] != '}' && doc[i] != ']' {
+		entry := jsonEntry{start: i}
+		if object {
+			keyEnd := skipValue(doc, i)
+			json.Unmarshal(doc[i:keyEnd], &entry.key)
+			i = skipSpace(doc, skipSpace(doc, keyEnd)+1) // past the colon
+		}
+		entry.valueStart, entry.valueEnd = i, skipValue(doc, i)
+		entry.end = entry.valueEnd
+		entries = append(entries, entry)
+		i = skipSpace(doc, entry.end)
+		if doc[i] == ',' {
+			i = skipSpace(doc, i+1)
+		}
+	}
+	return entries
+}
+
+// insert adds entry at the end of the object or array at doc[start]
+func insert(doc []byte, start int, entry []byte) []byte {
+	entries := jsonEntries(doc, start)
+	if len(entries) == 0 {
+		at := skipSpace(doc, start+1)
+		return splice(doc, at, at, entry)
+	}
+	at := entries[len(entries)-1].end
+	return splice(doc, at, at, append([]byte{','}, entry...))
+}
+
+func splice(doc []byte, start, end int, value []byte) []byte {
+	return slices.Concat(doc[:start], value, doc[end:])
+}
+
+func skipSpace(doc []byte, i int) int {
+	for i < len(doc) && (doc[i] == ' ' || doc[i] == '\t' || doc[i] == '\n' || doc[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipValue returns where the value starting at doc[i] ends, doc must be valid JSON
+func skipValue(doc []byte, i int) int {
+	depth := 0
+	for ; i < len(doc); i++ {
+		switch doc[i] {
+		case '"':
+			for i++; doc[i] != '"'; i++ {
+				if doc[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+			if depth < 0 {
+				return i
+			}
+		case ',', ' ', '\t', '\n', '\r', ':':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
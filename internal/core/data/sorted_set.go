@@ -0,0 +1,475 @@
+package data
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ScoredMember is a member of a sorted set with its score
+type ScoredMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+const (
+	skipMaxLevel    = 32
+	skipProbability = 0.25
+)
+
+// SortedSet keeps members ordered by score, then by member, in a skiplist. Each link counts the
+// nodes it passes over, so the rank of a member and the member at a rank are found in O(log n).
+// The cache changes a stored SortedSet in place under its shard lock, a reader outside the lock gets a Clone.
+type SortedSet struct {
+	head   *skipNode
+	level  int
+	length int
+	scores map[string]float64
+}
+
+type skipNode struct {
+	member string
+	score  float64
+	levels []skipLink
+}
+
+type skipLink struct {
+	next *skipNode
+	span int // nodes passed by following next, the nodes left behind this one when next is nil
+}
+
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		head:   &skipNode{levels: make([]skipLink, skipMaxLevel)},
+		level:  1,
+		scores: make(map[string]float64),
+	}
+}
+
+// Len returns the number of members, a nil set is empty
+func (z *SortedSet) Len() int {
+	if z == nil {
+		return 0
+	}
+	return z.length
+}
+
+func (z *SortedSet) Score(member string) (float64, bool) {
+	if z == nil {
+		return 0, false
+	}
+	score, exists := z.scores[member]
+	return score, exists
+}
+
+// Rank returns the 0-based position of member, counted from the highest score when rev is set
+func (z *SortedSet) Rank(member string, rev bool) (int, bool) {
+	score, exists := z.Score(member)
+	if !exists {
+		return 0, false
+	}
+	rank := z.countWhile(func(node *skipNode) bool { return before(node, score, member) })
+	if rev {
+		rank = z.length - 1 - rank
+	}
+	return rank, true
+}
+
+// Range returns the members ranked from `from` (included) to `to` (excluded), lowest score first
+func (z *SortedSet) Range(from, to int) []ScoredMember {
+	from, to = max(from, 0), min(to, z.Len())
+	if from >= to {
+		return []ScoredMember{}
+	}
+	members := make([]ScoredMember, 0, to-from)
+	for node := z.nodeAt(from); node != nil && len(members) < to-from; node = node.levels[0].next {
+		members = append(members, ScoredMember{Member: node.member, Score: node.score})
+	}
+	return members
+}
+
+// Add sets the score of member and reports whether it is new
+func (z *SortedSet) Add(member string, score float64) bool {
+	current, exists := z.scores[member]
+	if exists {
+		if current == score {
+			return false
+		}
+		z.delete(member, current)
+	}
+	z.insert(member, score)
+	z.scores[member] = score
+	return !exists
+}
+
+// Remove deletes member and reports whether it existed
+func (z *SortedSet) Remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+	z.delete(member, score)
+	delete(z.scores, member)
+	return true
+}
+
+// Clone copies the skiplist node by node, keeping the level of every node. A nil set clones to an empty one.
+func (z *SortedSet) Clone() *SortedSet {
+	clone := NewSortedSet()
+	if z == nil {
+		return clone
+	}
+	var last [skipMaxLevel]*skipNode
+	var lastRank [skipMaxLevel]int
+	for i := range last {
+		last[i] = clone.head
+	}
+	rank := 0
+	for node := z.head.levels[0].next; node != nil; node = node.levels[0].next {
+		rank++
+		copied := &skipNode{member: node.member, score: node.score, levels: make([]skipLink, len(node.levels))}
+		for i := range copied.levels {
+			last[i].levels[i] = skipLink{next: copied, span: rank - lastRank[i]}
+			last[i], lastRank[i] = copied, rank
+		}
+		clone.scores[node.member] = node.score
+	}
+	for i := 0; i < z.level; i++ {
+		last[i].levels[i].span = rank - lastRank[i]
+	}
+	clone.level, clone.length = z.level, z.length
+	return clone
+}
+
+// ScoreRanks returns the ranks, `from` included and `to` excluded, of the members scored between low and high
+func (z *SortedSet) ScoreRanks(low, high ScoreBound) (from, to int) {
+	from = z.countWhile(func(node *skipNode) bool { return !low.allows(node.score, true) })
+	to = z.countWhile(func(node *skipNode) bool { return high.allows(node.score, false) })
+	return from, max(from, to)
+}
+
+// LexRanks returns the ranks of the members between low and high. Like ZRANGEBYLEX, it expects
+// every member to share one score, so that score order is member order.
+func (z *SortedSet) LexRanks(low, high LexBound) (from, to int) {
+	from = z.countWhile(func(node *skipNode) bool { return !low.allows(node.member, true) })
+	to = z.countWhile(func(node *skipNode) bool { return high.allows(node.member, false) })
+	return from, max(from, to)
+}
+
+// Query returns the members q selects, see ZRangeQuery
+func (z *SortedSet) Query(q ZRangeQuery) ([]ScoredMember, error) {
+	var from, to int
+	switch q.By {
+	case ZRangeByRank, "":
+		start, err := strconv.Atoi(cmp.Or(q.Start, "0"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: start %q is not an integer", internal.ErrBadRequest, q.Start)
+		}
+		stop, err := strconv.Atoi(cmp.Or(q.Stop, "-1"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: stop %q is not an integer", internal.ErrBadRequest, q.Stop)
+		}
+		if q.Offset != 0 || q.Count >= 0 {
+			return nil, fmt.Errorf("%w: offset and count need a range by score or by lex", internal.ErrBadRequest)
+		}
+		first, end, ok := ListRange(z.Len(), start, stop)
+		if !ok {
+			return []ScoredMember{}, nil
+		}
+		from, to = first, end
+		if q.Rev {
+			from, to = z.Len()-end, z.Len()-first
+		}
+	case ZRangeByScore, ZRangeByLex:
+		low, high := q.Start, q.Stop
+		if q.Rev {
+			low, high = high, low // like ZRANGE ... REV, start is the upper bound
+		}
+		var err error
+		if q.By == ZRangeByScore {
+			from, to, err = parseRanks(cmp.Or(low, "-inf"), cmp.Or(high, "+inf"), ParseScoreBound, z.ScoreRanks)
+		} else {
+			from, to, err = parseRanks(cmp.Or(low, "-"), cmp.Or(high, "+"), ParseLexBound, z.LexRanks)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if q.Offset < 0 {
+			return []ScoredMember{}, nil
+		}
+		if q.Rev {
+			to = max(to-q.Offset, from)
+			if q.Count >= 0 {
+				from = max(from, to-q.Count)
+			}
+		} else {
+			from = min(from+q.Offset, to)
+			if q.Count >= 0 {
+				to = min(to, from+q.Count)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown range %q", internal.ErrBadRequest, q.By)
+	}
+	members := z.Range(from, to)
+	if q.Rev {
+		slices.Reverse(members)
+	}
+	return members, nil
+}
+
+// MarshalJSON writes the members in order, so the set is stored in the AOF and sent to peers as a plain array
+func (z *SortedSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(z.Range(0, z.Len()))
+}
+
+func (z *SortedSet) UnmarshalJSON(raw []byte) error {
+	var members []ScoredMember
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return err
+	}
+	*z = *NewSortedSet()
+	for _, member := range members {
+		z.Add(member.Member, member.Score)
+	}
+	return nil
+}
+
+func (z *SortedSet) insert(member string, score float64) {
+	var update [skipMaxLevel]*skipNode
+	var rank [skipMaxLevel]int
+	node := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		if i < z.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for node.levels[i].next != nil && before(node.levels[i].next, score, member) {
+			rank[i] += node.levels[i].span
+			node = node.levels[i].next
+		}
+		update[i] = node
+	}
+	level := randomLevel()
+	if level > z.level {
+		for i := z.level; i < level; i++ {
+			update[i] = z.head
+			z.head.levels[i].span = z.length
+		}
+		z.level = level
+	}
+	inserted := &skipNode{member: member, score: score, levels: make([]skipLink, level)}
+	for i := 0; i < level; i++ {
+		inserted.levels[i].next = update[i].levels[i].next
+		update[i].levels[i].next = inserted
+		inserted.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < z.level; i++ {
+		update[i].levels[i].span++
+	}
+	z.length++
+}
+
+func (z *SortedSet) delete(member string, score float64) {
+	var update [skipMaxLevel]*skipNode
+	node := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for node.levels[i].next != nil && before(node.levels[i].next, score, member) {
+			node = node.levels[i].next
+		}
+		update[i] = node
+	}
+	deleted := node.levels[0].next
+	for i := 0; i < z.level; i++ {
+		if update[i].levels[i].next == deleted {
+			update[i].levels[i].span += deleted.levels[i].span - 1
+			update[i].levels[i].next = deleted.levels[i].next
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	for z.level > 1 && z.head.levels[z.level-1].next == nil {
+		z.level--
+	}
+	z.length--
+}
+
+// countWhile returns how many members, from the lowest, satisfy pred. pred must hold for a prefix of the set.
+func (z *SortedSet) countWhile(pred func(*skipNode) bool) int {
+	if z == nil {
+		return 0
+	}
+	count := 0
+	node := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for node.levels[i].next != nil && pred(node.levels[i].next) {
+			count += node.levels[i].span
+			node = node.levels[i].next
+		}
+	}
+	return count
+}
+
+// nodeAt returns the node at the 0-based rank
+func (z *SortedSet) nodeAt(rank int) *skipNode {
+	traversed := 0
+	node := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for node.levels[i].next != nil && traversed+node.levels[i].span <= rank+1 {
+			traversed += node.levels[i].span
+			node = node.levels[i].next
+		}
+		if traversed == rank+1 {
+			return node
+		}
+	}
+	return nil
+}
+
+// before reports whether node sorts before the member with score
+func before(node *skipNode, score float64, member string) bool {
+	return node.score < score || (node.score == score && node.member < member)
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipMaxLevel && rand.Float64() < skipProbability {
+		level++
+	}
+	return level
+}
+
+// Ways ZRangeQuery selects members
+const (
+	ZRangeByRank  = "rank"
+	ZRangeByScore = "score"
+	ZRangeByLex   = "lex"
+)
+
+// ZRangeQuery selects members like ZRANGE. Start and Stop are ranks (negative ones count from the
+// end), score bounds ("1.5", "(1.5" exclusive, "-inf", "+inf") or lex bounds ("[a", "(a", "-", "+").
+// An empty Start or Stop leaves that end of the range open.
+// Rev walks from the highest score, so Start is then the upper bound. Offset and Count page a
+// range by score or lex, a negative Count returns every member after Offset.
+type ZRangeQuery struct {
+	By     string
+	Start  string
+	Stop   string
+	Rev    bool
+	Offset int
+	Count  int
+}
+
+// ScoreBound is one end of a score range
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+// ParseScoreBound reads "1.5", "(1.5" (exclusive), "-inf" or "+inf"
+func ParseScoreBound(bound string) (ScoreBound, error) {
+	exclusive := strings.HasPrefix(bound, "(")
+	score, err := strconv.ParseFloat(strings.TrimPrefix(bound, "("), 64)
+	if err != nil || math.IsNaN(score) {
+		return ScoreBound{}, fmt.Errorf("%w: score bound %q is not a number", internal.ErrBadRequest, bound)
+	}
+	return ScoreBound{Score: score, Exclusive: exclusive}, nil
+}
+
+// allows reports whether score is on the inner side of the bound, as the lower bound when lower is set
+func (b ScoreBound) allows(score float64, lower bool) bool {
+	switch {
+	case score == b.Score:
+		return !b.Exclusive
+	case lower:
+		return score > b.Score
+	default:
+		return score < b.Score
+	}
+}
+
+// LexBound is one end of a member range, Infinity is -1 for "-" and 1 for "+"
+type LexBound struct {
+	Member    string
+	Exclusive bool
+	Infinity  int
+}
+
+// ParseLexBound reads "[a" (inclusive), "(a" (exclusive), "-" or "+"
+func ParseLexBound(bound string) (LexBound, error) {
+	switch {
+	case bound == "-":
+		return LexBound{Infinity: -1}, nil
+	case bound == "+":
+		return LexBound{Infinity: 1}, nil
+	case strings.HasPrefix(bound, "["):
+		return LexBound{Member: bound[1:]}, nil
+	case strings.HasPrefix(bound, "("):
+		return LexBound{Member: bound[1:], Exclusive: true}, nil
+	}
+	return LexBound{}, fmt.Errorf("%w: lex bound %q must start with '[' or '(', or be '-' or '+'", internal.ErrBadRequest, bound)
+}
+
+func (b LexBound) allows(member string, lower bool) bool {
+	switch {
+	case b.Infinity != 0:
+		return (b.Infinity < 0) == lower
+	case member == b.Member:
+		return !b.Exclusive
+	case lower:
+		return member > b.Member
+	default:
+		return member < b.Member
+	}
+}
+
+func parseRanks[B any](low, high string, parse func(string) (B, error), ranks func(B, B) (int, int)) (int, int, error) {
+	lowBound, err := parse(low)
+	if err != nil {
+		return 0, 0, err
+	}
+	highBound, err := parse(high)
+	if err != nil {
+		return 0, 0, err
+	}
+	from, to := ranks(lowBound, highBound)
+	return from, to, nil
+}
+
+// ZAddOptions are the conditions of ZADD
+type ZAddOptions struct {
+	NX bool `json:"nx"` // only add new members
+	XX bool `json:"xx"` // only update existing members
+	GT bool `json:"gt"` // only update a member to a greater score
+	LT bool `json:"lt"` // only update a member to a lower score
+}
+
+// Validate rejects the combinations ZADD rejects
+func (o ZAddOptions) Validate() error {
+	if (o.NX && o.XX) || (o.GT && o.LT) || (o.NX && (o.GT || o.LT)) {
+		return fmt.Errorf("%w: nx, xx, gt and lt cannot be combined that way", internal.ErrBadRequest)
+	}
+	return nil
+}
+
+// Allows reports whether score may be written over current, exists is false for a new member
+func (o ZAddOptions) Allows(current float64, exists bool, score float64) bool {
+	if !exists {
+		return !o.XX
+	}
+	return !o.NX && (!o.GT || score > current) && (!o.LT || score < current)
+}
+
+// ValidScore rejects the scores JSON cannot carry
+func ValidScore(score float64) error {
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return fmt.Errorf("%w: score must be a finite number", internal.ErrBadRequest)
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+package data
+
+import (
+	"cmp"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamID orders the entries of a stream: the millisecond an entry was added at, then a sequence
+// within that millisecond. It is written "ms-seq".
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+func (id StreamID) Compare(other StreamID) int {
+	return cmp.Or(cmp.Compare(id.Ms, other.Ms), cmp.Compare(id.Seq, other.Seq))
+}
+
+func (id StreamID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *StreamID) UnmarshalText(text []byte) error {
+	parsed, err := ParseStreamID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// ParseStreamID reads "ms-seq", or "ms" for the first ID of that millisecond
+func ParseStreamID(id string) (StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, fmt.Errorf("%w: invalid stream ID %q", internal.ErrBadRequest, id)
+	}
+	var seq uint64
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return StreamID{}, fmt.Errorf("%w: invalid stream ID %q", internal.ErrBadRequest, id)
+		}
+	}
+	return StreamID{Ms: ms, Seq: seq}, nil
+}
+
+// StreamEntry is one record of a stream. Fields of a trimmed entry still pending in a group are nil.
+type StreamEntry struct {
+	ID     StreamID
+	Fields map[string][]byte
+}
+
+// PendingEntry is an entry delivered to a consumer of a group and not acknowledged yet
+type PendingEntry struct {
+	ID         StreamID  `json:"id"`
+	Consumer   string    `json:"consumer"`
+	Delivered  time.Time `json:"delivered"` // last delivery
+	Deliveries int       `json:"deliveries"`
+}
+
+// ConsumerGroup tracks the last entry handed to the group and the entries its consumers have not acknowledged
+type ConsumerGroup struct {
+	LastDelivered StreamID
+	Pending       map[StreamID]PendingEntry `json:",omitempty"`
+}
+
+// StreamTrim bounds a stream like XTRIM: keep the MaxLen newest entries, or the entries from MinID on.
+// The zero value does not trim.
+type StreamTrim struct {
+	MaxLen *int   `json:"maxlen,omitempty"`
+	MinID  string `json:"minid,omitempty"`
+}
+
+// IsZero reports whether the trim is unset
+func (t StreamTrim) IsZero() bool {
+	return t.MaxLen == nil && t.MinID == ""
+}
+
+// Validate rejects a trim naming both strategies or a negative length
+func (t StreamTrim) Validate() error {
+	if t.MaxLen != nil && t.MinID != "" {
+		return fmt.Errorf("%w: trim by maxlen or by minid, not both", internal.ErrBadRequest)
+	}
+	if t.MaxLen != nil && *t.MaxLen < 0 {
+		return fmt.Errorf("%w: maxlen must not be negative", internal.ErrBadRequest)
+	}
+	if t.MinID != "" {
+		if _, err := ParseStreamID(t.MinID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stream is an append-only log of entries in ID order with its consumer groups. The methods here
+// only read, a write is a Change the cache applies in place under the shard lock. A stream is not
+// deleted when it runs out of entries, its last ID and groups are kept until the key is deleted.
+type Stream struct {
+	Entries []StreamEntry
+	LastID  StreamID                 // highest ID ever added, trimmed entries included
+	Groups  map[string]ConsumerGroup `json:",omitempty"`
+}
+
+// Len returns the number of entries, a nil stream is empty
+func (s *Stream) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.Entries)
+}
+
+// NextID returns the ID of the entry XADD would add. id is "*" for an ID generated from now, "ms-*"
+// for the next sequence of that millisecond, or an explicit ID greater than every ID added before.
+func (s *Stream) NextID(id string, now time.Time) (StreamID, error) {
+	var last StreamID
+	if s != nil {
+		last = s.LastID
+	}
+	return nextStreamID(id, last, now)
+}
+
+func nextStreamID(id string, last StreamID, now time.Time) (StreamID, error) {
+	var next StreamID
+	switch msPart, ok := strings.CutSuffix(id, "-*"); {
+	case id == "*":
+		next = StreamID{Ms: max(uint64(now.UnixMilli()), last.Ms)}
+		if next.Ms == last.Ms {
+			if last.Seq == math.MaxUint64 {
+				return StreamID{}, fmt.Errorf("%w: the stream has run out of IDs", internal.ErrBadRequest)
+			}
+			next.Seq = last.Seq + 1
+		}
+	case ok:
+		ms, err := strconv.ParseUint(msPart, 10, 64)
+		if err != nil {
+			return StreamID{}, fmt.Errorf("%w: invalid stream ID %q", internal.ErrBadRequest, id)
+		}
+		next = StreamID{Ms: ms}
+		if ms == last.Ms && last.Seq < math.MaxUint64 {
+			next.Seq = last.Seq + 1
+		}
+	default:
+		parsed, err := ParseStreamID(id)
+		if err != nil {
+			return StreamID{}, err
+		}
+		next = parsed
+	}
+	if next.Compare(StreamID{}) == 0 || next.Compare(last) <= 0 {
+		return StreamID{}, fmt.Errorf("%w: stream ID %s must be greater than %s", internal.ErrBadRequest, next, last)
+	}
+	return next, nil
+}
+
+// Range returns up to count entries with IDs between start and end like XRANGE, count <= 0 returns them all.
+// Bounds are "-", "+", "ms", "ms-seq" or an ID prefixed with "(" to exclude it.
+func (s *Stream) Range(start, end string, count int) ([]StreamEntry, error) {
+	low, ok, err := parseStreamBound(start, false)
+	if err != nil {
+		return nil, err
+	}
+	high, highOK, err := parseStreamBound(end, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || !highOK || s == nil {
+		return []StreamEntry{}, nil
+	}
+	from := s.search(low)
+	to := from
+	for to < len(s.Entries) && s.Entries[to].ID.Compare(high) <= 0 && (count <= 0 || to-from < count) {
+		to++
+	}
+	return slices.Clone(s.Entries[from:to]), nil
+}
+
+// parseStreamBound turns a range bound into the inclusive ID it stands for, ok is false when no ID can match
+func parseStreamBound(bound string, upper bool) (StreamID, bool, error) {
+	switch bound {
+	case "-":
+		return StreamID{}, true, nil
+	case "+":
+		return StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}, true, nil
+	}
+	raw, exclusive := strings.CutPrefix(bound, "(")
+	id, err := ParseStreamID(raw)
+	if err != nil {
+		return StreamID{}, false, err
+	}
+	if upper && !strings.Contains(raw, "-") {
+		id.Seq = math.MaxUint64 // "ms" as an end takes the whole millisecond
+	}
+	if !exclusive {
+		return id, true, nil
+	}
+	if upper {
+		return id.prev()
+	}
+	return id.next()
+}
+
+func (id StreamID) next() (StreamID, bool, error) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{Ms: id.Ms, Seq: id.Seq + 1}, true, nil
+	case id.Ms < math.MaxUint64:
+		return StreamID{Ms: id.Ms + 1}, true, nil
+	}
+	return id, false, nil
+}
+
+func (id StreamID) prev() (StreamID, bool, error) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{Ms: id.Ms, Seq: id.Seq - 1}, true, nil
+	case id.Ms > 0:
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true, nil
+	}
+	return id, false, nil
+}
+
+// TrimCount returns how many of the oldest entries trim drops
+func (s *Stream) TrimCount(trim StreamTrim) int {
+	if s == nil || trim.IsZero() {
+		return 0
+	}
+	if trim.MaxLen != nil {
+		return max(len(s.Entries)-*trim.MaxLen, 0)
+	}
+	minID, _ := ParseStreamID(trim.MinID) // checked by Validate
+	return s.search(minID)
+}
+
+// GroupStart returns the ID a new consumer group delivers the entries after, id is "$" for the last
+// entry. It fails when the group exists.
+func (s *Stream) GroupStart(group, id string) (StreamID, error) {
+	if _, exists := s.group(group); exists {
+		return StreamID{}, fmt.Errorf("%w: consumer group %q already exists", internal.ErrBadRequest, group)
+	}
+	if id != "$" {
+		return ParseStreamID(id)
+	}
+	if s == nil {
+		return StreamID{}, nil
+	}
+	return s.LastID, nil
+}
+
+// ReadGroup reads for consumer like XREADGROUP. id ">" returns up to count entries no consumer of
+// the group has seen, delivered is then set and the caller adds them to the pending list with a
+// ChangeStreamDeliver. Any other id returns the pending entries of consumer after it.
+func (s *Stream) ReadGroup(group, consumer, id string, count int) (entries []StreamEntry, delivered bool, err error) {
+	state, exists := s.group(group)
+	if !exists {
+		return nil, false, fmt.Errorf("%w: no consumer group %q", internal.ErrNotFound, group)
+	}
+	if id != ">" {
+		after, err := ParseStreamID(id)
+		if err != nil {
+			return nil, false, err
+		}
+		pending := slices.SortedFunc(maps.Values(state.Pending), comparePending)
+		entries = []StreamEntry{}
+		for _, entry := range pending {
+			if entry.Consumer != consumer || entry.ID.Compare(after) <= 0 {
+				continue
+			}
+			if count > 0 && len(entries) == count {
+				break
+			}
+			entries = append(entries, s.entry(entry.ID))
+		}
+		return entries, false, nil
+	}
+
+	from := s.search(state.LastDelivered)
+	if from < len(s.Entries) && s.Entries[from].ID == state.LastDelivered {
+		from++
+	}
+	to := len(s.Entries)
+	if count > 0 {
+		to = min(to, from+count)
+	}
+	entries = slices.Clone(s.Entries[from:to])
+	return entries, len(entries) > 0, nil
+}
+
+// PendingIDs returns the ids pending in group, the ones XACK acknowledges
+func (s *Stream) PendingIDs(group string, ids []StreamID) []StreamID {
+	state, _ := s.group(group)
+	var pending []StreamID
+	for _, id := range ids {
+		if _, ok := state.Pending[id]; ok && !slices.Contains(pending, id) {
+			pending = append(pending, id)
+		}
+	}
+	return pending
+}
+
+// Pending lists the pending entries of group in ID order
+func (s *Stream) Pending(group string) ([]PendingEntry, error) {
+	state, exists := s.group(group)
+	if !exists {
+		return nil, fmt.Errorf("%w: no consumer group %q", internal.ErrNotFound, group)
+	}
+	return slices.SortedFunc(maps.Values(state.Pending), comparePending), nil
+}
+
+func (s *Stream) group(name string) (ConsumerGroup, bool) {
+	if s == nil {
+		return ConsumerGroup{}, false
+	}
+	state, exists := s.Groups[name]
+	return state, exists
+}
+
+// search returns the position of the first entry with an ID not below id
+func (s *Stream) search(id StreamID) int {
+	position, _ := slices.BinarySearchFunc(s.Entries, id, func(entry StreamEntry, id StreamID) int {
+		return entry.ID.Compare(id)
+	})
+	return position
+}
+
+// entry returns the entry with id, with nil fields once it has been trimmed
+func (s *Stream) entry(id StreamID) StreamEntry {
+	if position := s.search(id); position < len(s.Entries) && s.Entries[position].ID == id {
+		return s.Entries[position]
+	}
+	return StreamEntry{ID: id}
+}
+
+// Clone copies the entries, groups and pending lists. The fields of an entry are never changed and stay shared.
+func (s *Stream) Clone() *Stream {
+	clone := &Stream{Entries: slices.Clone(s.Entries), LastID: s.LastID, Groups: maps.Clone(s.Groups)}
+	for name, group := range clone.Groups {
+		group.Pending = maps.Clone(group.Pending)
+		clone.Groups[name] = group
+	}
+	return clone
+}
+
+// add appends entry, whose ID is above LastID, and drops the oldest entries as trim asks
+func (s *Stream) add(entry StreamEntry, trim StreamTrim) {
+	s.Entries = append(s.Entries, entry)
+	s.LastID = entry.ID
+	s.trim(trim)
+}
+
+// trim drops the oldest entries as trim asks. They are cleared so the room they leave does not keep them alive.
+func (s *Stream) trim(trim StreamTrim) {
+	drop := s.TrimCount(trim)
+	clear(s.Entries[:drop])
+	s.Entries = s.Entries[drop:]
+}
+
+// createGroup adds group delivering the entries after start
+func (s *Stream) createGroup(group string, start StreamID) {
+	if s.Groups == nil {
+		s.Groups = make(map[string]ConsumerGroup)
+	}
+	s.Groups[group] = ConsumerGroup{LastDelivered: start}
+}
+
+// deliver adds ids, handed to consumer at now, to the pending list of group
+func (s *Stream) deliver(group, consumer string, ids []StreamID, now time.Time) {
+	state, exists := s.group(group)
+	if !exists || len(ids) == 0 {
+		return
+	}
+	if state.Pending == nil {
+		state.Pending = make(map[StreamID]PendingEntry, len(ids))
+	}
+	for _, id := range ids {
+		state.Pending[id] = PendingEntry{ID: id, Consumer: consumer, Delivered: now, Deliveries: 1}
+	}
+	state.LastDelivered = ids[len(ids)-1]
+	s.Groups[group] = state
+}
+
+// ack removes ids from the pending list of group
+func (s *Stream) ack(group string, ids []StreamID) {
+	state, _ := s.group(group)
+	for _, id := range ids {
+		delete(state.Pending, id)
+	}
+}
+
+func comparePending(a, b PendingEntry) int {
+	return a.ID.Compare(b.ID)
+}
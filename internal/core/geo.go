@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+)
+
+// GeoAdd adds members to the geospatial index at key, a sorted set scored by geohash, and returns how
+// many were new. options work like for ZAdd, but only NX and XX are taken like by GEOADD.
+func (c *Cache) GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error) {
+	if options.GT || options.LT {
+		return 0, fmt.Errorf("%w: geoadd takes nx and xx only", internal.ErrBadRequest)
+	}
+	scored := make([]data.ScoredMember, len(members))
+	for i, member := range members {
+		if err := member.Validate(); err != nil {
+			return 0, err
+		}
+		scored[i] = data.ScoredMember{Member: member.Member, Score: data.GeoScore(member.GeoPoint)}
+	}
+	return c.ZAdd(ctx, key, scored, options)
+}
+
+// GeoPos returns the position of each of members, nil for a missing one
+func (c *Cache) GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return data.GeoPositions(zset, members), nil
+}
+
+// GeoDist returns the distance between two members in unit, found is false when either is missing
+func (c *Cache) GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return 0, false, err
+	}
+	return data.GeoDist(zset, from, to, unit)
+}
+
+// GeoSearch returns the members within a radius or a box, see data.GeoSearch
+func (c *Cache) GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return data.GeoSearch(zset, query)
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/util"
+	"maps"
+	"slices"
+)
+
+// HSet sets fields of the hash at key and returns how many of them were new
+func (c *Cache) HSet(ctx context.Context, key string, fields map[string][]byte) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	hash, err := c.hashLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for field := range fields {
+		if _, exists := hash[field]; !exists {
+			added++
+		}
+	}
+	c.changeTypedLocked(index, key, data.TypeHash, data.Change{Op: data.ChangeHashSet, Fields: fields})
+	return added, nil
+}
+
+func (c *Cache) HGet(ctx context.Context, key, field string) ([]byte, bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	hash, err := c.hashLocked(index, key)
+	if err != nil {
+		return nil, false, err
+	}
+	value, exists := hash[field]
+	return value, exists, nil
+}
+
+// HMGet returns the fields that exist among fields
+func (c *Cache) HMGet(ctx context.Context, key string, fields []string) (map[string][]byte, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	hash, err := c.hashLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(fields))
+	for _, field := range fields {
+		if value, exists := hash[field]; exists {
+			result[field] = value
+		}
+	}
+	return result, nil
+}
+
+// HDel removes fields and returns how many existed, the key is deleted with its last field
+func (c *Cache) HDel(ctx context.Context, key string, fields []string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	hash, err := c.hashLocked(index, key)
+	if err != nil || hash == nil {
+		return 0, err
+	}
+	deleted := make(map[string]struct{})
+	for _, field := range fields {
+		if _, exists := hash[field]; exists {
+			deleted[field] = struct{}{}
+		}
+	}
+	if len(deleted) > 0 {
+		c.changeTypedLocked(index, key, data.TypeHash, data.Change{Op: data.ChangeHashDel, Members: slices.Collect(maps.Keys(deleted))})
+	}
+	return len(deleted), nil
+}
+
+func (c *Cache) HGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	hash, err := c.hashLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil {
+		return map[string][]byte{}, nil
+	}
+	return maps.Clone(hash), nil
+}
+
+// HIncrBy adds delta to the integer in field, a missing field starts from 0
+func (c *Cache) HIncrBy(ctx context.Context, key, field string, delta int64) (int64, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	hash, err := c.hashLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	var value int64
+	if current, exists := hash[field]; exists {
+		if value, err = util.BytesToInt64(current); err != nil {
+			return 0, err
+		}
+	}
+	value += delta
+	c.changeTypedLocked(index, key, data.TypeHash, data.Change{Op: data.ChangeHashSet, Fields: map[string][]byte{field: util.Int64ToBytes(value)}})
+	return value, nil
+}
+
+func (c *Cache) HExists(ctx context.Context, key, field string) (bool, error) {
+	_, exists, err := c.HGet(ctx, key, field)
+	return exists, err
+}
+
+func (c *Cache) HLen(ctx context.Context, key string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	hash, err := c.hashLocked(index, key)
+	return len(hash), err
+}
+
+// hashLocked returns the fields of the hash at key, nil when the key is missing or expired.
+// The shard lock must be held.
+func (c *Cache) hashLocked(index int, key string) (map[string][]byte, error) {
+	item, _, err := c.loadTypedLocked(index, key, data.TypeHash)
+	return item.Hash, err
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/util"
+)
+
+// PFAdd adds elements to the HyperLogLog at key and reports whether a register changed, which is
+// when its count may have. A missing key is created, even without elements, and reported as changed.
+func (c *Cache) PFAdd(ctx context.Context, key string, elements []string) (bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	item, found, err := c.loadTypedLocked(index, key, data.TypeHLL)
+	if err != nil {
+		return false, err
+	}
+	raising := item.HLL.Raising(elements)
+	if len(raising) == 0 && found {
+		return false, nil
+	}
+	c.changeTypedLocked(index, key, data.TypeHLL, data.Change{Op: data.ChangeHLLAdd, Members: raising})
+	return true, nil
+}
+
+// PFCount estimates the number of distinct elements added to any of the HyperLogLogs at keys,
+// a missing key counts as empty
+func (c *Cache) PFCount(ctx context.Context, keys []string) (int64, error) {
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.RLock()
+	}
+	defer func() {
+		for j := len(indexList) - 1; j >= 0; j-- {
+			c.shardedMap[indexList[j]].lock.RUnlock()
+		}
+	}()
+	hlls, err := c.hllsLocked(keys)
+	if err != nil {
+		return 0, err
+	}
+	return data.CountHyperLogLogs(hlls), nil
+}
+
+// PFMerge stores in dest the union of dest, the HyperLogLogs at keys and sources, which come from
+// other nodes. Every shard involved is locked at once.
+func (c *Cache) PFMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error {
+	all := append([]string{dest}, keys...)
+	indexList := util.GetIndexListNoDup(all, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.Lock()
+	}
+	defer func() {
+		for j := len(indexList) - 1; j >= 0; j-- {
+			c.shardedMap[indexList[j]].lock.Unlock()
+		}
+	}()
+	hlls, err := c.hllsLocked(all)
+	if err != nil {
+		return err
+	}
+	c.storeHLLLocked(c.getShardedIndex(dest), dest, data.MergeHyperLogLogs(append(hlls, sources...)))
+	return nil
+}
+
+// hllsLocked returns the HyperLogLogs at keys, nil for a missing key. The shard locks must be held.
+func (c *Cache) hllsLocked(keys []string) ([]*data.HyperLogLog, error) {
+	hlls := make([]*data.HyperLogLog, len(keys))
+	for i, key := range keys {
+		item, _, err := c.loadTypedLocked(c.getShardedIndex(key), key, data.TypeHLL)
+		if err != nil {
+			return nil, err
+		}
+		hlls[i] = item.HLL
+	}
+	return hlls, nil
+}
+
+// storeHLLLocked writes hll back to key. The shard lock must be held.
+func (c *Cache) storeHLLLocked(index int, key string, hll *data.HyperLogLog) {
+	c.storeTypedLocked(index, key, data.CacheItem{HLL: hll, Type: data.TypeHLL}, false)
+}
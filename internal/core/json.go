@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"go-cache-server-mini/internal/core/data"
+)
+
+// JSONGet returns the value at path of the JSON document at key, found is false when the key or the path is missing
+func (c *Cache) JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	doc, err := c.stringLocked(index, key)
+	if err != nil || doc == nil {
+		return nil, false, err
+	}
+	return data.JSONGet(doc, path)
+}
+
+// JSONSet writes value at path of the document at key and reports whether options allowed it.
+// A missing key is created by setting the root path, with the default TTL.
+func (c *Cache) JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	doc, err := c.stringLocked(index, key)
+	if err != nil {
+		return false, err
+	}
+	updated, written, err := data.JSONSet(doc, path, value, options)
+	if err != nil || !written {
+		return false, err
+	}
+	c.storeTypedLocked(index, key, data.CacheItem{Value: updated}, false)
+	return true, nil
+}
+
+// JSONDel removes the value at path and returns how many were removed, 0 or 1. Removing the root deletes the key.
+func (c *Cache) JSONDel(ctx context.Context, key, path string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	doc, err := c.stringLocked(index, key)
+	if err != nil || doc == nil {
+		return 0, err
+	}
+	updated, removed, err := data.JSONDel(doc, path)
+	if err != nil || !removed {
+		return 0, err
+	}
+	c.storeTypedLocked(index, key, data.CacheItem{Value: updated}, updated == nil)
+	return 1, nil
+}
+
+// JSONArrAppend appends values to the array at path and returns its new length
+func (c *Cache) JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	doc, err := c.stringLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	updated, length, err := data.JSONArrAppend(doc, path, values)
+	if err != nil {
+		return 0, err
+	}
+	c.storeTypedLocked(index, key, data.CacheItem{Value: updated}, false)
+	return length, nil
+}
+
+// JSONNumIncrBy adds delta to the number at path and returns the new number
+func (c *Cache) JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	doc, err := c.stringLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	updated, number, err := data.JSONNumIncrBy(doc, path, delta)
+	if err != nil {
+		return nil, err
+	}
+	c.storeTypedLocked(index, key, data.CacheItem{Value: updated}, false)
+	return number, nil
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"slices"
+)
+
+func (c *Cache) LPush(ctx context.Context, key string, values [][]byte) (int, error) {
+	return c.push(key, values, true)
+}
+
+func (c *Cache) RPush(ctx context.Context, key string, values [][]byte) (int, error) {
+	return c.push(key, values, false)
+}
+
+func (c *Cache) LPop(ctx context.Context, key string, count int) ([][]byte, error) {
+	return c.pop(key, count, true)
+}
+
+func (c *Cache) RPop(ctx context.Context, key string, count int) ([][]byte, error) {
+	return c.pop(key, count, false)
+}
+
+func (c *Cache) LRange(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	list, err := c.listLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	from, to, ok := data.ListRange(len(list), start, stop)
+	if !ok {
+		return [][]byte{}, nil
+	}
+	return slices.Clone(list[from:to]), nil
+}
+
+func (c *Cache) LLen(ctx context.Context, key string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	list, err := c.listLocked(index, key)
+	return len(list), err
+}
+
+func (c *Cache) LTrim(ctx context.Context, key string, start, stop int) error {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	list, err := c.listLocked(index, key)
+	if err != nil || list == nil {
+		return err
+	}
+	from, to, ok := data.ListRange(len(list), start, stop)
+	if !ok {
+		from, to = 0, 0
+	}
+	if from > 0 || to < len(list) {
+		c.changeTypedLocked(index, key, data.TypeList, data.Change{Op: data.ChangeListTrim, Start: from, Stop: to})
+	}
+	return nil
+}
+
+func (c *Cache) LIndex(ctx context.Context, key string, index int) ([]byte, bool, error) {
+	shard := c.getShardedIndex(key)
+	c.shardedMap[shard].lock.RLock()
+	defer c.shardedMap[shard].lock.RUnlock()
+	list, err := c.listLocked(shard, key)
+	if err != nil {
+		return nil, false, err
+	}
+	position, ok := data.ListIndex(len(list), index)
+	if !ok {
+		return nil, false, nil
+	}
+	return list[position], true, nil
+}
+
+func (c *Cache) push(key string, values [][]byte, head bool) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	list, err := c.listLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	c.changeTypedLocked(index, key, data.TypeList, data.Change{Op: data.ChangeListPush, Values: values, Head: head})
+	return len(list) + len(values), nil
+}
+
+func (c *Cache) pop(key string, count int, head bool) ([][]byte, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	list, err := c.listLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, internal.ErrNotFound
+	}
+	count = min(max(count, 1), len(list))
+	var popped [][]byte
+	trim := data.Change{Op: data.ChangeListTrim, Start: count, Stop: len(list)}
+	if head {
+		popped = slices.Clone(list[:count])
+	} else {
+		popped = slices.Clone(list[len(list)-count:])
+		slices.Reverse(popped) // tail first, like RPOP
+		trim.Start, trim.Stop = 0, len(list)-count
+	}
+	c.changeTypedLocked(index, key, data.TypeList, trim)
+	return popped, nil
+}
+
+// listLocked returns the elements of the list at key, nil when the key is missing or expired.
+// The shard lock must be held.
+func (c *Cache) listLocked(index int, key string) ([][]byte, error) {
+	item, _, err := c.loadTypedLocked(index, key, data.TypeList)
+	return item.List, err
+}
@@ -55,7 +55,7 @@ func (a *AOF) loadFromFile(fileUtil *util.FileUtil, data map[string]data.CacheIt
 		if line == "" {
 			continue
 		}
-		cmd, key, item, parseErr := a.parser.ParseStringToCMD(line)
+		cmd, key, item, change, parseErr := a.parser.ParseStringToCMD(line)
 		if parseErr != nil {
 			return nil, parseErr
 		}
@@ -64,6 +64,15 @@ func (a *AOF) loadFromFile(fileUtil *util.FileUtil, data map[string]data.CacheIt
 			data[key] = item
 		case "DEL":
 			delete(data, key)
+		case "CHANGE":
+			// a change logged while the snapshot was taken may already be in it, its version tells
+			current, exists := data[key]
+			if !exists || change == nil || current.Type != item.Type || current.Version >= item.Version {
+				continue
+			}
+			current.Apply(*change)
+			current.Expiration, current.Persistent, current.Version = item.Expiration, item.Persistent, item.Version
+			data[key] = current
 		}
 	}
 	return data, nil
@@ -80,6 +89,10 @@ func (a *AOF) Save() error {
 		select {
 		case control, controlOk := <-a.AofControlChannel:
 			if !controlOk {
+				// Close shuts the data channel first, keep the commands still queued in it
+				for cmd := range a.AofDataChannel {
+					a.batchCmdBuffer = append(a.batchCmdBuffer, cmd)
+				}
 				if len(a.batchCmdBuffer) > 0 {
 					if err := a.flush(); err != nil {
 						return err
@@ -8,20 +8,22 @@ import (
 type Parser struct{}
 
 type LineFormat struct {
-	Cmd  string
-	Key  string
-	Item data.CacheItem
+	Cmd    string
+	Key    string
+	Item   data.CacheItem
+	Change *data.Change `json:",omitempty"` // for CHANGE, Item then only holds the type, expiration and version
 }
 
 func NewParser() *Parser {
 	return &Parser{}
 }
 
-func (p *Parser) ConvertCMDToString(cmd, key string, item data.CacheItem) (string, error) {
+func (p *Parser) ConvertCMDToString(cmd, key string, item data.CacheItem, change *data.Change) (string, error) {
 	line := LineFormat{
-		Cmd:  cmd,
-		Key:  key,
-		Item: item,
+		Cmd:    cmd,
+		Key:    key,
+		Item:   item,
+		Change: change,
 	}
 	jsonBytes, err := json.Marshal(line)
 	if err != nil {
@@ -30,14 +32,15 @@ func (p *Parser) ConvertCMDToString(cmd, key string, item data.CacheItem) (strin
 	return string(jsonBytes), nil
 }
 
-func (p *Parser) ParseStringToCMD(line string) (cmd string, key string, item data.CacheItem, err error) {
+func (p *Parser) ParseStringToCMD(line string) (cmd string, key string, item data.CacheItem, change *data.Change, err error) {
 	var lineFormat LineFormat
 	if err := json.Unmarshal([]byte(line), &lineFormat); err != nil {
-		return "", "", data.CacheItem{}, err
+		return "", "", data.CacheItem{}, nil, err
 	}
 	cmd = lineFormat.Cmd
 	key = lineFormat.Key
 	item = lineFormat.Item
+	change = lineFormat.Change
 
-	return cmd, key, item, nil
+	return cmd, key, item, change, nil
 }
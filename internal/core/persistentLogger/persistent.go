@@ -13,6 +13,7 @@ type Command struct {
 	Action string
 	Key    string
 	Item   data.CacheItem
+	Change *data.Change // set for CHANGE
 }
 
 type cacheChannel struct {
@@ -92,7 +93,7 @@ func (p *PersistentLogger) WriteAOF(command Command) {
 	p.ops.Add(1)
 	defer p.ops.Done()
 
-	cmd, err := p.parser.ConvertCMDToString(command.Action, command.Key, command.Item)
+	cmd, err := p.parser.ConvertCMDToString(command.Action, command.Key, command.Item, command.Change)
 	if err != nil {
 		return
 	}
@@ -103,7 +104,9 @@ func (p *PersistentLogger) WriteAOF(command Command) {
 	}
 }
 
-func (p *PersistentLogger) TriggerSnap(kvmap map[string]data.CacheItem) {
+// TriggerSnap writes the items collect returns to a new snapshot. collect runs once the AOF is paused,
+// so every write it misses is in the AOF that follows the snapshot.
+func (p *PersistentLogger) TriggerSnap(collect func() map[string]data.CacheItem) {
 	if atomic.LoadInt32(&p.closed) == 1 {
 		return
 	}
@@ -117,6 +120,7 @@ func (p *PersistentLogger) TriggerSnap(kvmap map[string]data.CacheItem) {
 	case p.cacheChan.aofControl <- "PAUSE":
 	}
 
+	kvmap := collect()
 	select {
 	case <-p.ctx.Done():
 		return
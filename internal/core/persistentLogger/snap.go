@@ -50,7 +50,7 @@ func (s *Snap) loadFromFile(fileUtil *util.FileUtil, data map[string]data.CacheI
 		return data, err
 	}
 	for _, line := range lines {
-		_, key, item, parseErr := s.parser.ParseStringToCMD(line)
+		_, key, item, _, parseErr := s.parser.ParseStringToCMD(line)
 		if parseErr != nil {
 			return nil, parseErr
 		}
@@ -77,7 +77,7 @@ func (s *Snap) Save() error {
 				continue
 			}
 			for key, item := range data {
-				cmd, err := s.parser.ConvertCMDToString("SET", key, item)
+				cmd, err := s.parser.ConvertCMDToString("SET", key, item, nil)
 				if err != nil {
 					log.Printf("Error converting CMD to string for snap: %v", err)
 					continue
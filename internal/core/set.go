@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/util"
+	"maps"
+	"math/rand"
+	"slices"
+)
+
+// SAdd adds members to the set at key and returns how many were new
+func (c *Cache) SAdd(ctx context.Context, key string, members []string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	set, err := c.setLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	added := make(map[string]struct{})
+	for _, member := range members {
+		if _, exists := set[member]; !exists {
+			added[member] = struct{}{}
+		}
+	}
+	if len(added) > 0 {
+		c.changeTypedLocked(index, key, data.TypeSet, data.Change{Op: data.ChangeSetAdd, Members: slices.Collect(maps.Keys(added))})
+	}
+	return len(added), nil
+}
+
+// SRem removes members and returns how many existed, the key is deleted with its last member
+func (c *Cache) SRem(ctx context.Context, key string, members []string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	set, err := c.setLocked(index, key)
+	if err != nil || set == nil {
+		return 0, err
+	}
+	removed := make(map[string]struct{})
+	for _, member := range members {
+		if _, exists := set[member]; exists {
+			removed[member] = struct{}{}
+		}
+	}
+	if len(removed) > 0 {
+		c.changeTypedLocked(index, key, data.TypeSet, data.Change{Op: data.ChangeSetRem, Members: slices.Collect(maps.Keys(removed))})
+	}
+	return len(removed), nil
+}
+
+func (c *Cache) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	set, err := c.setLocked(index, key)
+	_, exists := set[member]
+	return exists, err
+}
+
+func (c *Cache) SMembers(ctx context.Context, key string) ([]string, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	set, err := c.setLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return data.SetMembers(set), nil
+}
+
+func (c *Cache) SCard(ctx context.Context, key string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	set, err := c.setLocked(index, key)
+	return len(set), err
+}
+
+// SPop removes and returns up to count random members
+func (c *Cache) SPop(ctx context.Context, key string, count int) ([]string, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	set, err := c.setLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, internal.ErrNotFound
+	}
+	popped := randomMembers(set, count)
+	c.changeTypedLocked(index, key, data.TypeSet, data.Change{Op: data.ChangeSetRem, Members: popped})
+	return popped, nil
+}
+
+// SRandMember returns up to count distinct random members without removing them
+func (c *Cache) SRandMember(ctx context.Context, key string, count int) ([]string, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	set, err := c.setLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return randomMembers(set, count), nil
+}
+
+func (c *Cache) SUnion(ctx context.Context, keys []string) ([]string, error) {
+	return c.combineSets(data.SetUnion, keys)
+}
+
+func (c *Cache) SInter(ctx context.Context, keys []string) ([]string, error) {
+	return c.combineSets(data.SetIntersection, keys)
+}
+
+func (c *Cache) SDiff(ctx context.Context, keys []string) ([]string, error) {
+	return c.combineSets(data.SetDifference, keys)
+}
+
+// combineSets reads every set under the locks of all their shards at once, so no write lands between two reads
+func (c *Cache) combineSets(op string, keys []string) ([]string, error) {
+	indexList := util.GetIndexListNoDup(keys, c.getShardedIndex)
+	for _, index := range indexList {
+		c.shardedMap[index].lock.RLock()
+	}
+	defer func() {
+		for j := len(indexList) - 1; j >= 0; j-- {
+			c.shardedMap[indexList[j]].lock.RUnlock()
+		}
+	}()
+	sets := make([]map[string]struct{}, len(keys))
+	for i, key := range keys {
+		set, err := c.setLocked(c.getShardedIndex(key), key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return data.CombineSets(op, sets), nil
+}
+
+// setLocked returns the members of the set at key, nil when the key is missing or expired.
+// The shard lock must be held.
+func (c *Cache) setLocked(index int, key string) (map[string]struct{}, error) {
+	item, _, err := c.loadTypedLocked(index, key, data.TypeSet)
+	return item.Set, err
+}
+
+// randomMembers picks up to count distinct members of set at random
+func randomMembers(set map[string]struct{}, count int) []string {
+	members := data.SetMembers(set)
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	return members[:min(max(count, 1), len(members))]
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"maps"
+	"slices"
+)
+
+// ZAdd sets the scores of members under the conditions of options and returns how many members were new
+func (c *Cache) ZAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error) {
+	if err := options.Validate(); err != nil {
+		return 0, err
+	}
+	for _, member := range members {
+		if err := data.ValidScore(member.Score); err != nil {
+			return 0, err
+		}
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	// decide every write first, so an item nothing changes in is not logged
+	pending := make(map[string]float64)
+	var order []string
+	for _, member := range members {
+		current, exists := pending[member.Member]
+		queued := exists
+		if !queued {
+			current, exists = zset.Score(member.Member)
+		}
+		if !options.Allows(current, exists, member.Score) || (exists && current == member.Score) {
+			continue
+		}
+		if !queued {
+			order = append(order, member.Member)
+		}
+		pending[member.Member] = member.Score
+	}
+	if len(order) == 0 {
+		return 0, nil
+	}
+	scores := make([]data.ScoredMember, len(order))
+	added := 0
+	for i, member := range order {
+		if _, exists := zset.Score(member); !exists {
+			added++
+		}
+		scores[i] = data.ScoredMember{Member: member, Score: pending[member]}
+	}
+	c.changeTypedLocked(index, key, data.TypeZSet, data.Change{Op: data.ChangeZSetAdd, Scores: scores})
+	return added, nil
+}
+
+// ZIncrBy adds delta to the score of member, a missing member starts from 0
+func (c *Cache) ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	current, _ := zset.Score(member)
+	score := current + delta
+	if err := data.ValidScore(score); err != nil {
+		return 0, err
+	}
+	c.changeTypedLocked(index, key, data.TypeZSet, data.Change{Op: data.ChangeZSetAdd, Scores: []data.ScoredMember{{Member: member, Score: score}}})
+	return score, nil
+}
+
+func (c *Cache) ZScore(ctx context.Context, key, member string) (float64, bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return 0, false, err
+	}
+	score, exists := zset.Score(member)
+	return score, exists, nil
+}
+
+// ZRank returns the 0-based rank of member, from the highest score when rev is set
+func (c *Cache) ZRank(ctx context.Context, key, member string, rev bool) (int, bool, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return 0, false, err
+	}
+	rank, exists := zset.Rank(member, rev)
+	return rank, exists, nil
+}
+
+func (c *Cache) ZRange(ctx context.Context, key string, query data.ZRangeQuery) ([]data.ScoredMember, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return zset.Query(query)
+}
+
+func (c *Cache) ZCard(ctx context.Context, key string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	zset, err := c.zsetLocked(index, key)
+	return zset.Len(), err
+}
+
+// ZRem removes members and returns how many existed, the key is deleted with its last member
+func (c *Cache) ZRem(ctx context.Context, key string, members []string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+	return c.removeMembersLocked(index, key, zset, members), nil
+}
+
+// ZRemRangeByScore removes the members scored between min and max, bounds as in ZRangeQuery
+func (c *Cache) ZRemRangeByScore(ctx context.Context, key, min, max string) (int, error) {
+	low, err := data.ParseScoreBound(min)
+	if err != nil {
+		return 0, err
+	}
+	high, err := data.ParseScoreBound(max)
+	if err != nil {
+		return 0, err
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+	from, to := zset.ScoreRanks(low, high)
+	return c.removeMembersLocked(index, key, zset, memberNames(zset.Range(from, to))), nil
+}
+
+func (c *Cache) ZPopMin(ctx context.Context, key string, count int) ([]data.ScoredMember, error) {
+	return c.zpop(key, count, false)
+}
+
+func (c *Cache) ZPopMax(ctx context.Context, key string, count int) ([]data.ScoredMember, error) {
+	return c.zpop(key, count, true)
+}
+
+// zpop removes and returns up to count members with the lowest scores, or the highest when highest is set
+func (c *Cache) zpop(key string, count int, highest bool) ([]data.ScoredMember, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	zset, err := c.zsetLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	if zset == nil {
+		return nil, internal.ErrNotFound
+	}
+	count = min(max(count, 1), zset.Len())
+	var popped []data.ScoredMember
+	if highest {
+		popped = zset.Range(zset.Len()-count, zset.Len())
+		slices.Reverse(popped) // highest first, like ZPOPMAX
+	} else {
+		popped = zset.Range(0, count)
+	}
+	c.removeMembersLocked(index, key, zset, memberNames(popped))
+	return popped, nil
+}
+
+// removeMembersLocked removes members from zset at key and returns how many it held. The shard lock must be held.
+func (c *Cache) removeMembersLocked(index int, key string, zset *data.SortedSet, members []string) int {
+	removed := make(map[string]struct{})
+	for _, member := range members {
+		if _, exists := zset.Score(member); exists {
+			removed[member] = struct{}{}
+		}
+	}
+	if len(removed) > 0 {
+		c.changeTypedLocked(index, key, data.TypeZSet, data.Change{Op: data.ChangeZSetRem, Members: slices.Collect(maps.Keys(removed))})
+	}
+	return len(removed)
+}
+
+// zsetLocked returns the sorted set at key, nil when the key is missing or expired. The shard lock must be held.
+func (c *Cache) zsetLocked(index int, key string) (*data.SortedSet, error) {
+	item, _, err := c.loadTypedLocked(index, key, data.TypeZSet)
+	return item.ZSet, err
+}
+
+func memberNames(members []data.ScoredMember) []string {
+	names := make([]string, len(members))
+	for i, member := range members {
+		names[i] = member.Member
+	}
+	return names
+}
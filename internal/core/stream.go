@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"time"
+)
+
+// XAdd appends an entry to the stream at key and returns its ID, see data.Stream.NextID for id.
+// A set trim is applied after the entry is added, like XADD MAXLEN or MINID.
+func (c *Cache) XAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error) {
+	if err := trim.Validate(); err != nil {
+		return "", err
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return "", err
+	}
+	added, err := stream.NextID(id, time.Now())
+	if err != nil {
+		return "", err
+	}
+	entry := data.StreamEntry{ID: added, Fields: fields}
+	c.changeTypedLocked(index, key, data.TypeStream, data.Change{Op: data.ChangeStreamAdd, Entry: &entry, Trim: trim})
+	return added.String(), nil
+}
+
+// XRange returns up to count entries between start and end, count <= 0 returns them all
+func (c *Cache) XRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Range(start, end, count)
+}
+
+func (c *Cache) XLen(ctx context.Context, key string) (int, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	stream, err := c.streamLocked(index, key)
+	return stream.Len(), err
+}
+
+// XTrim drops the oldest entries as trim asks and returns how many went
+func (c *Cache) XTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error) {
+	if err := trim.Validate(); err != nil {
+		return 0, err
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	trimmed := stream.TrimCount(trim)
+	if trimmed > 0 {
+		c.changeTypedLocked(index, key, data.TypeStream, data.Change{Op: data.ChangeStreamTrim, Trim: trim})
+	}
+	return trimmed, nil
+}
+
+// XGroupCreate adds a consumer group reading after id, "$" for entries added from now on.
+// A missing stream is created when mkStream is set, otherwise it is internal.ErrNotFound.
+func (c *Cache) XGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return err
+	}
+	if stream == nil && !mkStream {
+		return internal.ErrNotFound
+	}
+	start, err := stream.GroupStart(group, id)
+	if err != nil {
+		return err
+	}
+	c.changeTypedLocked(index, key, data.TypeStream, data.Change{Op: data.ChangeStreamGroup, Group: group, ID: start})
+	return nil
+}
+
+// XReadGroup reads entries for consumer of group, see data.Stream.ReadGroup
+func (c *Cache) XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	entries, delivered, err := stream.ReadGroup(group, consumer, id, count)
+	if err != nil {
+		return nil, err
+	}
+	if delivered {
+		ids := make([]data.StreamID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		c.changeTypedLocked(index, key, data.TypeStream, data.Change{Op: data.ChangeStreamDeliver, Group: group, Consumer: consumer, IDs: ids, Time: time.Now()})
+	}
+	return entries, nil
+}
+
+// XAck acknowledges entries of group and returns how many were pending
+func (c *Cache) XAck(ctx context.Context, key, group string, ids []string) (int, error) {
+	parsed := make([]data.StreamID, len(ids))
+	for i, id := range ids {
+		var err error
+		if parsed[i], err = data.ParseStreamID(id); err != nil {
+			return 0, err
+		}
+	}
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.Lock()
+	defer c.shardedMap[index].lock.Unlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return 0, err
+	}
+	acked := stream.PendingIDs(group, parsed)
+	if len(acked) > 0 {
+		c.changeTypedLocked(index, key, data.TypeStream, data.Change{Op: data.ChangeStreamAck, Group: group, IDs: acked})
+	}
+	return len(acked), nil
+}
+
+// XPending lists the entries delivered to consumers of group and not acknowledged yet
+func (c *Cache) XPending(ctx context.Context, key, group string) ([]data.PendingEntry, error) {
+	index := c.getShardedIndex(key)
+	c.shardedMap[index].lock.RLock()
+	defer c.shardedMap[index].lock.RUnlock()
+	stream, err := c.streamLocked(index, key)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Pending(group)
+}
+
+// streamLocked returns the stream at key, nil when the key is missing or expired. The shard lock must be held.
+func (c *Cache) streamLocked(index int, key string) (*data.Stream, error) {
+	item, _, err := c.loadTypedLocked(index, key, data.TypeStream)
+	return item.Stream, err
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/util"
+	"time"
+)
+
+// Items other than strings are stored whole in one CacheItem. Lists, hashes, sets, sorted sets, streams and the
+// registers PFADD raises are changed in place through changeTypedLocked, which logs the change alone to the AOF.
+// The other types are replaced whole by storeTypedLocked. An item read out of the shard lock, by a peer or a
+// snapshot, is cloned first.
+
+// loadTypedLocked returns the live item at key, found is false when the key is missing or expired.
+// It fails with internal.ErrWrongType when the key holds another type. The shard lock must be held.
+func (c *Cache) loadTypedLocked(index int, key string, itemType data.ItemType) (data.CacheItem, bool, error) {
+	item, exists := c.shardedMap[index].kvmap[key]
+	if !exists || isExpired(item) {
+		return data.CacheItem{}, false, nil
+	}
+	if item.Type != itemType {
+		return data.CacheItem{}, false, internal.ErrWrongType
+	}
+	return item, true, nil
+}
+
+// storeTypedLocked writes next to key, keeping the expiration of the live item and giving a new
+// key the default TTL. An empty item deletes the key. The shard lock must be held.
+func (c *Cache) storeTypedLocked(index int, key string, next data.CacheItem, empty bool) {
+	item, exists := c.shardedMap[index].kvmap[key]
+	if empty {
+		c.deleteLocked(index, key)
+		return
+	}
+	if !exists || isExpired(item) {
+		expiration, persistent := util.SetExpiration(c.defaultTTL, c.maxTTL, 0)
+		item = data.CacheItem{Expiration: time.Now().Add(expiration), Persistent: persistent, Version: item.Version}
+	}
+	next.Expiration = item.Expiration
+	next.Persistent = item.Persistent
+	next.Version = nextVersion(item.Version)
+	c.shardedMap[index].kvmap[key] = next
+	// Write to AOF
+	c.setItemLog(key, next)
+}
+
+// changeTypedLocked applies change in place to the item at key and logs the change rather than the
+// whole item. A missing key is created with the default TTL and logged whole, an item the change
+// leaves empty deletes the key. The shard lock must be held.
+func (c *Cache) changeTypedLocked(index int, key string, itemType data.ItemType, change data.Change) {
+	item, exists := c.shardedMap[index].kvmap[key]
+	created := !exists || isExpired(item)
+	if created {
+		expiration, persistent := util.SetExpiration(c.defaultTTL, c.maxTTL, 0)
+		item = data.CacheItem{Type: itemType, Expiration: time.Now().Add(expiration), Persistent: persistent, Version: item.Version}
+	}
+	item.Apply(change)
+	if item.Empty() {
+		c.deleteLocked(index, key)
+		return
+	}
+	item.Version = nextVersion(item.Version)
+	c.shardedMap[index].kvmap[key] = item
+	// Write to AOF
+	if created {
+		c.setItemLog(key, item)
+	} else {
+		c.changeItemLog(key, item, change)
+	}
+}
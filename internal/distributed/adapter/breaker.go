@@ -0,0 +1,128 @@
+package adapter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // calls go through
+	BreakerOpen     BreakerState = "open"      // calls fail at once until the open timeout passes
+	BreakerHalfOpen BreakerState = "half-open" // a few trial calls decide whether to close or open again
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 5 * time.Second
+	defaultHalfOpenProbes   = 1
+)
+
+type BreakerStatus struct {
+	State    BreakerState `json:"state"`
+	Failures int          `json:"failures"` // consecutive failed calls
+	Rejected uint64       `json:"rejected"` // calls refused while open
+	OpenedAt time.Time    `json:"opened_at,omitzero"`
+}
+
+// breaker guards the calls to one peer, shared by every RemoteAdapter on the same address
+type breaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	mu               sync.Mutex
+	state            BreakerState
+	failures         int
+	trials           int // trial calls in flight while half-open
+	successes        int // trial calls that succeeded while half-open
+	rejected         uint64
+	openedAt         time.Time
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker) // base URL to breaker
+)
+
+// breakerFor returns the breaker of the peer at baseURL, the first adapter on an address sets its policy
+func breakerFor(baseURL string, policy RemotePolicy) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if b, ok := breakers[baseURL]; ok {
+		return b
+	}
+	b := &breaker{
+		failureThreshold: policy.FailureThreshold,
+		openTimeout:      policy.OpenTimeout,
+		halfOpenProbes:   policy.HalfOpenProbes,
+		state:            BreakerClosed,
+	}
+	breakers[baseURL] = b
+	return b
+}
+
+// allow reports whether a call may go to the peer, an open breaker turns half-open once its timeout passed
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.openTimeout {
+		b.state = BreakerHalfOpen
+		b.trials, b.successes = 0, 0
+	}
+	switch b.state {
+	case BreakerOpen:
+		b.rejected++
+		return false
+	case BreakerHalfOpen:
+		if b.trials >= b.halfOpenProbes {
+			b.rejected++
+			return false
+		}
+		b.trials++
+	}
+	return true
+}
+
+// record counts the outcome of an allowed call
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failed {
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+		return
+	}
+	b.failures = 0
+	if b.state == BreakerHalfOpen {
+		b.successes++
+		if b.successes >= b.halfOpenProbes {
+			b.state = BreakerClosed
+			b.openedAt = time.Time{}
+		}
+	}
+}
+
+// release gives back the trial slot of an allowed call that ended without an outcome, such as one
+// its caller cancelled, so a half-open breaker can still be closed by a later trial
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen && b.trials > 0 {
+		b.trials--
+	}
+}
+
+func (b *breaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStatus{State: b.state, Failures: b.failures, Rejected: b.rejected, OpenedAt: b.openedAt}
+}
@@ -1,23 +1,96 @@
 package adapter
 
 import (
+	"context"
+	"encoding/json"
+	"go-cache-server-mini/internal/core/data"
 	"time"
 )
 
+// AdapterInterface reaches the cache of one node, ctx cancels the call and its deadline travels to peers
 type AdapterInterface interface {
-	SetItem(key string, value []byte, expiration time.Duration) error
-	GetItem(key string) ([]byte, bool)
-	DeleteItem(key string) error
-	ExistsItem(key string) bool
-	ListKeys() []string
-	ClearCache() error
-	GetTTL(key string) (time.Duration, bool)
-	UpdateExpiration(key string, expiration time.Duration) error
-	RemoveExpiration(key string) error
-	Increment(key string) (int64, error)
-	Decrement(key string) (int64, error)
-	SetIfNotExists(key string, value []byte, expiration time.Duration) (bool, error)
-	GetAndSet(key string, value []byte) ([]byte, error)
-	GetMultiple(keys []string) map[string][]byte
-	SetMultiple(kv map[string][]byte, expiration time.Duration) error
+	SetItem(ctx context.Context, key string, value []byte, expiration time.Duration) error
+	GetItem(ctx context.Context, key string) ([]byte, bool, error)
+	DeleteItem(ctx context.Context, key string) error
+	ExistsItem(ctx context.Context, key string) (bool, error)
+	ListKeys(ctx context.Context) ([]string, error)
+	ClearCache(ctx context.Context) error
+	GetTTL(ctx context.Context, key string) (time.Duration, bool, error)
+	UpdateExpiration(ctx context.Context, key string, expiration time.Duration) error
+	RemoveExpiration(ctx context.Context, key string) error
+	Increment(ctx context.Context, key string) (int64, error)
+	Decrement(ctx context.Context, key string) (int64, error)
+	SetIfNotExists(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+	GetAndSet(ctx context.Context, key string, value []byte) ([]byte, error)
+	GetMultiple(ctx context.Context, keys []string) (map[string][]byte, error)
+	SetMultiple(ctx context.Context, kv map[string][]byte, expiration time.Duration) error
+	GetEntries(ctx context.Context, keys []string) (map[string]data.CacheItem, error)  // stored items with their expiration
+	SetEntries(ctx context.Context, entries map[string]data.CacheItem) error           // stores items as they are
+	DeleteEntries(ctx context.Context, versions map[string]uint64) error               // deletes keys older than their delete version
+	GetTombstones(ctx context.Context, keys []string) (map[string]uint64, error)       // delete versions of keys deleted and not written since
+	ListPush(ctx context.Context, key string, values [][]byte, head bool) (int, error) // LPUSH when head is set, RPUSH otherwise
+	ListPop(ctx context.Context, key string, count int, head bool) ([][]byte, error)   // LPOP when head is set, RPOP otherwise
+	ListRange(ctx context.Context, key string, start, stop int) ([][]byte, error)
+	ListLength(ctx context.Context, key string) (int, error)
+	ListTrim(ctx context.Context, key string, start, stop int) error
+	ListIndex(ctx context.Context, key string, index int) ([]byte, bool, error)
+	HashSet(ctx context.Context, key string, fields map[string][]byte) (int, error)
+	HashGet(ctx context.Context, key string, fields []string) (map[string][]byte, error) // the fields that exist
+	HashDelete(ctx context.Context, key string, fields []string) (int, error)
+	HashGetAll(ctx context.Context, key string) (map[string][]byte, error)
+	HashIncrement(ctx context.Context, key, field string, delta int64) (int64, error)
+	HashLength(ctx context.Context, key string) (int, error)
+	SetAdd(ctx context.Context, key string, members []string) (int, error)
+	SetRemove(ctx context.Context, key string, members []string) (int, error)
+	SetIsMember(ctx context.Context, key, member string) (bool, error)
+	SetMembers(ctx context.Context, key string) ([]string, error)
+	SetCard(ctx context.Context, key string) (int, error)
+	SetPop(ctx context.Context, key string, count int) ([]string, error)
+	SetRandom(ctx context.Context, key string, count int) ([]string, error)
+	SetCombine(ctx context.Context, op string, keys []string) ([]string, error) // op is data.SetUnion, SetIntersection or SetDifference
+	SortedSetAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error)
+	SortedSetIncrement(ctx context.Context, key, member string, delta float64) (float64, error)
+	SortedSetScore(ctx context.Context, key, member string) (float64, bool, error)
+	SortedSetRank(ctx context.Context, key, member string, rev bool) (int, bool, error)
+	SortedSetRange(ctx context.Context, key string, query data.ZRangeQuery) ([]data.ScoredMember, error)
+	SortedSetCard(ctx context.Context, key string) (int, error)
+	SortedSetRemove(ctx context.Context, key string, members []string) (int, error)
+	SortedSetRemoveRangeByScore(ctx context.Context, key, min, max string) (int, error)
+	SortedSetPop(ctx context.Context, key string, count int, highest bool) ([]data.ScoredMember, error) // highest pops like ZPOPMAX, else ZPOPMIN
+	StreamAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error)
+	StreamRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error)
+	StreamLength(ctx context.Context, key string) (int, error)
+	StreamTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error)
+	StreamGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error
+	StreamReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error)
+	StreamAck(ctx context.Context, key, group string, ids []string) (int, error)
+	StreamPending(ctx context.Context, key, group string) ([]data.PendingEntry, error)
+	SetRawItem(ctx context.Context, key string, value []byte, expiration time.Duration) error // SetItem for a value that is not JSON
+	GetRawItem(ctx context.Context, key string) ([]byte, bool, error)
+	BitmapSetBit(ctx context.Context, key string, offset int64, bit int) (int, error)
+	BitmapGetBit(ctx context.Context, key string, offset int64) (int, error)
+	BitmapCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error)
+	BitmapPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error)
+	BitmapOp(ctx context.Context, op, dest string, keys []string) (int, error)
+	BitmapField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error)
+	HLLAdd(ctx context.Context, key string, elements []string) (bool, error)
+	HLLCount(ctx context.Context, keys []string) (int64, error)
+	HLLMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error // sources are read from other nodes
+	GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error)
+	GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error)
+	GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error)
+	GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error)
+	JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error)
+	JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error)
+	JSONDel(ctx context.Context, key, path string) (int, error)
+	JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error)
+	JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error)
+	BloomReserve(ctx context.Context, key string, options data.BloomOptions) error
+	BloomAdd(ctx context.Context, key string, items []string) ([]bool, error)
+	BloomExists(ctx context.Context, key string, items []string) ([]bool, error)
+	CuckooReserve(ctx context.Context, key string, options data.CuckooOptions) error
+	CuckooAdd(ctx context.Context, key, item string, nx bool) (bool, error)
+	CuckooExists(ctx context.Context, key string, items []string) ([]bool, error)
+	CuckooCount(ctx context.Context, key, item string) (int, error)
+	CuckooDelete(ctx context.Context, key, item string) (bool, error)
 }
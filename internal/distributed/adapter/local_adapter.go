@@ -1,10 +1,16 @@
 package adapter
 
 import (
+	"context"
+	"encoding/json"
+	"go-cache-server-mini/internal"
 	"go-cache-server-mini/internal/core"
+	"go-cache-server-mini/internal/core/data"
 	"time"
 )
 
+// LocalAdapter serves calls from this node's cache. Every call first checks ctx, so a request
+// that was cancelled or ran out of time while it waited does not touch the cache.
 type LocalAdapter struct {
 	Cache core.CacheInterface
 }
@@ -15,62 +21,597 @@ func NewLocalAdapter(cache core.CacheInterface) *LocalAdapter {
 	}
 }
 
-func (la *LocalAdapter) SetItem(key string, value []byte, expiration time.Duration) error {
-	return la.Cache.Set(key, value, expiration)
+func (la *LocalAdapter) SetItem(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.Set(ctx, key, value, expiration)
+}
+
+func (la *LocalAdapter) GetItem(ctx context.Context, key string) ([]byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	value, found := la.Cache.Get(ctx, key)
+	return value, found, nil
+}
+
+func (la *LocalAdapter) DeleteItem(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.Del(ctx, key)
+}
+
+func (la *LocalAdapter) ExistsItem(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.Exists(ctx, key), nil
+}
+
+func (la *LocalAdapter) ListKeys(ctx context.Context) ([]string, error) {
+	return la.Cache.Keys(ctx)
+}
+
+func (la *LocalAdapter) ClearCache(ctx context.Context) error {
+	return la.Cache.Flush(ctx)
+}
+
+func (la *LocalAdapter) GetTTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	ttl, found := la.Cache.TTL(ctx, key)
+	return ttl, found, nil
+}
+
+func (la *LocalAdapter) UpdateExpiration(ctx context.Context, key string, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.Expire(ctx, key, expiration)
+}
+
+func (la *LocalAdapter) RemoveExpiration(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.Persist(ctx, key)
+}
+
+func (la *LocalAdapter) Increment(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.Incr(ctx, key)
+}
+
+func (la *LocalAdapter) Decrement(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.Decr(ctx, key)
+}
+
+func (la *LocalAdapter) SetIfNotExists(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.SetNX(ctx, key, value, expiration)
+}
+
+func (la *LocalAdapter) GetAndSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.GetSet(ctx, key, value)
+}
+
+func (la *LocalAdapter) GetMultiple(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.MGet(ctx, keys), nil
+}
+
+func (la *LocalAdapter) SetMultiple(ctx context.Context, kv map[string][]byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.MSet(ctx, kv, expiration)
+}
+
+func (la *LocalAdapter) GetEntries(ctx context.Context, keys []string) (map[string]data.CacheItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.GetEntries(ctx, keys), nil
+}
+
+func (la *LocalAdapter) SetEntries(ctx context.Context, entries map[string]data.CacheItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.SetEntries(ctx, entries)
+}
+
+func (la *LocalAdapter) DeleteEntries(ctx context.Context, versions map[string]uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.DeleteEntries(ctx, versions)
+}
+
+func (la *LocalAdapter) GetTombstones(ctx context.Context, keys []string) (map[string]uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.GetTombstones(ctx, keys), nil
+}
+
+func (la *LocalAdapter) ListPush(ctx context.Context, key string, values [][]byte, head bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if head {
+		return la.Cache.LPush(ctx, key, values)
+	}
+	return la.Cache.RPush(ctx, key, values)
+}
+
+func (la *LocalAdapter) ListPop(ctx context.Context, key string, count int, head bool) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if head {
+		return la.Cache.LPop(ctx, key, count)
+	}
+	return la.Cache.RPop(ctx, key, count)
+}
+
+func (la *LocalAdapter) ListRange(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.LRange(ctx, key, start, stop)
+}
+
+func (la *LocalAdapter) ListLength(ctx context.Context, key string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.LLen(ctx, key)
+}
+
+func (la *LocalAdapter) ListTrim(ctx context.Context, key string, start, stop int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.LTrim(ctx, key, start, stop)
+}
+
+func (la *LocalAdapter) ListIndex(ctx context.Context, key string, index int) ([]byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return la.Cache.LIndex(ctx, key, index)
+}
+
+func (la *LocalAdapter) HashSet(ctx context.Context, key string, fields map[string][]byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.HSet(ctx, key, fields)
+}
+
+func (la *LocalAdapter) HashGet(ctx context.Context, key string, fields []string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.HMGet(ctx, key, fields)
+}
+
+func (la *LocalAdapter) HashDelete(ctx context.Context, key string, fields []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.HDel(ctx, key, fields)
+}
+
+func (la *LocalAdapter) HashGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.HGetAll(ctx, key)
+}
+
+func (la *LocalAdapter) HashIncrement(ctx context.Context, key, field string, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.HIncrBy(ctx, key, field, delta)
+}
+
+func (la *LocalAdapter) HashLength(ctx context.Context, key string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.HLen(ctx, key)
+}
+
+func (la *LocalAdapter) SetAdd(ctx context.Context, key string, members []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.SAdd(ctx, key, members)
+}
+
+func (la *LocalAdapter) SetRemove(ctx context.Context, key string, members []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.SRem(ctx, key, members)
+}
+
+func (la *LocalAdapter) SetIsMember(ctx context.Context, key, member string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.SIsMember(ctx, key, member)
+}
+
+func (la *LocalAdapter) SetMembers(ctx context.Context, key string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.SMembers(ctx, key)
+}
+
+func (la *LocalAdapter) SetCard(ctx context.Context, key string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.SCard(ctx, key)
+}
+
+func (la *LocalAdapter) SetPop(ctx context.Context, key string, count int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.SPop(ctx, key, count)
+}
+
+func (la *LocalAdapter) SetRandom(ctx context.Context, key string, count int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.SRandMember(ctx, key, count)
+}
+
+func (la *LocalAdapter) SetCombine(ctx context.Context, op string, keys []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch op {
+	case data.SetUnion:
+		return la.Cache.SUnion(ctx, keys)
+	case data.SetIntersection:
+		return la.Cache.SInter(ctx, keys)
+	case data.SetDifference:
+		return la.Cache.SDiff(ctx, keys)
+	}
+	return nil, internal.ErrBadRequest
+}
+
+func (la *LocalAdapter) SortedSetAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.ZAdd(ctx, key, members, options)
+}
+
+func (la *LocalAdapter) SortedSetIncrement(ctx context.Context, key, member string, delta float64) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.ZIncrBy(ctx, key, member, delta)
+}
+
+func (la *LocalAdapter) SortedSetScore(ctx context.Context, key, member string) (float64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	return la.Cache.ZScore(ctx, key, member)
+}
+
+func (la *LocalAdapter) SortedSetRank(ctx context.Context, key, member string, rev bool) (int, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	return la.Cache.ZRank(ctx, key, member, rev)
+}
+
+func (la *LocalAdapter) SortedSetRange(ctx context.Context, key string, query data.ZRangeQuery) ([]data.ScoredMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.ZRange(ctx, key, query)
+}
+
+func (la *LocalAdapter) SortedSetCard(ctx context.Context, key string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.ZCard(ctx, key)
+}
+
+func (la *LocalAdapter) SortedSetRemove(ctx context.Context, key string, members []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.ZRem(ctx, key, members)
+}
+
+func (la *LocalAdapter) SortedSetRemoveRangeByScore(ctx context.Context, key, min, max string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.ZRemRangeByScore(ctx, key, min, max)
+}
+
+func (la *LocalAdapter) SortedSetPop(ctx context.Context, key string, count int, highest bool) ([]data.ScoredMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if highest {
+		return la.Cache.ZPopMax(ctx, key, count)
+	}
+	return la.Cache.ZPopMin(ctx, key, count)
+}
+
+func (la *LocalAdapter) StreamAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return la.Cache.XAdd(ctx, key, id, fields, trim)
+}
+
+func (la *LocalAdapter) StreamRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.XRange(ctx, key, start, end, count)
+}
+
+func (la *LocalAdapter) StreamLength(ctx context.Context, key string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.XLen(ctx, key)
 }
 
-func (la *LocalAdapter) GetItem(key string) ([]byte, bool) {
-	return la.Cache.Get(key)
+func (la *LocalAdapter) StreamTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.XTrim(ctx, key, trim)
 }
 
-func (la *LocalAdapter) DeleteItem(key string) error {
-	return la.Cache.Del(key)
+func (la *LocalAdapter) StreamGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.XGroupCreate(ctx, key, group, id, mkStream)
 }
 
-func (la *LocalAdapter) ExistsItem(key string) bool {
-	return la.Cache.Exists(key)
+func (la *LocalAdapter) StreamReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.XReadGroup(ctx, key, group, consumer, id, count)
 }
 
-func (la *LocalAdapter) ListKeys() []string {
-	return la.Cache.Keys()
+func (la *LocalAdapter) StreamAck(ctx context.Context, key, group string, ids []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.XAck(ctx, key, group, ids)
+}
+
+func (la *LocalAdapter) StreamPending(ctx context.Context, key, group string) ([]data.PendingEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.XPending(ctx, key, group)
 }
 
-func (la *LocalAdapter) ClearCache() error {
-	return la.Cache.Flush()
+func (la *LocalAdapter) SetRawItem(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return la.SetItem(ctx, key, value, expiration)
 }
 
-func (la *LocalAdapter) GetTTL(key string) (time.Duration, bool) {
-	return la.Cache.TTL(key)
+func (la *LocalAdapter) GetRawItem(ctx context.Context, key string) ([]byte, bool, error) {
+	return la.GetItem(ctx, key)
 }
 
-func (la *LocalAdapter) UpdateExpiration(key string, expiration time.Duration) error {
-	return la.Cache.Expire(key, expiration)
+func (la *LocalAdapter) BitmapSetBit(ctx context.Context, key string, offset int64, bit int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.SetBit(ctx, key, offset, bit)
 }
 
-func (la *LocalAdapter) RemoveExpiration(key string) error {
-	return la.Cache.Persist(key)
+func (la *LocalAdapter) BitmapGetBit(ctx context.Context, key string, offset int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.GetBit(ctx, key, offset)
 }
 
-func (la *LocalAdapter) Increment(key string) (int64, error) {
-	return la.Cache.Incr(key)
+func (la *LocalAdapter) BitmapCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.BitCount(ctx, key, bitRange)
 }
 
-func (la *LocalAdapter) Decrement(key string) (int64, error) {
-	return la.Cache.Decr(key)
+func (la *LocalAdapter) BitmapPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.BitPos(ctx, key, bit, bitRange)
 }
 
-func (la *LocalAdapter) SetIfNotExists(key string, value []byte, expiration time.Duration) (bool, error) {
-	return la.Cache.SetNX(key, value, expiration)
+func (la *LocalAdapter) BitmapOp(ctx context.Context, op, dest string, keys []string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.BitOp(ctx, op, dest, keys)
 }
 
-func (la *LocalAdapter) GetAndSet(key string, value []byte) ([]byte, error) {
-	return la.Cache.GetSet(key, value)
+func (la *LocalAdapter) BitmapField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.BitField(ctx, key, ops)
 }
 
-func (la *LocalAdapter) GetMultiple(keys []string) map[string][]byte {
-	return la.Cache.MGet(keys)
+func (la *LocalAdapter) HLLAdd(ctx context.Context, key string, elements []string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.PFAdd(ctx, key, elements)
 }
 
-func (la *LocalAdapter) SetMultiple(kv map[string][]byte, expiration time.Duration) error {
-	return la.Cache.MSet(kv, expiration)
+func (la *LocalAdapter) HLLCount(ctx context.Context, keys []string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.PFCount(ctx, keys)
+}
+
+func (la *LocalAdapter) HLLMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.PFMerge(ctx, dest, keys, sources)
+}
+
+func (la *LocalAdapter) GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.GeoAdd(ctx, key, members, options)
+}
+
+func (la *LocalAdapter) GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.GeoPos(ctx, key, members)
+}
+
+func (la *LocalAdapter) GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	return la.Cache.GeoDist(ctx, key, from, to, unit)
+}
+
+func (la *LocalAdapter) GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.GeoSearch(ctx, key, query)
+}
+
+func (la *LocalAdapter) JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return la.Cache.JSONGet(ctx, key, path)
+}
+
+func (la *LocalAdapter) JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.JSONSet(ctx, key, path, value, options)
+}
+
+func (la *LocalAdapter) JSONDel(ctx context.Context, key, path string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.JSONDel(ctx, key, path)
+}
+
+func (la *LocalAdapter) JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.JSONArrAppend(ctx, key, path, values)
+}
+
+func (la *LocalAdapter) JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.JSONNumIncrBy(ctx, key, path, delta)
+}
+
+func (la *LocalAdapter) BloomReserve(ctx context.Context, key string, options data.BloomOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.BFReserve(ctx, key, options)
+}
+
+func (la *LocalAdapter) BloomAdd(ctx context.Context, key string, items []string) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.BFAdd(ctx, key, items)
+}
+
+func (la *LocalAdapter) BloomExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.BFExists(ctx, key, items)
+}
+
+func (la *LocalAdapter) CuckooReserve(ctx context.Context, key string, options data.CuckooOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return la.Cache.CFReserve(ctx, key, options)
+}
+
+func (la *LocalAdapter) CuckooAdd(ctx context.Context, key, item string, nx bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.CFAdd(ctx, key, item, nx)
+}
+
+func (la *LocalAdapter) CuckooExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return la.Cache.CFExists(ctx, key, items)
+}
+
+func (la *LocalAdapter) CuckooCount(ctx context.Context, key, item string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return la.Cache.CFCount(ctx, key, item)
+}
+
+func (la *LocalAdapter) CuckooDelete(ctx context.Context, key, item string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return la.Cache.CFDel(ctx, key, item)
 }
@@ -1,87 +1,1212 @@
 package adapter
 
-import "time"
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/api/dto"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/raft"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRemoteTimeout = 2 * time.Second
+	defaultMaxAttempts   = 3
+	defaultBaseBackoff   = 50 * time.Millisecond
+	defaultMaxBackoff    = 500 * time.Millisecond
+)
+
+// TimeoutHeader carries the milliseconds a peer has left to answer, so it stops working
+// on a call the sender has already given up on
+const TimeoutHeader = "X-Request-Timeout"
+
+// peerPathPrefix is the route group a peer serves from its own cache only,
+// so a forwarded call is never routed a second time.
+const peerPathPrefix = "/internal"
+
+// sharedTransport keeps idle connections to every peer so that
+// consecutive calls reuse the same TCP connections.
+var sharedTransport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConns:        256,
+	MaxIdleConnsPerHost: 64,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// retryable lists the peer routes that are safe to send twice, counters and conditional writes are not.
+// Neither are HSET, SADD and ZADD: sent twice, the second one finds its own members and answers that
+// none were added.
+// Routes safe with some arguments only, like /json/set without nx or xx, decide per call through doRetrying.
+var retryable = map[string]bool{
+	"/get": true, "/exists": true, "/keys": true, "/ttl": true, "/mget": true,
+	"/set": true, "/del": true, "/flush": true, "/expire": true, "/persist": true, "/mset": true,
+	"/entries/get": true, "/entries/set": true, "/entries/del": true,
+	"/lrange": true, "/llen": true, "/lindex": true,
+	"/hmget": true, "/hdel": true, "/hgetall": true, "/hlen": true,
+	"/srem": true, "/sismember": true, "/smembers": true, "/scard": true, "/srandmember": true,
+	"/sunion": true, "/sinter": true, "/sdiff": true,
+	"/zscore": true, "/zrank": true, "/zrange": true, "/zcard": true, "/zrem": true, "/zremrangebyscore": true,
+	"/xrange": true, "/xlen": true, "/xtrim": true, "/xack": true, "/xpending": true,
+	"/getraw": true, "/setraw": true, "/setbit": true, "/getbit": true, "/bitcount": true, "/bitpos": true,
+	"/pfadd": true, "/pfcount": true, "/pfmerge": true,
+	"/geoadd": true, "/geopos": true, "/geodist": true, "/geosearch": true, "/json/get": true,
+	"/bf/add": true, "/bf/madd": true, "/bf/exists": true, "/bf/mexists": true,
+	"/cf/exists": true, "/cf/mexists": true, "/cf/count": true,
+	"/merkle/root": true, "/merkle/leaves": true, "/merkle/bucket": true,
+}
+
+// RemotePolicy bounds how long a RemoteAdapter waits for its peer, how often it retries and when it stops trying
+type RemotePolicy struct {
+	Timeout          time.Duration // deadline of one operation, retries included
+	MaxAttempts      int           // tries of a retryable operation that found the peer unavailable
+	BaseBackoff      time.Duration // first wait between tries, doubled each time with full jitter
+	MaxBackoff       time.Duration
+	FailureThreshold int           // consecutive failed calls that open the breaker
+	OpenTimeout      time.Duration // time the breaker stays open before a trial call
+	HalfOpenProbes   int           // trial calls that must succeed to close the breaker
+}
+
+func (p RemotePolicy) withDefaults() RemotePolicy {
+	if p.Timeout <= 0 {
+		p.Timeout = defaultRemoteTimeout
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = defaultBaseBackoff
+	}
+	if p.MaxBackoff < p.BaseBackoff {
+		p.MaxBackoff = max(defaultMaxBackoff, p.BaseBackoff)
+	}
+	if p.FailureThreshold <= 0 {
+		p.FailureThreshold = defaultFailureThreshold
+	}
+	if p.OpenTimeout <= 0 {
+		p.OpenTimeout = defaultOpenTimeout
+	}
+	if p.HalfOpenProbes <= 0 {
+		p.HalfOpenProbes = defaultHalfOpenProbes
+	}
+	return p
+}
 
 type RemoteAdapter struct {
-	// Implementation details for remote adapter
+	baseURL string
+	client  *http.Client
+	policy  RemotePolicy
+	breaker *breaker
+}
+
+// BaseURL turns a node address (host:port or URL) into a URL without trailing slash
+func BaseURL(address string) string {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+	return strings.TrimRight(address, "/")
+}
+
+// NewRemoteAdapter creates a new instance of RemoteAdapter talking to the peer at address (host:port or URL)
+func NewRemoteAdapter(address string, policy RemotePolicy) *RemoteAdapter {
+	policy = policy.withDefaults()
+	baseURL := BaseURL(address)
+	return &RemoteAdapter{
+		baseURL: baseURL,
+		client:  &http.Client{Transport: sharedTransport},
+		policy:  policy,
+		breaker: breakerFor(baseURL, policy),
+	}
+}
+
+func (ra *RemoteAdapter) Address() string {
+	return ra.baseURL
+}
+
+// Breaker reports the circuit breaker state of the peer
+func (ra *RemoteAdapter) Breaker() BreakerStatus {
+	return ra.breaker.status()
+}
+
+// Ping checks that the peer is up through its /ping health check route.
+// It bypasses the breaker, so health checks keep judging the peer on their own.
+func (ra *RemoteAdapter) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), ra.policy.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ra.baseURL+"/ping", nil)
+	if err != nil {
+		return err
+	}
+	return ra.send(req, nil)
+}
+
+func (ra *RemoteAdapter) SetItem(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	req := dto.SetRequest{Key: key, Value: value, TTL: ttlSeconds(expiration)}
+	return ra.do(ctx, http.MethodPost, "/set", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) GetItem(ctx context.Context, key string) ([]byte, bool, error) {
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodGet, "/get", keyQuery(key), nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return res.Value, true, nil
+}
+
+func (ra *RemoteAdapter) DeleteItem(ctx context.Context, key string) error {
+	return ra.do(ctx, http.MethodDelete, "/del", keyQuery(key), nil, nil)
+}
+
+func (ra *RemoteAdapter) ExistsItem(ctx context.Context, key string) (bool, error) {
+	var res struct {
+		Exists bool `json:"exists"`
+	}
+	if err := ra.do(ctx, http.MethodGet, "/exists", keyQuery(key), nil, &res); err != nil {
+		return false, err
+	}
+	return res.Exists, nil
+}
+
+func (ra *RemoteAdapter) ListKeys(ctx context.Context) ([]string, error) {
+	var res struct {
+		Keys []string `json:"keys"`
+	}
+	if err := ra.do(ctx, http.MethodGet, "/keys", strictQuery, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Keys, nil
+}
+
+func (ra *RemoteAdapter) ClearCache(ctx context.Context) error {
+	return ra.do(ctx, http.MethodPost, "/flush", strictQuery, nil, nil)
+}
+
+func (ra *RemoteAdapter) GetTTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	var res struct {
+		TTL int64 `json:"ttl"`
+	}
+	if err := ra.do(ctx, http.MethodGet, "/ttl", keyQuery(key), nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if res.TTL < 0 {
+		return -1, true, nil
+	}
+	return time.Duration(res.TTL) * time.Second, true, nil
+}
+
+func (ra *RemoteAdapter) UpdateExpiration(ctx context.Context, key string, expiration time.Duration) error {
+	ttl := ttlSeconds(expiration)
+	if expiration <= 0 {
+		ttl = -1 // the peer deletes the key for any non-positive ttl, 0 is rejected by binding
+	}
+	req := dto.ExpireRequest{Key: key, TTL: ttl}
+	return ra.do(ctx, http.MethodPost, "/expire", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) RemoveExpiration(ctx context.Context, key string) error {
+	return ra.do(ctx, http.MethodPost, "/persist", keyQuery(key), nil, nil)
+}
+
+func (ra *RemoteAdapter) Increment(ctx context.Context, key string) (int64, error) {
+	return ra.counter(ctx, "/incr", key)
+}
+
+func (ra *RemoteAdapter) Decrement(ctx context.Context, key string) (int64, error) {
+	return ra.counter(ctx, "/decr", key)
+}
+
+func (ra *RemoteAdapter) SetIfNotExists(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	req := dto.SetRequest{Key: key, Value: value, TTL: ttlSeconds(expiration)}
+	var res struct {
+		Success bool `json:"success"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/setnx", nil, req, &res); err != nil {
+		return false, err
+	}
+	return res.Success, nil
+}
+
+func (ra *RemoteAdapter) GetAndSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	req := dto.GetSetRequest{Key: key, Value: value}
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodPost, "/getset", nil, req, &res); err != nil {
+		return nil, err
+	}
+	if string(res.Value) == "null" {
+		return nil, nil
+	}
+	return res.Value, nil
+}
+
+func (ra *RemoteAdapter) GetMultiple(ctx context.Context, keys []string) (map[string][]byte, error) {
+	req := dto.MGetRequest{Keys: keys}
+	var res dto.MGetResponse
+	if err := ra.do(ctx, http.MethodPost, "/mget", nil, req, &res); err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(res.KV))
+	for key, value := range res.KV {
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (ra *RemoteAdapter) SetMultiple(ctx context.Context, kv map[string][]byte, expiration time.Duration) error {
+	req := dto.MSetRequest{KV: make(map[string]json.RawMessage, len(kv)), TTL: ttlSeconds(expiration)}
+	for key, value := range kv {
+		req.KV[key] = value
+	}
+	return ra.do(ctx, http.MethodPost, "/mset", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) GetEntries(ctx context.Context, keys []string) (map[string]data.CacheItem, error) {
+	req := dto.GetEntriesRequest{Keys: keys}
+	var res dto.EntriesResponse
+	if err := ra.do(ctx, http.MethodPost, "/entries/get", nil, req, &res); err != nil {
+		return nil, err
+	}
+	if res.Entries == nil {
+		res.Entries = map[string]data.CacheItem{}
+	}
+	return res.Entries, nil
+}
+
+func (ra *RemoteAdapter) SetEntries(ctx context.Context, entries map[string]data.CacheItem) error {
+	req := dto.SetEntriesRequest{Entries: entries}
+	return ra.do(ctx, http.MethodPost, "/entries/set", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) DeleteEntries(ctx context.Context, versions map[string]uint64) error {
+	req := dto.DeleteEntriesRequest{Versions: versions}
+	return ra.do(ctx, http.MethodPost, "/entries/del", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) GetTombstones(ctx context.Context, keys []string) (map[string]uint64, error) {
+	req := dto.GetEntriesRequest{Keys: keys}
+	var res dto.TombstonesResponse
+	if err := ra.do(ctx, http.MethodPost, "/entries/tombstones", nil, req, &res); err != nil {
+		return nil, err
+	}
+	if res.Versions == nil {
+		res.Versions = map[string]uint64{}
+	}
+	return res.Versions, nil
+}
+
+// MerkleRoot returns the peer's root hash of the keys it shares with node
+func (ra *RemoteAdapter) MerkleRoot(ctx context.Context, node string) (string, error) {
+	var res dto.MerkleRootResponse
+	if err := ra.do(ctx, http.MethodGet, "/merkle/root", url.Values{"node": []string{node}}, nil, &res); err != nil {
+		return "", err
+	}
+	return res.Root, nil
+}
+
+// MerkleLeaves returns the peer's leaf hashes of the keys it shares with node
+func (ra *RemoteAdapter) MerkleLeaves(ctx context.Context, node string) ([]string, error) {
+	var res dto.MerkleLeavesResponse
+	if err := ra.do(ctx, http.MethodGet, "/merkle/leaves", url.Values{"node": []string{node}}, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Leaves, nil
+}
+
+// MerkleBucket returns the peer's key versions and delete versions of one leaf
+func (ra *RemoteAdapter) MerkleBucket(ctx context.Context, node string, bucket int) (versions, deleted map[string]uint64, err error) {
+	query := url.Values{"node": []string{node}, "bucket": []string{strconv.Itoa(bucket)}}
+	var res dto.MerkleBucketResponse
+	if err := ra.do(ctx, http.MethodGet, "/merkle/bucket", query, nil, &res); err != nil {
+		return nil, nil, err
+	}
+	return res.Versions, res.Deleted, nil
+}
+
+func (ra *RemoteAdapter) ListPush(ctx context.Context, key string, values [][]byte, head bool) (int, error) {
+	path := "/rpush"
+	if head {
+		path = "/lpush"
+	}
+	req := dto.ListPushRequest{Key: key, Values: make([]json.RawMessage, len(values))}
+	for i, value := range values {
+		req.Values[i] = value
+	}
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodPost, path, nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) ListPop(ctx context.Context, key string, count int, head bool) ([][]byte, error) {
+	path := "/rpop"
+	if head {
+		path = "/lpop"
+	}
+	query := keyQuery(key)
+	query.Set("count", strconv.Itoa(count))
+	var res dto.ListValuesResponse
+	if err := ra.do(ctx, http.MethodPost, path, query, nil, &res); err != nil {
+		return nil, err
+	}
+	return listValues(res.Values), nil
+}
+
+func (ra *RemoteAdapter) ListRange(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	query := keyQuery(key)
+	query.Set("start", strconv.Itoa(start))
+	query.Set("stop", strconv.Itoa(stop))
+	var res dto.ListValuesResponse
+	if err := ra.do(ctx, http.MethodGet, "/lrange", query, nil, &res); err != nil {
+		return nil, err
+	}
+	return listValues(res.Values), nil
+}
+
+func (ra *RemoteAdapter) ListLength(ctx context.Context, key string) (int, error) {
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodGet, "/llen", keyQuery(key), nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) ListTrim(ctx context.Context, key string, start, stop int) error {
+	req := dto.ListTrimRequest{Key: key, Start: start, Stop: stop}
+	return ra.do(ctx, http.MethodPost, "/ltrim", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) ListIndex(ctx context.Context, key string, index int) ([]byte, bool, error) {
+	query := keyQuery(key)
+	query.Set("index", strconv.Itoa(index))
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodGet, "/lindex", query, nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return res.Value, true, nil
+}
+
+func (ra *RemoteAdapter) HashSet(ctx context.Context, key string, fields map[string][]byte) (int, error) {
+	req := dto.HashSetRequest{Key: key, Fields: make(map[string]json.RawMessage, len(fields))}
+	for field, value := range fields {
+		req.Fields[field] = value
+	}
+	var res struct {
+		Added int `json:"added"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/hset", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Added, nil
+}
+
+func (ra *RemoteAdapter) HashGet(ctx context.Context, key string, fields []string) (map[string][]byte, error) {
+	req := dto.HashMGetRequest{Key: key, Fields: fields}
+	var res dto.HashFieldsResponse
+	if err := ra.do(ctx, http.MethodPost, "/hmget", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return hashFields(res.Fields), nil
+}
+
+func (ra *RemoteAdapter) HashDelete(ctx context.Context, key string, fields []string) (int, error) {
+	query := keyQuery(key)
+	query["field"] = fields
+	var res struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := ra.do(ctx, http.MethodDelete, "/hdel", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Deleted, nil
+}
+
+func (ra *RemoteAdapter) HashGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	var res dto.HashFieldsResponse
+	if err := ra.do(ctx, http.MethodGet, "/hgetall", keyQuery(key), nil, &res); err != nil {
+		return nil, err
+	}
+	return hashFields(res.Fields), nil
+}
+
+func (ra *RemoteAdapter) HashIncrement(ctx context.Context, key, field string, delta int64) (int64, error) {
+	req := dto.HashIncrByRequest{Key: key, Field: field, Increment: delta}
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodPost, "/hincrby", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(res.Value), 10, 64)
+}
+
+func (ra *RemoteAdapter) HashLength(ctx context.Context, key string) (int, error) {
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodGet, "/hlen", keyQuery(key), nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) SetAdd(ctx context.Context, key string, members []string) (int, error) {
+	req := dto.SetAddRequest{Key: key, Members: members}
+	var res struct {
+		Added int `json:"added"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/sadd", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Added, nil
+}
+
+func (ra *RemoteAdapter) SetRemove(ctx context.Context, key string, members []string) (int, error) {
+	query := keyQuery(key)
+	query["member"] = members
+	var res struct {
+		Removed int `json:"removed"`
+	}
+	if err := ra.do(ctx, http.MethodDelete, "/srem", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Removed, nil
+}
+
+func (ra *RemoteAdapter) SetIsMember(ctx context.Context, key, member string) (bool, error) {
+	query := keyQuery(key)
+	query.Set("member", member)
+	var res struct {
+		Exists bool `json:"exists"`
+	}
+	if err := ra.do(ctx, http.MethodGet, "/sismember", query, nil, &res); err != nil {
+		return false, err
+	}
+	return res.Exists, nil
+}
+
+func (ra *RemoteAdapter) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return ra.members(ctx, http.MethodGet, "/smembers", keyQuery(key), nil)
+}
+
+func (ra *RemoteAdapter) SetCard(ctx context.Context, key string) (int, error) {
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodGet, "/scard", keyQuery(key), nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) SetPop(ctx context.Context, key string, count int) ([]string, error) {
+	query := keyQuery(key)
+	query.Set("count", strconv.Itoa(count))
+	return ra.members(ctx, http.MethodPost, "/spop", query, nil)
+}
+
+func (ra *RemoteAdapter) SetRandom(ctx context.Context, key string, count int) ([]string, error) {
+	query := keyQuery(key)
+	query.Set("count", strconv.Itoa(count))
+	return ra.members(ctx, http.MethodGet, "/srandmember", query, nil)
+}
+
+func (ra *RemoteAdapter) SetCombine(ctx context.Context, op string, keys []string) ([]string, error) {
+	return ra.members(ctx, http.MethodPost, "/s"+op, nil, dto.SetAlgebraRequest{Keys: keys}) // /sunion, /sinter, /sdiff
+}
+
+func (ra *RemoteAdapter) members(ctx context.Context, method, path string, query url.Values, body any) ([]string, error) {
+	var res dto.SetMembersResponse
+	if err := ra.do(ctx, method, path, query, body, &res); err != nil {
+		return nil, err
+	}
+	return res.Members, nil
+}
+
+func (ra *RemoteAdapter) SortedSetAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error) {
+	req := dto.SortedSetAddRequest{Key: key, Members: members, ZAddOptions: options}
+	var res struct {
+		Added int `json:"added"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/zadd", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Added, nil
+}
+
+func (ra *RemoteAdapter) SortedSetIncrement(ctx context.Context, key, member string, delta float64) (float64, error) {
+	req := dto.SortedSetIncrByRequest{Key: key, Member: member, Increment: delta}
+	var res dto.ScoreResponse
+	if err := ra.do(ctx, http.MethodPost, "/zincrby", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Score, nil
+}
+
+func (ra *RemoteAdapter) SortedSetScore(ctx context.Context, key, member string) (float64, bool, error) {
+	query := keyQuery(key)
+	query.Set("member", member)
+	var res dto.ScoreResponse
+	if err := ra.do(ctx, http.MethodGet, "/zscore", query, nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return res.Score, true, nil
+}
+
+func (ra *RemoteAdapter) SortedSetRank(ctx context.Context, key, member string, rev bool) (int, bool, error) {
+	query := keyQuery(key)
+	query.Set("member", member)
+	query.Set("rev", strconv.FormatBool(rev))
+	var res dto.RankResponse
+	if err := ra.do(ctx, http.MethodGet, "/zrank", query, nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return res.Rank, true, nil
+}
+
+func (ra *RemoteAdapter) SortedSetRange(ctx context.Context, key string, q data.ZRangeQuery) ([]data.ScoredMember, error) {
+	query := keyQuery(key)
+	query.Set("by", cmp.Or(q.By, data.ZRangeByRank))
+	query.Set("start", q.Start)
+	query.Set("stop", q.Stop)
+	query.Set("rev", strconv.FormatBool(q.Rev))
+	query.Set("offset", strconv.Itoa(q.Offset))
+	query.Set("count", strconv.Itoa(q.Count))
+	return ra.scoredMembers(ctx, http.MethodGet, "/zrange", query)
+}
+
+func (ra *RemoteAdapter) SortedSetCard(ctx context.Context, key string) (int, error) {
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodGet, "/zcard", keyQuery(key), nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) SortedSetRemove(ctx context.Context, key string, members []string) (int, error) {
+	query := keyQuery(key)
+	query["member"] = members
+	var res struct {
+		Removed int `json:"removed"`
+	}
+	if err := ra.do(ctx, http.MethodDelete, "/zrem", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Removed, nil
+}
+
+func (ra *RemoteAdapter) SortedSetRemoveRangeByScore(ctx context.Context, key, min, max string) (int, error) {
+	req := dto.SortedSetRemRangeRequest{Key: key, Min: min, Max: max}
+	var res struct {
+		Removed int `json:"removed"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/zremrangebyscore", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Removed, nil
+}
+
+func (ra *RemoteAdapter) SortedSetPop(ctx context.Context, key string, count int, highest bool) ([]data.ScoredMember, error) {
+	path := "/zpopmin"
+	if highest {
+		path = "/zpopmax"
+	}
+	query := keyQuery(key)
+	query.Set("count", strconv.Itoa(count))
+	return ra.scoredMembers(ctx, http.MethodPost, path, query)
+}
+
+func (ra *RemoteAdapter) scoredMembers(ctx context.Context, method, path string, query url.Values) ([]data.ScoredMember, error) {
+	var res dto.ScoredMembersResponse
+	if err := ra.do(ctx, method, path, query, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Members, nil
+}
+
+func (ra *RemoteAdapter) StreamAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error) {
+	req := dto.StreamAddRequest{Key: key, ID: id, Fields: make(map[string]json.RawMessage, len(fields)), StreamTrim: trim}
+	for field, value := range fields {
+		req.Fields[field] = value
+	}
+	var res struct {
+		ID string `json:"id"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/xadd", nil, req, &res); err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+func (ra *RemoteAdapter) StreamRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error) {
+	query := keyQuery(key)
+	query.Set("start", start)
+	query.Set("end", end)
+	query.Set("count", strconv.Itoa(count))
+	var res dto.StreamEntriesResponse
+	if err := ra.do(ctx, http.MethodGet, "/xrange", query, nil, &res); err != nil {
+		return nil, err
+	}
+	return streamEntries(res.Entries)
+}
+
+func (ra *RemoteAdapter) StreamLength(ctx context.Context, key string) (int, error) {
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodGet, "/xlen", keyQuery(key), nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) StreamTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error) {
+	req := dto.StreamTrimRequest{Key: key, StreamTrim: trim}
+	var res struct {
+		Trimmed int `json:"trimmed"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/xtrim", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Trimmed, nil
+}
+
+func (ra *RemoteAdapter) StreamGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error {
+	req := dto.StreamGroupCreateRequest{Key: key, Group: group, ID: id, MkStream: mkStream}
+	return ra.do(ctx, http.MethodPost, "/xgroup/create", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) StreamReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error) {
+	req := dto.StreamReadGroupRequest{Key: key, Group: group, Consumer: consumer, ID: id, Count: count}
+	var res dto.StreamEntriesResponse
+	if err := ra.do(ctx, http.MethodPost, "/xreadgroup", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return streamEntries(res.Entries)
+}
+
+func (ra *RemoteAdapter) StreamAck(ctx context.Context, key, group string, ids []string) (int, error) {
+	req := dto.StreamAckRequest{Key: key, Group: group, IDs: ids}
+	var res struct {
+		Acked int `json:"acked"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/xack", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Acked, nil
+}
+
+func (ra *RemoteAdapter) StreamPending(ctx context.Context, key, group string) ([]data.PendingEntry, error) {
+	query := keyQuery(key)
+	query.Set("group", group)
+	var res dto.StreamPendingResponse
+	if err := ra.do(ctx, http.MethodGet, "/xpending", query, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Pending, nil
+}
+
+func (ra *RemoteAdapter) SetRawItem(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	req := dto.SetRawRequest{Key: key, Value: value, TTL: ttlSeconds(expiration)}
+	return ra.do(ctx, http.MethodPost, "/setraw", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) GetRawItem(ctx context.Context, key string) ([]byte, bool, error) {
+	var res dto.RawValueResponse
+	if err := ra.do(ctx, http.MethodGet, "/getraw", keyQuery(key), nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return res.Value, true, nil
+}
+
+func (ra *RemoteAdapter) BitmapSetBit(ctx context.Context, key string, offset int64, bit int) (int, error) {
+	req := dto.SetBitRequest{Key: key, Offset: offset, Bit: bit}
+	var res dto.BitResponse
+	if err := ra.do(ctx, http.MethodPost, "/setbit", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Bit, nil
+}
+
+func (ra *RemoteAdapter) BitmapGetBit(ctx context.Context, key string, offset int64) (int, error) {
+	query := keyQuery(key)
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	var res dto.BitResponse
+	if err := ra.do(ctx, http.MethodGet, "/getbit", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Bit, nil
+}
+
+func (ra *RemoteAdapter) BitmapCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error) {
+	var res dto.BitCountResponse
+	if err := ra.do(ctx, http.MethodGet, "/bitcount", bitRangeQuery(key, bitRange), nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+func (ra *RemoteAdapter) BitmapPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error) {
+	query := bitRangeQuery(key, bitRange)
+	query.Set("bit", strconv.Itoa(bit))
+	var res dto.BitPosResponse
+	if err := ra.do(ctx, http.MethodGet, "/bitpos", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Position, nil
+}
+
+func (ra *RemoteAdapter) BitmapOp(ctx context.Context, op, dest string, keys []string) (int, error) {
+	req := dto.BitOpRequest{Op: op, Dest: dest, Keys: keys}
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodPost, "/bitop", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
+}
+
+func (ra *RemoteAdapter) BitmapField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error) {
+	req := dto.BitFieldRequest{Key: key, Ops: ops}
+	var res dto.BitFieldResponse
+	if err := ra.do(ctx, http.MethodPost, "/bitfield", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
+
+func (ra *RemoteAdapter) HLLAdd(ctx context.Context, key string, elements []string) (bool, error) {
+	req := dto.HLLAddRequest{Key: key, Elements: elements}
+	var res struct {
+		Changed bool `json:"changed"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/pfadd", nil, req, &res); err != nil {
+		return false, err
+	}
+	return res.Changed, nil
+}
+
+func (ra *RemoteAdapter) HLLCount(ctx context.Context, keys []string) (int64, error) {
+	var res dto.HLLCountResponse
+	if err := ra.do(ctx, http.MethodGet, "/pfcount", url.Values{"key": keys}, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+func (ra *RemoteAdapter) HLLMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error {
+	req := dto.HLLMergeRequest{Dest: dest, Keys: keys, Sources: sources}
+	return ra.do(ctx, http.MethodPost, "/pfmerge", nil, req, nil)
+}
+
+func (ra *RemoteAdapter) RaftVote(req raft.VoteRequest) (raft.VoteResponse, error) {
+	var res raft.VoteResponse
+	err := ra.do(context.Background(), http.MethodPost, "/raft/vote", nil, req, &res)
+	return res, err
+}
+
+func (ra *RemoteAdapter) RaftAppend(req raft.AppendRequest) (raft.AppendResponse, error) {
+	var res raft.AppendResponse
+	err := ra.do(context.Background(), http.MethodPost, "/raft/append", nil, req, &res)
+	return res, err
+}
+
+// RaftPropose hands a command to the raft leader and waits until it is committed
+func (ra *RemoteAdapter) RaftPropose(command []byte) error {
+	return ra.do(context.Background(), http.MethodPost, "/raft/propose", nil, raft.ProposeRequest{Command: command}, nil)
+}
+
+func (ra *RemoteAdapter) counter(ctx context.Context, path, key string) (int64, error) {
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodPost, path, keyQuery(key), nil, &res); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(res.Value), 10, 64)
+}
+
+// do sends a request to the peer through its breaker and decodes the JSON response into out.
+// The operation ends at the deadline of ctx or after the policy timeout, whichever comes first,
+// and the time left is sent in TimeoutHeader. Retryable routes are tried again with backoff
+// while the peer is unavailable and the deadline allows. 404 responses are mapped to internal.ErrNotFound,
+// 409 responses to internal.ErrWrongType.
+func (ra *RemoteAdapter) do(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	return ra.doRetrying(ctx, method, path, query, body, out, retryable[path])
+}
+
+// doRetrying is do for a route that is only safe to send twice with some arguments, retry tells whether these are
+func (ra *RemoteAdapter) doRetrying(ctx context.Context, method, path string, query url.Values, body any, out any, retry bool) error {
+	opCtx, cancel := context.WithTimeout(ctx, ra.policy.Timeout)
+	defer cancel()
+
+	target := ra.baseURL + peerPathPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	attempts := 1
+	if retry {
+		attempts = ra.policy.MaxAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 && !ra.backoff(opCtx, attempt) {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if !ra.breaker.allow() {
+			return fmt.Errorf("%w: %w: remote %s %s", internal.ErrUnavailable, ErrCircuitOpen, method, ra.baseURL+path)
+		}
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		req, reqErr := http.NewRequestWithContext(opCtx, method, target, reader)
+		if reqErr != nil {
+			ra.breaker.release()
+			return reqErr
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if deadline, ok := opCtx.Deadline(); ok {
+			req.Header.Set(TimeoutHeader, strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+		}
+		err = ra.send(req, out)
+		if err != nil && ctx.Err() != nil {
+			ra.breaker.release()
+			break // the caller gave up, not the peer's fault
+		}
+		unavailable := errors.Is(err, internal.ErrUnavailable)
+		ra.breaker.record(unavailable)
+		if !unavailable {
+			return err
+		}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("remote %s %s: %w", method, path, ctxErr)
+	}
+	return err
+}
+
+// backoff waits before try number attempt, false if the operation deadline comes first
+func (ra *RemoteAdapter) backoff(ctx context.Context, attempt int) bool {
+	ceiling := ra.policy.MaxBackoff
+	if attempt < 32 { // keep the shift from overflowing
+		ceiling = min(ra.policy.BaseBackoff<<(attempt-1), ceiling)
+	}
+	wait := time.Duration(rand.Int63n(int64(ceiling) + 1))
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+		return false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// send executes req and decodes the JSON response into out.
+// Transport failures and gateway errors wrap internal.ErrUnavailable.
+func (ra *RemoteAdapter) send(req *http.Request, out any) error {
+	method, path := req.Method, req.URL.Path
+	resp, err := ra.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: remote %s %s: %v", internal.ErrUnavailable, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, resp.Body) // drain so the connection can be reused
+		return internal.ErrNotFound
+	}
+	if resp.StatusCode == http.StatusConflict {
+		io.Copy(io.Discard, resp.Body)
+		return internal.ErrWrongType
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errRes struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errRes)
+		if resp.StatusCode == http.StatusBadRequest {
+			// the peer's message already names the error, keep only its detail
+			return fmt.Errorf("%w: %s", internal.ErrBadRequest, strings.TrimPrefix(errRes.Error, internal.ErrBadRequest.Error()+": "))
+		}
+		if resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout {
+			return fmt.Errorf("%w: remote %s %s: status %d: %s", internal.ErrUnavailable, method, path, resp.StatusCode, errRes.Error)
+		}
+		return fmt.Errorf("remote %s %s: status %d: %s", method, path, resp.StatusCode, errRes.Error)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// strictQuery makes a peer answer 503 instead of a partial result when its own part fails
+var strictQuery = url.Values{"strict": []string{"true"}}
+
+func keyQuery(key string) url.Values {
+	return url.Values{"key": []string{key}}
+}
+
+func bitRangeQuery(key string, bitRange data.BitRange) url.Values {
+	query := keyQuery(key)
+	query.Set("start", strconv.FormatInt(bitRange.Start, 10))
+	if bitRange.End != nil {
+		query.Set("end", strconv.FormatInt(*bitRange.End, 10))
+	}
+	if bitRange.Bit {
+		query.Set("unit", "bit")
+	}
+	return query
+}
+
+func listValues(raw []json.RawMessage) [][]byte {
+	values := make([][]byte, len(raw))
+	for i, value := range raw {
+		values[i] = value
+	}
+	return values
+}
+
+func streamEntries(raw []dto.StreamEntry) ([]data.StreamEntry, error) {
+	entries := make([]data.StreamEntry, len(raw))
+	for i, entry := range raw {
+		id, err := data.ParseStreamID(entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = data.StreamEntry{ID: id}
+		if entry.Fields != nil {
+			entries[i].Fields = hashFields(entry.Fields)
+		}
+	}
+	return entries, nil
+}
+
+func hashFields(raw map[string]json.RawMessage) map[string][]byte {
+	fields := make(map[string][]byte, len(raw))
+	for field, value := range raw {
+		fields[field] = value
+	}
+	return fields
+}
+
+func ttlSeconds(expiration time.Duration) int64 {
+	if expiration < 0 {
+		return -1
+	}
+	return int64(expiration.Seconds())
+}
+
+func (ra *RemoteAdapter) GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error) {
+	if options.GT || options.LT {
+		return 0, fmt.Errorf("%w: geoadd takes nx and xx only", internal.ErrBadRequest)
+	}
+	req := dto.GeoAddRequest{Key: key, Members: members, NX: options.NX, XX: options.XX}
+	var res struct {
+		Added int `json:"added"`
+	}
+	if err := ra.do(ctx, http.MethodPost, "/geoadd", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Added, nil
+}
+
+func (ra *RemoteAdapter) GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error) {
+	query := keyQuery(key)
+	query["member"] = members
+	var res dto.GeoPositionsResponse
+	if err := ra.do(ctx, http.MethodGet, "/geopos", query, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Positions, nil
 }
 
-// NewRemoteAdapter creates a new instance of RemoteAdapter
-func NewRemoteAdapter() *RemoteAdapter {
-	return &RemoteAdapter{}
+func (ra *RemoteAdapter) GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error) {
+	query := keyQuery(key)
+	query.Set("from", from)
+	query.Set("to", to)
+	query.Set("unit", cmp.Or(unit, "m"))
+	var res dto.GeoDistanceResponse
+	if err := ra.do(ctx, http.MethodGet, "/geodist", query, nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return res.Distance, true, nil
 }
 
-func (ra *RemoteAdapter) SetItem(key string, value []byte, expiration time.Duration) error {
-	// Implementation for setting item in remote cache
-	return nil
+func (ra *RemoteAdapter) GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error) {
+	req := dto.GeoSearchRequest{Key: key, GeoQuery: query}
+	var res dto.GeoSearchResponse
+	if err := ra.do(ctx, http.MethodPost, "/geosearch", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return res.Results, nil
 }
 
-func (ra *RemoteAdapter) GetItem(key string) ([]byte, bool) {
-	// Implementation for getting item from remote cache
-	return nil, false
+func (ra *RemoteAdapter) JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error) {
+	query := keyQuery(key)
+	query.Set("path", cmp.Or(path, "$"))
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodGet, "/json/get", query, nil, &res); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return res.Value, true, nil
 }
 
-func (ra *RemoteAdapter) DeleteItem(key string) error {
-	// Implementation for deleting item from remote cache
-	return nil
+func (ra *RemoteAdapter) JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error) {
+	req := dto.JSONSetRequest{Key: key, Path: path, Value: value, JSONSetOptions: options}
+	var res struct {
+		Set bool `json:"set"`
+	}
+	// a conditional set may find its own first write when sent twice
+	retry := !options.NX && !options.XX
+	if err := ra.doRetrying(ctx, http.MethodPost, "/json/set", nil, req, &res, retry); err != nil {
+		return false, err
+	}
+	return res.Set, nil
 }
 
-func (ra *RemoteAdapter) ExistsItem(key string) bool {
-	// Implementation for checking existence of item in remote cache
-	return false
+func (ra *RemoteAdapter) JSONDel(ctx context.Context, key, path string) (int, error) {
+	query := keyQuery(key)
+	query.Set("path", cmp.Or(path, "$"))
+	var res struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := ra.do(ctx, http.MethodDelete, "/json/del", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Deleted, nil
 }
 
-func (ra *RemoteAdapter) ListKeys() []string {
-	// Implementation for listing keys in remote cache
-	return nil
+func (ra *RemoteAdapter) JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error) {
+	req := dto.JSONArrAppendRequest{Key: key, Path: path, Values: values}
+	var res dto.LengthResponse
+	if err := ra.do(ctx, http.MethodPost, "/json/arrappend", nil, req, &res); err != nil {
+		return 0, err
+	}
+	return res.Length, nil
 }
 
-func (ra *RemoteAdapter) ClearCache() error {
-	// Implementation for clearing remote cache
-	return nil
+func (ra *RemoteAdapter) JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error) {
+	req := dto.JSONNumIncrByRequest{Key: key, Path: path, Value: delta}
+	var res dto.ValueResponse
+	if err := ra.do(ctx, http.MethodPost, "/json/numincrby", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return res.Value, nil
 }
 
-func (ra *RemoteAdapter) GetTTL(key string) (time.Duration, bool) {
-	// Implementation for getting TTL of item in remote cache
-	return 0, false
+func (ra *RemoteAdapter) BloomReserve(ctx context.Context, key string, options data.BloomOptions) error {
+	req := dto.BloomReserveRequest{Key: key, BloomOptions: options}
+	return ra.do(ctx, http.MethodPost, "/bf/reserve", nil, req, nil)
 }
 
-func (ra *RemoteAdapter) UpdateExpiration(key string, expiration time.Duration) error {
-	// Implementation for updating expiration of item in remote cache
-	return nil
+func (ra *RemoteAdapter) BloomAdd(ctx context.Context, key string, items []string) ([]bool, error) {
+	req := dto.BloomMAddRequest{Key: key, Items: items}
+	var res dto.FilterAddedResponse
+	if err := ra.do(ctx, http.MethodPost, "/bf/madd", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return res.Added, nil
 }
 
-func (ra *RemoteAdapter) RemoveExpiration(key string) error {
-	// Implementation for removing expiration of item in remote cache
-	return nil
+func (ra *RemoteAdapter) BloomExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	return ra.filterExists(ctx, "/bf/mexists", key, items)
 }
 
-func (ra *RemoteAdapter) Increment(key string) (int64, error) {
-	// Implementation for incrementing item in remote cache
-	return 0, nil
+func (ra *RemoteAdapter) CuckooReserve(ctx context.Context, key string, options data.CuckooOptions) error {
+	req := dto.CuckooReserveRequest{Key: key, CuckooOptions: options}
+	return ra.do(ctx, http.MethodPost, "/cf/reserve", nil, req, nil)
 }
 
-func (ra *RemoteAdapter) Decrement(key string) (int64, error) {
-	// Implementation for decrementing item in remote cache
-	return 0, nil
+func (ra *RemoteAdapter) CuckooAdd(ctx context.Context, key, item string, nx bool) (bool, error) {
+	path := "/cf/add"
+	if nx {
+		path = "/cf/addnx"
+	}
+	req := dto.FilterAddRequest{Key: key, Item: item}
+	var res struct {
+		Added bool `json:"added"`
+	}
+	if err := ra.do(ctx, http.MethodPost, path, nil, req, &res); err != nil {
+		return false, err
+	}
+	return res.Added, nil
 }
 
-func (ra *RemoteAdapter) SetIfNotExists(key string, value []byte, expiration time.Duration) (bool, error) {
-	// Implementation for setting item if not exists in remote cache
-	return false, nil
+func (ra *RemoteAdapter) CuckooExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	return ra.filterExists(ctx, "/cf/mexists", key, items)
 }
 
-func (ra *RemoteAdapter) GetAndSet(key string, value []byte) ([]byte, error) {
-	// Implementation for getting and setting item in remote cache
-	return nil, nil
+func (ra *RemoteAdapter) CuckooCount(ctx context.Context, key, item string) (int, error) {
+	query := keyQuery(key)
+	query.Set("item", item)
+	var res struct {
+		Count int `json:"count"`
+	}
+	if err := ra.do(ctx, http.MethodGet, "/cf/count", query, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Count, nil
 }
 
-func (ra *RemoteAdapter) GetMultiple(keys []string) map[string][]byte {
-	// Implementation for getting multiple items from remote cache
-	return nil
+func (ra *RemoteAdapter) CuckooDelete(ctx context.Context, key, item string) (bool, error) {
+	query := keyQuery(key)
+	query.Set("item", item)
+	var res struct {
+		Deleted bool `json:"deleted"`
+	}
+	if err := ra.do(ctx, http.MethodDelete, "/cf/del", query, nil, &res); err != nil {
+		return false, err
+	}
+	return res.Deleted, nil
 }
 
-func (ra *RemoteAdapter) SetMultiple(kv map[string][]byte, expiration time.Duration) error {
-	// Implementation for setting multiple items in remote cache
-	return nil
+func (ra *RemoteAdapter) filterExists(ctx context.Context, path, key string, items []string) ([]bool, error) {
+	query := keyQuery(key)
+	query["item"] = items
+	var res dto.FilterExistsResponse
+	if err := ra.do(ctx, http.MethodGet, path, query, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Exists, nil
 }
@@ -0,0 +1,238 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+)
+
+// newFlakyPeer serves the peer routes, answering 503 while failing is set and counting every call
+func newFlakyPeer(t *testing.T, failing *atomic.Bool, calls *atomic.Int32) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"unavailable"}`))
+			return
+		}
+		w.Write([]byte(`{"value":"1","exists":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestRemoteAdapterRetriesOnlyRepeatableCalls(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	failing.Store(true)
+	remote := NewRemoteAdapter(newFlakyPeer(t, &failing, &calls), RemotePolicy{
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		BaseBackoff:      time.Millisecond,
+		FailureThreshold: 100,
+	})
+
+	if _, err := remote.ExistsItem(t.Context(), "k"); !errors.Is(err, internal.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected a read to be tried 3 times, got %d", got)
+	}
+
+	calls.Store(0)
+	if _, err := remote.Increment(t.Context(), "k"); !errors.Is(err, internal.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected incr to be sent once, got %d", got)
+	}
+
+	calls.Store(0)
+	remote.JSONSet(t.Context(), "k", "$.a", []byte("1"), data.JSONSetOptions{})
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected an unconditional json/set to be tried 3 times, got %d", got)
+	}
+	calls.Store(0)
+	remote.JSONSet(t.Context(), "k", "$.a", []byte("1"), data.JSONSetOptions{NX: true})
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected json/set with nx to be sent once, got %d", got)
+	}
+	calls.Store(0)
+	remote.JSONDel(t.Context(), "k", "$[0]")
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected json/del to be sent once, got %d", got)
+	}
+	calls.Store(0)
+	remote.SortedSetAdd(t.Context(), "k", []data.ScoredMember{{Member: "a", Score: 1}}, data.ZAddOptions{XX: true})
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected zadd with xx to be sent once, got %d", got)
+	}
+	calls.Store(0)
+	remote.HashSet(t.Context(), "k", map[string][]byte{"f": []byte("1")})
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected hset to be sent once, got %d", got)
+	}
+}
+
+func TestRemoteAdapterBreakerOpensAndRecovers(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	failing.Store(true)
+	remote := NewRemoteAdapter(newFlakyPeer(t, &failing, &calls), RemotePolicy{
+		Timeout:          time.Second,
+		MaxAttempts:      1,
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		HalfOpenProbes:   1,
+	})
+
+	for range 2 {
+		remote.ExistsItem(t.Context(), "k")
+	}
+	if state := remote.Breaker().State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to open after 2 failures, got %s", state)
+	}
+
+	calls.Store(0)
+	_, err := remote.ExistsItem(t.Context(), "k")
+	if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, internal.ErrUnavailable) {
+		t.Fatalf("expected an open circuit error, got %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Fatal("expected an open breaker to fail without calling the peer")
+	}
+	if remote.Breaker().Rejected == 0 {
+		t.Fatal("expected the rejected call to be counted")
+	}
+
+	// another adapter on the same peer shares the breaker
+	if state := NewRemoteAdapter(remote.Address(), RemotePolicy{}).Breaker().State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to be shared per peer, got %s", state)
+	}
+
+	// a failed trial call opens the breaker again
+	time.Sleep(60 * time.Millisecond)
+	remote.ExistsItem(t.Context(), "k")
+	if state := remote.Breaker().State; state != BreakerOpen {
+		t.Fatalf("expected a failed trial to open the breaker again, got %s", state)
+	}
+
+	failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if exists, err := remote.ExistsItem(t.Context(), "k"); err != nil || !exists {
+		t.Fatalf("expected the trial call to succeed, got %v %v", exists, err)
+	}
+	if status := remote.Breaker(); status.State != BreakerClosed || status.Failures != 0 {
+		t.Fatalf("expected the breaker to close after a successful trial, got %+v", status)
+	}
+}
+
+func TestRemoteAdapterRetriesStopAtDeadline(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	failing.Store(true)
+	remote := NewRemoteAdapter(newFlakyPeer(t, &failing, &calls), RemotePolicy{
+		Timeout:          100 * time.Millisecond,
+		MaxAttempts:      50,
+		BaseBackoff:      40 * time.Millisecond,
+		MaxBackoff:       40 * time.Millisecond,
+		FailureThreshold: 100,
+	})
+
+	start := time.Now()
+	if _, _, err := remote.GetItem(t.Context(), "k"); !errors.Is(err, internal.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("expected retries to stop at the operation deadline, took %s", elapsed)
+	}
+	if got := calls.Load(); got >= 50 {
+		t.Fatalf("expected the deadline to cut retries short, got %d calls", got)
+	}
+}
+
+func TestRemoteAdapterSendsDeadlineAndSparesBreakerOnCancel(t *testing.T) {
+	timeouts := make(chan int64, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		milliseconds, _ := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+		select {
+		case timeouts <- milliseconds:
+		default:
+		}
+		<-r.Context().Done() // answer only once the caller gave up
+	}))
+	t.Cleanup(server.Close)
+	remote := NewRemoteAdapter(server.URL, RemotePolicy{Timeout: time.Second, MaxAttempts: 1, FailureThreshold: 1})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	_, err := remote.ExistsItem(ctx, "k")
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, internal.ErrUnavailable) {
+		t.Fatalf("expected the caller's deadline error, got %v", err)
+	}
+	if milliseconds := <-timeouts; milliseconds <= 0 || milliseconds > 100 {
+		t.Fatalf("expected the peer to get the caller's remaining time, got %dms", milliseconds)
+	}
+	if state := remote.Breaker().State; state != BreakerClosed {
+		t.Fatalf("expected a caller timeout not to count against the peer, got %s", state)
+	}
+}
+
+func TestRemoteAdapterBreakerRecoversAfterCancelledTrial(t *testing.T) {
+	var failing, hanging atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hanging.Load() {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"unavailable"}`))
+			return
+		}
+		w.Write([]byte(`{"exists":true}`))
+	}))
+	t.Cleanup(server.Close)
+	remote := NewRemoteAdapter(server.URL, RemotePolicy{
+		Timeout:          time.Second,
+		MaxAttempts:      1,
+		FailureThreshold: 1,
+		OpenTimeout:      50 * time.Millisecond,
+		HalfOpenProbes:   1,
+	})
+
+	failing.Store(true)
+	remote.ExistsItem(t.Context(), "k")
+	if state := remote.Breaker().State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to open, got %s", state)
+	}
+
+	// the only trial call is cancelled by its caller while the peer is slow
+	time.Sleep(60 * time.Millisecond)
+	failing.Store(false)
+	hanging.Store(true)
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := remote.ExistsItem(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller's deadline error, got %v", err)
+	}
+
+	hanging.Store(false)
+	if exists, err := remote.ExistsItem(t.Context(), "k"); err != nil || !exists {
+		t.Fatalf("expected a new trial call once the cancelled one gave back its slot, got %v %v", exists, err)
+	}
+	if state := remote.Breaker().State; state != BreakerClosed {
+		t.Fatalf("expected the breaker to close after a successful trial, got %s", state)
+	}
+}
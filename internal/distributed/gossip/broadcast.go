@@ -0,0 +1,76 @@
+package gossip
+
+import (
+	"math"
+	"sync"
+)
+
+const retransmitMult = 4 // each update is piggybacked retransmitMult * log10(n+1) times
+
+type broadcast struct {
+	member    Member
+	transmits int // remaining piggyback count
+}
+
+// broadcastQueue holds the membership updates still to be piggybacked on outgoing messages
+type broadcastQueue struct {
+	mu    sync.Mutex
+	items map[string]*broadcast // member name to latest update
+}
+
+func newBroadcastQueue() *broadcastQueue {
+	return &broadcastQueue{
+		items: make(map[string]*broadcast),
+	}
+}
+
+// queue replaces any pending update about the same member
+func (q *broadcastQueue) queue(member Member, clusterSize int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items[member.Name] = &broadcast{
+		member:    member,
+		transmits: retransmitLimit(clusterSize),
+	}
+}
+
+// next returns up to limit updates, the least transmitted first
+func (q *broadcastQueue) next(limit int) []Member {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	updates := make([]Member, 0, min(limit, len(q.items)))
+	for len(updates) < limit {
+		var pick *broadcast
+		for _, item := range q.items {
+			if containsMember(updates, item.member.Name) {
+				continue
+			}
+			if pick == nil || item.transmits > pick.transmits {
+				pick = item
+			}
+		}
+		if pick == nil {
+			break
+		}
+		updates = append(updates, pick.member)
+		pick.transmits--
+		if pick.transmits <= 0 {
+			delete(q.items, pick.member.Name)
+		}
+	}
+	return updates
+}
+
+func retransmitLimit(clusterSize int) int {
+	return max(1, retransmitMult*int(math.Ceil(math.Log10(float64(clusterSize+1)))))
+}
+
+func containsMember(members []Member, name string) bool {
+	for _, member := range members {
+		if member.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package gossip
+
+import "time"
+
+type State string
+
+const (
+	StateAlive   State = "alive"
+	StateSuspect State = "suspect" // missed a probe, can still refute
+	StateDead    State = "dead"
+)
+
+type Member struct {
+	Name          string `json:"name"`
+	Address       string `json:"address"`        // HTTP address served by the member
+	GossipAddress string `json:"gossip_address"` // UDP address used for probes
+	State         State  `json:"state"`
+	Incarnation   uint64 `json:"incarnation"` // only the member itself increases it, to refute suspicion
+}
+
+// Delegate receives every state change of a remote member
+type Delegate interface {
+	NotifyChange(member Member)
+	NotifyAck(name string) // a probe of the member was answered
+}
+
+type memberState struct {
+	Member
+	stateChange time.Time
+}
+
+// overrides reports whether update carries newer information than the current state,
+// following the SWIM ordering: higher incarnation wins, and at the same incarnation
+// dead overrides suspect which overrides alive.
+func overrides(update Member, current Member) bool {
+	if update.Incarnation != current.Incarnation {
+		return update.Incarnation > current.Incarnation
+	}
+	return stateRank(update.State) > stateRank(current.State)
+}
+
+func stateRank(state State) int {
+	switch state {
+	case StateSuspect:
+		return 1
+	case StateDead:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,455 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxPiggyback  = 16        // membership updates attached to one message
+	maxPacketSize = 64 * 1024 // UDP datagram limit
+)
+
+type messageType string
+
+const (
+	pingMsg    messageType = "ping"
+	ackMsg     messageType = "ack"
+	pingReqMsg messageType = "ping-req" // ask another member to probe Target for us
+	joinMsg    messageType = "join"
+	syncMsg    messageType = "sync" // full member list, answer to join
+)
+
+type message struct {
+	Type    messageType `json:"type"`
+	Seq     uint64      `json:"seq,omitempty"`
+	From    string      `json:"from"`             // gossip address to answer to
+	Target  string      `json:"target,omitempty"` // ping-req target
+	Updates []Member    `json:"updates,omitempty"`
+}
+
+type Config struct {
+	Name             string
+	Address          string // HTTP address announced to other members
+	BindAddress      string // UDP address to listen on
+	AdvertiseAddress string // UDP address other members use, defaults to the bound address
+	Seeds            []string
+	ProbeInterval    time.Duration
+	ProbeTimeout     time.Duration
+	SuspicionTimeout time.Duration
+	IndirectChecks   int
+}
+
+// Node runs the SWIM failure detector and membership dissemination for one process
+type Node struct {
+	config     Config
+	delegate   Delegate
+	conn       *net.UDPConn
+	members    map[string]*memberState // member name to state, ourselves included
+	mu         sync.RWMutex
+	broadcasts *broadcastQueue
+	seq        atomic.Uint64
+	acks       map[uint64]func() // pending probe sequence numbers
+	ackMu      sync.Mutex
+	probeOrder []string
+	probeIndex int
+	leaving    atomic.Bool
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func NewNode(config Config, delegate Delegate) *Node {
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = time.Second
+	}
+	if config.ProbeTimeout <= 0 || config.ProbeTimeout >= config.ProbeInterval {
+		config.ProbeTimeout = config.ProbeInterval / 3
+	}
+	if config.SuspicionTimeout <= 0 {
+		config.SuspicionTimeout = 5 * config.ProbeInterval
+	}
+	if config.IndirectChecks <= 0 {
+		config.IndirectChecks = 3
+	}
+	return &Node{
+		config:     config,
+		delegate:   delegate,
+		members:    make(map[string]*memberState),
+		broadcasts: newBroadcastQueue(),
+		acks:       make(map[uint64]func()),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start binds the UDP socket, joins the seeds and probes members until ctx is done.
+// On shutdown the node announces that it is leaving.
+func (n *Node) Start(ctx context.Context) error {
+	bindAddr, err := net.ResolveUDPAddr("udp", n.config.BindAddress)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", bindAddr)
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+	if n.config.AdvertiseAddress == "" {
+		n.config.AdvertiseAddress = conn.LocalAddr().String()
+	}
+
+	n.mu.Lock()
+	n.members[n.config.Name] = &memberState{
+		Member: Member{
+			Name:          n.config.Name,
+			Address:       n.config.Address,
+			GossipAddress: n.config.AdvertiseAddress,
+			State:         StateAlive,
+			// start above any incarnation a previous run of this node may have used
+			Incarnation: uint64(time.Now().UnixMilli()),
+		},
+		stateChange: time.Now(),
+	}
+	n.mu.Unlock()
+
+	go n.readLoop()
+	go n.probeLoop(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+			n.leave()
+			n.shutdown()
+		case <-n.done:
+		}
+	}()
+	n.join()
+	return nil
+}
+
+func (n *Node) GossipAddress() string {
+	return n.config.AdvertiseAddress
+}
+
+// Members returns every known member, ourselves included
+func (n *Node) Members() []Member {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	members := make([]Member, 0, len(n.members))
+	for _, member := range n.members {
+		members = append(members, member.Member)
+	}
+	slices.SortFunc(members, func(a, b Member) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return members
+}
+
+func (n *Node) shutdown() {
+	n.closeOnce.Do(func() {
+		close(n.done)
+		n.conn.Close()
+	})
+}
+
+// leave tells the other members that we are going away on purpose
+func (n *Node) leave() {
+	n.leaving.Store(true)
+	n.mu.Lock()
+	self := n.members[n.config.Name]
+	self.State = StateDead
+	leaveUpdate := self.Member
+	n.mu.Unlock()
+
+	for _, member := range n.aliveMembers("") {
+		n.send(member.GossipAddress, message{Type: pingMsg, Seq: n.seq.Add(1), Updates: []Member{leaveUpdate}})
+	}
+}
+
+func (n *Node) join() {
+	self := n.self()
+	for _, seed := range n.config.Seeds {
+		if seed == "" || seed == n.config.AdvertiseAddress {
+			continue
+		}
+		n.send(seed, message{Type: joinMsg, Updates: []Member{self}})
+	}
+}
+
+func (n *Node) readLoop() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		size, _, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-n.done:
+				return
+			default:
+				log.Printf("Gossip read error: %v", err)
+				continue
+			}
+		}
+		var msg message
+		if err := json.Unmarshal(buf[:size], &msg); err != nil {
+			log.Printf("Gossip message decode error: %v", err)
+			continue
+		}
+		n.handle(msg)
+	}
+}
+
+func (n *Node) handle(msg message) {
+	for _, update := range msg.Updates {
+		n.applyUpdate(update)
+	}
+	switch msg.Type {
+	case pingMsg:
+		n.send(msg.From, message{Type: ackMsg, Seq: msg.Seq})
+	case ackMsg:
+		n.resolveAck(msg.Seq)
+	case pingReqMsg:
+		// probe the target on behalf of the sender and relay its ack
+		seq := n.seq.Add(1)
+		n.registerAck(seq, func() {
+			n.send(msg.From, message{Type: ackMsg, Seq: msg.Seq})
+		})
+		time.AfterFunc(n.config.ProbeInterval, func() { n.cancelAck(seq) })
+		n.send(msg.Target, message{Type: pingMsg, Seq: seq})
+	case joinMsg:
+		n.send(msg.From, message{Type: syncMsg, Updates: n.Members()})
+	}
+}
+
+func (n *Node) probeLoop(ctx context.Context) {
+	ticker := time.NewTicker(n.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.done:
+			return
+		case <-ticker.C:
+			n.reapSuspects()
+			target, ok := n.nextProbeTarget()
+			if !ok {
+				n.join() // alone, keep trying the seeds
+				continue
+			}
+			n.probe(ctx, target)
+		}
+	}
+}
+
+// probe pings target directly, then through IndirectChecks other members,
+// and suspects it when no ack arrives within the protocol period.
+func (n *Node) probe(ctx context.Context, target Member) {
+	seq := n.seq.Add(1)
+	acked := make(chan struct{}, 1)
+	n.registerAck(seq, func() {
+		select {
+		case acked <- struct{}{}:
+		default:
+		}
+	})
+	defer n.cancelAck(seq)
+
+	n.send(target.GossipAddress, message{Type: pingMsg, Seq: seq})
+	select {
+	case <-acked:
+		n.notifyAck(target.Name)
+		return
+	case <-ctx.Done():
+		return
+	case <-time.After(n.config.ProbeTimeout):
+	}
+
+	relays := n.aliveMembers(target.Name)
+	rand.Shuffle(len(relays), func(i, j int) { relays[i], relays[j] = relays[j], relays[i] })
+	for i := 0; i < len(relays) && i < n.config.IndirectChecks; i++ {
+		n.send(relays[i].GossipAddress, message{Type: pingReqMsg, Seq: seq, Target: target.GossipAddress})
+	}
+	wait := max(n.config.ProbeInterval-n.config.ProbeTimeout, n.config.ProbeTimeout)
+	select {
+	case <-acked:
+		n.notifyAck(target.Name)
+		return
+	case <-ctx.Done():
+		return
+	case <-time.After(wait):
+	}
+
+	target.State = StateSuspect
+	n.applyUpdate(target)
+}
+
+func (n *Node) notifyAck(name string) {
+	if n.delegate != nil {
+		n.delegate.NotifyAck(name)
+	}
+}
+
+// reapSuspects declares dead the members that did not refute their suspicion in time
+func (n *Node) reapSuspects() {
+	n.mu.RLock()
+	var expired []Member
+	for _, member := range n.members {
+		if member.State == StateSuspect && time.Since(member.stateChange) > n.config.SuspicionTimeout {
+			dead := member.Member
+			dead.State = StateDead
+			expired = append(expired, dead)
+		}
+	}
+	n.mu.RUnlock()
+	for _, member := range expired {
+		n.applyUpdate(member)
+	}
+}
+
+// applyUpdate merges one membership update and reports changes to the delegate
+func (n *Node) applyUpdate(update Member) {
+	now := time.Now()
+	n.mu.Lock()
+	if update.Name == n.config.Name {
+		self := n.members[n.config.Name]
+		if update.State == StateAlive || update.Incarnation < self.Incarnation || n.leaving.Load() {
+			n.mu.Unlock()
+			return
+		}
+		// someone suspects us, refute with a higher incarnation
+		self.Incarnation = update.Incarnation + 1
+		self.stateChange = now
+		refute := self.Member
+		n.mu.Unlock()
+		n.broadcasts.queue(refute, n.size())
+		return
+	}
+
+	current, exists := n.members[update.Name]
+	if exists && !overrides(update, current.Member) {
+		n.mu.Unlock()
+		return
+	}
+	changed := !exists || current.State != update.State || current.Address != update.Address
+	n.members[update.Name] = &memberState{Member: update, stateChange: now}
+	n.mu.Unlock()
+
+	n.broadcasts.queue(update, n.size())
+	if !exists && update.State == StateDead {
+		return // never announced, nothing to retract
+	}
+	if changed && n.delegate != nil {
+		n.delegate.NotifyChange(update)
+	}
+}
+
+func (n *Node) nextProbeTarget() (Member, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		for n.probeIndex < len(n.probeOrder) {
+			name := n.probeOrder[n.probeIndex]
+			n.probeIndex++
+			if member, ok := n.members[name]; ok && member.State != StateDead {
+				return member.Member, true
+			}
+		}
+		// round finished, probe every live member again in a new random order
+		n.probeOrder = n.probeOrder[:0]
+		for name, member := range n.members {
+			if name != n.config.Name && member.State != StateDead {
+				n.probeOrder = append(n.probeOrder, name)
+			}
+		}
+		rand.Shuffle(len(n.probeOrder), func(i, j int) {
+			n.probeOrder[i], n.probeOrder[j] = n.probeOrder[j], n.probeOrder[i]
+		})
+		n.probeIndex = 0
+	}
+	return Member{}, false
+}
+
+// aliveMembers returns alive members other than ourselves and exclude
+func (n *Node) aliveMembers(exclude string) []Member {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	members := make([]Member, 0, len(n.members))
+	for name, member := range n.members {
+		if name == n.config.Name || name == exclude || member.State != StateAlive {
+			continue
+		}
+		members = append(members, member.Member)
+	}
+	return members
+}
+
+func (n *Node) self() Member {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.members[n.config.Name].Member
+}
+
+// size returns the number of members that are not dead
+func (n *Node) size() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	size := 0
+	for _, member := range n.members {
+		if member.State != StateDead {
+			size++
+		}
+	}
+	return size
+}
+
+func (n *Node) send(addr string, msg message) {
+	if addr == "" {
+		return
+	}
+	msg.From = n.config.AdvertiseAddress
+	if msg.Updates == nil {
+		msg.Updates = n.broadcasts.next(maxPiggyback)
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Gossip message encode error: %v", err)
+		return
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		log.Printf("Gossip address %s: %v", addr, err)
+		return
+	}
+	if _, err := n.conn.WriteToUDP(payload, udpAddr); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("Gossip send to %s: %v", addr, err)
+	}
+}
+
+func (n *Node) registerAck(seq uint64, onAck func()) {
+	n.ackMu.Lock()
+	defer n.ackMu.Unlock()
+	n.acks[seq] = onAck
+}
+
+func (n *Node) resolveAck(seq uint64) {
+	n.ackMu.Lock()
+	onAck, ok := n.acks[seq]
+	delete(n.acks, seq)
+	n.ackMu.Unlock()
+	if ok {
+		onAck()
+	}
+}
+
+func (n *Node) cancelAck(seq uint64) {
+	n.ackMu.Lock()
+	defer n.ackMu.Unlock()
+	delete(n.acks, seq)
+}
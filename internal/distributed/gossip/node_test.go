@@ -0,0 +1,150 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingDelegate struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func (d *recordingDelegate) NotifyChange(member Member) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.states[member.Name] = member.State
+}
+
+func (d *recordingDelegate) NotifyAck(name string) {}
+
+func (d *recordingDelegate) state(name string) State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.states[name]
+}
+
+type testNode struct {
+	node     *Node
+	delegate *recordingDelegate
+	cancel   context.CancelFunc
+}
+
+// startTestNodes runs count nodes on loopback ports, every node seeded with the first one
+func startTestNodes(t *testing.T, count int) []*testNode {
+	t.Helper()
+	nodes := make([]*testNode, 0, count)
+	var seeds []string
+	for i := 0; i < count; i++ {
+		delegate := &recordingDelegate{states: make(map[string]State)}
+		node := NewNode(Config{
+			Name:             fmt.Sprintf("node-%d", i),
+			Address:          fmt.Sprintf("127.0.0.1:%d", 8080+i),
+			BindAddress:      "127.0.0.1:0",
+			Seeds:            seeds,
+			ProbeInterval:    50 * time.Millisecond,
+			ProbeTimeout:     15 * time.Millisecond,
+			SuspicionTimeout: 150 * time.Millisecond,
+		}, delegate)
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := node.Start(ctx); err != nil {
+			cancel()
+			t.Fatalf("Start returned error: %v", err)
+		}
+		t.Cleanup(cancel)
+		if i == 0 {
+			seeds = []string{node.GossipAddress()}
+		}
+		nodes = append(nodes, &testNode{node: node, delegate: delegate, cancel: cancel})
+	}
+	return nodes
+}
+
+func eventually(t *testing.T, message string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(message)
+}
+
+func aliveCount(node *Node) int {
+	count := 0
+	for _, member := range node.Members() {
+		if member.State == StateAlive {
+			count++
+		}
+	}
+	return count
+}
+
+func TestGossipMembersConverge(t *testing.T) {
+	nodes := startTestNodes(t, 4)
+
+	for _, n := range nodes {
+		eventually(t, "members did not converge", func() bool {
+			return aliveCount(n.node) == len(nodes)
+		})
+	}
+	// node-3 only knows node-0 as a seed, it learns node-1 and node-2 through gossip
+	for _, name := range []string{"node-0", "node-1", "node-2"} {
+		if state := nodes[3].delegate.state(name); state != StateAlive {
+			t.Fatalf("expected delegate to see %s alive, got %q", name, state)
+		}
+	}
+}
+
+func TestGossipDetectsFailedMember(t *testing.T) {
+	nodes := startTestNodes(t, 3)
+	for _, n := range nodes {
+		eventually(t, "members did not converge", func() bool {
+			return aliveCount(n.node) == len(nodes)
+		})
+	}
+
+	// crash without announcing a leave
+	nodes[2].node.shutdown()
+
+	for _, n := range nodes[:2] {
+		eventually(t, "failed member was not declared dead", func() bool {
+			return n.delegate.state("node-2") == StateDead
+		})
+	}
+}
+
+func TestGossipLeaveAndRejoin(t *testing.T) {
+	nodes := startTestNodes(t, 3)
+	for _, n := range nodes {
+		eventually(t, "members did not converge", func() bool {
+			return aliveCount(n.node) == len(nodes)
+		})
+	}
+
+	nodes[1].cancel()
+	eventually(t, "leaving member was not removed", func() bool {
+		return nodes[0].delegate.state("node-1") == StateDead && nodes[2].delegate.state("node-1") == StateDead
+	})
+
+	// a restarted process comes back with a higher incarnation
+	restarted := NewNode(Config{
+		Name:          "node-1",
+		BindAddress:   "127.0.0.1:0",
+		Seeds:         []string{nodes[0].node.GossipAddress()},
+		ProbeInterval: 50 * time.Millisecond,
+	}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := restarted.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	eventually(t, "restarted member did not rejoin", func() bool {
+		return nodes[0].delegate.state("node-1") == StateAlive && nodes[2].delegate.state("node-1") == StateAlive
+	})
+}
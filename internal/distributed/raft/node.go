@@ -0,0 +1,504 @@
+package raft
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type Role string
+
+const (
+	Follower  Role = "follower"
+	Candidate Role = "candidate"
+	Leader    Role = "leader"
+)
+
+type Config struct {
+	ID                string
+	Peers             []string      // ids of the other voters
+	ElectionTimeout   time.Duration // followers wait between this and twice this without a leader before standing
+	HeartbeatInterval time.Duration
+	ProposeTimeout    time.Duration // a proposal not committed in time fails with ErrNoQuorum
+	StatePath         string        // file keeping term, vote and log across restarts, memory only when empty
+}
+
+type Status struct {
+	ID          string `json:"id"`
+	Role        Role   `json:"role"`
+	Leader      string `json:"leader"`
+	Term        uint64 `json:"term"`
+	LastIndex   uint64 `json:"last_index"`
+	CommitIndex uint64 `json:"commit_index"`
+	LastApplied uint64 `json:"last_applied"`
+	Voters      int    `json:"voters"`
+}
+
+type waiter struct {
+	term uint64
+	done chan error
+}
+
+// Node is one voter of a Raft group: leader election, log replication and commit.
+// The log is small (cluster metadata) so it is kept whole, without snapshots.
+type Node struct {
+	config           Config
+	transport        Transport
+	fsm              StateMachine
+	mu               sync.Mutex
+	role             Role
+	term             uint64
+	votedFor         string
+	log              []Entry // log[0] is a sentinel, so log[i].Index == i
+	commitIndex      uint64
+	lastApplied      uint64
+	leader           string
+	nextIndex        map[string]uint64    // leader: next entry to send to each peer
+	matchIndex       map[string]uint64    // leader: highest entry known replicated on each peer
+	lastContact      map[string]time.Time // leader: last answer of each peer
+	electionDeadline time.Time
+	waiters          map[uint64]waiter // log index to pending proposal
+	applyNotify      chan struct{}
+	replicateNotify  chan struct{}
+}
+
+func NewNode(config Config, transport Transport, fsm StateMachine) (*Node, error) {
+	if config.ElectionTimeout <= 0 {
+		config.ElectionTimeout = time.Second
+	}
+	if config.HeartbeatInterval <= 0 || config.HeartbeatInterval >= config.ElectionTimeout {
+		config.HeartbeatInterval = config.ElectionTimeout / 5
+	}
+	if config.ProposeTimeout <= 0 {
+		config.ProposeTimeout = 3 * config.ElectionTimeout
+	}
+	state, err := loadState(config.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	node := &Node{
+		config:          config,
+		transport:       transport,
+		fsm:             fsm,
+		role:            Follower,
+		term:            state.Term,
+		votedFor:        state.VotedFor,
+		log:             append([]Entry{{}}, state.Log...),
+		waiters:         make(map[uint64]waiter),
+		applyNotify:     make(chan struct{}, 1),
+		replicateNotify: make(chan struct{}, 1),
+	}
+	node.resetElectionDeadline()
+	return node, nil
+}
+
+// Start runs the election timer, heartbeats and the apply loop until ctx is done
+func (n *Node) Start(ctx context.Context) {
+	go n.run(ctx)
+	go n.applyLoop(ctx)
+}
+
+func (n *Node) run(ctx context.Context) {
+	ticker := time.NewTicker(n.config.HeartbeatInterval / 2)
+	defer ticker.Stop()
+	var lastHeartbeat time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.replicateNotify:
+			n.broadcast()
+			lastHeartbeat = time.Now()
+		case now := <-ticker.C:
+			n.mu.Lock()
+			role := n.role
+			if role == Leader && !n.hasQuorumContactLocked(now) {
+				log.Printf("Raft leader %s lost contact with a quorum, stepping down", n.config.ID)
+				n.becomeFollowerLocked(n.term, "")
+				role = Follower
+			}
+			electionDue := role != Leader && now.After(n.electionDeadline)
+			n.mu.Unlock()
+
+			switch {
+			case role == Leader && now.Sub(lastHeartbeat) >= n.config.HeartbeatInterval:
+				n.broadcast()
+				lastHeartbeat = now
+			case electionDue:
+				n.startElection()
+			}
+		}
+	}
+}
+
+// Propose appends command to the log and returns once it is committed and applied.
+// A follower forwards it to the leader; without a leader or quorum it fails with ErrNoQuorum.
+func (n *Node) Propose(ctx context.Context, command []byte) error {
+	n.mu.Lock()
+	if n.role != Leader {
+		leader := n.leader
+		n.mu.Unlock()
+		if leader == "" {
+			return ErrNoQuorum
+		}
+		return n.transport.Propose(leader, command)
+	}
+	entry := Entry{Index: n.lastIndexLocked() + 1, Term: n.term, Command: command}
+	n.log = append(n.log, entry)
+	if err := n.persistLocked(); err != nil {
+		n.log = n.log[:entry.Index]
+		n.mu.Unlock()
+		return err
+	}
+	done := make(chan error, 1)
+	n.waiters[entry.Index] = waiter{term: entry.Term, done: done}
+	n.advanceCommitLocked()
+	n.mu.Unlock()
+	n.triggerReplicate()
+
+	timer := time.NewTimer(n.config.ProposeTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		n.dropWaiter(entry.Index)
+		return ctx.Err()
+	case <-timer.C:
+		n.dropWaiter(entry.Index)
+		return ErrNoQuorum
+	}
+}
+
+// IsLeader reports whether this node currently leads the group
+func (n *Node) IsLeader() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.role == Leader
+}
+
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return Status{
+		ID:          n.config.ID,
+		Role:        n.role,
+		Leader:      n.leader,
+		Term:        n.term,
+		LastIndex:   n.lastIndexLocked(),
+		CommitIndex: n.commitIndex,
+		LastApplied: n.lastApplied,
+		Voters:      len(n.config.Peers) + 1,
+	}
+}
+
+// HandleRequestVote answers a candidate
+func (n *Node) HandleRequestVote(req VoteRequest) VoteResponse {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if req.Term > n.term {
+		n.becomeFollowerLocked(req.Term, "")
+	}
+	lastIndex := n.lastIndexLocked()
+	lastTerm := n.log[lastIndex].Term
+	upToDate := req.LastLogTerm > lastTerm || (req.LastLogTerm == lastTerm && req.LastLogIndex >= lastIndex)
+	granted := req.Term == n.term && (n.votedFor == "" || n.votedFor == req.Candidate) && upToDate
+	if granted {
+		votedFor := n.votedFor
+		n.votedFor = req.Candidate
+		if err := n.persistLocked(); err != nil {
+			// a vote not on disk could be cast again for another candidate after a restart
+			n.votedFor = votedFor
+			return VoteResponse{Term: n.term}
+		}
+		n.resetElectionDeadline()
+	}
+	return VoteResponse{Term: n.term, Granted: granted}
+}
+
+// HandleAppendEntries accepts entries and heartbeats from the leader
+func (n *Node) HandleAppendEntries(req AppendRequest) AppendResponse {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if req.Term < n.term {
+		return AppendResponse{Term: n.term, LastIndex: n.lastIndexLocked()}
+	}
+	if req.Term > n.term || n.role != Follower {
+		n.becomeFollowerLocked(req.Term, req.Leader)
+	}
+	n.leader = req.Leader
+	n.resetElectionDeadline()
+
+	lastIndex := n.lastIndexLocked()
+	if req.PrevLogIndex > lastIndex {
+		return AppendResponse{Term: n.term, LastIndex: lastIndex}
+	}
+	if n.log[req.PrevLogIndex].Term != req.PrevLogTerm {
+		// mismatch at PrevLogIndex, the leader resends from the entry before it
+		return AppendResponse{Term: n.term, LastIndex: req.PrevLogIndex - 1}
+	}
+	previous, changed := n.log, false
+	for _, entry := range req.Entries {
+		if entry.Index <= n.lastIndexLocked() {
+			if n.log[entry.Index].Term == entry.Term {
+				continue
+			}
+			// conflicting suffix, never committed, cut without its room so previous keeps it until saved
+			n.log = n.log[:entry.Index:entry.Index]
+		}
+		n.log = append(n.log, entry)
+		changed = true
+	}
+	if changed {
+		if err := n.persistLocked(); err != nil {
+			// entries not on disk must not count towards the leader's majority
+			n.log = previous
+			return AppendResponse{Term: n.term, LastIndex: n.lastIndexLocked()}
+		}
+	}
+	// only entries known to match the leader's log can be committed
+	if commit := min(req.LeaderCommit, req.PrevLogIndex+uint64(len(req.Entries))); commit > n.commitIndex {
+		n.commitIndex = commit
+		n.notifyApply()
+	}
+	return AppendResponse{Term: n.term, Success: true, LastIndex: n.lastIndexLocked()}
+}
+
+func (n *Node) startElection() {
+	n.mu.Lock()
+	n.role = Candidate
+	n.term++
+	n.votedFor = n.config.ID
+	n.leader = ""
+	n.resetElectionDeadline()
+	if err := n.persistLocked(); err != nil {
+		// without its own vote on disk the node could vote twice in this term after a restart
+		n.role = Follower
+		n.mu.Unlock()
+		return
+	}
+	lastIndex := n.lastIndexLocked()
+	req := VoteRequest{Term: n.term, Candidate: n.config.ID, LastLogIndex: lastIndex, LastLogTerm: n.log[lastIndex].Term}
+	votes := 1
+	if votes >= n.majority() {
+		n.becomeLeaderLocked()
+	}
+	n.mu.Unlock()
+
+	for _, peer := range n.config.Peers {
+		go func(peer string) {
+			resp, err := n.transport.RequestVote(peer, req)
+			if err != nil {
+				return
+			}
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			if resp.Term > n.term {
+				n.becomeFollowerLocked(resp.Term, "")
+				return
+			}
+			if n.role != Candidate || n.term != req.Term || !resp.Granted {
+				return
+			}
+			votes++
+			if votes >= n.majority() {
+				n.becomeLeaderLocked()
+			}
+		}(peer)
+	}
+}
+
+func (n *Node) broadcast() {
+	for _, peer := range n.config.Peers {
+		go n.replicateTo(peer)
+	}
+}
+
+// replicateTo sends the entries peer is missing, or a heartbeat when it has them all
+func (n *Node) replicateTo(peer string) {
+	n.mu.Lock()
+	if n.role != Leader {
+		n.mu.Unlock()
+		return
+	}
+	prevIndex := n.nextIndex[peer] - 1
+	req := AppendRequest{
+		Term:         n.term,
+		Leader:       n.config.ID,
+		PrevLogIndex: prevIndex,
+		PrevLogTerm:  n.log[prevIndex].Term,
+		Entries:      append([]Entry(nil), n.log[prevIndex+1:]...),
+		LeaderCommit: n.commitIndex,
+	}
+	n.mu.Unlock()
+
+	resp, err := n.transport.AppendEntries(peer, req)
+	if err != nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if resp.Term > n.term {
+		n.becomeFollowerLocked(resp.Term, "")
+		return
+	}
+	if n.role != Leader || n.term != req.Term {
+		return
+	}
+	n.lastContact[peer] = time.Now()
+	if resp.Success {
+		match := req.PrevLogIndex + uint64(len(req.Entries))
+		n.matchIndex[peer] = max(n.matchIndex[peer], match)
+		n.nextIndex[peer] = n.matchIndex[peer] + 1
+		n.advanceCommitLocked()
+		return
+	}
+	n.nextIndex[peer] = max(1, min(n.nextIndex[peer]-1, resp.LastIndex+1))
+}
+
+// advanceCommitLocked commits the highest entry of the current term stored on a majority, n.mu must be held
+func (n *Node) advanceCommitLocked() {
+	if n.role != Leader {
+		return
+	}
+	for index := n.lastIndexLocked(); index > n.commitIndex; index-- {
+		if n.log[index].Term != n.term {
+			return // entries of older terms only commit under a newer one
+		}
+		count := 1
+		for _, peer := range n.config.Peers {
+			if n.matchIndex[peer] >= index {
+				count++
+			}
+		}
+		if count >= n.majority() {
+			n.commitIndex = index
+			n.notifyApply()
+			return
+		}
+	}
+}
+
+func (n *Node) applyLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.applyNotify:
+		}
+		n.mu.Lock()
+		entries := append([]Entry(nil), n.log[n.lastApplied+1:n.commitIndex+1]...)
+		n.mu.Unlock()
+
+		for _, entry := range entries {
+			if len(entry.Command) > 0 {
+				n.fsm.Apply(entry)
+			}
+			n.mu.Lock()
+			n.lastApplied = entry.Index
+			if w, ok := n.waiters[entry.Index]; ok {
+				delete(n.waiters, entry.Index)
+				if w.term == entry.Term {
+					w.done <- nil
+				} else {
+					w.done <- ErrNotLeader // another leader replaced the proposal
+				}
+			}
+			n.mu.Unlock()
+		}
+	}
+}
+
+// becomeLeaderLocked takes over the group and appends a no-op to commit the entries of earlier terms, n.mu must be held
+func (n *Node) becomeLeaderLocked() {
+	log.Printf("Raft node %s is leader for term %d", n.config.ID, n.term)
+	n.role = Leader
+	n.leader = n.config.ID
+	n.nextIndex = make(map[string]uint64, len(n.config.Peers))
+	n.matchIndex = make(map[string]uint64, len(n.config.Peers))
+	n.lastContact = make(map[string]time.Time, len(n.config.Peers))
+	now := time.Now()
+	for _, peer := range n.config.Peers {
+		n.nextIndex[peer] = n.lastIndexLocked() + 1
+		n.lastContact[peer] = now
+	}
+	n.log = append(n.log, Entry{Index: n.lastIndexLocked() + 1, Term: n.term})
+	if err := n.persistLocked(); err != nil {
+		// the leader counts its own log in every majority, so it cannot lead with the no-op only in memory
+		n.log = n.log[:n.lastIndexLocked()]
+		n.becomeFollowerLocked(n.term, "")
+		return
+	}
+	n.advanceCommitLocked()
+	n.triggerReplicate()
+}
+
+// becomeFollowerLocked moves to term and follows leader (empty when unknown), n.mu must be held
+func (n *Node) becomeFollowerLocked(term uint64, leader string) {
+	if term > n.term {
+		n.term = term
+		n.votedFor = ""
+		// a lost term only makes the node learn it again, it cast no vote in it yet
+		_ = n.persistLocked()
+	}
+	n.role = Follower
+	n.leader = leader
+	n.resetElectionDeadline()
+}
+
+// hasQuorumContactLocked reports whether a majority answered the leader within an election timeout, n.mu must be held
+func (n *Node) hasQuorumContactLocked(now time.Time) bool {
+	count := 1
+	for _, peer := range n.config.Peers {
+		if now.Sub(n.lastContact[peer]) < n.config.ElectionTimeout {
+			count++
+		}
+	}
+	return count >= n.majority()
+}
+
+func (n *Node) majority() int {
+	return (len(n.config.Peers)+1)/2 + 1
+}
+
+func (n *Node) lastIndexLocked() uint64 {
+	return uint64(len(n.log) - 1)
+}
+
+func (n *Node) resetElectionDeadline() {
+	timeout := n.config.ElectionTimeout + time.Duration(rand.Int63n(int64(n.config.ElectionTimeout)))
+	n.electionDeadline = time.Now().Add(timeout)
+}
+
+// persistLocked saves the term, the vote and the log, n.mu must be held. A caller undoes what it
+// changed when this fails, or it could promise something a restart forgets.
+func (n *Node) persistLocked() error {
+	state := persistentState{Term: n.term, VotedFor: n.votedFor, Log: n.log[1:]}
+	err := saveState(n.config.StatePath, state)
+	if err != nil {
+		log.Printf("Error saving raft state: %v", err)
+	}
+	return err
+}
+
+func (n *Node) dropWaiter(index uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.waiters, index)
+}
+
+func (n *Node) notifyApply() {
+	select {
+	case n.applyNotify <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Node) triggerReplicate() {
+	select {
+	case n.replicateNotify <- struct{}{}:
+	default:
+	}
+}
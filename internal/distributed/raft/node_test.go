@@ -0,0 +1,274 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMachine struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (m *recordingMachine) Apply(entry Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands = append(m.commands, string(entry.Command))
+}
+
+func (m *recordingMachine) applied() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.commands)
+}
+
+// memTransport connects in-process nodes, a node in down neither sends nor receives
+type memTransport struct {
+	mu    sync.Mutex
+	nodes map[string]*Node
+	down  map[string]bool
+}
+
+func (tr *memTransport) peer(from, to string) (*Node, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.down[from] || tr.down[to] {
+		return nil, errors.New("unreachable")
+	}
+	return tr.nodes[to], nil
+}
+
+func (tr *memTransport) setDown(id string, down bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.down[id] = down
+}
+
+type nodeTransport struct {
+	*memTransport
+	from string
+}
+
+func (tr nodeTransport) RequestVote(peer string, req VoteRequest) (VoteResponse, error) {
+	node, err := tr.peer(tr.from, peer)
+	if err != nil {
+		return VoteResponse{}, err
+	}
+	return node.HandleRequestVote(req), nil
+}
+
+func (tr nodeTransport) AppendEntries(peer string, req AppendRequest) (AppendResponse, error) {
+	node, err := tr.peer(tr.from, peer)
+	if err != nil {
+		return AppendResponse{}, err
+	}
+	return node.HandleAppendEntries(req), nil
+}
+
+func (tr nodeTransport) Propose(peer string, command []byte) error {
+	node, err := tr.peer(tr.from, peer)
+	if err != nil {
+		return ErrNoQuorum
+	}
+	return node.Propose(context.Background(), command)
+}
+
+type testGroup struct {
+	transport *memTransport
+	nodes     []*Node
+	machines  []*recordingMachine
+}
+
+func startTestGroup(t *testing.T, size int, stateDir string) *testGroup {
+	t.Helper()
+	group := &testGroup{transport: &memTransport{nodes: make(map[string]*Node), down: make(map[string]bool)}}
+	ids := make([]string, size)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("node-%d", i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	for _, id := range ids {
+		statePath := ""
+		if stateDir != "" {
+			statePath = filepath.Join(stateDir, id+".json")
+		}
+		machine := &recordingMachine{}
+		node, err := NewNode(Config{
+			ID:                id,
+			Peers:             slices.DeleteFunc(slices.Clone(ids), func(peer string) bool { return peer == id }),
+			ElectionTimeout:   50 * time.Millisecond,
+			HeartbeatInterval: 10 * time.Millisecond,
+			ProposeTimeout:    300 * time.Millisecond,
+			StatePath:         statePath,
+		}, nodeTransport{memTransport: group.transport, from: id}, machine)
+		if err != nil {
+			t.Fatalf("NewNode returned error: %v", err)
+		}
+		group.transport.nodes[id] = node
+		group.nodes = append(group.nodes, node)
+		group.machines = append(group.machines, machine)
+	}
+	for _, node := range group.nodes {
+		node.Start(ctx)
+	}
+	return group
+}
+
+// leader waits for a single leader among the nodes that are up
+func (g *testGroup) leader(t *testing.T) *Node {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		var leaders []*Node
+		for _, node := range g.nodes {
+			if !g.transport.down[node.config.ID] && node.IsLeader() {
+				leaders = append(leaders, node)
+			}
+		}
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no single leader was elected")
+	return nil
+}
+
+func eventually(t *testing.T, message string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(message)
+}
+
+func TestRaftReplicatesCommittedCommands(t *testing.T) {
+	group := startTestGroup(t, 3, "")
+	leader := group.leader(t)
+
+	for _, command := range []string{"a", "b", "c"} {
+		if err := leader.Propose(context.Background(), []byte(command)); err != nil {
+			t.Fatalf("Propose returned error: %v", err)
+		}
+	}
+	for i, machine := range group.machines {
+		eventually(t, fmt.Sprintf("node-%d did not apply the commands", i), func() bool {
+			return slices.Equal(machine.applied(), []string{"a", "b", "c"})
+		})
+	}
+}
+
+func TestRaftFollowerForwardsProposals(t *testing.T) {
+	group := startTestGroup(t, 3, "")
+	leader := group.leader(t)
+	var follower *Node
+	for _, node := range group.nodes {
+		if node != leader {
+			follower = node
+			break
+		}
+	}
+	eventually(t, "follower did not learn the leader", func() bool {
+		return follower.Status().Leader == leader.config.ID
+	})
+	if err := follower.Propose(context.Background(), []byte("x")); err != nil {
+		t.Fatalf("Propose through a follower returned error: %v", err)
+	}
+}
+
+func TestRaftElectsNewLeaderAndRefusesWithoutQuorum(t *testing.T) {
+	group := startTestGroup(t, 3, "")
+	first := group.leader(t)
+	if err := first.Propose(context.Background(), []byte("before")); err != nil {
+		t.Fatalf("Propose returned error: %v", err)
+	}
+
+	group.transport.setDown(first.config.ID, true)
+	second := group.leader(t)
+	if second == first {
+		t.Fatal("expected another leader once the first is cut off")
+	}
+	if err := second.Propose(context.Background(), []byte("after")); err != nil {
+		t.Fatalf("Propose on the new leader returned error: %v", err)
+	}
+	// the old leader alone cannot commit anything
+	if err := first.Propose(context.Background(), []byte("lost")); !errors.Is(err, ErrNoQuorum) && !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("expected the isolated node to refuse, got %v", err)
+	}
+
+	for _, node := range group.nodes {
+		if node != second {
+			group.transport.setDown(node.config.ID, true)
+		}
+	}
+	if err := second.Propose(context.Background(), []byte("alone")); !errors.Is(err, ErrNoQuorum) && !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("expected a leader without quorum to refuse, got %v", err)
+	}
+	for _, machine := range group.machines {
+		if slices.Contains(machine.applied(), "lost") || slices.Contains(machine.applied(), "alone") {
+			t.Fatalf("a command without quorum was applied: %v", machine.applied())
+		}
+	}
+}
+
+func TestRaftRestoresLogFromState(t *testing.T) {
+	stateDir := t.TempDir()
+	group := startTestGroup(t, 1, stateDir)
+	leader := group.leader(t)
+	if err := leader.Propose(context.Background(), []byte("kept")); err != nil {
+		t.Fatalf("Propose returned error: %v", err)
+	}
+
+	state, err := loadState(filepath.Join(stateDir, "node-0.json"))
+	if err != nil {
+		t.Fatalf("loadState returned error: %v", err)
+	}
+	if state.Term == 0 || state.VotedFor != "node-0" || string(state.Log[len(state.Log)-1].Command) != "kept" {
+		t.Fatalf("unexpected saved state %+v", state)
+	}
+
+	machine := &recordingMachine{}
+	restarted, err := NewNode(Config{ID: "node-0", ElectionTimeout: 50 * time.Millisecond, StatePath: filepath.Join(stateDir, "node-0.json")}, nil, machine)
+	if err != nil {
+		t.Fatalf("NewNode returned error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	restarted.Start(ctx)
+	eventually(t, "restarted node did not apply its log again", func() bool {
+		return slices.Equal(machine.applied(), []string{"kept"})
+	})
+}
+
+func TestRaftRefusesWhatItCannotSave(t *testing.T) {
+	stateDir := filepath.Join(t.TempDir(), "state")
+	node, err := NewNode(Config{ID: "node-0", Peers: []string{"node-1"}, StatePath: filepath.Join(stateDir, "node-0.json")}, nil, &recordingMachine{})
+	if err != nil {
+		t.Fatalf("NewNode returned error: %v", err)
+	}
+	// the state directory turns out to be a regular file, so every save fails
+	if err := os.WriteFile(stateDir, nil, 0644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	vote := node.HandleRequestVote(VoteRequest{Term: 1, Candidate: "node-1"})
+	if vote.Granted || node.votedFor != "" {
+		t.Fatalf("vote granted without saving it: %+v, voted for %q", vote, node.votedFor)
+	}
+	resp := node.HandleAppendEntries(AppendRequest{Term: 1, Leader: "node-1", Entries: []Entry{{Index: 1, Term: 1, Command: []byte("lost")}}})
+	if resp.Success || resp.LastIndex != 0 || len(node.log) != 1 {
+		t.Fatalf("entries accepted without saving them: %+v, log %+v", resp, node.log)
+	}
+}
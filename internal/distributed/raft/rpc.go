@@ -0,0 +1,57 @@
+package raft
+
+import "errors"
+
+var (
+	ErrNotLeader = errors.New("not the raft leader")
+	ErrNoQuorum  = errors.New("no raft quorum")
+)
+
+type Entry struct {
+	Index   uint64 `json:"index"`
+	Term    uint64 `json:"term"`
+	Command []byte `json:"command,omitempty"` // empty for the no-op a new leader appends
+}
+
+type VoteRequest struct {
+	Term         uint64 `json:"term"`
+	Candidate    string `json:"candidate"`
+	LastLogIndex uint64 `json:"last_log_index"`
+	LastLogTerm  uint64 `json:"last_log_term"`
+}
+
+type VoteResponse struct {
+	Term    uint64 `json:"term"`
+	Granted bool   `json:"granted"`
+}
+
+type AppendRequest struct {
+	Term         uint64  `json:"term"`
+	Leader       string  `json:"leader"`
+	PrevLogIndex uint64  `json:"prev_log_index"`
+	PrevLogTerm  uint64  `json:"prev_log_term"`
+	Entries      []Entry `json:"entries"`
+	LeaderCommit uint64  `json:"leader_commit"`
+}
+
+type AppendResponse struct {
+	Term      uint64 `json:"term"`
+	Success   bool   `json:"success"`
+	LastIndex uint64 `json:"last_index"` // last index the follower can match, lets the leader skip back at once
+}
+
+type ProposeRequest struct {
+	Command []byte `json:"command"`
+}
+
+// Transport carries the RPCs between voters, peers are named by their node id
+type Transport interface {
+	RequestVote(peer string, req VoteRequest) (VoteResponse, error)
+	AppendEntries(peer string, req AppendRequest) (AppendResponse, error)
+	Propose(peer string, command []byte) error // forwards a proposal to the leader
+}
+
+// StateMachine receives every committed command once, in log order
+type StateMachine interface {
+	Apply(entry Entry)
+}
@@ -0,0 +1,75 @@
+package raft
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// persistentState is what a voter must remember across restarts
+type persistentState struct {
+	Term     uint64  `json:"term"`
+	VotedFor string  `json:"voted_for"`
+	Log      []Entry `json:"log"` // without the sentinel at index 0
+}
+
+func loadState(path string) (persistentState, error) {
+	var state persistentState
+	if path == "" {
+		return state, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(content, &state)
+	return state, err
+}
+
+// saveState replaces the state file through a rename, so a crash leaves either version whole. The
+// file and its directory are synced first, so a vote or an entry saved survives a crash.
+func saveState(path string, state persistentState) error {
+	if path == "" {
+		return nil
+	}
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := writeSynced(tmpPath, content); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func writeSynced(path string, content []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,291 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultAntiEntropyInterval = 60 * time.Second
+
+// MerklePeer is implemented by adapters that answer anti-entropy exchanges, see RemoteAdapter.
+// node is the name of the asking node, the peer only hashes the keys both of them replicate.
+type MerklePeer interface {
+	MerkleRoot(ctx context.Context, node string) (string, error)
+	MerkleLeaves(ctx context.Context, node string) ([]string, error)
+	MerkleBucket(ctx context.Context, node string, bucket int) (versions, deleted map[string]uint64, err error)
+}
+
+// AntiEntropyReport describes one anti-entropy round
+type AntiEntropyReport struct {
+	Peers            int      `json:"peers"`
+	PeersInSync      int      `json:"peers_in_sync"`
+	BucketsDiffering int      `json:"buckets_differing"`
+	KeysPulled       int      `json:"keys_pulled"`
+	KeysPushed       int      `json:"keys_pushed"`
+	KeysDeleted      int      `json:"keys_deleted"`
+	DurationMs       int64    `json:"duration_ms"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// AntiEntropyMetrics adds up every round since the node started
+type AntiEntropyMetrics struct {
+	Rounds           int64             `json:"rounds"`
+	BucketsDiffering int64             `json:"buckets_differing"`
+	KeysPulled       int64             `json:"keys_pulled"`
+	KeysPushed       int64             `json:"keys_pushed"`
+	KeysDeleted      int64             `json:"keys_deleted"`
+	Errors           int64             `json:"errors"`
+	LastRun          time.Time         `json:"last_run"`
+	LastReport       AntiEntropyReport `json:"last_report"`
+}
+
+// AntiEntropy compares Merkle trees of the keys this node shares with each peer
+// and copies the newer version of every key that differs, in both directions.
+// A key one side lacks is deleted on the other when its tombstone is newer than the copy.
+type AntiEntropy struct {
+	nodeRouter *NodeRouter
+	cache      core.CacheInterface
+	interval   time.Duration
+	runMu      sync.Mutex // one round at a time
+	mu         sync.Mutex
+	metrics    AntiEntropyMetrics
+}
+
+func NewAntiEntropy(nodeRouter *NodeRouter, cache core.CacheInterface, config *config.Config) *AntiEntropy {
+	return &AntiEntropy{
+		nodeRouter: nodeRouter,
+		cache:      cache,
+		interval:   durationOrDefault(config.Cluster.AntiEntropy.Interval, defaultAntiEntropyInterval),
+	}
+}
+
+// Start runs a round every interval until ctx is done
+func (ae *AntiEntropy) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ae.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ae.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Run synchronizes with every peer once, a peer not reached before ctx is done reports the error
+func (ae *AntiEntropy) Run(ctx context.Context) AntiEntropyReport {
+	ae.runMu.Lock()
+	defer ae.runMu.Unlock()
+
+	start := time.Now()
+	var report AntiEntropyReport
+	if ae.nodeRouter.GetBackupNodes() > 0 { // without backups no key lives on two nodes
+		localName := ae.nodeRouter.GetLocalName()
+		for name, peerAdapter := range ae.nodeRouter.GetNodes() {
+			peer, ok := peerAdapter.(MerklePeer)
+			if name == localName || !ok {
+				continue
+			}
+			report.Peers++
+			if err := ae.syncPeer(ctx, peerAdapter, peer, &report); err != nil {
+				log.Printf("Anti-entropy with %s failed: %v", name, err)
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	ae.mu.Lock()
+	ae.metrics.Rounds++
+	ae.metrics.BucketsDiffering += int64(report.BucketsDiffering)
+	ae.metrics.KeysPulled += int64(report.KeysPulled)
+	ae.metrics.KeysPushed += int64(report.KeysPushed)
+	ae.metrics.KeysDeleted += int64(report.KeysDeleted)
+	ae.metrics.Errors += int64(len(report.Errors))
+	ae.metrics.LastRun = start
+	ae.metrics.LastReport = report
+	ae.mu.Unlock()
+	return report
+}
+
+func (ae *AntiEntropy) Metrics() AntiEntropyMetrics {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	return ae.metrics
+}
+
+// syncPeer walks down from the root: equal roots end the exchange, otherwise only the
+// buckets whose leaves differ are compared key by key
+func (ae *AntiEntropy) syncPeer(ctx context.Context, peerAdapter adapter.AdapterInterface, peer MerklePeer, report *AntiEntropyReport) error {
+	localName := ae.nodeRouter.GetLocalName()
+	buckets := ae.sharedBuckets(peerAdapter)
+	deleted := ae.sharedTombstones(peerAdapter)
+	tree := newMerkleTree(buckets)
+
+	root, err := peer.MerkleRoot(ctx, localName)
+	if err != nil {
+		return err
+	}
+	if root == tree.root() {
+		report.PeersInSync++
+		return nil
+	}
+	peerLeaves, err := peer.MerkleLeaves(ctx, localName)
+	if err != nil {
+		return err
+	}
+	if len(peerLeaves) != merkleLeafCount {
+		return fmt.Errorf("peer sent %d leaves, expected %d", len(peerLeaves), merkleLeafCount)
+	}
+
+	var pull, push []string
+	deleteLocal := make(map[string]uint64)
+	deletePeer := make(map[string]uint64)
+	for i, leaf := range tree.leaves() {
+		if leaf == peerLeaves[i] {
+			continue
+		}
+		report.BucketsDiffering++
+		peerVersions, peerDeleted, err := peer.MerkleBucket(ctx, localName, i)
+		if err != nil {
+			return err
+		}
+		for key, peerVersion := range peerVersions {
+			localVersion, ok := buckets[i][key]
+			switch {
+			case !ok && deleted[i][key] > peerVersion: // deleted here after the peer's copy was written
+				deletePeer[key] = deleted[i][key]
+			case !ok || peerVersion > localVersion:
+				pull = append(pull, key)
+			}
+		}
+		for key, localVersion := range buckets[i] {
+			peerVersion, ok := peerVersions[key]
+			switch {
+			case !ok && peerDeleted[key] > localVersion:
+				deleteLocal[key] = peerDeleted[key]
+			case !ok || localVersion > peerVersion:
+				push = append(push, key)
+			}
+		}
+	}
+
+	if len(pull) > 0 {
+		entries, err := peerAdapter.GetEntries(ctx, pull)
+		if err != nil {
+			return err
+		}
+		if err := ae.cache.SetEntries(ctx, entries); err != nil {
+			return err
+		}
+		report.KeysPulled += len(entries)
+	}
+	if len(push) > 0 {
+		entries := ae.cache.GetEntries(ctx, push)
+		if err := peerAdapter.SetEntries(ctx, entries); err != nil {
+			return err
+		}
+		report.KeysPushed += len(entries)
+	}
+	if len(deleteLocal) > 0 {
+		if err := ae.cache.DeleteEntries(ctx, deleteLocal); err != nil {
+			return err
+		}
+		report.KeysDeleted += len(deleteLocal)
+	}
+	if len(deletePeer) > 0 {
+		if err := peerAdapter.DeleteEntries(ctx, deletePeer); err != nil {
+			return err
+		}
+		report.KeysDeleted += len(deletePeer)
+	}
+	return nil
+}
+
+// sharedBuckets groups the local key versions by Merkle bucket, keeping the keys peerAdapter also replicates
+func (ae *AntiEntropy) sharedBuckets(peerAdapter adapter.AdapterInterface) [merkleLeafCount]map[string]uint64 {
+	var buckets [merkleLeafCount]map[string]uint64
+	for i := range buckets {
+		buckets[i] = make(map[string]uint64)
+	}
+	localAdapter := ae.nodeRouter.GetLocalAdapter()
+	for key, version := range ae.cache.Versions() {
+		if ae.sharedWith(peerAdapter, localAdapter, key) {
+			buckets[merkleBucket(key)][key] = version
+		}
+	}
+	return buckets
+}
+
+// sharedTombstones groups the local delete versions like sharedBuckets, they are left out of the
+// Merkle tree and only compared in the buckets whose live keys differ
+func (ae *AntiEntropy) sharedTombstones(peerAdapter adapter.AdapterInterface) [merkleLeafCount]map[string]uint64 {
+	var buckets [merkleLeafCount]map[string]uint64
+	for i := range buckets {
+		buckets[i] = make(map[string]uint64)
+	}
+	localAdapter := ae.nodeRouter.GetLocalAdapter()
+	for key, version := range ae.cache.Tombstones() {
+		if ae.sharedWith(peerAdapter, localAdapter, key) {
+			buckets[merkleBucket(key)][key] = version
+		}
+	}
+	return buckets
+}
+
+// sharedWith tells whether both the local node and peerAdapter replicate key
+func (ae *AntiEntropy) sharedWith(peerAdapter, localAdapter adapter.AdapterInterface, key string) bool {
+	adapters, err := ae.nodeRouter.GetAdapters(key)
+	return err == nil && slices.Contains(adapters, localAdapter) && slices.Contains(adapters, peerAdapter)
+}
+
+func (ae *AntiEntropy) peerTree(node string) (*merkleTree, [merkleLeafCount]map[string]uint64, error) {
+	peerAdapter, ok := ae.nodeRouter.GetNodeAdapter(node)
+	if !ok {
+		return nil, [merkleLeafCount]map[string]uint64{}, fmt.Errorf("%w: node %s is not in the ring", internal.ErrNotFound, node)
+	}
+	buckets := ae.sharedBuckets(peerAdapter)
+	return newMerkleTree(buckets), buckets, nil
+}
+
+// Root returns the root hash of the keys shared with node
+func (ae *AntiEntropy) Root(node string) (string, error) {
+	tree, _, err := ae.peerTree(node)
+	if err != nil {
+		return "", err
+	}
+	return tree.root(), nil
+}
+
+// Leaves returns the leaf hashes of the keys shared with node
+func (ae *AntiEntropy) Leaves(node string) ([]string, error) {
+	tree, _, err := ae.peerTree(node)
+	if err != nil {
+		return nil, err
+	}
+	return tree.leaves(), nil
+}
+
+// Bucket returns the versions and the delete versions of the keys shared with node that fall in bucket
+func (ae *AntiEntropy) Bucket(node string, bucket int) (versions, deleted map[string]uint64, err error) {
+	if bucket < 0 || bucket >= merkleLeafCount {
+		return nil, nil, fmt.Errorf("%w: bucket %d out of range", internal.ErrBadRequest, bucket)
+	}
+	_, buckets, err := ae.peerTree(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	peerAdapter, _ := ae.nodeRouter.GetNodeAdapter(node)
+	return buckets[bucket], ae.sharedTombstones(peerAdapter)[bucket], nil
+}
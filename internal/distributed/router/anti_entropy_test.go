@@ -0,0 +1,183 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+// merkleAdapter reaches an in-process node together with its anti-entropy state, like RemoteAdapter does over HTTP
+type merkleAdapter struct {
+	*adapter.LocalAdapter
+	antiEntropy *AntiEntropy
+}
+
+func (ma *merkleAdapter) MerkleRoot(ctx context.Context, node string) (string, error) {
+	return ma.antiEntropy.Root(node)
+}
+
+func (ma *merkleAdapter) MerkleLeaves(ctx context.Context, node string) ([]string, error) {
+	return ma.antiEntropy.Leaves(node)
+}
+
+func (ma *merkleAdapter) MerkleBucket(ctx context.Context, node string, bucket int) (versions, deleted map[string]uint64, err error) {
+	return ma.antiEntropy.Bucket(node, bucket)
+}
+
+// newAntiEntropyNode builds a node keeping one backup of each key, alone in its ring
+func newAntiEntropyNode(t *testing.T, name string) (*NodeRouter, *core.Cache, *AntiEntropy) {
+	t.Helper()
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.NodeName = name
+	testConfig.Cluster.Replication.BackupNodes = 1
+	cache := newTestNodeCache(t)
+	nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(cache))
+	return nodeRouter, cache, NewAntiEntropy(nodeRouter, cache, testConfig)
+}
+
+// newAntiEntropyPair builds two nodes that see each other in their rings
+func newAntiEntropyPair(t *testing.T) (*AntiEntropy, *core.Cache, *AntiEntropy, *core.Cache) {
+	t.Helper()
+	routerA, cacheA, antiEntropyA := newAntiEntropyNode(t, "node-a")
+	routerB, cacheB, antiEntropyB := newAntiEntropyNode(t, "node-b")
+	routerA.AddAdapter("node-b", &merkleAdapter{LocalAdapter: adapter.NewLocalAdapter(cacheB), antiEntropy: antiEntropyB})
+	routerB.AddAdapter("node-a", &merkleAdapter{LocalAdapter: adapter.NewLocalAdapter(cacheA), antiEntropy: antiEntropyA})
+	return antiEntropyA, cacheA, antiEntropyB, cacheB
+}
+
+func TestAntiEntropySyncsDivergentReplicas(t *testing.T) {
+	antiEntropyA, cacheA, antiEntropyB, cacheB := newAntiEntropyPair(t)
+
+	for i := 0; i < 50; i++ {
+		cacheA.Set(t.Context(), fmt.Sprintf("a-%d", i), []byte("1"), time.Minute) // missed by node-b
+		cacheB.Set(t.Context(), fmt.Sprintf("b-%d", i), []byte("1"), time.Minute) // missed by node-a
+	}
+	cacheA.Set(t.Context(), "shared", []byte("old"), time.Minute)
+	cacheB.Set(t.Context(), "shared", []byte("new"), time.Minute) // newer version
+
+	report := antiEntropyA.Run(t.Context())
+	if len(report.Errors) > 0 {
+		t.Fatalf("round reported errors: %v", report.Errors)
+	}
+	if report.KeysPulled != 51 || report.KeysPushed != 50 {
+		t.Fatalf("expected 51 keys pulled and 50 pushed, got %+v", report)
+	}
+	if !maps.Equal(cacheA.Versions(), cacheB.Versions()) {
+		t.Fatalf("replicas still differ after a round")
+	}
+	if value, _ := cacheA.Get(t.Context(), "shared"); string(value) != "new" {
+		t.Fatalf("expected the newer version to win, got %s", value)
+	}
+
+	report = antiEntropyB.Run(t.Context())
+	if report.PeersInSync != 1 || report.BucketsDiffering != 0 {
+		t.Fatalf("expected node-b to find equal roots, got %+v", report)
+	}
+	metrics := antiEntropyA.Metrics()
+	if metrics.Rounds != 1 || metrics.KeysPulled != 51 {
+		t.Fatalf("unexpected metrics: %+v", metrics)
+	}
+	if _, err := antiEntropyA.Root("unknown-node"); err == nil {
+		t.Fatalf("expected an unknown node to be rejected")
+	}
+}
+
+func TestAntiEntropyKeepsDeletes(t *testing.T) {
+	antiEntropyA, cacheA, antiEntropyB, cacheB := newAntiEntropyPair(t)
+
+	for _, key := range []string{"gone-a", "gone-b", "back"} {
+		cacheA.Set(t.Context(), key, []byte("1"), time.Minute)
+		if err := cacheB.SetEntries(t.Context(), cacheA.GetEntries(t.Context(), []string{key})); err != nil {
+			t.Fatalf("copying %s failed: %v", key, err)
+		}
+	}
+	cacheA.Del(t.Context(), "gone-a") // missed by node-b
+	cacheB.Del(t.Context(), "gone-b") // missed by node-a
+	cacheA.Del(t.Context(), "back")
+	cacheB.Set(t.Context(), "back", []byte("2"), time.Minute) // written after the delete
+
+	report := antiEntropyA.Run(t.Context())
+	if len(report.Errors) > 0 {
+		t.Fatalf("round reported errors: %v", report.Errors)
+	}
+	if report.KeysDeleted != 2 || report.KeysPulled != 1 || report.KeysPushed != 0 {
+		t.Fatalf("expected 2 keys deleted and 1 pulled, got %+v", report)
+	}
+	for _, key := range []string{"gone-a", "gone-b"} {
+		if _, ok := cacheA.Get(t.Context(), key); ok {
+			t.Fatalf("expected %s to stay deleted on node-a", key)
+		}
+		if _, ok := cacheB.Get(t.Context(), key); ok {
+			t.Fatalf("expected %s to stay deleted on node-b", key)
+		}
+	}
+	if value, _ := cacheA.Get(t.Context(), "back"); string(value) != "2" {
+		t.Fatalf("expected the write after the delete to win, got %s", value)
+	}
+
+	for _, antiEntropy := range []*AntiEntropy{antiEntropyB, antiEntropyA} {
+		if report := antiEntropy.Run(t.Context()); report.PeersInSync != 1 {
+			t.Fatalf("expected replicas to stay in sync, got %+v", report)
+		}
+	}
+}
+
+func TestAntiEntropyKeepsKeysARebalanceHandedOff(t *testing.T) {
+	routerA, cacheA, antiEntropyA := newAntiEntropyNode(t, "node-a")
+	routerB, cacheB, antiEntropyB := newAntiEntropyNode(t, "node-b")
+	routerA.AddAdapter("node-b", &merkleAdapter{LocalAdapter: adapter.NewLocalAdapter(cacheB), antiEntropy: antiEntropyB})
+	routerB.AddAdapter("node-a", &merkleAdapter{LocalAdapter: adapter.NewLocalAdapter(cacheA), antiEntropy: antiEntropyA})
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		cacheA.Set(t.Context(), key, []byte("1"), time.Minute)
+		if err := cacheB.SetEntries(t.Context(), cacheA.GetEntries(t.Context(), []string{key})); err != nil {
+			t.Fatalf("copying %s failed: %v", key, err)
+		}
+	}
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Rebalance.Rate = 100000
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	NewRebalancer(routerA, cacheA, testConfig).Start(ctx)
+
+	// node-c joins the ring of node-a, which hands off the keys node-b and node-c now replicate
+	if err := routerA.AddAdapter("node-c", adapter.NewLocalAdapter(newTestNodeCache(t))); err != nil {
+		t.Fatalf("AddAdapter returned error: %v", err)
+	}
+	var handedOff []string
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if adapters, _ := routerA.GetAdapters(key); !slices.Contains(adapters, routerA.GetLocalAdapter()) {
+			handedOff = append(handedOff, key)
+		}
+	}
+	if len(handedOff) == 0 {
+		t.Fatal("expected node-a to hand off some keys")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for slices.ContainsFunc(handedOff, func(key string) bool { return cacheA.Exists(t.Context(), key) }) {
+		if time.Now().After(deadline) {
+			t.Fatal("node-a did not drop the keys it handed off")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// node-c leaves again, node-a and node-b replicate every key once more
+	if err := routerA.RemoveAdapter("node-c"); err != nil {
+		t.Fatalf("RemoveAdapter returned error: %v", err)
+	}
+	report := antiEntropyA.Run(t.Context())
+	if len(report.Errors) > 0 || report.KeysDeleted != 0 || report.KeysPulled != len(handedOff) {
+		t.Fatalf("expected the %d handed-off keys to be pulled back and none deleted, got %+v", len(handedOff), report)
+	}
+	if len(keysOf(t, cacheA)) != 50 || len(keysOf(t, cacheB)) != 50 {
+		t.Fatalf("expected both nodes to keep every key, node-a=%d node-b=%d", len(keysOf(t, cacheA)), len(keysOf(t, cacheB)))
+	}
+}
@@ -1 +1,298 @@
 package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"go-cache-server-mini/internal/distributed/gossip"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MembershipStatic = "static" // peers from config, checked through /ping
+	MembershipGossip = "gossip" // peers discovered and checked through SWIM gossip
+)
+
+const (
+	defaultHealthCheckInterval = 1 * time.Second
+	defaultFailureThreshold    = 3
+	defaultRequestTimeout      = 2 * time.Second
+)
+
+type NodeState string
+
+const (
+	NodeUnknown NodeState = "unknown" // not checked yet
+	NodeAlive   NodeState = "alive"
+	NodeSuspect NodeState = "suspect" // missed checks, still in the ring
+	NodeDead    NodeState = "dead"    // removed from the ring
+)
+
+type NodeStatus struct {
+	Name     string                 `json:"name"`
+	Address  string                 `json:"address"`
+	State    NodeState              `json:"state"`
+	Local    bool                   `json:"local"`
+	LastSeen time.Time              `json:"last_seen"`
+	Breaker  *adapter.BreakerStatus `json:"breaker,omitempty"` // circuit breaker of the calls to a peer
+}
+
+type clusterNode struct {
+	status   NodeStatus
+	failures int // consecutive failed health checks
+	adapter  *adapter.RemoteAdapter
+}
+
+// ClusterManager keeps the NodeRouter ring in line with the peers that answer health checks,
+// or with the live members reported by gossip
+type ClusterManager struct {
+	nodeRouter       *NodeRouter
+	localStatus      NodeStatus
+	membership       string
+	gossipConfig     gossip.Config
+	interval         time.Duration
+	failureThreshold int
+	remotePolicy     adapter.RemotePolicy
+	consensus        bool                    // the ring follows the raft log, checks only track node states
+	nodes            map[string]*clusterNode // peer name to node
+	mu               sync.RWMutex
+}
+
+func NewClusterManager(nodeRouter *NodeRouter, config *config.Config) *ClusterManager {
+	clusterConfig := config.Cluster
+	manager := &ClusterManager{
+		nodeRouter: nodeRouter,
+		localStatus: NodeStatus{
+			Name:    nodeRouter.GetLocalName(),
+			Address: clusterConfig.Address,
+			State:   NodeAlive,
+			Local:   true,
+		},
+		membership:       clusterConfig.Membership,
+		interval:         durationOrDefault(clusterConfig.HealthCheckInterval, defaultHealthCheckInterval),
+		failureThreshold: clusterConfig.FailureThreshold,
+		remotePolicy:     remotePolicy(clusterConfig),
+		consensus:        clusterConfig.Raft.Enabled,
+		nodes:            make(map[string]*clusterNode),
+	}
+	if manager.failureThreshold <= 0 {
+		manager.failureThreshold = defaultFailureThreshold
+	}
+	if manager.membership == "" {
+		manager.membership = MembershipStatic
+	}
+	if !clusterConfig.Enabled {
+		return manager
+	}
+	if manager.membership == MembershipGossip {
+		gossipConfig := clusterConfig.Gossip
+		manager.gossipConfig = gossip.Config{
+			Name:             manager.localStatus.Name,
+			Address:          clusterConfig.Address,
+			BindAddress:      gossipConfig.BindAddress,
+			AdvertiseAddress: gossipConfig.AdvertiseAddress,
+			Seeds:            gossipConfig.Seeds,
+			ProbeInterval:    durationOrDefault(gossipConfig.ProbeInterval, 0),
+			ProbeTimeout:     durationOrDefault(gossipConfig.ProbeTimeout, 0),
+			SuspicionTimeout: durationOrDefault(gossipConfig.SuspicionTimeout, 0),
+			IndirectChecks:   gossipConfig.IndirectChecks,
+		}
+		return manager // peers are learned from gossip
+	}
+	for _, peer := range clusterConfig.Peers {
+		// every node may share the same peer list, skip ourselves
+		if peer.Name == "" || peer.Name == manager.localStatus.Name {
+			continue
+		}
+		manager.nodes[peer.Name] = &clusterNode{
+			status: NodeStatus{
+				Name:    peer.Name,
+				Address: peer.Address,
+				State:   NodeUnknown,
+			},
+			adapter: adapter.NewRemoteAdapter(peer.Address, manager.remotePolicy),
+		}
+	}
+	return manager
+}
+
+// Start runs the health check loop, or joins the gossip cluster, until ctx is done
+func (cm *ClusterManager) Start(ctx context.Context) error {
+	if cm.membership == MembershipGossip {
+		return gossip.NewNode(cm.gossipConfig, cm).Start(ctx)
+	}
+	go func() {
+		ticker := time.NewTicker(cm.interval)
+		defer ticker.Stop()
+		cm.checkPeers()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cm.checkPeers()
+			}
+		}
+	}()
+	return nil
+}
+
+// Nodes returns the state of every known node, local node included
+func (cm *ClusterManager) Nodes() []NodeStatus {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	local := cm.localStatus
+	local.LastSeen = time.Now()
+	nodes := make([]NodeStatus, 0, len(cm.nodes)+1)
+	nodes = append(nodes, local)
+	for _, node := range cm.nodes {
+		status := node.status
+		breaker := node.adapter.Breaker()
+		status.Breaker = &breaker
+		nodes = append(nodes, status)
+	}
+	slices.SortFunc(nodes, func(a, b NodeStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return nodes
+}
+
+// Distribution reports how the keyspace is split between the nodes in the ring
+func (cm *ClusterManager) Distribution() KeyspaceDistribution {
+	return cm.nodeRouter.Distribution()
+}
+
+// Slots publishes the ring for clients that route keys themselves
+func (cm *ClusterManager) Slots() ClusterSlots {
+	return cm.nodeRouter.Slots()
+}
+
+func (cm *ClusterManager) checkPeers() {
+	cm.mu.RLock()
+	peers := make([]*clusterNode, 0, len(cm.nodes))
+	for _, node := range cm.nodes {
+		peers = append(peers, node)
+	}
+	cm.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, node := range peers {
+		wg.Add(1)
+		go func(node *clusterNode) {
+			defer wg.Done()
+			err := node.adapter.Ping()
+			cm.recordCheck(node, err)
+		}(node)
+	}
+	wg.Wait()
+}
+
+// recordCheck moves a peer between states after a health check
+func (cm *ClusterManager) recordCheck(node *clusterNode, checkErr error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if checkErr == nil {
+		node.failures = 0
+		cm.markAlive(node)
+		return
+	}
+
+	node.failures++
+	switch {
+	case node.failures >= cm.failureThreshold && node.status.State != NodeDead:
+		log.Printf("Cluster node %s failed %d health checks: %v", node.status.Name, node.failures, checkErr)
+		cm.markDead(node)
+	case node.status.State == NodeAlive:
+		node.status.State = NodeSuspect
+	}
+}
+
+// NotifyChange applies a membership change reported by gossip
+func (cm *ClusterManager) NotifyChange(member gossip.Member) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	node, exists := cm.nodes[member.Name]
+	if !exists || node.status.Address != member.Address {
+		if exists && !cm.consensus && cm.nodeRouter.HasNode(member.Name) {
+			cm.nodeRouter.RemoveAdapter(member.Name) // the node came back on another address
+		}
+		node = &clusterNode{
+			status: NodeStatus{
+				Name:    member.Name,
+				Address: member.Address,
+				State:   NodeUnknown,
+			},
+			adapter: adapter.NewRemoteAdapter(member.Address, cm.remotePolicy),
+		}
+		cm.nodes[member.Name] = node
+	}
+	switch member.State {
+	case gossip.StateAlive:
+		cm.markAlive(node)
+	case gossip.StateSuspect:
+		node.status.State = NodeSuspect
+	case gossip.StateDead:
+		cm.markDead(node)
+	}
+}
+
+// NotifyAck records that gossip heard from the member
+func (cm *ClusterManager) NotifyAck(name string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if node, ok := cm.nodes[name]; ok {
+		node.status.LastSeen = time.Now()
+	}
+}
+
+// markAlive adds the node to the ring, or leaves it to the raft leader, cm.mu must be held
+func (cm *ClusterManager) markAlive(node *clusterNode) {
+	node.status.LastSeen = time.Now()
+	if node.status.State == NodeAlive {
+		return
+	}
+	log.Printf("Cluster node %s (%s) is alive", node.status.Name, node.status.Address)
+	if !cm.consensus && !cm.nodeRouter.HasNode(node.status.Name) {
+		cm.nodeRouter.AddAdapter(node.status.Name, node.adapter)
+	}
+	node.status.State = NodeAlive
+}
+
+// markDead removes the node from the ring, or leaves it to the raft leader, cm.mu must be held
+func (cm *ClusterManager) markDead(node *clusterNode) {
+	if node.status.State == NodeDead {
+		return
+	}
+	log.Printf("Cluster node %s (%s) is dead", node.status.Name, node.status.Address)
+	if !cm.consensus {
+		cm.nodeRouter.RemoveAdapter(node.status.Name)
+	}
+	node.status.State = NodeDead
+}
+
+// remotePolicy builds the deadline, retry and breaker settings of the adapters to peers
+func remotePolicy(clusterConfig config.ClusterConfig) adapter.RemotePolicy {
+	return adapter.RemotePolicy{
+		Timeout:          durationOrDefault(clusterConfig.RequestTimeout, defaultRequestTimeout),
+		MaxAttempts:      clusterConfig.Retry.MaxAttempts,
+		BaseBackoff:      durationOrDefault(clusterConfig.Retry.BaseBackoff, 0),
+		MaxBackoff:       durationOrDefault(clusterConfig.Retry.MaxBackoff, 0),
+		FailureThreshold: clusterConfig.CircuitBreaker.FailureThreshold,
+		OpenTimeout:      durationOrDefault(clusterConfig.CircuitBreaker.OpenTimeout, 0),
+		HalfOpenProbes:   clusterConfig.CircuitBreaker.HalfOpenProbes,
+	}
+}
+
+func durationOrDefault(milliseconds int64, defaultDuration time.Duration) time.Duration {
+	if milliseconds <= 0 {
+		return defaultDuration
+	}
+	return time.Duration(milliseconds) * time.Millisecond
+}
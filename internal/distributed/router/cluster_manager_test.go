@@ -0,0 +1,119 @@
+package router
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func waitForState(t *testing.T, manager *ClusterManager, name string, state NodeState) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, node := range manager.Nodes() {
+			if node.Name == name && node.State == state {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("node %s did not reach state %s: %+v", name, state, manager.Nodes())
+}
+
+func TestClusterManagerTracksPeerHealth(t *testing.T) {
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"pong"}`))
+	}))
+	defer peer.Close()
+
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Enabled = true
+	testConfig.Cluster.HealthCheckInterval = 20
+	testConfig.Cluster.FailureThreshold = 2
+	testConfig.Cluster.RequestTimeout = 100
+	testConfig.Cluster.Peers = []config.PeerConfig{
+		{Name: testConfig.Cluster.NodeName, Address: testConfig.Cluster.Address}, // ourselves, skipped
+		{Name: "peer-node", Address: peer.URL},
+	}
+
+	nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(newTestNodeCache(t)))
+	manager := NewClusterManager(nodeRouter, testConfig)
+	if nodes := manager.Nodes(); len(nodes) != 2 {
+		t.Fatalf("expected local node and one peer, got %+v", nodes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager.Start(ctx)
+
+	waitForState(t, manager, "peer-node", NodeAlive)
+	if !nodeRouter.HasNode("peer-node") {
+		t.Fatalf("alive peer should be added to the ring")
+	}
+
+	peer.Close()
+	waitForState(t, manager, "peer-node", NodeDead)
+	if nodeRouter.HasNode("peer-node") {
+		t.Fatalf("dead peer should be removed from the ring")
+	}
+}
+
+func freeUDPAddress(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to reserve udp port: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+func TestClusterManagerGossipMembership(t *testing.T) {
+	seedAddress := freeUDPAddress(t)
+	newGossipManager := func(name, gossipAddress string, seeds []string) (*ClusterManager, *NodeRouter) {
+		testConfig := config.LoadTestConfig()
+		testConfig.Cluster.Enabled = true
+		testConfig.Cluster.NodeName = name
+		testConfig.Cluster.Membership = MembershipGossip
+		testConfig.Cluster.Gossip = config.GossipConfig{
+			BindAddress:      gossipAddress,
+			Seeds:            seeds,
+			ProbeInterval:    50,
+			ProbeTimeout:     15,
+			SuspicionTimeout: 150,
+		}
+		nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(newTestNodeCache(t)))
+		return NewClusterManager(nodeRouter, testConfig), nodeRouter
+	}
+
+	seedManager, seedRouter := newGossipManager("node-a", seedAddress, nil)
+	joinManager, joinRouter := newGossipManager("node-b", "127.0.0.1:0", []string{seedAddress})
+	seedCtx, seedCancel := context.WithCancel(context.Background())
+	defer seedCancel()
+	joinCtx, joinCancel := context.WithCancel(context.Background())
+	defer joinCancel()
+	if err := seedManager.Start(seedCtx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := joinManager.Start(joinCtx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	waitForState(t, seedManager, "node-b", NodeAlive)
+	waitForState(t, joinManager, "node-a", NodeAlive)
+	if !seedRouter.HasNode("node-b") || !joinRouter.HasNode("node-a") {
+		t.Fatalf("gossip members should be added to the ring")
+	}
+
+	joinCancel()
+	waitForState(t, seedManager, "node-b", NodeDead)
+	if seedRouter.HasNode("node-b") {
+		t.Fatalf("a member that left should be removed from the ring")
+	}
+}
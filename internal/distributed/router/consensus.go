@@ -0,0 +1,312 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"go-cache-server-mini/internal/distributed/raft"
+	"log"
+	"maps"
+	"sync"
+	"time"
+)
+
+const (
+	metadataJoin      = "join"      // add a node to the ring, or move it to a new address
+	metadataLeave     = "leave"     // remove a node from the ring
+	metadataConfigure = "configure" // set placement, virtual nodes and weights
+)
+
+var errConsensusDisabled = fmt.Errorf("%w: raft is not enabled", internal.ErrBadRequest)
+
+// ClusterMetadata is the cluster configuration agreed through the raft log
+type ClusterMetadata struct {
+	Version      uint64            `json:"version"` // log index of the last change, used as the ring epoch
+	Placement    string            `json:"placement"`
+	VirtualNodes int               `json:"virtual_nodes"`
+	Weights      map[string]int    `json:"weights"`
+	Members      map[string]string `json:"members"` // node name to address
+}
+
+type metadataCommand struct {
+	Op           string         `json:"op"`
+	Name         string         `json:"name,omitempty"`
+	Address      string         `json:"address,omitempty"`
+	Placement    string         `json:"placement,omitempty"`
+	VirtualNodes int            `json:"virtual_nodes,omitempty"`
+	Weights      map[string]int `json:"weights,omitempty"`
+}
+
+type ConsensusStatus struct {
+	Enabled  bool            `json:"enabled"`
+	Raft     *raft.Status    `json:"raft,omitempty"`
+	Metadata ClusterMetadata `json:"metadata"`
+}
+
+// ClusterConsensus keeps the ring of every node on the same raft log. The leader proposes
+// joins and leaves from the node states of the ClusterManager; every node applies them in order.
+type ClusterConsensus struct {
+	raft         *raft.Node
+	nodeRouter   *NodeRouter
+	cluster      *ClusterManager
+	defaults     metadataCommand // placement from the config, proposed once by the first leader
+	remotePolicy adapter.RemotePolicy
+	interval     time.Duration
+	mu           sync.RWMutex
+	metadata     ClusterMetadata
+	adapters     map[string]*adapter.RemoteAdapter // member name to adapter, kept while the address stays
+}
+
+func NewClusterConsensus(nodeRouter *NodeRouter, cluster *ClusterManager, config *config.Config) (*ClusterConsensus, error) {
+	policy := remotePolicy(config.Cluster)
+	transport := raftTransport{peers: make(map[string]*adapter.RemoteAdapter)}
+	var voters []string
+	for _, peer := range config.Cluster.Peers {
+		if peer.Name == "" || peer.Name == nodeRouter.GetLocalName() {
+			continue
+		}
+		voters = append(voters, peer.Name)
+		transport.peers[peer.Name] = adapter.NewRemoteAdapter(peer.Address, policy)
+	}
+	return newClusterConsensus(nodeRouter, cluster, config, voters, transport)
+}
+
+func newClusterConsensus(nodeRouter *NodeRouter, cluster *ClusterManager, config *config.Config, voters []string, transport raft.Transport) (*ClusterConsensus, error) {
+	clusterConfig := config.Cluster
+	consensus := &ClusterConsensus{
+		nodeRouter: nodeRouter,
+		cluster:    cluster,
+		defaults: metadataCommand{
+			Op:           metadataConfigure,
+			Placement:    clusterConfig.Placement,
+			VirtualNodes: clusterConfig.VirtualNodes,
+			Weights:      clusterConfig.Weights,
+		},
+		remotePolicy: remotePolicy(clusterConfig),
+		interval:     durationOrDefault(clusterConfig.HealthCheckInterval, defaultHealthCheckInterval),
+		metadata:     ClusterMetadata{Members: make(map[string]string)},
+		adapters:     make(map[string]*adapter.RemoteAdapter),
+	}
+	if !clusterConfig.Enabled || !clusterConfig.Raft.Enabled {
+		return consensus, nil
+	}
+
+	raftConfig := clusterConfig.Raft
+	node, err := raft.NewNode(raft.Config{
+		ID:                nodeRouter.GetLocalName(),
+		Peers:             voters,
+		ElectionTimeout:   durationOrDefault(raftConfig.ElectionTimeout, 0),
+		HeartbeatInterval: durationOrDefault(raftConfig.HeartbeatInterval, 0),
+		ProposeTimeout:    durationOrDefault(raftConfig.ProposeTimeout, 0),
+		StatePath:         raftConfig.StatePath,
+	}, transport, consensus)
+	if err != nil {
+		return nil, fmt.Errorf("error loading raft state: %w", err)
+	}
+	consensus.raft = node
+	return consensus, nil
+}
+
+// Start runs the raft node and, while this node leads, proposes the ring changes seen by the cluster manager
+func (cc *ClusterConsensus) Start(ctx context.Context) {
+	if cc.raft == nil {
+		return
+	}
+	cc.raft.Start(ctx)
+	go func() {
+		ticker := time.NewTicker(cc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cc.reconcile(ctx)
+			}
+		}
+	}()
+}
+
+// Apply updates the metadata with a committed command and moves the ring to it
+func (cc *ClusterConsensus) Apply(entry raft.Entry) {
+	var command metadataCommand
+	if err := json.Unmarshal(entry.Command, &command); err != nil {
+		log.Printf("Error decoding raft entry %d: %v", entry.Index, err)
+		return
+	}
+	cc.mu.Lock()
+	switch command.Op {
+	case metadataJoin:
+		cc.metadata.Members[command.Name] = command.Address
+	case metadataLeave:
+		delete(cc.metadata.Members, command.Name)
+	case metadataConfigure:
+		if command.Placement != "" {
+			cc.metadata.Placement = command.Placement
+		}
+		if command.VirtualNodes > 0 {
+			cc.metadata.VirtualNodes = command.VirtualNodes
+		}
+		if command.Weights != nil {
+			cc.metadata.Weights = command.Weights
+		}
+	}
+	cc.metadata.Version = entry.Index
+	metadata := cc.cloneLocked()
+	nodes := cc.ringNodesLocked(metadata.Members)
+	cc.mu.Unlock()
+
+	if len(nodes) == 0 {
+		return // keep serving locally until a node has joined
+	}
+	cc.nodeRouter.SetRing(nodes, metadata.Placement, metadata.VirtualNodes, metadata.Weights, metadata.Version)
+}
+
+// Configure proposes a new placement, virtual node count or weights; zero values keep the current ones
+func (cc *ClusterConsensus) Configure(ctx context.Context, placement string, vnodes int, weights map[string]int) error {
+	if cc.raft == nil {
+		return errConsensusDisabled
+	}
+	if placement != "" && !validPlacement(placement) {
+		return fmt.Errorf("%w: unknown placement %q", internal.ErrBadRequest, placement)
+	}
+	return cc.propose(ctx, metadataCommand{Op: metadataConfigure, Placement: placement, VirtualNodes: vnodes, Weights: weights})
+}
+
+func (cc *ClusterConsensus) Status() ConsensusStatus {
+	cc.mu.RLock()
+	status := ConsensusStatus{Enabled: cc.raft != nil, Metadata: cc.cloneLocked()}
+	cc.mu.RUnlock()
+	if cc.raft != nil {
+		raftStatus := cc.raft.Status()
+		status.Raft = &raftStatus
+	}
+	return status
+}
+
+func (cc *ClusterConsensus) RequestVote(req raft.VoteRequest) (raft.VoteResponse, error) {
+	if cc.raft == nil {
+		return raft.VoteResponse{}, errConsensusDisabled
+	}
+	return cc.raft.HandleRequestVote(req), nil
+}
+
+func (cc *ClusterConsensus) AppendEntries(req raft.AppendRequest) (raft.AppendResponse, error) {
+	if cc.raft == nil {
+		return raft.AppendResponse{}, errConsensusDisabled
+	}
+	return cc.raft.HandleAppendEntries(req), nil
+}
+
+// Propose commits a command forwarded by a follower
+func (cc *ClusterConsensus) Propose(ctx context.Context, command []byte) error {
+	if cc.raft == nil {
+		return errConsensusDisabled
+	}
+	return cc.raft.Propose(ctx, command)
+}
+
+// reconcile proposes the joins and leaves that bring the agreed ring in line with the node states
+func (cc *ClusterConsensus) reconcile(ctx context.Context) {
+	if !cc.raft.IsLeader() {
+		return
+	}
+	cc.mu.RLock()
+	metadata := cc.cloneLocked()
+	cc.mu.RUnlock()
+
+	if metadata.Placement == "" {
+		if err := cc.propose(ctx, cc.defaults); err != nil {
+			log.Printf("Ring configuration refused: %v", err)
+			return
+		}
+	}
+	for _, node := range cc.cluster.Nodes() {
+		address, member := metadata.Members[node.Name]
+		var command metadataCommand
+		switch {
+		case node.State == NodeAlive && (!member || address != node.Address):
+			command = metadataCommand{Op: metadataJoin, Name: node.Name, Address: node.Address}
+		case node.State == NodeDead && member:
+			command = metadataCommand{Op: metadataLeave, Name: node.Name}
+		default:
+			continue
+		}
+		if err := cc.propose(ctx, command); err != nil {
+			log.Printf("Ring change %s %s refused: %v", command.Op, command.Name, err)
+			return
+		}
+	}
+}
+
+func (cc *ClusterConsensus) propose(ctx context.Context, command metadataCommand) error {
+	payload, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	return cc.raft.Propose(ctx, payload)
+}
+
+// ringNodesLocked resolves the adapter of every member, cc.mu must be held
+func (cc *ClusterConsensus) ringNodesLocked(members map[string]string) map[string]adapter.AdapterInterface {
+	nodes := make(map[string]adapter.AdapterInterface, len(members))
+	for name, address := range members {
+		if name == cc.nodeRouter.GetLocalName() {
+			nodes[name] = cc.nodeRouter.GetLocalAdapter()
+			continue
+		}
+		remote, ok := cc.adapters[name]
+		if !ok || remote.Address() != adapter.BaseURL(address) {
+			remote = adapter.NewRemoteAdapter(address, cc.remotePolicy)
+			cc.adapters[name] = remote
+		}
+		nodes[name] = remote
+	}
+	return nodes
+}
+
+func (cc *ClusterConsensus) cloneLocked() ClusterMetadata {
+	metadata := cc.metadata
+	metadata.Members = maps.Clone(cc.metadata.Members)
+	metadata.Weights = maps.Clone(cc.metadata.Weights)
+	return metadata
+}
+
+// raftTransport carries raft RPCs over the /internal/raft routes of each voter
+type raftTransport struct {
+	peers map[string]*adapter.RemoteAdapter // voter name to adapter
+}
+
+func (tr raftTransport) RequestVote(peer string, req raft.VoteRequest) (raft.VoteResponse, error) {
+	remote, ok := tr.peers[peer]
+	if !ok {
+		return raft.VoteResponse{}, fmt.Errorf("unknown raft voter %s", peer)
+	}
+	return remote.RaftVote(req)
+}
+
+func (tr raftTransport) AppendEntries(peer string, req raft.AppendRequest) (raft.AppendResponse, error) {
+	remote, ok := tr.peers[peer]
+	if !ok {
+		return raft.AppendResponse{}, fmt.Errorf("unknown raft voter %s", peer)
+	}
+	return remote.RaftAppend(req)
+}
+
+func (tr raftTransport) Propose(peer string, command []byte) error {
+	remote, ok := tr.peers[peer]
+	if !ok {
+		return raft.ErrNoQuorum
+	}
+	if err := remote.RaftPropose(command); err != nil {
+		if errors.Is(err, internal.ErrUnavailable) {
+			return fmt.Errorf("%w: %v", raft.ErrNoQuorum, err) // the leader could not commit or be reached
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"go-cache-server-mini/internal/distributed/gossip"
+	"go-cache-server-mini/internal/distributed/raft"
+)
+
+// consensusTransport connects in-process consensus nodes, a node in down neither sends nor receives
+type consensusTransport struct {
+	mu    sync.Mutex
+	nodes map[string]*ClusterConsensus
+	down  map[string]bool
+}
+
+func (tr *consensusTransport) peer(from, to string) (*ClusterConsensus, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.down[from] || tr.down[to] {
+		return nil, errors.New("unreachable")
+	}
+	return tr.nodes[to], nil
+}
+
+type consensusNodeTransport struct {
+	*consensusTransport
+	from string
+}
+
+func (tr consensusNodeTransport) RequestVote(peer string, req raft.VoteRequest) (raft.VoteResponse, error) {
+	node, err := tr.peer(tr.from, peer)
+	if err != nil {
+		return raft.VoteResponse{}, err
+	}
+	return node.RequestVote(req)
+}
+
+func (tr consensusNodeTransport) AppendEntries(peer string, req raft.AppendRequest) (raft.AppendResponse, error) {
+	node, err := tr.peer(tr.from, peer)
+	if err != nil {
+		return raft.AppendResponse{}, err
+	}
+	return node.AppendEntries(req)
+}
+
+func (tr consensusNodeTransport) Propose(peer string, command []byte) error {
+	node, err := tr.peer(tr.from, peer)
+	if err != nil {
+		return raft.ErrNoQuorum
+	}
+	return node.Propose(context.Background(), command)
+}
+
+type consensusTestNode struct {
+	nodeRouter *NodeRouter
+	consensus  *ClusterConsensus
+}
+
+// startConsensusNodes runs count nodes that all see each other alive, agreeing on the ring through raft
+func startConsensusNodes(t *testing.T, count int) ([]consensusTestNode, *consensusTransport) {
+	t.Helper()
+	transport := &consensusTransport{nodes: make(map[string]*ClusterConsensus), down: make(map[string]bool)}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("node-%d", i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodes := make([]consensusTestNode, 0, count)
+	for i, name := range names {
+		testConfig := config.LoadTestConfig()
+		testConfig.Cluster.Enabled = true
+		testConfig.Cluster.NodeName = name
+		testConfig.Cluster.Address = fmt.Sprintf("127.0.0.1:%d", 18080+i)
+		testConfig.Cluster.HealthCheckInterval = 20
+		testConfig.Cluster.Placement = PlacementConsistentHash
+		testConfig.Cluster.Raft = config.RaftConfig{Enabled: true, ElectionTimeout: 50, HeartbeatInterval: 10, ProposeTimeout: 300}
+		nodeRouter := NewNodeRouter(ctx, testConfig, adapter.NewLocalAdapter(newTestNodeCache(t)))
+		manager := NewClusterManager(nodeRouter, testConfig)
+		var voters []string
+		for j, peer := range names {
+			if peer == name {
+				continue
+			}
+			voters = append(voters, peer)
+			manager.NotifyChange(gossip.Member{Name: peer, Address: fmt.Sprintf("127.0.0.1:%d", 18080+j), State: gossip.StateAlive})
+		}
+		consensus, err := newClusterConsensus(nodeRouter, manager, testConfig, voters, consensusNodeTransport{consensusTransport: transport, from: name})
+		if err != nil {
+			t.Fatalf("newClusterConsensus returned error: %v", err)
+		}
+		if nodeRouter.HasNode(voters[0]) {
+			t.Fatal("expected the ring to wait for raft instead of adding peers directly")
+		}
+		transport.nodes[name] = consensus
+		nodes = append(nodes, consensusTestNode{nodeRouter: nodeRouter, consensus: consensus})
+	}
+	for _, node := range nodes {
+		node.consensus.Start(ctx)
+	}
+	return nodes, transport
+}
+
+func waitForRing(t *testing.T, nodes []consensusTestNode, message string, condition func(slots ClusterSlots) bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		done := true
+		for _, node := range nodes {
+			if !condition(node.nodeRouter.Slots()) {
+				done = false
+				break
+			}
+		}
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(message)
+}
+
+func TestClusterConsensusAgreesOnRing(t *testing.T) {
+	nodes, _ := startConsensusNodes(t, 3)
+
+	waitForRing(t, nodes, "nodes did not agree on a ring of three", func(slots ClusterSlots) bool {
+		return len(slots.Nodes) == 3
+	})
+	epoch := nodes[0].nodeRouter.Slots().Epoch
+	for _, node := range nodes {
+		if slots := node.nodeRouter.Slots(); slots.Epoch != epoch {
+			t.Fatalf("expected every node on ring version %d, got %d", epoch, slots.Epoch)
+		}
+	}
+
+	// a change through any node reaches every ring
+	if err := nodes[1].consensus.Configure(context.Background(), PlacementRendezvous, 0, map[string]int{"node-2": 2}); err != nil {
+		t.Fatalf("Configure returned error: %v", err)
+	}
+	waitForRing(t, nodes, "placement change was not applied everywhere", func(slots ClusterSlots) bool {
+		return slots.Placement == PlacementRendezvous && slots.Nodes[2].Weight == 2
+	})
+	if err := nodes[0].consensus.Configure(context.Background(), "modulo", 0, nil); err == nil {
+		t.Fatal("expected an unknown placement to be refused")
+	}
+}
+
+func TestClusterConsensusRefusesChangesWithoutQuorum(t *testing.T) {
+	nodes, transport := startConsensusNodes(t, 3)
+	waitForRing(t, nodes, "nodes did not agree on a ring of three", func(slots ClusterSlots) bool {
+		return len(slots.Nodes) == 3
+	})
+
+	transport.mu.Lock()
+	transport.down["node-1"] = true
+	transport.down["node-2"] = true
+	transport.mu.Unlock()
+
+	err := nodes[0].consensus.Configure(context.Background(), PlacementJump, 0, nil)
+	if !errors.Is(err, raft.ErrNoQuorum) && !errors.Is(err, raft.ErrNotLeader) {
+		t.Fatalf("expected the change to be refused without quorum, got %v", err)
+	}
+	if placement := nodes[0].nodeRouter.Slots().Placement; placement != PlacementConsistentHash {
+		t.Fatalf("expected the ring to keep its placement, got %s", placement)
+	}
+}
@@ -0,0 +1,29 @@
+package router
+
+import "fmt"
+
+const (
+	ConsistencyOne    = "one"    // the first replica to answer
+	ConsistencyQuorum = "quorum" // a majority of the replicas of a key
+	ConsistencyAll    = "all"    // every replica of a key
+)
+
+func validConsistency(level string) error {
+	switch level {
+	case ConsistencyOne, ConsistencyQuorum, ConsistencyAll:
+		return nil
+	}
+	return fmt.Errorf("invalid consistency level %q, expected one, quorum or all", level)
+}
+
+// requiredReplicas returns how many of replicaCount replicas must answer at level
+func requiredReplicas(level string, replicaCount int) int {
+	switch level {
+	case ConsistencyAll:
+		return replicaCount
+	case ConsistencyQuorum:
+		return replicaCount/2 + 1
+	default:
+		return 1
+	}
+}
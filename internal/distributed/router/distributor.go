@@ -1,294 +1,895 @@
 package router
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"log"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 )
 
+const (
+	ReplicationSync  = "sync"  // writes default to "all": they return once every backup has a copy
+	ReplicationAsync = "async" // writes default to "one": backups follow in background
+)
+
+var (
+	errNoAdapter    = errors.New("no adapter found for key")
+	errLocalAdapter = errors.New("local adapter not found")
+)
+
 type Distributor struct {
-	nodeRouter *NodeRouter
+	nodeRouter         *NodeRouter
+	localOnly          bool   // serve every call from the local adapter (peer-to-peer calls)
+	redirect           bool   // answer MovedError for single keys owned by another node instead of forwarding
+	readConsistency    string // replicas that must answer a read
+	writeConsistency   string // replicas that must acknowledge a write
+	hints              *hintStore
+	hintReplayInterval time.Duration
 }
 
-func NewDistributor(nodeRouter *NodeRouter) *Distributor {
+func NewDistributor(nodeRouter *NodeRouter, config *config.Config) *Distributor {
+	replicationConfig := config.Cluster.Replication
+	readConsistency := replicationConfig.ReadConsistency
+	if validConsistency(readConsistency) != nil {
+		readConsistency = ConsistencyOne
+	}
+	writeConsistency := replicationConfig.WriteConsistency
+	if validConsistency(writeConsistency) != nil {
+		writeConsistency = ConsistencyAll
+		if replicationConfig.Mode == ReplicationAsync {
+			writeConsistency = ConsistencyOne
+		}
+	}
 	return &Distributor{
-		nodeRouter: nodeRouter,
+		nodeRouter:         nodeRouter,
+		redirect:           config.Cluster.Routing == RoutingRedirect,
+		readConsistency:    readConsistency,
+		writeConsistency:   writeConsistency,
+		hints:              newHintStore(replicationConfig.HintLimit),
+		hintReplayInterval: durationOrDefault(replicationConfig.HintReplayInterval, defaultHintReplayInterval),
+	}
+}
+
+// Local returns a distributor that never forwards, used for calls coming from other nodes
+func (d *Distributor) Local() DistributorInterface {
+	local := *d
+	local.localOnly = true
+	return &local
+}
+
+// WithConsistency returns a distributor that reads and writes at level instead of the configured defaults
+func (d *Distributor) WithConsistency(level string) (DistributorInterface, error) {
+	if err := validConsistency(level); err != nil {
+		return nil, err
+	}
+	leveled := *d
+	leveled.readConsistency = level
+	leveled.writeConsistency = level
+	return &leveled, nil
+}
+
+// replicas resolves the adapters holding key, the primary first and then its backups
+func (d *Distributor) replicas(key string) ([]adapter.AdapterInterface, error) {
+	if d.localOnly {
+		localAdapter := d.nodeRouter.GetLocalAdapter()
+		if localAdapter == nil {
+			return nil, errLocalAdapter
+		}
+		return []adapter.AdapterInterface{localAdapter}, nil
+	}
+	adapters, err := d.nodeRouter.GetAdapters(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(adapters) == 0 {
+		return nil, errNoAdapter
+	}
+	return adapters, nil
+}
+
+// allNodes returns every node a cluster-wide call has to reach, by name
+func (d *Distributor) allNodes() (map[string]adapter.AdapterInterface, error) {
+	if d.localOnly {
+		localAdapter := d.nodeRouter.GetLocalAdapter()
+		if localAdapter == nil {
+			return nil, errLocalAdapter
+		}
+		return map[string]adapter.AdapterInterface{d.nodeRouter.GetLocalName(): localAdapter}, nil
+	}
+	nodes := d.nodeRouter.GetNodes()
+	if len(nodes) == 0 {
+		return nil, errNoAdapter
+	}
+	return nodes, nil
+}
+
+// moved returns a *MovedError in redirect mode when another node owns key
+func (d *Distributor) moved(key string) error {
+	if !d.redirect || d.localOnly {
+		return nil
 	}
+	return d.nodeRouter.Moved(key)
 }
 
-func (d *Distributor) Set(key string, value []byte, expiration time.Duration) error {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return errors.New("local adapter not found")
+// read runs op on the primary of key, falling back to the backups while the node tried is unavailable
+func (d *Distributor) read(key string, op func(adapterInst adapter.AdapterInterface) error) error {
+	if err := d.moved(key); err != nil {
+		return err
 	}
-	if err := localAdapter.SetItem(key, value, expiration); err != nil {
+	adapters, err := d.replicas(key)
+	if err != nil {
 		return err
 	}
+	for _, adapterInst := range adapters {
+		err = op(adapterInst)
+		if !errors.Is(err, internal.ErrUnavailable) {
+			return err
+		}
+	}
+	return err
+}
 
-	// TODO: Optimize by setting only on relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return err
-	// }
-	// go func() {
-	// 	for _, adapter := range adapters {
-	// 		adapter.SetItem(key, value, expiration)
-	// 	}
-	// }()
-	return nil
+// readPrevious asks the nodes that held key before a ring change still being rebalanced,
+// for keys their new replicas do not have yet
+func (d *Distributor) readPrevious(key string, op func(adapterInst adapter.AdapterInterface) (bool, error)) bool {
+	if d.localOnly {
+		return false
+	}
+	previous := d.nodeRouter.GetPreviousAdapters(key)
+	if len(previous) == 0 {
+		return false
+	}
+	current, err := d.replicas(key)
+	if err != nil {
+		return false
+	}
+	for _, adapterInst := range previous {
+		if slices.Contains(current, adapterInst) {
+			continue // already asked
+		}
+		if found, err := op(adapterInst); err == nil && found {
+			return true
+		}
+	}
+	return false
 }
 
-func (d *Distributor) Get(key string) ([]byte, bool, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return nil, false, errors.New("local adapter not found")
+// write runs op on the primary of key and then copies the result to the backups
+func (d *Distributor) write(ctx context.Context, key string, op func(adapterInst adapter.AdapterInterface) error) error {
+	if err := d.moved(key); err != nil {
+		return err
+	}
+	adapters, err := d.replicas(key)
+	if err != nil {
+		return err
 	}
-	if value, found := localAdapter.GetItem(key); found {
-		return value, true, nil
+	if err := op(adapters[0]); err != nil {
+		return err
+	}
+	if len(adapters) == 1 {
+		return nil
+	}
+	return d.replicate(ctx, adapters[0], map[string][]adapter.AdapterInterface{key: adapters[1:]})
+}
+
+// readGroups calls fetch once per primary with the keys it owns. Keys of an unavailable
+// node are fetched again from their next replica.
+func (d *Distributor) readGroups(ctx context.Context, keys []string, fetch func(adapterInst adapter.AdapterInterface, keys []string) error) error {
+	replicaSets := make(map[string][]adapter.AdapterInterface, len(keys))
+	for _, key := range keys {
+		adapters, err := d.replicas(key)
+		if err != nil {
+			return err
+		}
+		replicaSets[key] = adapters
 	}
 
-	// TODO: Optimize by getting from only relevant adapters
-	return nil, false, nil
+	pending := keys
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		groups := make(map[adapter.AdapterInterface][]string)
+		for _, key := range pending {
+			if attempt >= len(replicaSets[key]) {
+				return internal.ErrUnavailable // every replica of key failed
+			}
+			adapterInst := replicaSets[key][attempt]
+			groups[adapterInst] = append(groups[adapterInst], key)
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var errs []error
+		var retry []string
+		for adapterInst, groupKeys := range groups {
+			wg.Add(1)
+			go func(adapterInst adapter.AdapterInterface, groupKeys []string) {
+				defer wg.Done()
+				err := fetch(adapterInst, groupKeys)
+				if err == nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if errors.Is(err, internal.ErrUnavailable) {
+					retry = append(retry, groupKeys...)
+				} else {
+					errs = append(errs, err)
+				}
+			}(adapterInst, groupKeys)
+		}
+		wg.Wait()
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		pending = retry
+	}
+	return nil
 }
 
-func (d *Distributor) Del(key string) error {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return errors.New("local adapter not found")
+// replicate copies the entries the primary now holds for each key to that key's backups.
+// Keys gone from the primary are deleted from the backups at the primary's delete version, so a
+// backup keeps a copy written after the delete.
+func (d *Distributor) replicate(ctx context.Context, primary adapter.AdapterInterface, backups map[string][]adapter.AdapterInterface) error {
+	keys := make([]string, 0, len(backups))
+	for key := range backups {
+		keys = append(keys, key)
 	}
-	if err := localAdapter.DeleteItem(key); err != nil {
+	entries, err := primary.GetEntries(ctx, keys)
+	if err != nil {
 		return err
 	}
+	var missing []string
+	for _, key := range keys {
+		if _, exists := entries[key]; !exists {
+			missing = append(missing, key)
+		}
+	}
+	tombstones := map[string]uint64{}
+	if len(missing) > 0 {
+		if tombstones, err = primary.GetTombstones(ctx, missing); err != nil {
+			return err
+		}
+	}
+
+	copies := make(map[adapter.AdapterInterface]map[string]data.CacheItem)
+	deletes := make(map[adapter.AdapterInterface]map[string]uint64)
+	for key, targets := range backups {
+		item, exists := entries[key]
+		for _, target := range targets {
+			if !exists {
+				version, deleted := tombstones[key]
+				if !deleted {
+					continue // expired, the backups expire their copies on their own
+				}
+				if deletes[target] == nil {
+					deletes[target] = make(map[string]uint64)
+				}
+				deletes[target][key] = version
+				continue
+			}
+			if copies[target] == nil {
+				copies[target] = make(map[string]data.CacheItem)
+			}
+			copies[target][key] = item
+		}
+	}
+
+	// every push reports the keys it stored, failures are only logged so async copies leave a trace.
+	// Pushes are detached from ctx: a caller that stops waiting must not leave backups half written.
+	detached := context.WithoutCancel(ctx)
+	results := make(chan []string, len(copies)+len(deletes))
+	for target, targetEntries := range copies {
+		go func(target adapter.AdapterInterface, targetEntries map[string]data.CacheItem) {
+			if err := target.SetEntries(detached, targetEntries); err != nil {
+				log.Printf("Error replicating %d keys to a backup node: %v", len(targetEntries), err)
+				if errors.Is(err, internal.ErrUnavailable) {
+					d.hints.storeEntries(target, targetEntries)
+				}
+				results <- nil
+				return
+			}
+			stored := make([]string, 0, len(targetEntries))
+			for key := range targetEntries {
+				stored = append(stored, key)
+			}
+			results <- stored
+		}(target, targetEntries)
+	}
+	for target, targetVersions := range deletes {
+		go func(target adapter.AdapterInterface, targetVersions map[string]uint64) {
+			if err := target.DeleteEntries(detached, targetVersions); err != nil {
+				log.Printf("Error deleting %d keys from a backup node: %v", len(targetVersions), err)
+				if errors.Is(err, internal.ErrUnavailable) {
+					d.hints.storeDeletes(target, targetVersions)
+				}
+				results <- nil
+				return
+			}
+			results <- slices.Collect(maps.Keys(targetVersions))
+		}(target, targetVersions)
+	}
 
-	// TODO: Optimize by deleting from only relevant adapters
+	// the primary has already acknowledged every key, wait only for the missing acknowledgements
+	acks := make(map[string]int, len(backups))
+	required := make(map[string]int, len(backups))
+	short := 0
+	for key, targets := range backups {
+		acks[key] = 1
+		if _, exists := entries[key]; !exists {
+			if _, deleted := tombstones[key]; !deleted {
+				acks[key] += len(targets) // nothing to send
+			}
+		}
+		required[key] = requiredReplicas(d.writeConsistency, len(targets)+1)
+		if acks[key] < required[key] {
+			short++
+		}
+	}
+	for pending := len(copies) + len(deletes); short > 0 && pending > 0; pending-- {
+		var stored []string
+		select {
+		case stored = <-results:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		for _, key := range stored {
+			acks[key]++
+			if acks[key] == required[key] {
+				short--
+			}
+		}
+	}
+	if short > 0 {
+		return fmt.Errorf("%w: %d of %d keys were not acknowledged by %s of their replicas", internal.ErrQuorum, short, len(backups), d.writeConsistency)
+	}
 	return nil
 }
 
-func (d *Distributor) Exists(key string) (bool, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return false, errors.New("local adapter not found")
+// readQuorum asks every replica of keys for its stored item and keeps the newest version of each key.
+// It fails when fewer replicas than the read consistency answered for any key. A key some replicas
+// lack is missing when one of them deleted it after the newest copy was written. Replicas that
+// answered with a missing or older item get the newest one pushed back, or the delete (read repair).
+func (d *Distributor) readQuorum(ctx context.Context, keys []string) (map[string]data.CacheItem, error) {
+	replicaCounts := make(map[string]int, len(keys))
+	groups := make(map[adapter.AdapterInterface][]string)
+	for _, key := range keys {
+		adapters, err := d.replicas(key)
+		if err != nil {
+			return nil, err
+		}
+		replicaCounts[key] = len(adapters)
+		for _, adapterInst := range adapters {
+			groups[adapterInst] = append(groups[adapterInst], key)
+		}
 	}
-	if localAdapter.ExistsItem(key) {
-		return true, nil
+
+	newest := make(map[string]data.CacheItem, len(keys))
+	answers := make(map[string]int, len(keys))
+	versions := make(map[adapter.AdapterInterface]map[string]uint64, len(groups)) // 0 when missing
+	var lastErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for adapterInst, groupKeys := range groups {
+		wg.Add(1)
+		go func(adapterInst adapter.AdapterInterface, groupKeys []string) {
+			defer wg.Done()
+			entries, err := adapterInst.GetEntries(ctx, groupKeys)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+				return
+			}
+			versions[adapterInst] = make(map[string]uint64, len(groupKeys))
+			for _, key := range groupKeys {
+				answers[key]++
+				item, found := entries[key]
+				versions[adapterInst][key] = item.Version
+				if current, seen := newest[key]; found && (!seen || item.Version > current.Version) {
+					newest[key] = item
+				}
+			}
+		}(adapterInst, groupKeys)
 	}
+	wg.Wait()
+	deletes := d.readDeletes(ctx, newest, versions)
+	d.readRepair(ctx, newest, deletes, versions)
 
-	// TODO: Optimize by checking only relevant adapters
-	return false, nil
+	for _, key := range keys {
+		if required := requiredReplicas(d.readConsistency, replicaCounts[key]); answers[key] < required {
+			return nil, fmt.Errorf("%w: %d of %d replicas answered for %s, %s needs %d (last error: %v)",
+				internal.ErrQuorum, answers[key], replicaCounts[key], key, d.readConsistency, required, lastErr)
+		}
+	}
+	return newest, nil
 }
 
-func (d *Distributor) Keys() ([]string, error) {
-	var allKeys []string
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter != nil {
-		keys := localAdapter.ListKeys()
-		allKeys = append(allKeys, keys...)
-	} else {
-		return nil, errors.New("local adapter not found")
+// readDeletes asks the replicas that answered without a key another replica holds for their delete
+// version of it. It returns the keys deleted after their newest copy was written, with the newest
+// delete version, and removes them from newest.
+func (d *Distributor) readDeletes(ctx context.Context, newest map[string]data.CacheItem, versions map[adapter.AdapterInterface]map[string]uint64) map[string]uint64 {
+	asks := make(map[adapter.AdapterInterface][]string)
+	for adapterInst, keyVersions := range versions {
+		for key, version := range keyVersions {
+			if _, found := newest[key]; found && version == 0 {
+				asks[adapterInst] = append(asks[adapterInst], key)
+			}
+		}
+	}
+	deletes := make(map[string]uint64)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for adapterInst, keys := range asks {
+		wg.Add(1)
+		go func(adapterInst adapter.AdapterInterface, keys []string) {
+			defer wg.Done()
+			tombstones, err := adapterInst.GetTombstones(ctx, keys)
+			if err != nil {
+				log.Printf("Error reading the deletes of %d keys from a replica: %v", len(keys), err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for key, version := range tombstones {
+				deletes[key] = max(deletes[key], version)
+			}
+		}(adapterInst, keys)
+	}
+	wg.Wait()
+	for key, version := range deletes {
+		if version > newest[key].Version {
+			delete(newest, key)
+		} else {
+			delete(deletes, key)
+		}
 	}
-	// TODO: Consider fetching keys from other adapters if needed
-	return allKeys, nil
+	return deletes
 }
 
-func (d *Distributor) Flush() error {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return errors.New("local adapter not found")
+// readRepair pushes the newest items, and the deletes that beat them, to the replicas that answered
+// with a stale copy, in background
+func (d *Distributor) readRepair(ctx context.Context, newest map[string]data.CacheItem, deletes map[string]uint64, versions map[adapter.AdapterInterface]map[string]uint64) {
+	repairs := make(map[adapter.AdapterInterface]map[string]data.CacheItem)
+	deleteRepairs := make(map[adapter.AdapterInterface]map[string]uint64)
+	for adapterInst, keyVersions := range versions {
+		for key, version := range keyVersions {
+			if deleted, found := deletes[key]; found && version > 0 {
+				if deleteRepairs[adapterInst] == nil {
+					deleteRepairs[adapterInst] = make(map[string]uint64)
+				}
+				deleteRepairs[adapterInst][key] = deleted
+				continue
+			}
+			item, found := newest[key]
+			if !found || version >= item.Version {
+				continue
+			}
+			if repairs[adapterInst] == nil {
+				repairs[adapterInst] = make(map[string]data.CacheItem)
+			}
+			repairs[adapterInst][key] = item
+		}
+	}
+	detached := context.WithoutCancel(ctx)
+	for adapterInst, entries := range repairs {
+		go func(adapterInst adapter.AdapterInterface, entries map[string]data.CacheItem) {
+			if err := adapterInst.SetEntries(detached, entries); err != nil {
+				log.Printf("Error repairing %d stale keys on a replica: %v", len(entries), err)
+			}
+		}(adapterInst, entries)
+	}
+	for adapterInst, versions := range deleteRepairs {
+		go func(adapterInst adapter.AdapterInterface, versions map[string]uint64) {
+			if err := adapterInst.DeleteEntries(detached, versions); err != nil {
+				log.Printf("Error repairing %d deleted keys on a replica: %v", len(versions), err)
+			}
+		}(adapterInst, versions)
 	}
-	// TODO: Consider flushing other adapters if needed
-	return localAdapter.ClearCache()
 }
 
-func (d *Distributor) TTL(key string) (time.Duration, bool, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return 0, false, errors.New("local adapter not found")
+// readItem reads the newest stored item of key when the read consistency needs more than one replica
+func (d *Distributor) readItem(ctx context.Context, key string) (data.CacheItem, bool, error) {
+	if err := d.moved(key); err != nil {
+		return data.CacheItem{}, false, err
 	}
-	if ttl, found := localAdapter.GetTTL(key); found {
-		return ttl, true, nil
+	entries, err := d.readQuorum(ctx, []string{key})
+	if err != nil {
+		return data.CacheItem{}, false, err
 	}
-	// TODO: Optimize by getting from only relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return 0, false
-	// }
-	// for _, adapter := range adapters {
-	// 	if ttl, found := adapter.GetTTL(key); found {
-	// 		return ttl, true, nil
-	// 	}
-	// }
-	return 0, false, nil
+	item, found := entries[key]
+	return item, found, nil
+}
+
+func (d *Distributor) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.SetItem(ctx, key, value, expiration)
+	})
+}
+
+func (d *Distributor) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return d.getValue(ctx, key, adapter.AdapterInterface.GetItem)
 }
 
-func (d *Distributor) Expire(key string, expiration time.Duration) error {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return errors.New("local adapter not found")
+// getValue reads the string at key with get, from the nodes that held key before a ring change
+// too while it is missing
+func (d *Distributor) getValue(ctx context.Context, key string, get func(adapterInst adapter.AdapterInterface, ctx context.Context, key string) ([]byte, bool, error)) ([]byte, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		item, found, err := d.readItem(ctx, key)
+		if item.Type != data.TypeString {
+			return nil, false, err
+		}
+		return item.Value, found, err
 	}
-	if err := localAdapter.UpdateExpiration(key, expiration); err != nil {
+	var value []byte
+	var found bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		value, found, err = get(adapterInst, ctx, key)
 		return err
+	})
+	if err == nil && !found {
+		found = d.readPrevious(key, func(adapterInst adapter.AdapterInterface) (bool, error) {
+			var previousFound bool
+			var err error
+			value, previousFound, err = get(adapterInst, ctx, key)
+			return previousFound, err
+		})
 	}
-	// TODO: Optimize by updating only on relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return err
-	// }
-	// for _, adapter := range adapters {
-	// 	if err := adapter.UpdateExpiration(key, expiration); err != nil {
-	// 		return err
-	// 	}
-	// }
-	return nil
+	return value, found, err
 }
 
-func (d *Distributor) Persist(key string) error {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return errors.New("local adapter not found")
+func (d *Distributor) Del(ctx context.Context, key string) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.DeleteItem(ctx, key)
+	})
+}
+
+func (d *Distributor) Exists(ctx context.Context, key string) (bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		_, found, err := d.readItem(ctx, key)
+		return found, err
 	}
-	if err := localAdapter.RemoveExpiration(key); err != nil {
+	var exists bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		exists, err = adapterInst.ExistsItem(ctx, key)
 		return err
+	})
+	if err == nil && !exists {
+		exists = d.readPrevious(key, func(adapterInst adapter.AdapterInterface) (bool, error) {
+			return adapterInst.ExistsItem(ctx, key)
+		})
 	}
-	// TODO: Optimize by removing only from relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return err
-	// }
-	// for _, adapter := range adapters {
-	// 	if err := adapter.RemoveExpiration(key); err != nil {
-	// 		return err
-	// 	}
-	// }
-	return nil
+	return exists, err
 }
 
-func (d *Distributor) Incr(key string) (int64, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return 0, errors.New("local adapter not found")
+// Keys lists the keys of every node, the summary tells which nodes answered
+func (d *Distributor) Keys(ctx context.Context) ([]string, FanOutSummary, error) {
+	nodes, err := d.allNodes()
+	if err != nil {
+		return nil, FanOutSummary{}, err
 	}
-	val, err := localAdapter.Increment(key)
+	var mu sync.Mutex
+	seen := make(map[string]struct{})
+	allKeys := []string{}
+	summary := fanOut(ctx, nodes, func(ctx context.Context, adapterInst adapter.AdapterInterface) (int, error) {
+		keys, err := adapterInst.ListKeys(ctx)
+		if err != nil {
+			return 0, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// backups hold copies of keys owned by other nodes
+		for _, key := range keys {
+			if _, dup := seen[key]; !dup {
+				seen[key] = struct{}{}
+				allKeys = append(allKeys, key)
+			}
+		}
+		return len(keys), nil
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, summary, err
+	}
+	return allKeys, summary, nil
+}
+
+// Flush clears every node, the summary tells which nodes did not
+func (d *Distributor) Flush(ctx context.Context) (FanOutSummary, error) {
+	nodes, err := d.allNodes()
 	if err != nil {
-		return 0, err
-	}
-
-	// TODO: Optimize by incrementing only on relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return 0, err
-	// }
-	// var result int64
-	// for _, adapter := range adapters {
-	// 	val, err := adapter.Increment(key)
-	// 	if err != nil {
-	// 		return 0, err
-	// 	}
-	// 	result = val
-	// }
-	return val, nil
-}
-
-func (d *Distributor) Decr(key string) (int64, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return 0, errors.New("local adapter not found")
-	}
-	val, err := localAdapter.Decrement(key)
+		return FanOutSummary{}, err
+	}
+	summary := fanOut(ctx, nodes, func(ctx context.Context, adapterInst adapter.AdapterInterface) (int, error) {
+		return 0, adapterInst.ClearCache(ctx)
+	})
+	return summary, ctx.Err()
+}
+
+func (d *Distributor) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		item, found, err := d.readItem(ctx, key)
+		if err != nil || !found {
+			return 0, false, err
+		}
+		if item.Persistent {
+			return -1, true, nil
+		}
+		return time.Until(item.Expiration), true, nil
+	}
+	var ttl time.Duration
+	var found bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		ttl, found, err = adapterInst.GetTTL(ctx, key)
+		return err
+	})
+	if err == nil && !found {
+		found = d.readPrevious(key, func(adapterInst adapter.AdapterInterface) (bool, error) {
+			var previousFound bool
+			var err error
+			ttl, previousFound, err = adapterInst.GetTTL(ctx, key)
+			return previousFound, err
+		})
+	}
+	return ttl, found, err
+}
+
+func (d *Distributor) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.UpdateExpiration(ctx, key, expiration)
+	})
+}
+
+func (d *Distributor) Persist(ctx context.Context, key string) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.RemoveExpiration(ctx, key)
+	})
+}
+
+func (d *Distributor) Incr(ctx context.Context, key string) (int64, error) {
+	var value int64
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		value, err = adapterInst.Increment(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (d *Distributor) Decr(ctx context.Context, key string) (int64, error) {
+	var value int64
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		value, err = adapterInst.Decrement(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (d *Distributor) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	var success bool
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		success, err = adapterInst.SetIfNotExists(ctx, key, value, expiration)
+		return err
+	})
+	return success, err
+}
+
+func (d *Distributor) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	var oldValue []byte
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		oldValue, err = adapterInst.GetAndSet(ctx, key, value)
+		return err
+	})
+	return oldValue, err
+}
+
+func (d *Distributor) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	if d.readConsistency != ConsistencyOne {
+		entries, err := d.readQuorum(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		for key, item := range entries {
+			if item.Type == data.TypeString {
+				result[key] = item.Value
+			}
+		}
+		return result, nil
+	}
+	var mu sync.Mutex
+	err := d.readGroups(ctx, keys, func(adapterInst adapter.AdapterInterface, groupKeys []string) error {
+		kv, err := adapterInst.GetMultiple(ctx, groupKeys)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for key, value := range kv {
+			result[key] = value
+		}
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
-		return 0, err
-	}
-	// TODO: Optimize by decrementing only on relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return 0, err
-	// }
-	// var result int64
-	// for _, adapter := range adapters {
-	// 	val, err := adapter.Decrement(key)
-	// 	if err != nil {
-	// 		return 0, err
-	// 	}
-	// 	result = val
-	// }
-	return val, nil
-}
-
-func (d *Distributor) SetNX(key string, value []byte, expiration time.Duration) (bool, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return false, errors.New("local adapter not found")
-	}
-	success, err := localAdapter.SetIfNotExists(key, value, expiration)
+		return nil, err
+	}
+	for _, key := range keys {
+		if _, ok := result[key]; ok {
+			continue
+		}
+		d.readPrevious(key, func(adapterInst adapter.AdapterInterface) (bool, error) {
+			value, found, err := adapterInst.GetItem(ctx, key)
+			if found {
+				result[key] = value
+			}
+			return found, err
+		})
+	}
+	return result, nil
+}
+
+func (d *Distributor) MSet(ctx context.Context, kv map[string][]byte, expiration time.Duration) error {
+	type ownerGroup struct {
+		kv      map[string][]byte
+		backups map[string][]adapter.AdapterInterface
+	}
+	groups := make(map[adapter.AdapterInterface]*ownerGroup)
+	for key, value := range kv {
+		adapters, err := d.replicas(key)
+		if err != nil {
+			return err
+		}
+		group, ok := groups[adapters[0]]
+		if !ok {
+			group = &ownerGroup{kv: make(map[string][]byte), backups: make(map[string][]adapter.AdapterInterface)}
+			groups[adapters[0]] = group
+		}
+		group.kv[key] = value
+		if len(adapters) > 1 {
+			group.backups[key] = adapters[1:]
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	for owner, group := range groups {
+		wg.Add(1)
+		go func(owner adapter.AdapterInterface, group *ownerGroup) {
+			defer wg.Done()
+			err := owner.SetMultiple(ctx, group.kv, expiration)
+			if err == nil && len(group.backups) > 0 {
+				err = d.replicate(ctx, owner, group.backups)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(owner, group)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// GetEntries returns the stored items of keys, with their expiration
+func (d *Distributor) GetEntries(ctx context.Context, keys []string) (map[string]data.CacheItem, error) {
+	result := make(map[string]data.CacheItem, len(keys))
+	var mu sync.Mutex
+	err := d.readGroups(ctx, keys, func(adapterInst adapter.AdapterInterface, groupKeys []string) error {
+		entries, err := adapterInst.GetEntries(ctx, groupKeys)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for key, item := range entries {
+			result[key] = item
+		}
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
-		return false, err
-	}
-	if success {
-		return true, nil
-	}
-	// TODO: Optimize by setting only on relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return false, err
-	// }
-	// var setSuccess bool
-	// for _, adapter := range adapters {
-	// 	success, err := adapter.SetIfNotExists(key, value, expiration)
-	// 	if err != nil {
-	// 		return false, err
-	// 	}
-	// 	if success {
-	// 		setSuccess = true
-	// 	}
-	// }
-	return success, nil
-}
-
-func (d *Distributor) GetSet(key string, value []byte) ([]byte, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return nil, errors.New("local adapter not found")
-	}
-	oldValue, err := localAdapter.GetAndSet(key, value)
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetEntries stores items as they are on every replica of their keys
+func (d *Distributor) SetEntries(ctx context.Context, entries map[string]data.CacheItem) error {
+	groups := make(map[adapter.AdapterInterface]map[string]data.CacheItem)
+	for key, item := range entries {
+		adapters, err := d.replicas(key)
+		if err != nil {
+			return err
+		}
+		for _, adapterInst := range adapters {
+			if groups[adapterInst] == nil {
+				groups[adapterInst] = make(map[string]data.CacheItem)
+			}
+			groups[adapterInst][key] = item
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	for adapterInst, groupEntries := range groups {
+		wg.Add(1)
+		go func(adapterInst adapter.AdapterInterface, groupEntries map[string]data.CacheItem) {
+			defer wg.Done()
+			if err := adapterInst.SetEntries(ctx, groupEntries); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(adapterInst, groupEntries)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// GetTombstones returns the delete versions of keys deleted and not written since
+func (d *Distributor) GetTombstones(ctx context.Context, keys []string) (map[string]uint64, error) {
+	result := make(map[string]uint64, len(keys))
+	var mu sync.Mutex
+	err := d.readGroups(ctx, keys, func(adapterInst adapter.AdapterInterface, groupKeys []string) error {
+		versions, err := adapterInst.GetTombstones(ctx, groupKeys)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		maps.Copy(result, versions)
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if oldValue != nil {
-		return oldValue, nil
-	}
-
-	// TODO : Optimize by getting and setting on only relevant adapters
-	// adapters, err := d.nodeRouter.GetAdapters(key)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// var oldValue []byte
-	// for _, adapter := range adapters {
-	// 	val, err := adapter.GetAndSet(key, value)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	oldValue = val
-	// }
-	return oldValue, nil
-}
-
-func (d *Distributor) MGet(keys []string) (map[string][]byte, error) {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return nil, errors.New("local adapter not found")
-	}
-	result := localAdapter.GetMultiple(keys)
-	// TODO: Optimize by getting from only relevant adapters
 	return result, nil
 }
 
-func (d *Distributor) MSet(kv map[string][]byte, expiration time.Duration) error {
-	localAdapter := d.nodeRouter.GetLocalAdapter()
-	if localAdapter == nil {
-		return errors.New("local adapter not found")
+// DeleteEntries deletes keys older than their delete version on every replica of the keys
+func (d *Distributor) DeleteEntries(ctx context.Context, versions map[string]uint64) error {
+	groups := make(map[adapter.AdapterInterface]map[string]uint64)
+	for key, version := range versions {
+		adapters, err := d.replicas(key)
+		if err != nil {
+			return err
+		}
+		for _, adapterInst := range adapters {
+			if groups[adapterInst] == nil {
+				groups[adapterInst] = make(map[string]uint64)
+			}
+			groups[adapterInst][key] = version
+		}
 	}
-	if err := localAdapter.SetMultiple(kv, expiration); err != nil {
-		return err
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	for adapterInst, groupVersions := range groups {
+		wg.Add(1)
+		go func(adapterInst adapter.AdapterInterface, groupVersions map[string]uint64) {
+			defer wg.Done()
+			if err := adapterInst.DeleteEntries(ctx, groupVersions); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(adapterInst, groupVersions)
 	}
-	// TODO: Optimize by setting only on relevant adapters
-	return nil
+	wg.Wait()
+	return errors.Join(errs...)
 }
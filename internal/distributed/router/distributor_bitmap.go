@@ -0,0 +1,152 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"time"
+)
+
+// SetRaw and GetRaw move string values that are not JSON, such as bitmaps, between nodes as base64
+
+func (d *Distributor) SetRaw(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.SetRawItem(ctx, key, value, expiration)
+	})
+}
+
+func (d *Distributor) GetRaw(ctx context.Context, key string) ([]byte, bool, error) {
+	return d.getValue(ctx, key, adapter.AdapterInterface.GetRawItem)
+}
+
+func (d *Distributor) SetBit(ctx context.Context, key string, offset int64, bit int) (int, error) {
+	var previous int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		previous, err = adapterInst.BitmapSetBit(ctx, key, offset, bit)
+		return err
+	})
+	return previous, err
+}
+
+func (d *Distributor) GetBit(ctx context.Context, key string, offset int64) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		value, err := d.readString(ctx, key)
+		return data.GetBit(value, offset), err
+	}
+	var bit int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		bit, err = adapterInst.BitmapGetBit(ctx, key, offset)
+		return err
+	})
+	return bit, err
+}
+
+func (d *Distributor) BitCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error) {
+	if d.readConsistency != ConsistencyOne {
+		value, err := d.readString(ctx, key)
+		return data.BitCount(value, bitRange), err
+	}
+	var count int64
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		count, err = adapterInst.BitmapCount(ctx, key, bitRange)
+		return err
+	})
+	return count, err
+}
+
+func (d *Distributor) BitPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error) {
+	if d.readConsistency != ConsistencyOne {
+		value, err := d.readString(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		return data.BitPos(value, bit, bitRange)
+	}
+	var position int64
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		position, err = adapterInst.BitmapPos(ctx, key, bit, bitRange)
+		return err
+	})
+	return position, err
+}
+
+// BitOp runs on the primary of dest when dest and keys share their replicas, so it stays atomic.
+// Otherwise it reads the strings node by node, combines them here and writes the result to dest.
+func (d *Distributor) BitOp(ctx context.Context, op, dest string, keys []string) (int, error) {
+	if _, shared := d.sharedReplicas(append([]string{dest}, keys...)); shared && d.readConsistency == ConsistencyOne {
+		var length int
+		err := d.write(ctx, dest, func(adapterInst adapter.AdapterInterface) error {
+			var err error
+			length, err = adapterInst.BitmapOp(ctx, op, dest, keys)
+			return err
+		})
+		return length, err
+	}
+	var items map[string]data.CacheItem
+	var err error
+	if d.readConsistency != ConsistencyOne {
+		items, err = d.readQuorum(ctx, keys)
+	} else {
+		items, err = d.GetEntries(ctx, keys)
+	}
+	if err != nil {
+		return 0, err
+	}
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		item, found := items[key]
+		if found && item.Type != data.TypeString {
+			return 0, internal.ErrWrongType
+		}
+		values[i] = item.Value
+	}
+	result, err := data.BitOp(op, values)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, d.Del(ctx, dest)
+	}
+	return len(result), d.SetRaw(ctx, dest, result, 0)
+}
+
+// BitField is a read when every op is a get, a write otherwise
+func (d *Distributor) BitField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error) {
+	var results []*int64
+	run := func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		results, err = adapterInst.BitmapField(ctx, key, ops)
+		return err
+	}
+	if !data.BitFieldReadOnly(ops) {
+		err := d.write(ctx, key, run)
+		return results, err
+	}
+	if d.readConsistency != ConsistencyOne {
+		value, err := d.readString(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		_, results, err = data.BitField(value, ops)
+		return results, err
+	}
+	err := d.read(key, run)
+	return results, err
+}
+
+// readString reads the newest string stored at key from the replicas the read consistency asks for
+func (d *Distributor) readString(ctx context.Context, key string) ([]byte, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeString {
+		return nil, internal.ErrWrongType
+	}
+	return item.Value, nil
+}
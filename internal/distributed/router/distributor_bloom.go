@@ -0,0 +1,47 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) BFReserve(ctx context.Context, key string, options data.BloomOptions) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.BloomReserve(ctx, key, options)
+	})
+}
+
+func (d *Distributor) BFAdd(ctx context.Context, key string, items []string) ([]bool, error) {
+	var added []bool
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.BloomAdd(ctx, key, items)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) BFExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		item, found, err := d.readItem(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return make([]bool, len(items)), nil
+		}
+		if item.Type != data.TypeBloom {
+			return nil, internal.ErrWrongType
+		}
+		return item.Bloom.Exists(items...), nil
+	}
+	var exists []bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		exists, err = adapterInst.BloomExists(ctx, key, items)
+		return err
+	})
+	return exists, err
+}
@@ -0,0 +1,83 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) CFReserve(ctx context.Context, key string, options data.CuckooOptions) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.CuckooReserve(ctx, key, options)
+	})
+}
+
+func (d *Distributor) CFAdd(ctx context.Context, key, item string, nx bool) (bool, error) {
+	var added bool
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.CuckooAdd(ctx, key, item, nx)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) CFExists(ctx context.Context, key string, items []string) ([]bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		filter, err := d.readCuckoo(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil {
+			return make([]bool, len(items)), nil
+		}
+		return filter.Exists(items...), nil
+	}
+	var exists []bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		exists, err = adapterInst.CuckooExists(ctx, key, items)
+		return err
+	})
+	return exists, err
+}
+
+func (d *Distributor) CFCount(ctx context.Context, key, item string) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		filter, err := d.readCuckoo(ctx, key)
+		if err != nil || filter == nil {
+			return 0, err
+		}
+		return filter.Count(item), nil
+	}
+	var count int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		count, err = adapterInst.CuckooCount(ctx, key, item)
+		return err
+	})
+	return count, err
+}
+
+func (d *Distributor) CFDel(ctx context.Context, key, item string) (bool, error) {
+	var deleted bool
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		deleted, err = adapterInst.CuckooDelete(ctx, key, item)
+		return err
+	})
+	return deleted, err
+}
+
+// readCuckoo reads the cuckoo filter at key from the replicas the read consistency asks for, nil when it is missing
+func (d *Distributor) readCuckoo(ctx context.Context, key string) (*data.CuckooFilter, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeCuckoo {
+		return nil, internal.ErrWrongType
+	}
+	return item.Cuckoo, nil
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+// A geospatial index is a sorted set, so reads past ConsistencyOne resolve it like ZRange does with readZSet
+
+func (d *Distributor) GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error) {
+	var added int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.GeoAdd(ctx, key, members, options)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return data.GeoPositions(zset, members), nil
+	}
+	var positions []*data.GeoPoint
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		positions, err = adapterInst.GeoPos(ctx, key, members)
+		return err
+	})
+	return positions, err
+}
+
+func (d *Distributor) GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		if err != nil {
+			return 0, false, err
+		}
+		return data.GeoDist(zset, from, to, unit)
+	}
+	var distance float64
+	var found bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		distance, found, err = adapterInst.GeoDist(ctx, key, from, to, unit)
+		return err
+	})
+	return distance, found, err
+}
+
+func (d *Distributor) GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return data.GeoSearch(zset, query)
+	}
+	var results []data.GeoResult
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		results, err = adapterInst.GeoSearch(ctx, key, query)
+		return err
+	})
+	return results, err
+}
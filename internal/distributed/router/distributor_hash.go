@@ -0,0 +1,121 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) HSet(ctx context.Context, key string, fields map[string][]byte) (int, error) {
+	var added int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.HashSet(ctx, key, fields)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) HGet(ctx context.Context, key, field string) ([]byte, bool, error) {
+	values, err := d.HMGet(ctx, key, []string{field})
+	if err != nil {
+		return nil, false, err
+	}
+	value, exists := values[field]
+	return value, exists, nil
+}
+
+func (d *Distributor) HMGet(ctx context.Context, key string, fields []string) (map[string][]byte, error) {
+	if d.readConsistency != ConsistencyOne {
+		hash, err := d.readHash(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string][]byte, len(fields))
+		for _, field := range fields {
+			if value, exists := hash[field]; exists {
+				result[field] = value
+			}
+		}
+		return result, nil
+	}
+	var values map[string][]byte
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		values, err = adapterInst.HashGet(ctx, key, fields)
+		return err
+	})
+	return values, err
+}
+
+func (d *Distributor) HDel(ctx context.Context, key string, fields []string) (int, error) {
+	var deleted int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		deleted, err = adapterInst.HashDelete(ctx, key, fields)
+		return err
+	})
+	return deleted, err
+}
+
+func (d *Distributor) HGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	if d.readConsistency != ConsistencyOne {
+		hash, err := d.readHash(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if hash == nil {
+			return map[string][]byte{}, nil
+		}
+		return hash, nil
+	}
+	var hash map[string][]byte
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		hash, err = adapterInst.HashGetAll(ctx, key)
+		return err
+	})
+	return hash, err
+}
+
+func (d *Distributor) HIncrBy(ctx context.Context, key, field string, delta int64) (int64, error) {
+	var value int64
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		value, err = adapterInst.HashIncrement(ctx, key, field, delta)
+		return err
+	})
+	return value, err
+}
+
+func (d *Distributor) HExists(ctx context.Context, key, field string) (bool, error) {
+	_, exists, err := d.HGet(ctx, key, field)
+	return exists, err
+}
+
+func (d *Distributor) HLen(ctx context.Context, key string) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		hash, err := d.readHash(ctx, key)
+		return len(hash), err
+	}
+	var length int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.HashLength(ctx, key)
+		return err
+	})
+	return length, err
+}
+
+// readHash reads the newest hash stored at key from the replicas the read consistency asks for
+func (d *Distributor) readHash(ctx context.Context, key string) (map[string][]byte, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeHash {
+		return nil, internal.ErrWrongType
+	}
+	return item.Hash, nil
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) PFAdd(ctx context.Context, key string, elements []string) (bool, error) {
+	var changed bool
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		changed, err = adapterInst.HLLAdd(ctx, key, elements)
+		return err
+	})
+	return changed, err
+}
+
+// PFCount runs on the node holding every key when the keys share their replicas. Otherwise it reads
+// the HyperLogLogs node by node and counts their union here.
+func (d *Distributor) PFCount(ctx context.Context, keys []string) (int64, error) {
+	if adapters, shared := d.sharedReplicas(keys); shared && d.readConsistency == ConsistencyOne {
+		var count int64
+		var err error
+		for _, adapterInst := range adapters {
+			count, err = adapterInst.HLLCount(ctx, keys)
+			if !errors.Is(err, internal.ErrUnavailable) {
+				break
+			}
+		}
+		return count, err
+	}
+	hlls, err := d.readHLLs(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	return data.CountHyperLogLogs(hlls), nil
+}
+
+// PFMerge runs on the primary of dest, which reads the keys itself when they share its replicas.
+// Otherwise they are read node by node first and sent along with sources.
+func (d *Distributor) PFMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error {
+	if _, shared := d.sharedReplicas(append([]string{dest}, keys...)); !shared || d.readConsistency != ConsistencyOne {
+		hlls, err := d.readHLLs(ctx, keys)
+		if err != nil {
+			return err
+		}
+		keys, sources = nil, append(hlls, sources...)
+	}
+	return d.write(ctx, dest, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.HLLMerge(ctx, dest, keys, sources)
+	})
+}
+
+// readHLLs reads the HyperLogLogs at keys from the replicas the read consistency asks for, nil for a missing key
+func (d *Distributor) readHLLs(ctx context.Context, keys []string) ([]*data.HyperLogLog, error) {
+	var items map[string]data.CacheItem
+	var err error
+	if d.readConsistency != ConsistencyOne {
+		items, err = d.readQuorum(ctx, keys)
+	} else {
+		items, err = d.GetEntries(ctx, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hlls := make([]*data.HyperLogLog, len(keys))
+	for i, key := range keys {
+		item, found := items[key]
+		if found && item.Type != data.TypeHLL {
+			return nil, internal.ErrWrongType
+		}
+		hlls[i] = item.HLL
+	}
+	return hlls, nil
+}
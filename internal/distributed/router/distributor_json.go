@@ -0,0 +1,66 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		doc, err := d.readString(ctx, key)
+		if err != nil || doc == nil {
+			return nil, false, err
+		}
+		return data.JSONGet(doc, path)
+	}
+	var value json.RawMessage
+	var found bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		value, found, err = adapterInst.JSONGet(ctx, key, path)
+		return err
+	})
+	return value, found, err
+}
+
+func (d *Distributor) JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error) {
+	var written bool
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		written, err = adapterInst.JSONSet(ctx, key, path, value, options)
+		return err
+	})
+	return written, err
+}
+
+func (d *Distributor) JSONDel(ctx context.Context, key, path string) (int, error) {
+	var deleted int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		deleted, err = adapterInst.JSONDel(ctx, key, path)
+		return err
+	})
+	return deleted, err
+}
+
+func (d *Distributor) JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error) {
+	var length int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.JSONArrAppend(ctx, key, path, values)
+		return err
+	})
+	return length, err
+}
+
+func (d *Distributor) JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error) {
+	var number json.RawMessage
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		number, err = adapterInst.JSONNumIncrBy(ctx, key, path, delta)
+		return err
+	})
+	return number, err
+}
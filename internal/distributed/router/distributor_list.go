@@ -0,0 +1,119 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) LPush(ctx context.Context, key string, values [][]byte) (int, error) {
+	return d.push(ctx, key, values, true)
+}
+
+func (d *Distributor) RPush(ctx context.Context, key string, values [][]byte) (int, error) {
+	return d.push(ctx, key, values, false)
+}
+
+func (d *Distributor) LPop(ctx context.Context, key string, count int) ([][]byte, error) {
+	return d.pop(ctx, key, count, true)
+}
+
+func (d *Distributor) RPop(ctx context.Context, key string, count int) ([][]byte, error) {
+	return d.pop(ctx, key, count, false)
+}
+
+func (d *Distributor) LRange(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	if d.readConsistency != ConsistencyOne {
+		list, err := d.readList(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		from, to, ok := data.ListRange(len(list), start, stop)
+		if !ok {
+			return [][]byte{}, nil
+		}
+		return list[from:to], nil
+	}
+	var values [][]byte
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		values, err = adapterInst.ListRange(ctx, key, start, stop)
+		return err
+	})
+	return values, err
+}
+
+func (d *Distributor) LLen(ctx context.Context, key string) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		list, err := d.readList(ctx, key)
+		return len(list), err
+	}
+	var length int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.ListLength(ctx, key)
+		return err
+	})
+	return length, err
+}
+
+func (d *Distributor) LTrim(ctx context.Context, key string, start, stop int) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.ListTrim(ctx, key, start, stop)
+	})
+}
+
+func (d *Distributor) LIndex(ctx context.Context, key string, index int) ([]byte, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		list, err := d.readList(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+		position, ok := data.ListIndex(len(list), index)
+		if !ok {
+			return nil, false, nil
+		}
+		return list[position], true, nil
+	}
+	var value []byte
+	var found bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		value, found, err = adapterInst.ListIndex(ctx, key, index)
+		return err
+	})
+	return value, found, err
+}
+
+func (d *Distributor) push(ctx context.Context, key string, values [][]byte, head bool) (int, error) {
+	var length int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.ListPush(ctx, key, values, head)
+		return err
+	})
+	return length, err
+}
+
+func (d *Distributor) pop(ctx context.Context, key string, count int, head bool) ([][]byte, error) {
+	var values [][]byte
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		values, err = adapterInst.ListPop(ctx, key, count, head)
+		return err
+	})
+	return values, err
+}
+
+// readList reads the newest list stored at key from the replicas the read consistency asks for
+func (d *Distributor) readList(ctx context.Context, key string) ([][]byte, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeList {
+		return nil, internal.ErrWrongType
+	}
+	return item.List, nil
+}
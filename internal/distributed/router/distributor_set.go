@@ -0,0 +1,170 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"slices"
+)
+
+func (d *Distributor) SAdd(ctx context.Context, key string, members []string) (int, error) {
+	var added int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.SetAdd(ctx, key, members)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) SRem(ctx context.Context, key string, members []string) (int, error) {
+	var removed int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		removed, err = adapterInst.SetRemove(ctx, key, members)
+		return err
+	})
+	return removed, err
+}
+
+func (d *Distributor) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		set, err := d.readSet(ctx, key)
+		_, exists := set[member]
+		return exists, err
+	}
+	var exists bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		exists, err = adapterInst.SetIsMember(ctx, key, member)
+		return err
+	})
+	return exists, err
+}
+
+func (d *Distributor) SMembers(ctx context.Context, key string) ([]string, error) {
+	if d.readConsistency != ConsistencyOne {
+		set, err := d.readSet(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return data.SetMembers(set), nil
+	}
+	var members []string
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		members, err = adapterInst.SetMembers(ctx, key)
+		return err
+	})
+	return members, err
+}
+
+func (d *Distributor) SCard(ctx context.Context, key string) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		set, err := d.readSet(ctx, key)
+		return len(set), err
+	}
+	var length int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.SetCard(ctx, key)
+		return err
+	})
+	return length, err
+}
+
+func (d *Distributor) SPop(ctx context.Context, key string, count int) ([]string, error) {
+	var members []string
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		members, err = adapterInst.SetPop(ctx, key, count)
+		return err
+	})
+	return members, err
+}
+
+func (d *Distributor) SRandMember(ctx context.Context, key string, count int) ([]string, error) {
+	var members []string
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		members, err = adapterInst.SetRandom(ctx, key, count)
+		return err
+	})
+	return members, err
+}
+
+func (d *Distributor) SUnion(ctx context.Context, keys []string) ([]string, error) {
+	return d.combineSets(ctx, data.SetUnion, keys)
+}
+
+func (d *Distributor) SInter(ctx context.Context, keys []string) ([]string, error) {
+	return d.combineSets(ctx, data.SetIntersection, keys)
+}
+
+func (d *Distributor) SDiff(ctx context.Context, keys []string) ([]string, error) {
+	return d.combineSets(ctx, data.SetDifference, keys)
+}
+
+// combineSets runs op on the node holding every set when the keys share their replicas, so it
+// stays atomic. Otherwise it reads the sets node by node and combines them here.
+func (d *Distributor) combineSets(ctx context.Context, op string, keys []string) ([]string, error) {
+	var items map[string]data.CacheItem
+	var err error
+	if d.readConsistency != ConsistencyOne {
+		items, err = d.readQuorum(ctx, keys)
+	} else if adapters, shared := d.sharedReplicas(keys); shared {
+		var members []string
+		for _, adapterInst := range adapters {
+			members, err = adapterInst.SetCombine(ctx, op, keys)
+			if !errors.Is(err, internal.ErrUnavailable) {
+				break
+			}
+		}
+		return members, err
+	} else {
+		items, err = d.GetEntries(ctx, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sets := make([]map[string]struct{}, len(keys))
+	for i, key := range keys {
+		item, found := items[key]
+		if found && item.Type != data.TypeSet {
+			return nil, internal.ErrWrongType
+		}
+		sets[i] = item.Set
+	}
+	return data.CombineSets(op, sets), nil
+}
+
+// sharedReplicas returns the replicas of keys when every key has the same ones
+func (d *Distributor) sharedReplicas(keys []string) ([]adapter.AdapterInterface, bool) {
+	var shared []adapter.AdapterInterface
+	for i, key := range keys {
+		adapters, err := d.replicas(key)
+		if err != nil {
+			return nil, false
+		}
+		if i == 0 {
+			shared = adapters
+		} else if !slices.Equal(adapters, shared) {
+			return nil, false
+		}
+	}
+	return shared, true
+}
+
+// readSet reads the newest set stored at key from the replicas the read consistency asks for
+func (d *Distributor) readSet(ctx context.Context, key string) (map[string]struct{}, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeSet {
+		return nil, internal.ErrWrongType
+	}
+	return item.Set, nil
+}
@@ -0,0 +1,147 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func (d *Distributor) ZAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error) {
+	var added int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.SortedSetAdd(ctx, key, members, options)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error) {
+	var score float64
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		score, err = adapterInst.SortedSetIncrement(ctx, key, member, delta)
+		return err
+	})
+	return score, err
+}
+
+func (d *Distributor) ZScore(ctx context.Context, key, member string) (float64, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		if err != nil {
+			return 0, false, err
+		}
+		score, exists := zset.Score(member)
+		return score, exists, nil
+	}
+	var score float64
+	var exists bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		score, exists, err = adapterInst.SortedSetScore(ctx, key, member)
+		return err
+	})
+	return score, exists, err
+}
+
+func (d *Distributor) ZRank(ctx context.Context, key, member string, rev bool) (int, bool, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		if err != nil {
+			return 0, false, err
+		}
+		rank, exists := zset.Rank(member, rev)
+		return rank, exists, nil
+	}
+	var rank int
+	var exists bool
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		rank, exists, err = adapterInst.SortedSetRank(ctx, key, member, rev)
+		return err
+	})
+	return rank, exists, err
+}
+
+func (d *Distributor) ZRange(ctx context.Context, key string, query data.ZRangeQuery) ([]data.ScoredMember, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return zset.Query(query)
+	}
+	var members []data.ScoredMember
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		members, err = adapterInst.SortedSetRange(ctx, key, query)
+		return err
+	})
+	return members, err
+}
+
+func (d *Distributor) ZCard(ctx context.Context, key string) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		zset, err := d.readZSet(ctx, key)
+		return zset.Len(), err
+	}
+	var length int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.SortedSetCard(ctx, key)
+		return err
+	})
+	return length, err
+}
+
+func (d *Distributor) ZRem(ctx context.Context, key string, members []string) (int, error) {
+	var removed int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		removed, err = adapterInst.SortedSetRemove(ctx, key, members)
+		return err
+	})
+	return removed, err
+}
+
+func (d *Distributor) ZRemRangeByScore(ctx context.Context, key, min, max string) (int, error) {
+	var removed int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		removed, err = adapterInst.SortedSetRemoveRangeByScore(ctx, key, min, max)
+		return err
+	})
+	return removed, err
+}
+
+func (d *Distributor) ZPopMin(ctx context.Context, key string, count int) ([]data.ScoredMember, error) {
+	return d.zpop(ctx, key, count, false)
+}
+
+func (d *Distributor) ZPopMax(ctx context.Context, key string, count int) ([]data.ScoredMember, error) {
+	return d.zpop(ctx, key, count, true)
+}
+
+func (d *Distributor) zpop(ctx context.Context, key string, count int, highest bool) ([]data.ScoredMember, error) {
+	var popped []data.ScoredMember
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		popped, err = adapterInst.SortedSetPop(ctx, key, count, highest)
+		return err
+	})
+	return popped, err
+}
+
+// readZSet reads the newest sorted set stored at key from the replicas the read consistency asks for
+func (d *Distributor) readZSet(ctx context.Context, key string) (*data.SortedSet, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeZSet {
+		return nil, internal.ErrWrongType
+	}
+	return item.ZSet, nil
+}
@@ -0,0 +1,117 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+// XAdd runs on the primary, which picks the entry ID, and the whole stream is copied to the
+// backups, so every replica holds the same IDs.
+func (d *Distributor) XAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error) {
+	var added string
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		added, err = adapterInst.StreamAdd(ctx, key, id, fields, trim)
+		return err
+	})
+	return added, err
+}
+
+func (d *Distributor) XRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error) {
+	if d.readConsistency != ConsistencyOne {
+		stream, err := d.readStream(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return stream.Range(start, end, count)
+	}
+	var entries []data.StreamEntry
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		entries, err = adapterInst.StreamRange(ctx, key, start, end, count)
+		return err
+	})
+	return entries, err
+}
+
+func (d *Distributor) XLen(ctx context.Context, key string) (int, error) {
+	if d.readConsistency != ConsistencyOne {
+		stream, err := d.readStream(ctx, key)
+		return stream.Len(), err
+	}
+	var length int
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		length, err = adapterInst.StreamLength(ctx, key)
+		return err
+	})
+	return length, err
+}
+
+func (d *Distributor) XTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error) {
+	var trimmed int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		trimmed, err = adapterInst.StreamTrim(ctx, key, trim)
+		return err
+	})
+	return trimmed, err
+}
+
+func (d *Distributor) XGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error {
+	return d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		return adapterInst.StreamGroupCreate(ctx, key, group, id, mkStream)
+	})
+}
+
+// XReadGroup is a write: handing out entries moves the group forward and fills its pending list
+func (d *Distributor) XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error) {
+	var entries []data.StreamEntry
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		entries, err = adapterInst.StreamReadGroup(ctx, key, group, consumer, id, count)
+		return err
+	})
+	return entries, err
+}
+
+func (d *Distributor) XAck(ctx context.Context, key, group string, ids []string) (int, error) {
+	var acked int
+	err := d.write(ctx, key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		acked, err = adapterInst.StreamAck(ctx, key, group, ids)
+		return err
+	})
+	return acked, err
+}
+
+func (d *Distributor) XPending(ctx context.Context, key, group string) ([]data.PendingEntry, error) {
+	if d.readConsistency != ConsistencyOne {
+		stream, err := d.readStream(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return stream.Pending(group)
+	}
+	var pending []data.PendingEntry
+	err := d.read(key, func(adapterInst adapter.AdapterInterface) error {
+		var err error
+		pending, err = adapterInst.StreamPending(ctx, key, group)
+		return err
+	})
+	return pending, err
+}
+
+// readStream reads the newest stream stored at key from the replicas the read consistency asks for
+func (d *Distributor) readStream(ctx context.Context, key string) (*data.Stream, error) {
+	item, found, err := d.readItem(ctx, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	if item.Type != data.TypeStream {
+		return nil, internal.ErrWrongType
+	}
+	return item.Stream, nil
+}
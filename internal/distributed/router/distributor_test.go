@@ -0,0 +1,921 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func newTestNodeCache(t *testing.T) *core.Cache {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	config := config.LoadTestConfig()
+	config.Persistent.Type = "memory"
+	cache, err := core.NewCache(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	return cache
+}
+
+// keysOf lists the keys of a node cache
+func keysOf(t *testing.T, cache *core.Cache) []string {
+	t.Helper()
+	keys, err := cache.Keys(t.Context())
+	if err != nil {
+		t.Fatalf("Keys returned error: %v", err)
+	}
+	return keys
+}
+
+// newTestCluster builds a distributor over one local and one peer node, both in-process.
+func newTestCluster(t *testing.T) (*Distributor, *core.Cache, *core.Cache) {
+	t.Helper()
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, config.LoadTestConfig())
+	return distributor, localCache, peerCache
+}
+
+func newTestClusterWithConfig(t *testing.T, testConfig *config.Config) (*Distributor, *core.Cache, *core.Cache, *unreliableAdapter) {
+	t.Helper()
+	localCache := newTestNodeCache(t)
+	peerCache := newTestNodeCache(t)
+	peerAdapter := &unreliableAdapter{LocalAdapter: adapter.NewLocalAdapter(peerCache)}
+	nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(localCache))
+	if err := nodeRouter.AddAdapter("peer-node", peerAdapter); err != nil {
+		t.Fatalf("AddAdapter returned error: %v", err)
+	}
+	return NewDistributor(nodeRouter, testConfig), localCache, peerCache, peerAdapter
+}
+
+// unreliableAdapter answers with internal.ErrUnavailable while down is set
+type unreliableAdapter struct {
+	*adapter.LocalAdapter
+	down atomic.Bool
+}
+
+func (ua *unreliableAdapter) GetItem(ctx context.Context, key string) ([]byte, bool, error) {
+	if ua.down.Load() {
+		return nil, false, internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.GetItem(ctx, key)
+}
+
+func (ua *unreliableAdapter) GetMultiple(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if ua.down.Load() {
+		return nil, internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.GetMultiple(ctx, keys)
+}
+
+func (ua *unreliableAdapter) GetEntries(ctx context.Context, keys []string) (map[string]data.CacheItem, error) {
+	if ua.down.Load() {
+		return nil, internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.GetEntries(ctx, keys)
+}
+
+func (ua *unreliableAdapter) SetEntries(ctx context.Context, entries map[string]data.CacheItem) error {
+	if ua.down.Load() {
+		return internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.SetEntries(ctx, entries)
+}
+
+func (ua *unreliableAdapter) DeleteEntries(ctx context.Context, versions map[string]uint64) error {
+	if ua.down.Load() {
+		return internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.DeleteEntries(ctx, versions)
+}
+
+func (ua *unreliableAdapter) GetTombstones(ctx context.Context, keys []string) (map[string]uint64, error) {
+	if ua.down.Load() {
+		return nil, internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.GetTombstones(ctx, keys)
+}
+
+func (ua *unreliableAdapter) DeleteItem(ctx context.Context, key string) error {
+	if ua.down.Load() {
+		return internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.DeleteItem(ctx, key)
+}
+
+func (ua *unreliableAdapter) ListKeys(ctx context.Context) ([]string, error) {
+	if ua.down.Load() {
+		return nil, internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.ListKeys(ctx)
+}
+
+func (ua *unreliableAdapter) ClearCache(ctx context.Context) error {
+	if ua.down.Load() {
+		return internal.ErrUnavailable
+	}
+	return ua.LocalAdapter.ClearCache(ctx)
+}
+
+// localKeys returns count keys whose primary is the local node of distributor
+func localKeys(t *testing.T, distributor *Distributor, count int) []string {
+	t.Helper()
+	keys := make([]string, 0, count)
+	for i := 0; i < 1000 && len(keys) < count; i++ {
+		key := fmt.Sprintf("local-%d", i)
+		if owner, err := distributor.nodeRouter.GetOwner(key); err == nil && owner == distributor.nodeRouter.GetLocalAdapter() {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) < count {
+		t.Fatalf("found %d keys owned by the local node, need %d", len(keys), count)
+	}
+	return keys
+}
+
+func localKey(t *testing.T, distributor *Distributor) string {
+	t.Helper()
+	return localKeys(t, distributor, 1)[0]
+}
+
+func TestDistributorRoutesKeysToOwner(t *testing.T) {
+	distributor, localCache, peerCache := newTestCluster(t)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := distributor.Set(t.Context(), key, []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+	localKeys, peerKeys := keysOf(t, localCache), keysOf(t, peerCache)
+	if len(localKeys) == 0 || len(peerKeys) == 0 {
+		t.Fatalf("expected keys on both nodes, got local=%d peer=%d", len(localKeys), len(peerKeys))
+	}
+	if len(localKeys)+len(peerKeys) != 100 {
+		t.Fatalf("expected every key stored once, got %d", len(localKeys)+len(peerKeys))
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if value, ok, err := distributor.Get(t.Context(), key); err != nil || !ok || string(value) != "1" {
+			t.Fatalf("Get(%s) returned value=%s ok=%v err=%v", key, value, ok, err)
+		}
+		if value, err := distributor.Incr(t.Context(), key); err != nil || value != 2 {
+			t.Fatalf("Incr(%s) returned value=%d err=%v", key, value, err)
+		}
+	}
+
+	local := distributor.Local()
+	keys, _, err := local.Keys(t.Context())
+	if err != nil {
+		t.Fatalf("Keys returned error: %v", err)
+	}
+	if len(keys) != len(localKeys) {
+		t.Fatalf("local distributor should only see local keys, got %d want %d", len(keys), len(localKeys))
+	}
+}
+
+func TestDistributorMultiKeyFanOut(t *testing.T) {
+	distributor, localCache, peerCache := newTestCluster(t)
+
+	kv := make(map[string][]byte)
+	expected := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("multi-%d", i)
+		kv[key] = []byte(fmt.Sprintf("%d", i))
+		expected = append(expected, key)
+	}
+	if err := distributor.MSet(t.Context(), kv, time.Minute); err != nil {
+		t.Fatalf("MSet returned error: %v", err)
+	}
+	if len(keysOf(t, localCache)) == 0 || len(keysOf(t, peerCache)) == 0 {
+		t.Fatalf("expected MSet to split keys across nodes")
+	}
+
+	result, err := distributor.MGet(t.Context(), append(expected, "missing"))
+	if err != nil {
+		t.Fatalf("MGet returned error: %v", err)
+	}
+	if len(result) != len(kv) {
+		t.Fatalf("expected %d keys from MGet, got %d", len(kv), len(result))
+	}
+	for key, value := range kv {
+		if string(result[key]) != string(value) {
+			t.Fatalf("MGet(%s) = %s, want %s", key, result[key], value)
+		}
+	}
+
+	keys, _, err := distributor.Keys(t.Context())
+	if err != nil {
+		t.Fatalf("Keys returned error: %v", err)
+	}
+	slices.Sort(keys)
+	slices.Sort(expected)
+	if !slices.Equal(keys, expected) {
+		t.Fatalf("expected cluster keys %v, got %v", expected, keys)
+	}
+
+	if _, err := distributor.Flush(t.Context()); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if len(keysOf(t, localCache)) != 0 || len(keysOf(t, peerCache)) != 0 {
+		t.Fatalf("expected Flush to clear every node")
+	}
+}
+
+func TestDistributorReportsFailedNodes(t *testing.T) {
+	distributor, localCache, _, peerAdapter := newTestClusterWithConfig(t, config.LoadTestConfig())
+	for i := 0; i < 20; i++ {
+		if err := distributor.Set(t.Context(), fmt.Sprintf("key-%d", i), []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+	localName := distributor.nodeRouter.GetLocalName()
+
+	keys, summary, err := distributor.Keys(t.Context())
+	if err != nil || len(keys) != 20 {
+		t.Fatalf("expected 20 keys, got %d err=%v", len(keys), err)
+	}
+	if len(summary.Nodes) != 2 || len(summary.Failed) != 0 || summary.Err() != nil {
+		t.Fatalf("expected both nodes to answer, got %+v", summary)
+	}
+
+	peerAdapter.down.Store(true)
+	keys, summary, err = distributor.Keys(t.Context())
+	if err != nil {
+		t.Fatalf("Keys returned error: %v", err)
+	}
+	if len(keys) != len(keysOf(t, localCache)) {
+		t.Fatalf("expected only the local keys, got %d", len(keys))
+	}
+	if !slices.Equal(summary.Failed, []string{"peer-node"}) || !errors.Is(summary.Err(), internal.ErrUnavailable) {
+		t.Fatalf("expected peer-node to be reported as failed, got %+v", summary)
+	}
+	for _, result := range summary.Nodes {
+		if result.Node == localName && (result.Error != "" || result.Keys != len(keys)) {
+			t.Fatalf("expected the local node to list its keys, got %+v", result)
+		}
+		if result.Node == "peer-node" && result.Error == "" {
+			t.Fatalf("expected the peer node to carry its error, got %+v", result)
+		}
+	}
+
+	summary, err = distributor.Flush(t.Context())
+	if err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if !slices.Equal(summary.Failed, []string{"peer-node"}) {
+		t.Fatalf("expected peer-node to be reported as failed, got %+v", summary)
+	}
+	if len(keysOf(t, localCache)) != 0 {
+		t.Fatal("expected the reachable node to be flushed")
+	}
+}
+
+func TestDistributorStopsWhenContextIsDone(t *testing.T) {
+	distributor, localCache, peerCache := newTestCluster(t)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if err := distributor.MSet(ctx, map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3")}, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected MSet to fail with context.Canceled, got %v", err)
+	}
+	if len(keysOf(t, localCache))+len(keysOf(t, peerCache)) != 0 {
+		t.Fatal("expected a cancelled MSet to write nothing")
+	}
+	if _, _, err := distributor.Keys(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Keys to fail with context.Canceled, got %v", err)
+	}
+	if _, err := distributor.MGet(ctx, []string{"a", "b", "c"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected MGet to fail with context.Canceled, got %v", err)
+	}
+}
+
+func TestDistributorReplicatesToBackups(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := distributor.Set(t.Context(), key, []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+		if _, err := distributor.Incr(t.Context(), key); err != nil {
+			t.Fatalf("Incr returned error: %v", err)
+		}
+	}
+	if err := distributor.MSet(t.Context(), map[string][]byte{"multi-a": []byte("a"), "multi-b": []byte("b")}, time.Minute); err != nil {
+		t.Fatalf("MSet returned error: %v", err)
+	}
+	if err := distributor.Del(t.Context(), "key-0"); err != nil {
+		t.Fatalf("Del returned error: %v", err)
+	}
+
+	if len(keysOf(t, localCache)) != 21 || len(keysOf(t, peerCache)) != 21 {
+		t.Fatalf("expected every key on both nodes, got local=%d peer=%d", len(keysOf(t, localCache)), len(keysOf(t, peerCache)))
+	}
+	localEntries := localCache.GetEntries(t.Context(), keysOf(t, localCache))
+	peerEntries := peerCache.GetEntries(t.Context(), keysOf(t, localCache))
+	for key, item := range localEntries {
+		backup, ok := peerEntries[key]
+		if !ok || string(backup.Value) != string(item.Value) || !backup.Expiration.Equal(item.Expiration) {
+			t.Fatalf("backup of %s differs: primary=%+v backup=%+v", key, item, backup)
+		}
+	}
+	if value, _ := peerCache.Get(t.Context(), "key-1"); string(value) != "2" {
+		t.Fatalf("expected replicated counter 2, got %s", value)
+	}
+	keys, _, err := distributor.Keys(t.Context())
+	if err != nil || len(keys) != 21 {
+		t.Fatalf("expected 21 distinct keys, got %d err=%v", len(keys), err)
+	}
+}
+
+func TestDistributorReplicatesLists(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("list-%d", i)
+		if _, err := distributor.RPush(t.Context(), key, [][]byte{[]byte("1"), []byte("2"), []byte("3")}); err != nil {
+			t.Fatalf("RPush returned error: %v", err)
+		}
+		if _, err := distributor.LPop(t.Context(), key, 1); err != nil {
+			t.Fatalf("LPop returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			values, err := cache.LRange(t.Context(), key, 0, -1)
+			if err != nil || len(values) != 2 || string(values[0]) != "2" {
+				t.Fatalf("expected both replicas to hold [2 3] for %s, got %q err=%v", key, values, err)
+			}
+		}
+	}
+
+	quorum, err := distributor.WithConsistency(ConsistencyAll)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if value, found, err := quorum.LIndex(t.Context(), "list-0", -1); err != nil || !found || string(value) != "3" {
+		t.Fatalf("LIndex at consistency all returned %s found=%v err=%v", value, found, err)
+	}
+	if err := distributor.Set(t.Context(), "plain", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if _, err := quorum.LLen(t.Context(), "plain"); !errors.Is(err, internal.ErrWrongType) {
+		t.Fatalf("expected ErrWrongType reading a string as a list, got %v", err)
+	}
+}
+
+func TestDistributorReplicatesHashes(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("hash-%d", i)
+		if _, err := distributor.HSet(t.Context(), key, map[string][]byte{"a": []byte("1"), "b": []byte("2")}); err != nil {
+			t.Fatalf("HSet returned error: %v", err)
+		}
+		if _, err := distributor.HIncrBy(t.Context(), key, "a", 9); err != nil {
+			t.Fatalf("HIncrBy returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if value, ok, err := cache.HGet(t.Context(), key, "a"); err != nil || !ok || string(value) != "10" {
+				t.Fatalf("expected both replicas to hold a=10 for %s, got %s ok=%v err=%v", key, value, ok, err)
+			}
+		}
+	}
+
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if length, err := quorum.HLen(t.Context(), "hash-0"); err != nil || length != 2 {
+		t.Fatalf("HLen at consistency quorum returned %d err=%v", length, err)
+	}
+}
+
+func TestDistributorCombinesSetsAcrossNodes(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	keys := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("set-%d", i)
+		keys = append(keys, key)
+		if _, err := distributor.SAdd(t.Context(), key, []string{"shared", key}); err != nil {
+			t.Fatalf("SAdd returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if card, err := cache.SCard(t.Context(), key); err != nil || card != 2 {
+				t.Fatalf("expected both replicas to hold 2 members for %s, got %d err=%v", key, card, err)
+			}
+		}
+	}
+
+	if union, err := distributor.SUnion(t.Context(), keys); err != nil || len(union) != 11 {
+		t.Fatalf("SUnion returned %v err=%v", union, err)
+	}
+	if inter, err := distributor.SInter(t.Context(), keys); err != nil || !slices.Equal(inter, []string{"shared"}) {
+		t.Fatalf("SInter returned %v err=%v", inter, err)
+	}
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if diff, err := quorum.SDiff(t.Context(), keys[:2]); err != nil || !slices.Equal(diff, []string{"set-0"}) {
+		t.Fatalf("SDiff at consistency quorum returned %v err=%v", diff, err)
+	}
+}
+
+func TestDistributorReplicatesSortedSets(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("board-%d", i)
+		members := []data.ScoredMember{{Member: "kim", Score: 3}, {Member: "lee", Score: 1}, {Member: "park", Score: 2}}
+		if _, err := distributor.ZAdd(t.Context(), key, members, data.ZAddOptions{}); err != nil {
+			t.Fatalf("ZAdd returned error: %v", err)
+		}
+		if _, err := distributor.ZIncrBy(t.Context(), key, "lee", 5); err != nil {
+			t.Fatalf("ZIncrBy returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if rank, ok, err := cache.ZRank(t.Context(), key, "lee", true); err != nil || !ok || rank != 0 {
+				t.Fatalf("expected both replicas to rank lee first for %s, got %d ok=%v err=%v", key, rank, ok, err)
+			}
+		}
+	}
+
+	quorum, err := distributor.WithConsistency(ConsistencyAll)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	members, err := quorum.ZRange(t.Context(), "board-0", data.ZRangeQuery{By: data.ZRangeByScore, Start: "2", Stop: "3", Count: -1})
+	if err != nil || !slices.Equal(members, []data.ScoredMember{{Member: "park", Score: 2}, {Member: "kim", Score: 3}}) {
+		t.Fatalf("ZRange at consistency all returned %v err=%v", members, err)
+	}
+}
+
+func TestDistributorReplicatesStreams(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("events-%d", i)
+		id, err := distributor.XAdd(t.Context(), key, "*", map[string][]byte{"n": []byte("1")}, data.StreamTrim{})
+		if err != nil {
+			t.Fatalf("XAdd returned error: %v", err)
+		}
+		if err := distributor.XGroupCreate(t.Context(), key, "workers", "0", false); err != nil {
+			t.Fatalf("XGroupCreate returned error: %v", err)
+		}
+		if _, err := distributor.XReadGroup(t.Context(), key, "workers", "w1", ">", 0); err != nil {
+			t.Fatalf("XReadGroup returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			pending, err := cache.XPending(t.Context(), key, "workers")
+			if err != nil || len(pending) != 1 || pending[0].ID.String() != id {
+				t.Fatalf("expected both replicas to hold %s as pending for %s, got %+v err=%v", id, key, pending, err)
+			}
+		}
+	}
+
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if entries, err := quorum.XRange(t.Context(), "events-0", "-", "+", 0); err != nil || len(entries) != 1 || string(entries[0].Fields["n"]) != "1" {
+		t.Fatalf("XRange at consistency quorum returned %v err=%v", entries, err)
+	}
+}
+
+func TestDistributorRunsBitOpsAcrossNodes(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	keys := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("day-%d", i)
+		keys = append(keys, key)
+		if _, err := distributor.SetBit(t.Context(), key, int64(i), 1); err != nil {
+			t.Fatalf("SetBit returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if bit, err := cache.GetBit(t.Context(), key, int64(i)); err != nil || bit != 1 {
+				t.Fatalf("expected both replicas to hold bit %d of %s, got %d err=%v", i, key, bit, err)
+			}
+		}
+	}
+
+	if length, err := distributor.BitOp(t.Context(), data.BitOr, "week", keys); err != nil || length != 2 {
+		t.Fatalf("BitOp returned %d err=%v", length, err)
+	}
+	if value, found, err := distributor.GetRaw(t.Context(), "week"); err != nil || !found || string(value) != "\xff\xc0" {
+		t.Fatalf("GetRaw returned %q found=%v err=%v", value, found, err)
+	}
+	if results, err := distributor.BitField(t.Context(), "week", []data.BitFieldOp{{Op: "incrby", Type: "u8", Offset: "#1", Value: 1}}); err != nil || *results[0] != 0xc1 {
+		t.Fatalf("BitField returned %v err=%v", results, err)
+	}
+
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if count, err := quorum.BitCount(t.Context(), "week", data.BitRange{}); err != nil || count != 11 {
+		t.Fatalf("BitCount at consistency quorum returned %d err=%v", count, err)
+	}
+	if results, err := quorum.BitField(t.Context(), "week", []data.BitFieldOp{{Op: "get", Type: "u8", Offset: "8"}}); err != nil || *results[0] != 0xc1 {
+		t.Fatalf("BitField get at consistency quorum returned %v err=%v", results, err)
+	}
+}
+
+func TestDistributorMergesHyperLogLogsAcrossNodes(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	keys := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("page-%d", i)
+		keys = append(keys, key)
+		if _, err := distributor.PFAdd(t.Context(), key, []string{"shared", key}); err != nil {
+			t.Fatalf("PFAdd returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if count, err := cache.PFCount(t.Context(), []string{key}); err != nil || count != 2 {
+				t.Fatalf("expected both replicas to count 2 for %s, got %d err=%v", key, count, err)
+			}
+		}
+	}
+
+	if count, err := distributor.PFCount(t.Context(), keys); err != nil || count != 11 {
+		t.Fatalf("PFCount returned %d err=%v", count, err)
+	}
+	if err := distributor.PFMerge(t.Context(), "site", keys, nil); err != nil {
+		t.Fatalf("PFMerge returned error: %v", err)
+	}
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if count, err := quorum.PFCount(t.Context(), []string{"site"}); err != nil || count != 11 {
+		t.Fatalf("PFCount at consistency quorum returned %d err=%v", count, err)
+	}
+}
+
+func TestDistributorServesGeoIndexesFromReplicas(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	stores := []data.GeoMember{
+		{Member: "gangnam", GeoPoint: data.GeoPoint{Longitude: 127.0276, Latitude: 37.4979}},
+		{Member: "hongdae", GeoPoint: data.GeoPoint{Longitude: 126.9237, Latitude: 37.5563}},
+		{Member: "busan", GeoPoint: data.GeoPoint{Longitude: 129.0756, Latitude: 35.1796}},
+	}
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("stores-%d", i)
+		if added, err := distributor.GeoAdd(t.Context(), key, stores, data.ZAddOptions{}); err != nil || added != 3 {
+			t.Fatalf("GeoAdd returned %d err=%v", added, err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if positions, err := cache.GeoPos(t.Context(), key, []string{"busan"}); err != nil || positions[0] == nil {
+				t.Fatalf("expected both replicas to hold busan in %s, got %v err=%v", key, positions, err)
+			}
+		}
+	}
+
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	want := data.GeoDistance(stores[0].GeoPoint, stores[2].GeoPoint) / 1000
+	seoul := data.GeoQuery{From: &data.GeoPoint{Longitude: 126.978, Latitude: 37.5665}, Radius: 20, Unit: "km"}
+	for _, cache := range []DistributorInterface{distributor, quorum} {
+		for i := 0; i < 5; i++ {
+			key := fmt.Sprintf("stores-%d", i)
+			results, err := cache.GeoSearch(t.Context(), key, seoul)
+			if err != nil || len(results) != 2 || results[0].Member != "hongdae" || results[1].Member != "gangnam" {
+				t.Fatalf("expected the two Seoul stores nearest first in %s, got %v err=%v", key, results, err)
+			}
+			distance, found, err := cache.GeoDist(t.Context(), key, "gangnam", "busan", "km")
+			if err != nil || !found || math.Abs(distance-want) > 0.01 {
+				t.Fatalf("expected gangnam and busan %f km apart in %s, got %f found=%v err=%v", want, key, distance, found, err)
+			}
+		}
+	}
+}
+
+func TestDistributorEditsJSONDocumentsOnEveryReplica(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("cart-%d", i)
+		if _, err := distributor.JSONSet(t.Context(), key, "$", []byte(`{"items":[],"total":0}`), data.JSONSetOptions{}); err != nil {
+			t.Fatalf("JSONSet returned error: %v", err)
+		}
+		if length, err := distributor.JSONArrAppend(t.Context(), key, "$.items", []json.RawMessage{[]byte(`"book"`)}); err != nil || length != 1 {
+			t.Fatalf("JSONArrAppend returned %d err=%v", length, err)
+		}
+		if _, err := distributor.JSONNumIncrBy(t.Context(), key, "$.total", "12.5"); err != nil {
+			t.Fatalf("JSONNumIncrBy returned error: %v", err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if value, found, err := cache.JSONGet(t.Context(), key, "$"); err != nil || !found || string(value) != `{"items":["book"],"total":12.5}` {
+				t.Fatalf("expected both replicas to hold the edited %s, got %s found=%v err=%v", key, value, found, err)
+			}
+		}
+		for _, reader := range []DistributorInterface{distributor, quorum} {
+			if value, found, err := reader.JSONGet(t.Context(), key, "$.items[0]"); err != nil || !found || string(value) != `"book"` {
+				t.Fatalf("JSONGet(%s) returned %s found=%v err=%v", key, value, found, err)
+			}
+		}
+		if deleted, err := distributor.JSONDel(t.Context(), key, "$"); err != nil || deleted != 1 {
+			t.Fatalf("JSONDel returned %d err=%v", deleted, err)
+		}
+		if _, found, err := quorum.JSONGet(t.Context(), key, "$"); err != nil || found {
+			t.Fatalf("expected %s to be deleted, got found=%v err=%v", key, found, err)
+		}
+	}
+}
+
+func TestDistributorKeepsFiltersOnEveryReplica(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		bloomKey, cuckooKey := fmt.Sprintf("seen-%d", i), fmt.Sprintf("dedup-%d", i)
+		if err := distributor.BFReserve(t.Context(), bloomKey, data.BloomOptions{ErrorRate: 0.001, Capacity: 100}); err != nil {
+			t.Fatalf("BFReserve returned error: %v", err)
+		}
+		if added, err := distributor.BFAdd(t.Context(), bloomKey, []string{"a", "b", "a"}); err != nil || !slices.Equal(added, []bool{true, true, false}) {
+			t.Fatalf("BFAdd returned %v err=%v", added, err)
+		}
+		distributor.CFAdd(t.Context(), cuckooKey, "a", false)
+		distributor.CFAdd(t.Context(), cuckooKey, "a", false)
+		if deleted, err := distributor.CFDel(t.Context(), cuckooKey, "a"); err != nil || !deleted {
+			t.Fatalf("CFDel returned %v err=%v", deleted, err)
+		}
+		for _, cache := range []*core.Cache{localCache, peerCache} {
+			if exists, err := cache.BFExists(t.Context(), bloomKey, []string{"a", "b", "c"}); err != nil || !slices.Equal(exists, []bool{true, true, false}) {
+				t.Fatalf("expected both replicas to hold %s, got %v err=%v", bloomKey, exists, err)
+			}
+			if count, err := cache.CFCount(t.Context(), cuckooKey, "a"); err != nil || count != 1 {
+				t.Fatalf("expected both replicas to hold one copy in %s, got %d err=%v", cuckooKey, count, err)
+			}
+		}
+		for _, reader := range []DistributorInterface{distributor, quorum} {
+			if exists, err := reader.BFExists(t.Context(), bloomKey, []string{"b", "c"}); err != nil || !slices.Equal(exists, []bool{true, false}) {
+				t.Fatalf("BFExists(%s) returned %v err=%v", bloomKey, exists, err)
+			}
+			if exists, err := reader.CFExists(t.Context(), cuckooKey, []string{"a", "b"}); err != nil || !slices.Equal(exists, []bool{true, false}) {
+				t.Fatalf("CFExists(%s) returned %v err=%v", cuckooKey, exists, err)
+			}
+			if count, err := reader.CFCount(t.Context(), cuckooKey, "a"); err != nil || count != 1 {
+				t.Fatalf("CFCount(%s) returned %d err=%v", cuckooKey, count, err)
+			}
+		}
+		if _, err := quorum.CFExists(t.Context(), bloomKey, []string{"a"}); !errors.Is(err, internal.ErrWrongType) {
+			t.Fatalf("expected ErrWrongType reading a Bloom filter as a cuckoo filter, got %v", err)
+		}
+	}
+}
+
+func TestDistributorReadsFallBackToBackup(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, _, _, peerAdapter := newTestClusterWithConfig(t, testConfig)
+
+	keys := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		keys = append(keys, key)
+		if err := distributor.Set(t.Context(), key, []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+
+	peerAdapter.down.Store(true)
+	for _, key := range keys {
+		if value, ok, err := distributor.Get(t.Context(), key); err != nil || !ok || string(value) != "1" {
+			t.Fatalf("Get(%s) returned value=%s ok=%v err=%v", key, value, ok, err)
+		}
+	}
+	result, err := distributor.MGet(t.Context(), keys)
+	if err != nil || len(result) != len(keys) {
+		t.Fatalf("MGet returned %d keys, err=%v", len(result), err)
+	}
+
+	// without backups the keys owned by the peer cannot be read
+	testConfig.Cluster.Replication.BackupNodes = 0
+	unreplicated, _, _, unreliablePeer := newTestClusterWithConfig(t, testConfig)
+	for _, key := range keys {
+		unreplicated.Set(t.Context(), key, []byte("1"), time.Minute)
+	}
+	unreliablePeer.down.Store(true)
+	if _, err := unreplicated.MGet(t.Context(), keys); err == nil {
+		t.Fatalf("expected MGet to fail without backups")
+	}
+}
+
+func TestDistributorAsyncReplication(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	testConfig.Cluster.Replication.Mode = ReplicationAsync
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	for i := 0; i < 20; i++ {
+		if err := distributor.Set(t.Context(), fmt.Sprintf("key-%d", i), []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(keysOf(t, localCache)) != 20 || len(keysOf(t, peerCache)) != 20 {
+		if time.Now().After(deadline) {
+			t.Fatalf("backups did not catch up, local=%d peer=%d", len(keysOf(t, localCache)), len(keysOf(t, peerCache)))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDistributorQuorumReadReturnsNewestVersion(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, _, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+	key := localKey(t, distributor)
+
+	if err := distributor.Set(t.Context(), key, []byte("old"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	// the backup received a newer write the primary missed
+	newer := peerCache.GetEntries(t.Context(), []string{key})[key]
+	newer.Value = []byte("new")
+	newer.Version++
+	peerCache.SetEntries(t.Context(), map[string]data.CacheItem{key: newer})
+
+	if value, _, _ := distributor.Get(t.Context(), key); string(value) != "old" {
+		t.Fatalf("consistency one should read the primary, got %s", value)
+	}
+	quorum, err := distributor.WithConsistency(ConsistencyQuorum)
+	if err != nil {
+		t.Fatalf("WithConsistency returned error: %v", err)
+	}
+	if value, ok, err := quorum.Get(t.Context(), key); err != nil || !ok || string(value) != "new" {
+		t.Fatalf("quorum Get returned value=%s ok=%v err=%v", value, ok, err)
+	}
+	if _, err := distributor.WithConsistency("most"); err == nil {
+		t.Fatalf("expected an unknown level to be rejected")
+	}
+}
+
+func TestDistributorQuorumNotMet(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	testConfig.Cluster.Replication.ReadConsistency = ConsistencyQuorum
+	distributor, _, _, peerAdapter := newTestClusterWithConfig(t, testConfig)
+	key := localKey(t, distributor)
+
+	if err := distributor.Set(t.Context(), key, []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	peerAdapter.down.Store(true)
+
+	if _, _, err := distributor.Get(t.Context(), key); !errors.Is(err, internal.ErrQuorum) {
+		t.Fatalf("expected quorum read to fail with ErrQuorum, got %v", err)
+	}
+	if err := distributor.Set(t.Context(), key, []byte("2"), time.Minute); !errors.Is(err, internal.ErrQuorum) {
+		t.Fatalf("expected sync write to fail with ErrQuorum, got %v", err)
+	}
+	one, _ := distributor.WithConsistency(ConsistencyOne)
+	if err := one.Set(t.Context(), key, []byte("3"), time.Minute); err != nil {
+		t.Fatalf("Set at consistency one returned error: %v", err)
+	}
+	if value, ok, err := one.Get(t.Context(), key); err != nil || !ok || string(value) != "3" {
+		t.Fatalf("Get at consistency one returned value=%s ok=%v err=%v", value, ok, err)
+	}
+}
+
+func TestDistributorHintedHandoff(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	testConfig.Cluster.Replication.WriteConsistency = ConsistencyOne
+	distributor, _, peerCache, peerAdapter := newTestClusterWithConfig(t, testConfig)
+	keys := localKeys(t, distributor, 2)
+	key, deleted := keys[0], keys[1]
+	if err := distributor.Set(t.Context(), deleted, []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !peerCache.Exists(t.Context(), deleted) { // consistency one copies to the backup in background
+		if time.Now().After(deadline) {
+			t.Fatalf("backup did not receive %s", deleted)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	peerAdapter.down.Store(true)
+	if err := distributor.Set(t.Context(), key, []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := distributor.Del(t.Context(), deleted); err != nil {
+		t.Fatalf("Del returned error: %v", err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for distributor.PendingHints() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 hinted writes, got %d", distributor.PendingHints())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	distributor.replayHints(t.Context())
+	if distributor.PendingHints() != 2 {
+		t.Fatalf("hints should be kept while the node is down, got %d", distributor.PendingHints())
+	}
+
+	peerAdapter.down.Store(false)
+	distributor.replayHints(t.Context())
+	if distributor.PendingHints() != 0 {
+		t.Fatalf("expected hints to be delivered, %d left", distributor.PendingHints())
+	}
+	if value, ok := peerCache.Get(t.Context(), key); !ok || string(value) != "1" {
+		t.Fatalf("expected hinted write on the peer, got %s ok=%v", value, ok)
+	}
+	if peerCache.Exists(t.Context(), deleted) {
+		t.Fatalf("expected hinted delete on the peer")
+	}
+}
+
+func TestDistributorReadRepair(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+	key := localKey(t, distributor)
+
+	if err := distributor.Set(t.Context(), key, []byte("old"), time.Minute); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	newer := peerCache.GetEntries(t.Context(), []string{key})[key]
+	newer.Value = []byte("new")
+	newer.Version++
+	peerCache.SetEntries(t.Context(), map[string]data.CacheItem{key: newer})
+
+	quorum, _ := distributor.WithConsistency(ConsistencyAll)
+	if value, _, err := quorum.Get(t.Context(), key); err != nil || string(value) != "new" {
+		t.Fatalf("quorum Get returned value=%s err=%v", value, err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if value, _ := localCache.Get(t.Context(), key); string(value) == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stale primary was not repaired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// a delete the peer missed wins over its older copy, which is then deleted too
+	localCache.Del(t.Context(), key)
+	if _, found, err := quorum.Get(t.Context(), key); err != nil || found {
+		t.Fatalf("expected the quorum Get to miss the deleted key, got found=%v err=%v", found, err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for peerCache.Exists(t.Context(), key) {
+		if time.Now().After(deadline) {
+			t.Fatalf("stale peer copy was not deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,62 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// NodeResult is what one node answered to a call sent to every node
+type NodeResult struct {
+	Node  string `json:"node"`
+	Keys  int    `json:"keys,omitempty"`  // keys the node listed
+	Error string `json:"error,omitempty"` // why the node's part is missing
+}
+
+// FanOutSummary reports a call sent to every node, node by node
+type FanOutSummary struct {
+	Nodes  []NodeResult `json:"nodes"`
+	Failed []string     `json:"failed"` // nodes whose part of the answer is missing
+}
+
+// Err returns an error naming the failed nodes, nil when every node answered
+func (s FanOutSummary) Err() error {
+	if len(s.Failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d nodes failed: %s", internal.ErrUnavailable, len(s.Failed), len(s.Nodes), strings.Join(s.Failed, ", "))
+}
+
+// fanOut runs call on every node at once and sums up the answers, call returns the keys it handled
+func fanOut(ctx context.Context, nodes map[string]adapter.AdapterInterface, call func(ctx context.Context, adapterInst adapter.AdapterInterface) (int, error)) FanOutSummary {
+	summary := FanOutSummary{Nodes: make([]NodeResult, 0, len(nodes)), Failed: []string{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, adapterInst := range nodes {
+		wg.Add(1)
+		go func(name string, adapterInst adapter.AdapterInterface) {
+			defer wg.Done()
+			keys, err := call(ctx, adapterInst)
+			result := NodeResult{Node: name, Keys: keys}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("Cluster-wide call failed on %s: %v", name, err)
+				result.Error = err.Error()
+				summary.Failed = append(summary.Failed, name)
+			}
+			summary.Nodes = append(summary.Nodes, result)
+		}(name, adapterInst)
+	}
+	wg.Wait()
+	slices.SortFunc(summary.Nodes, func(a, b NodeResult) int {
+		return strings.Compare(a.Node, b.Node)
+	})
+	slices.Sort(summary.Failed)
+	return summary
+}
@@ -0,0 +1,163 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/adapter"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHintLimit          = 10000 // hinted writes kept per unreachable node
+	defaultHintReplayInterval = 1 * time.Second
+)
+
+// nodeHints holds the latest write for each key that a node missed, a delete by its version
+type nodeHints struct {
+	entries map[string]data.CacheItem
+	deletes map[string]uint64
+}
+
+func (h *nodeHints) size() int {
+	return len(h.entries) + len(h.deletes)
+}
+
+// hintStore keeps the writes meant for unreachable nodes until they answer again
+type hintStore struct {
+	limit int
+	nodes map[adapter.AdapterInterface]*nodeHints
+	mu    sync.Mutex
+}
+
+func newHintStore(limit int) *hintStore {
+	if limit <= 0 {
+		limit = defaultHintLimit
+	}
+	return &hintStore{
+		limit: limit,
+		nodes: make(map[adapter.AdapterInterface]*nodeHints),
+	}
+}
+
+func (hs *hintStore) hintsFor(target adapter.AdapterInterface) *nodeHints {
+	hints, ok := hs.nodes[target]
+	if !ok {
+		hints = &nodeHints{
+			entries: make(map[string]data.CacheItem),
+			deletes: make(map[string]uint64),
+		}
+		hs.nodes[target] = hints
+	}
+	return hints
+}
+
+// storeEntries records items target could not receive, replacing older hints of the same keys
+func (hs *hintStore) storeEntries(target adapter.AdapterInterface, entries map[string]data.CacheItem) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hints := hs.hintsFor(target)
+	for key, item := range entries {
+		current, known := hints.entries[key]
+		if known && current.Version > item.Version {
+			continue
+		}
+		deleted, deleteKnown := hints.deletes[key]
+		if deleteKnown && deleted >= item.Version {
+			continue
+		}
+		if !known && !deleteKnown && hints.size() >= hs.limit {
+			log.Printf("Hint limit %d reached, dropping hinted write of %s", hs.limit, key)
+			continue
+		}
+		delete(hints.deletes, key)
+		hints.entries[key] = item
+	}
+}
+
+// storeDeletes records the deletes target could not receive with their versions, replacing older
+// hints of the same keys
+func (hs *hintStore) storeDeletes(target adapter.AdapterInterface, versions map[string]uint64) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hints := hs.hintsFor(target)
+	for key, version := range versions {
+		current, known := hints.entries[key]
+		if known && current.Version > version {
+			continue
+		}
+		deleted, deleteKnown := hints.deletes[key]
+		if !known && !deleteKnown && hints.size() >= hs.limit {
+			log.Printf("Hint limit %d reached, dropping hinted delete of %s", hs.limit, key)
+			continue
+		}
+		delete(hints.entries, key)
+		hints.deletes[key] = max(deleted, version)
+	}
+}
+
+// take removes and returns every hint, the caller stores back what it could not deliver
+func (hs *hintStore) take() map[adapter.AdapterInterface]*nodeHints {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	nodes := hs.nodes
+	hs.nodes = make(map[adapter.AdapterInterface]*nodeHints)
+	return nodes
+}
+
+func (hs *hintStore) count() int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	total := 0
+	for _, hints := range hs.nodes {
+		total += hints.size()
+	}
+	return total
+}
+
+// StartHintedHandoff replays the hinted writes every interval until ctx is done
+func (d *Distributor) StartHintedHandoff(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.hintReplayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.replayHints(ctx)
+			}
+		}
+	}()
+}
+
+// PendingHints returns the number of writes waiting for an unreachable node
+func (d *Distributor) PendingHints() int {
+	return d.hints.count()
+}
+
+// replayHints delivers the hinted writes to nodes that answer again, keeping the rest for later
+func (d *Distributor) replayHints(ctx context.Context) {
+	for target, hints := range d.hints.take() {
+		entries := make(map[string]data.CacheItem, len(hints.entries))
+		for key, item := range hints.entries {
+			if item.Persistent || time.Now().Before(item.Expiration) {
+				entries[key] = item
+			}
+		}
+		if len(entries) > 0 {
+			if err := target.SetEntries(ctx, entries); err != nil {
+				d.hints.storeEntries(target, entries)
+				d.hints.storeDeletes(target, hints.deletes)
+				continue // still unreachable, try again on the next round
+			}
+		}
+		if len(hints.deletes) > 0 {
+			if err := target.DeleteEntries(ctx, hints.deletes); err != nil {
+				d.hints.storeDeletes(target, hints.deletes)
+			}
+		}
+		log.Printf("Replayed %d hinted writes and %d hinted deletes", len(entries), len(hints.deletes))
+	}
+}
@@ -1,21 +1,129 @@
 package router
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"go-cache-server-mini/internal/core/data"
+	"go-cache-server-mini/internal/distributed/raft"
+	"time"
+)
 
+// DistributorInterface routes cache calls to the nodes owning the keys. ctx comes from the
+// request: once it is done, forwarding and fan-out stop and the call returns ctx.Err().
 type DistributorInterface interface {
-	Set(key string, value []byte, expiration time.Duration) error
-	Get(key string) ([]byte, bool, error)
-	Del(key string) error
-	Exists(key string) (bool, error)
-	Keys() ([]string, error)
-	Flush() error
-	TTL(key string) (time.Duration, bool, error)
-	Expire(key string, expiration time.Duration) error
-	Persist(key string) error
-	Incr(key string) (int64, error)
-	Decr(key string) (int64, error)
-	SetNX(key string, value []byte, expiration time.Duration) (bool, error)
-	GetSet(key string, value []byte) ([]byte, error)
-	MGet(keys []string) (map[string][]byte, error)
-	MSet(kv map[string][]byte, expiration time.Duration) error
+	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Del(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Keys(ctx context.Context) ([]string, FanOutSummary, error) // every node, with what each node answered
+	Flush(ctx context.Context) (FanOutSummary, error)
+	TTL(ctx context.Context, key string) (time.Duration, bool, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	Persist(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Decr(ctx context.Context, key string) (int64, error)
+	SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+	GetSet(ctx context.Context, key string, value []byte) ([]byte, error)
+	MGet(ctx context.Context, keys []string) (map[string][]byte, error)
+	MSet(ctx context.Context, kv map[string][]byte, expiration time.Duration) error
+	GetEntries(ctx context.Context, keys []string) (map[string]data.CacheItem, error) // stored items with their expiration
+	SetEntries(ctx context.Context, entries map[string]data.CacheItem) error          // stores items as they are
+	DeleteEntries(ctx context.Context, versions map[string]uint64) error              // deletes keys older than their delete version
+	GetTombstones(ctx context.Context, keys []string) (map[string]uint64, error)      // delete versions of keys deleted and not written since
+	LPush(ctx context.Context, key string, values [][]byte) (int, error)
+	RPush(ctx context.Context, key string, values [][]byte) (int, error)
+	LPop(ctx context.Context, key string, count int) ([][]byte, error) // internal.ErrNotFound when the list is missing
+	RPop(ctx context.Context, key string, count int) ([][]byte, error)
+	LRange(ctx context.Context, key string, start, stop int) ([][]byte, error)
+	LLen(ctx context.Context, key string) (int, error)
+	LTrim(ctx context.Context, key string, start, stop int) error
+	LIndex(ctx context.Context, key string, index int) ([]byte, bool, error)
+	HSet(ctx context.Context, key string, fields map[string][]byte) (int, error) // returns how many fields were new
+	HGet(ctx context.Context, key, field string) ([]byte, bool, error)
+	HMGet(ctx context.Context, key string, fields []string) (map[string][]byte, error) // the fields that exist
+	HDel(ctx context.Context, key string, fields []string) (int, error)
+	HGetAll(ctx context.Context, key string) (map[string][]byte, error)
+	HIncrBy(ctx context.Context, key, field string, delta int64) (int64, error)
+	HExists(ctx context.Context, key, field string) (bool, error)
+	HLen(ctx context.Context, key string) (int, error)
+	SAdd(ctx context.Context, key string, members []string) (int, error) // returns how many members were new
+	SRem(ctx context.Context, key string, members []string) (int, error)
+	SIsMember(ctx context.Context, key, member string) (bool, error)
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SCard(ctx context.Context, key string) (int, error)
+	SPop(ctx context.Context, key string, count int) ([]string, error) // internal.ErrNotFound when the set is missing
+	SRandMember(ctx context.Context, key string, count int) ([]string, error)
+	SUnion(ctx context.Context, keys []string) ([]string, error) // atomic when the keys share their replicas
+	SInter(ctx context.Context, keys []string) ([]string, error)
+	SDiff(ctx context.Context, keys []string) ([]string, error)
+	ZAdd(ctx context.Context, key string, members []data.ScoredMember, options data.ZAddOptions) (int, error)
+	ZIncrBy(ctx context.Context, key, member string, delta float64) (float64, error)
+	ZScore(ctx context.Context, key, member string) (float64, bool, error)
+	ZRank(ctx context.Context, key, member string, rev bool) (int, bool, error)
+	ZRange(ctx context.Context, key string, query data.ZRangeQuery) ([]data.ScoredMember, error)
+	ZCard(ctx context.Context, key string) (int, error)
+	ZRem(ctx context.Context, key string, members []string) (int, error)
+	ZRemRangeByScore(ctx context.Context, key, min, max string) (int, error)
+	ZPopMin(ctx context.Context, key string, count int) ([]data.ScoredMember, error)
+	ZPopMax(ctx context.Context, key string, count int) ([]data.ScoredMember, error)
+	XAdd(ctx context.Context, key, id string, fields map[string][]byte, trim data.StreamTrim) (string, error)
+	XRange(ctx context.Context, key, start, end string, count int) ([]data.StreamEntry, error)
+	XLen(ctx context.Context, key string) (int, error)
+	XTrim(ctx context.Context, key string, trim data.StreamTrim) (int, error)
+	XGroupCreate(ctx context.Context, key, group, id string, mkStream bool) error
+	XReadGroup(ctx context.Context, key, group, consumer, id string, count int) ([]data.StreamEntry, error)
+	XAck(ctx context.Context, key, group string, ids []string) (int, error)
+	XPending(ctx context.Context, key, group string) ([]data.PendingEntry, error)
+	SetRaw(ctx context.Context, key string, value []byte, expiration time.Duration) error // Set for a value that is not JSON
+	GetRaw(ctx context.Context, key string) ([]byte, bool, error)
+	SetBit(ctx context.Context, key string, offset int64, bit int) (int, error)
+	GetBit(ctx context.Context, key string, offset int64) (int, error)
+	BitCount(ctx context.Context, key string, bitRange data.BitRange) (int64, error)
+	BitPos(ctx context.Context, key string, bit int, bitRange data.BitRange) (int64, error)
+	BitOp(ctx context.Context, op, dest string, keys []string) (int, error)
+	BitField(ctx context.Context, key string, ops []data.BitFieldOp) ([]*int64, error)
+	PFAdd(ctx context.Context, key string, elements []string) (bool, error)
+	PFCount(ctx context.Context, keys []string) (int64, error)
+	PFMerge(ctx context.Context, dest string, keys []string, sources []*data.HyperLogLog) error
+	GeoAdd(ctx context.Context, key string, members []data.GeoMember, options data.ZAddOptions) (int, error)
+	GeoPos(ctx context.Context, key string, members []string) ([]*data.GeoPoint, error)
+	GeoDist(ctx context.Context, key, from, to, unit string) (float64, bool, error)
+	GeoSearch(ctx context.Context, key string, query data.GeoQuery) ([]data.GeoResult, error)
+	JSONGet(ctx context.Context, key, path string) (json.RawMessage, bool, error)
+	JSONSet(ctx context.Context, key, path string, value []byte, options data.JSONSetOptions) (bool, error)
+	JSONDel(ctx context.Context, key, path string) (int, error)
+	JSONArrAppend(ctx context.Context, key, path string, values []json.RawMessage) (int, error)
+	JSONNumIncrBy(ctx context.Context, key, path string, delta json.Number) (json.RawMessage, error)
+	BFReserve(ctx context.Context, key string, options data.BloomOptions) error
+	BFAdd(ctx context.Context, key string, items []string) ([]bool, error)
+	BFExists(ctx context.Context, key string, items []string) ([]bool, error)
+	CFReserve(ctx context.Context, key string, options data.CuckooOptions) error
+	CFAdd(ctx context.Context, key, item string, nx bool) (bool, error)
+	CFExists(ctx context.Context, key string, items []string) ([]bool, error)
+	CFCount(ctx context.Context, key, item string) (int, error)
+	CFDel(ctx context.Context, key, item string) (bool, error)
+	Local() DistributorInterface                                // same operations served only by this node
+	WithConsistency(level string) (DistributorInterface, error) // same operations at another consistency level
+}
+
+type ClusterManagerInterface interface {
+	Nodes() []NodeStatus
+	Distribution() KeyspaceDistribution
+	Slots() ClusterSlots
+}
+
+type AntiEntropyInterface interface {
+	Root(node string) (string, error)                                                // root hash of the keys shared with node
+	Leaves(node string) ([]string, error)                                            // leaf hashes of the keys shared with node
+	Bucket(node string, bucket int) (versions, deleted map[string]uint64, err error) // key and delete versions of one leaf
+	Run(ctx context.Context) AntiEntropyReport                                       // runs a round now
+	Metrics() AntiEntropyMetrics
+}
+
+type ConsensusInterface interface {
+	RequestVote(req raft.VoteRequest) (raft.VoteResponse, error)
+	AppendEntries(req raft.AppendRequest) (raft.AppendResponse, error)
+	Propose(ctx context.Context, command []byte) error // a command forwarded by a follower
+	Configure(ctx context.Context, placement string, vnodes int, weights map[string]int) error
+	Status() ConsensusStatus
 }
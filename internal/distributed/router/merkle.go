@@ -0,0 +1,69 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"go-cache-server-mini/internal/util"
+	"slices"
+)
+
+// merkleLeafCount follows the cache shard layout, a key falls in the leaf of its shard
+const merkleLeafCount = 256
+
+func merkleBucket(key string) int {
+	return int(util.Fnv32aHash(key) % merkleLeafCount)
+}
+
+// merkleTree hashes the key versions of each bucket into a leaf and pairs leaves up to one root
+type merkleTree struct {
+	levels [][][]byte // levels[0] holds the leaves, the last level holds the root
+}
+
+func newMerkleTree(buckets [merkleLeafCount]map[string]uint64) *merkleTree {
+	leaves := make([][]byte, merkleLeafCount)
+	for i, versions := range buckets {
+		leaves[i] = leafHash(versions)
+	}
+	tree := &merkleTree{levels: [][][]byte{leaves}}
+	for level := leaves; len(level) > 1; {
+		parents := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			sum := sha256.Sum256(append(slices.Clone(level[i]), level[i+1]...))
+			parents = append(parents, sum[:])
+		}
+		tree.levels = append(tree.levels, parents)
+		level = parents
+	}
+	return tree
+}
+
+func (t *merkleTree) root() string {
+	return hex.EncodeToString(t.levels[len(t.levels)-1][0])
+}
+
+func (t *merkleTree) leaves() []string {
+	leaves := make([]string, len(t.levels[0]))
+	for i, leaf := range t.levels[0] {
+		leaves[i] = hex.EncodeToString(leaf)
+	}
+	return leaves
+}
+
+// leafHash hashes the keys of a bucket in sorted order together with their versions
+func leafHash(versions map[string]uint64) []byte {
+	keys := make([]string, 0, len(versions))
+	for key := range versions {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	hash := sha256.New()
+	var version [8]byte
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		binary.BigEndian.PutUint64(version[:], versions[key])
+		hash.Write(version[:])
+	}
+	return hash.Sum(nil)
+}
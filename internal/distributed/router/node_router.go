@@ -2,93 +2,132 @@ package router
 
 import (
 	"context"
-	"fmt"
+	"go-cache-server-mini/internal/config"
 	"go-cache-server-mini/internal/distributed/adapter"
 	"go-cache-server-mini/internal/util"
 	"slices"
+	"strings"
 	"sync"
 )
 
+const DefaultNodeName = "local-node"
+
 type NodeRouter struct {
-	replicas    int      // number of virtual nodes per physical node
-	backupNodes int      // number of backup nodes
-	nodeMap     sync.Map // hash to adapter mapping
-	hashes      []uint32 // sorted hash ring
-	// localAdapter adapter.AdapterInterface
-	mu sync.RWMutex
+	strategy     string                              // placement strategy, see Placement
+	vnodes       int                                 // number of virtual nodes per unit of weight (consistent_hash)
+	weights      map[string]int                      // node name to weight, 1 when unset
+	backupNodes  int                                 // number of backup nodes following the primary
+	epoch        uint64                              // number of placements built so far
+	placement    Placement                           // rebuilt on every membership change
+	nodes        map[string]adapter.AdapterInterface // node name to adapter mapping
+	localName    string
+	localAddress string // base URL clients use to reach this node
+	localAdapter adapter.AdapterInterface
+	listeners    []func(change *ringChange) // told about every ring change
+	previousRing *ringSnapshot              // ring before the changes still being rebalanced
+	mu           sync.RWMutex
 }
 
-func NewNodeRouter(ctx context.Context, localAdapter adapter.AdapterInterface) *NodeRouter {
+func NewNodeRouter(ctx context.Context, config *config.Config, localAdapter adapter.AdapterInterface) *NodeRouter {
+	clusterConfig := config.Cluster
+	nodeName := clusterConfig.NodeName
+	if nodeName == "" {
+		nodeName = DefaultNodeName
+	}
 	nodeRouter := &NodeRouter{
-		replicas:    3,          // number of virtual nodes per physical node, TODO : make it configurable
-		backupNodes: 0,          // No backup nodes for now, TODO: implement later
-		nodeMap:     sync.Map{}, // node-ip to adapter mapping
-		// localAdapter: localAdapter,
-		hashes: []uint32{},
+		strategy:     clusterConfig.Placement,
+		vnodes:       clusterConfig.VirtualNodes,
+		weights:      clusterConfig.Weights,
+		backupNodes:  max(0, clusterConfig.Replication.BackupNodes),
+		nodes:        make(map[string]adapter.AdapterInterface),
+		localName:    nodeName,
+		localAddress: adapter.BaseURL(clusterConfig.Address),
+		localAdapter: localAdapter,
+	}
+	if !validPlacement(nodeRouter.strategy) {
+		nodeRouter.strategy = PlacementConsistentHash
+	}
+	if nodeRouter.vnodes <= 0 {
+		nodeRouter.vnodes = defaultVirtualNodes
 	}
-	nodeRouter.AddAdapter("local-node", localAdapter)
+	nodeRouter.rebuildLocked()
+	nodeRouter.AddAdapter(nodeName, localAdapter)
 	return nodeRouter
 }
 
 func (nr *NodeRouter) GetLocalAdapter() adapter.AdapterInterface {
 	nr.mu.RLock()
 	defer nr.mu.RUnlock()
+	return nr.localAdapter
+}
 
-	node_name := fmt.Sprintf("%s-%d", "local-node", 0)
-	hash := util.Fnv32aHash(node_name)
-	local_adapter, ok := nr.nodeMap.Load(hash)
-	if !ok {
-		return nil
+func (nr *NodeRouter) GetLocalName() string {
+	return nr.localName
+}
+
+// HasNode reports whether nodeIP is currently part of the ring
+func (nr *NodeRouter) HasNode(nodeIP string) bool {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	_, ok := nr.nodes[nodeIP]
+	return ok
+}
+
+// GetNodeAdapter returns the adapter of a node in the ring by name
+func (nr *NodeRouter) GetNodeAdapter(nodeIP string) (adapter.AdapterInterface, bool) {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	adapterInst, ok := nr.nodes[nodeIP]
+	return adapterInst, ok
+}
+
+// GetNodes returns every node in the ring by name
+func (nr *NodeRouter) GetNodes() map[string]adapter.AdapterInterface {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	nodes := make(map[string]adapter.AdapterInterface, len(nr.nodes))
+	for name, adapterInst := range nr.nodes {
+		nodes[name] = adapterInst
 	}
-	return local_adapter.(adapter.AdapterInterface)
+	return nodes
+}
+
+// GetBackupNodes returns how many nodes after the primary keep a copy of each key
+func (nr *NodeRouter) GetBackupNodes() int {
+	return nr.backupNodes
+}
+
+// GetOwner returns the primary adapter responsible for key
+func (nr *NodeRouter) GetOwner(key string) (adapter.AdapterInterface, error) {
+	adapters, err := nr.GetAdapters(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(adapters) == 0 {
+		return nil, errNoAdapter
+	}
+	return adapters[0], nil
 }
 
 func (nr *NodeRouter) GetAdapters(key string) ([]adapter.AdapterInterface, error) {
 	nr.mu.RLock()
 	defer nr.mu.RUnlock()
 
-	adapters := []adapter.AdapterInterface{}
-	if len(nr.hashes) == 0 {
-		return adapters, nil
-	}
-	hash := util.Fnv32aHash(key)
-	// Find the nodes >= hash
-	idx, _ := slices.BinarySearch(nr.hashes, hash)
-	uniqueAdapters := make(map[adapter.AdapterInterface]struct{})
-	resultAdapters := make([]adapter.AdapterInterface, 0, nr.backupNodes+1)
 	// Get primary + backup nodes
-	for i := 0; i < len(nr.hashes) && len(resultAdapters) < nr.backupNodes+1; i++ {
-		current_idx := (idx + i) % len(nr.hashes)
-		nodeHash := nr.hashes[current_idx]
-		adapterInterface, ok := nr.nodeMap.Load(nodeHash)
-		if !ok {
-			continue
-		}
-		adapterInst := adapterInterface.(adapter.AdapterInterface)
-		if _, visited := uniqueAdapters[adapterInst]; visited {
-			continue
-		}
-		uniqueAdapters[adapterInst] = struct{}{}
-		resultAdapters = append(resultAdapters, adapterInst)
+	names := nr.placement.Replicas(util.Fnv32aHash(key), nr.backupNodes+1)
+	adapters := make([]adapter.AdapterInterface, 0, len(names))
+	for _, name := range names {
+		adapters = append(adapters, nr.nodes[name])
 	}
-	return resultAdapters, nil
+	return adapters, nil
 }
 
 func (nr *NodeRouter) GetAllAdapters() ([]adapter.AdapterInterface, error) {
 	nr.mu.RLock()
 	defer nr.mu.RUnlock()
 
-	uniqueAdapters := make(map[adapter.AdapterInterface]struct{})
-	for _, hash := range nr.hashes {
-		adapterInterface, ok := nr.nodeMap.Load(hash)
-		if !ok {
-			continue
-		}
-		adapterInst := adapterInterface.(adapter.AdapterInterface)
-		uniqueAdapters[adapterInst] = struct{}{}
-	}
-	adapters := make([]adapter.AdapterInterface, 0, len(uniqueAdapters))
-	for adapterInst := range uniqueAdapters {
+	adapters := make([]adapter.AdapterInterface, 0, len(nr.nodes))
+	for _, adapterInst := range nr.nodes {
 		adapters = append(adapters, adapterInst)
 	}
 	return adapters, nil
@@ -96,33 +135,218 @@ func (nr *NodeRouter) GetAllAdapters() ([]adapter.AdapterInterface, error) {
 
 func (nr *NodeRouter) AddAdapter(nodeIP string, adapter adapter.AdapterInterface) error {
 	nr.mu.Lock()
-	defer nr.mu.Unlock()
-
-	for i := 0; i < nr.replicas; i++ {
-		hash := util.Fnv32aHash(fmt.Sprintf("%s-%d", nodeIP, i))
-		nr.nodeMap.Store(hash, adapter)
-		nr.hashes = append(nr.hashes, hash)
+	_, exists := nr.nodes[nodeIP]
+	nr.nodes[nodeIP] = adapter
+	if exists { // re-adding a node only swaps its adapter
+		nr.mu.Unlock()
+		return nil
 	}
-	slices.Sort(nr.hashes)
+	previous := nr.snapshotLocked()
+	nr.rebuildLocked()
+	listeners := nr.ringChangedLocked(previous)
+	nr.mu.Unlock()
+
+	nr.notify(listeners, previous)
 	return nil
 }
 
 func (nr *NodeRouter) RemoveAdapter(nodeIP string) error {
+	nr.mu.Lock()
+	if _, exists := nr.nodes[nodeIP]; !exists {
+		nr.mu.Unlock()
+		return nil
+	}
+	previous := nr.snapshotLocked()
+	delete(nr.nodes, nodeIP)
+	nr.rebuildLocked()
+	listeners := nr.ringChangedLocked(previous)
+	nr.mu.Unlock()
+
+	nr.notify(listeners, previous)
+	return nil
+}
+
+// SetRing replaces the nodes and placement of the ring with a configuration agreed by the cluster,
+// epoch is the version every node applies it under
+func (nr *NodeRouter) SetRing(nodes map[string]adapter.AdapterInterface, strategy string, vnodes int, weights map[string]int, epoch uint64) {
+	nr.mu.Lock()
+	previous := nr.snapshotLocked()
+	nr.nodes = make(map[string]adapter.AdapterInterface, len(nodes))
+	for name, adapterInst := range nodes {
+		nr.nodes[name] = adapterInst
+	}
+	if validPlacement(strategy) {
+		nr.strategy = strategy
+	}
+	if vnodes > 0 {
+		nr.vnodes = vnodes
+	}
+	nr.weights = weights
+	nr.rebuildLocked()
+	nr.epoch = epoch
+	listeners := nr.ringChangedLocked(previous)
+	nr.mu.Unlock()
+
+	nr.notify(listeners, previous)
+}
+
+// Distribution samples the keyspace to show the share each node owns and replicates
+func (nr *NodeRouter) Distribution() KeyspaceDistribution {
+	nr.mu.RLock()
+	placement := nr.placement
+	distribution := KeyspaceDistribution{
+		Placement:    placement.Name(),
+		VirtualNodes: nr.vnodes,
+		BackupNodes:  nr.backupNodes,
+		Nodes:        make([]NodeShare, 0, len(nr.nodes)),
+	}
+	for name := range nr.nodes {
+		distribution.Nodes = append(distribution.Nodes, NodeShare{Name: name, Weight: nr.weight(name)})
+	}
+	nr.mu.RUnlock()
+
+	primary := make(map[string]int, len(distribution.Nodes))
+	replica := make(map[string]int, len(distribution.Nodes))
+	step := uint32(1 << (32 - distributionSampleBits))
+	for i := 0; i < 1<<distributionSampleBits; i++ {
+		names := placement.Replicas(uint32(i)*step+step/2, distribution.BackupNodes+1)
+		for j, name := range names {
+			if j == 0 {
+				primary[name]++
+			}
+			replica[name]++
+		}
+	}
+	for i := range distribution.Nodes {
+		node := &distribution.Nodes[i]
+		node.Primary = float64(primary[node.Name]) / (1 << distributionSampleBits)
+		node.Replica = float64(replica[node.Name]) / (1 << distributionSampleBits)
+	}
+	slices.SortFunc(distribution.Nodes, func(a, b NodeShare) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return distribution
+}
+
+// Moved returns a *MovedError when key is owned by another node, nil when this node owns it
+func (nr *NodeRouter) Moved(key string) error {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	names := nr.placement.Replicas(util.Fnv32aHash(key), 1)
+	if len(names) == 0 || names[0] == nr.localName {
+		return nil
+	}
+	return &MovedError{Key: key, Node: names[0], Address: nr.addressLocked(names[0]), Epoch: nr.epoch}
+}
+
+// Slots returns the ring as published to clients
+func (nr *NodeRouter) Slots() ClusterSlots {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	slots := ClusterSlots{
+		Epoch:        nr.epoch,
+		Hash:         "fnv1a-32",
+		Placement:    nr.placement.Name(),
+		VirtualNodes: nr.vnodes,
+		BackupNodes:  nr.backupNodes,
+		Nodes:        make([]SlotNode, 0, len(nr.nodes)),
+	}
+	for name := range nr.nodes {
+		slots.Nodes = append(slots.Nodes, SlotNode{Name: name, Address: nr.addressLocked(name), Weight: nr.weight(name)})
+	}
+	slices.SortFunc(slots.Nodes, func(a, b SlotNode) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	if ring, ok := nr.placement.(*consistentHashPlacement); ok {
+		slots.Ranges = ring.ranges(nr.backupNodes + 1)
+	}
+	return slots
+}
+
+// addressLocked returns the base URL of a node, nr.mu must be held
+func (nr *NodeRouter) addressLocked(name string) string {
+	if name == nr.localName {
+		return nr.localAddress
+	}
+	if remote, ok := nr.nodes[name].(interface{ Address() string }); ok {
+		return remote.Address()
+	}
+	return ""
+}
+
+// onRingChange registers listener for every later change of the ring
+func (nr *NodeRouter) onRingChange(listener func(change *ringChange)) {
 	nr.mu.Lock()
 	defer nr.mu.Unlock()
+	nr.listeners = append(nr.listeners, listener)
+}
 
-	hashToRemove := make(map[uint32]struct{}, nr.replicas)
-	for i := 0; i < nr.replicas; i++ {
-		hash := util.Fnv32aHash(fmt.Sprintf("%s-%d", nodeIP, i))
-		nr.nodeMap.Delete(hash)
-		hashToRemove[hash] = struct{}{}
+// GetPreviousAdapters returns the adapters that held key before the ring changes still being
+// rebalanced, nil when no data is moving
+func (nr *NodeRouter) GetPreviousAdapters(key string) []adapter.AdapterInterface {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+	if nr.previousRing == nil {
+		return nil
 	}
-	newHashes := make([]uint32, 0, len(nr.hashes)-len(hashToRemove))
-	for _, hash := range nr.hashes {
-		if _, found := hashToRemove[hash]; !found {
-			newHashes = append(newHashes, hash)
+	var adapters []adapter.AdapterInterface
+	for _, name := range nr.previousRing.replicas(util.Fnv32aHash(key)) {
+		if adapterInst, ok := nr.nodes[name]; ok { // a node that left cannot be asked anymore
+			adapters = append(adapters, adapterInst)
 		}
 	}
-	nr.hashes = newHashes
-	return nil
+	return adapters
+}
+
+// clearPreviousRing ends the read fallback once the data has moved
+func (nr *NodeRouter) clearPreviousRing() {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+	nr.previousRing = nil
+}
+
+// snapshotLocked returns the current ring, nr.mu must be held
+func (nr *NodeRouter) snapshotLocked() *ringSnapshot {
+	return &ringSnapshot{placement: nr.placement, backupNodes: nr.backupNodes}
+}
+
+// rebuildLocked places the current nodes again, nr.mu must be held
+func (nr *NodeRouter) rebuildLocked() {
+	nodes := make([]placementNode, 0, len(nr.nodes))
+	for name := range nr.nodes {
+		nodes = append(nodes, placementNode{name: name, weight: nr.weight(name)})
+	}
+	nr.placement = newPlacement(nr.strategy, nodes, nr.vnodes)
+	nr.epoch++
+}
+
+func (nr *NodeRouter) weight(name string) int {
+	if weight := nr.weights[name]; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// ringChangedLocked keeps the oldest ring not yet rebalanced for read fallback and returns
+// the listeners to notify, nr.mu must be held
+func (nr *NodeRouter) ringChangedLocked(previous *ringSnapshot) []func(change *ringChange) {
+	if previous == nil || len(nr.listeners) == 0 {
+		return nil
+	}
+	if nr.previousRing == nil {
+		nr.previousRing = previous
+	}
+	return slices.Clone(nr.listeners)
+}
+
+func (nr *NodeRouter) notify(listeners []func(change *ringChange), previous *ringSnapshot) {
+	if len(listeners) == 0 {
+		return
+	}
+	nr.mu.RLock()
+	change := newRingChange(previous, nr.snapshotLocked())
+	nr.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(change)
+	}
 }
@@ -0,0 +1,216 @@
+package router
+
+import (
+	"cmp"
+	"fmt"
+	"go-cache-server-mini/internal/util"
+	"math"
+	"slices"
+)
+
+const (
+	PlacementConsistentHash = "consistent_hash" // ring of virtual nodes, a change only moves the ranges next to the node
+	PlacementRendezvous     = "rendezvous"      // highest random weight, every node scores every key
+	PlacementJump           = "jump"            // jump consistent hash over the nodes sorted by name
+)
+
+const (
+	defaultVirtualNodes    = 128
+	distributionSampleBits = 16 // Distribution checks 2^16 evenly spaced hashes
+)
+
+// Placement decides which nodes hold a key hash. Implementations are immutable,
+// NodeRouter builds a new one on every membership change.
+type Placement interface {
+	Name() string
+	// Replicas returns the names of up to count distinct nodes holding hash, the primary first
+	Replicas(hash uint32, count int) []string
+}
+
+// NodeShare is the part of the keyspace a node holds, from 0 to 1
+type NodeShare struct {
+	Name    string  `json:"name"`
+	Weight  int     `json:"weight"`
+	Primary float64 `json:"primary"` // keys the node owns
+	Replica float64 `json:"replica"` // keys the node has a copy of, owned ones included
+}
+
+type KeyspaceDistribution struct {
+	Placement    string      `json:"placement"`
+	VirtualNodes int         `json:"virtual_nodes"`
+	BackupNodes  int         `json:"backup_nodes"`
+	Nodes        []NodeShare `json:"nodes"`
+}
+
+type placementNode struct {
+	name   string
+	weight int // relative share of the keyspace, at least 1
+}
+
+func validPlacement(strategy string) bool {
+	switch strategy {
+	case PlacementConsistentHash, PlacementRendezvous, PlacementJump:
+		return true
+	}
+	return false
+}
+
+func newPlacement(strategy string, nodes []placementNode, vnodes int) Placement {
+	nodes = slices.Clone(nodes)
+	slices.SortFunc(nodes, func(a, b placementNode) int {
+		return cmp.Compare(a.name, b.name)
+	})
+	switch strategy {
+	case PlacementRendezvous:
+		return newRendezvousPlacement(nodes)
+	case PlacementJump:
+		return newJumpPlacement(nodes)
+	default:
+		return newConsistentHashPlacement(nodes, vnodes)
+	}
+}
+
+// consistentHashPlacement places weight*vnodes virtual nodes per node on a hash ring,
+// a key belongs to the first virtual nodes at or after its hash
+type consistentHashPlacement struct {
+	hashes []uint32          // sorted virtual node hashes
+	owners map[uint32]string // virtual node hash to node name
+	nodes  int
+}
+
+func newConsistentHashPlacement(nodes []placementNode, vnodes int) *consistentHashPlacement {
+	placement := &consistentHashPlacement{
+		owners: make(map[uint32]string),
+		nodes:  len(nodes),
+	}
+	for _, node := range nodes {
+		for i := 0; i < vnodes*node.weight; i++ {
+			hash := util.Fnv32aHash(fmt.Sprintf("%s-%d", node.name, i))
+			if _, taken := placement.owners[hash]; taken {
+				continue // the first node by name keeps a colliding hash
+			}
+			placement.owners[hash] = node.name
+			placement.hashes = append(placement.hashes, hash)
+		}
+	}
+	slices.Sort(placement.hashes)
+	return placement
+}
+
+func (p *consistentHashPlacement) Name() string {
+	return PlacementConsistentHash
+}
+
+func (p *consistentHashPlacement) Replicas(hash uint32, count int) []string {
+	if len(p.hashes) == 0 {
+		return nil
+	}
+	count = min(count, p.nodes)
+	idx, _ := slices.BinarySearch(p.hashes, hash)
+	names := make([]string, 0, count)
+	for i := 0; i < len(p.hashes) && len(names) < count; i++ {
+		name := p.owners[p.hashes[(idx+i)%len(p.hashes)]]
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// rendezvousPlacement scores every node for a key and keeps the highest scores.
+// Scores follow weighted rendezvous hashing: -weight / ln(u), u uniform in (0, 1).
+type rendezvousPlacement struct {
+	nodes []placementNode
+	seeds []uint64 // hash of each node name
+}
+
+func newRendezvousPlacement(nodes []placementNode) *rendezvousPlacement {
+	placement := &rendezvousPlacement{nodes: nodes, seeds: make([]uint64, len(nodes))}
+	for i, node := range nodes {
+		placement.seeds[i] = mix64(uint64(util.Fnv32aHash(node.name)) << 32)
+	}
+	return placement
+}
+
+func (p *rendezvousPlacement) Name() string {
+	return PlacementRendezvous
+}
+
+func (p *rendezvousPlacement) Replicas(hash uint32, count int) []string {
+	type scored struct {
+		name  string
+		score float64
+	}
+	scores := make([]scored, len(p.nodes))
+	for i, node := range p.nodes {
+		// 53 random bits, shifted away from 0 so the logarithm stays finite
+		u := (float64(mix64(p.seeds[i]^uint64(hash))>>11) + 0.5) / (1 << 53)
+		scores[i] = scored{name: node.name, score: -float64(node.weight) / math.Log(u)}
+	}
+	slices.SortFunc(scores, func(a, b scored) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	names := make([]string, 0, min(count, len(scores)))
+	for _, node := range scores[:min(count, len(scores))] {
+		names = append(names, node.name)
+	}
+	return names
+}
+
+// jumpPlacement maps a key to a bucket with jump consistent hash, each node owning weight buckets.
+// Adding or removing the node sorted last moves the fewest keys; backups are the next distinct nodes.
+type jumpPlacement struct {
+	buckets []string // bucket to node name
+	nodes   int
+}
+
+func newJumpPlacement(nodes []placementNode) *jumpPlacement {
+	placement := &jumpPlacement{nodes: len(nodes)}
+	for _, node := range nodes {
+		for i := 0; i < node.weight; i++ {
+			placement.buckets = append(placement.buckets, node.name)
+		}
+	}
+	return placement
+}
+
+func (p *jumpPlacement) Name() string {
+	return PlacementJump
+}
+
+func (p *jumpPlacement) Replicas(hash uint32, count int) []string {
+	if len(p.buckets) == 0 {
+		return nil
+	}
+	count = min(count, p.nodes)
+	idx := jumpHash(mix64(uint64(hash)), len(p.buckets))
+	names := make([]string, 0, count)
+	for i := 0; i < len(p.buckets) && len(names) < count; i++ {
+		name := p.buckets[(idx+i)%len(p.buckets)]
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// jumpHash is the jump consistent hash of Lamping and Veach
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// mix64 spreads the bits of x, the splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+var placementStrategies = []string{PlacementConsistentHash, PlacementRendezvous, PlacementJump}
+
+func testPlacementNodes(count int) []placementNode {
+	nodes := make([]placementNode, 0, count)
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, placementNode{name: fmt.Sprintf("node-%d", i), weight: 1})
+	}
+	return nodes
+}
+
+// primaryShares returns the share of sample hashes each node owns
+func primaryShares(placement Placement) map[string]float64 {
+	const samples = 1 << 14
+	shares := make(map[string]float64)
+	for i := 0; i < samples; i++ {
+		shares[placement.Replicas(uint32(i)*(1<<18), 1)[0]] += 1.0 / samples
+	}
+	return shares
+}
+
+func TestPlacementReturnsDistinctReplicas(t *testing.T) {
+	for _, strategy := range placementStrategies {
+		placement := newPlacement(strategy, testPlacementNodes(4), defaultVirtualNodes)
+		for i := 0; i < 1000; i++ {
+			names := placement.Replicas(uint32(i)*4294967, 3)
+			if len(names) != 3 || names[0] == names[1] || names[1] == names[2] || names[0] == names[2] {
+				t.Fatalf("%s: expected 3 distinct replicas, got %v", strategy, names)
+			}
+		}
+		if names := placement.Replicas(1, 10); len(names) != 4 {
+			t.Fatalf("%s: expected replicas capped at the node count, got %v", strategy, names)
+		}
+	}
+}
+
+func TestPlacementFollowsWeights(t *testing.T) {
+	nodes := testPlacementNodes(3)
+	nodes[2].weight = 2
+	for _, strategy := range placementStrategies {
+		shares := primaryShares(newPlacement(strategy, nodes, defaultVirtualNodes))
+		for _, node := range nodes {
+			want := float64(node.weight) / 4
+			if math.Abs(shares[node.name]-want) > 0.08 {
+				t.Fatalf("%s: %s owns %.3f of the keyspace, want about %.2f", strategy, node.name, shares[node.name], want)
+			}
+		}
+	}
+}
+
+func TestPlacementMovesKeysOnlyToJoiningNode(t *testing.T) {
+	for _, strategy := range placementStrategies {
+		before := newPlacement(strategy, testPlacementNodes(3), defaultVirtualNodes)
+		after := newPlacement(strategy, testPlacementNodes(4), defaultVirtualNodes) // node-3 sorts last
+		moved := 0
+		for i := 0; i < 10000; i++ {
+			hash := uint32(i) * 429497
+			previous, current := before.Replicas(hash, 1)[0], after.Replicas(hash, 1)[0]
+			if previous == current {
+				continue
+			}
+			if current != "node-3" {
+				t.Fatalf("%s: key moved from %s to %s instead of the new node", strategy, previous, current)
+			}
+			moved++
+		}
+		if moved < 1500 || moved > 3500 {
+			t.Fatalf("%s: expected about a quarter of the keys to move, %d of 10000 did", strategy, moved)
+		}
+	}
+}
+
+func TestNodeRouterDistribution(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Placement = PlacementRendezvous
+	testConfig.Cluster.Weights = map[string]int{"peer-node": 3}
+	testConfig.Cluster.Replication.BackupNodes = 1
+	nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(newTestNodeCache(t)))
+	nodeRouter.AddAdapter("peer-node", adapter.NewLocalAdapter(newTestNodeCache(t)))
+
+	distribution := nodeRouter.Distribution()
+	if distribution.Placement != PlacementRendezvous || len(distribution.Nodes) != 2 {
+		t.Fatalf("unexpected distribution: %+v", distribution)
+	}
+	names := []string{distribution.Nodes[0].Name, distribution.Nodes[1].Name}
+	if !slices.Equal(names, []string{"local-node", "peer-node"}) {
+		t.Fatalf("expected nodes sorted by name, got %v", names)
+	}
+	local, peer := distribution.Nodes[0], distribution.Nodes[1]
+	if peer.Weight != 3 || math.Abs(peer.Primary-0.75) > 0.05 || math.Abs(local.Primary+peer.Primary-1) > 1e-9 {
+		t.Fatalf("expected peer-node to own about 3/4 of the keyspace, got %+v", distribution.Nodes)
+	}
+	if local.Replica != 1 || peer.Replica != 1 {
+		t.Fatalf("expected both nodes to replicate every key with one backup, got %+v", distribution.Nodes)
+	}
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"context"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRebalanceBatchSize      = 100
+	defaultRebalanceRate           = 1000 // keys per second
+	defaultRebalanceFallbackWindow = 10 * time.Second
+)
+
+// Rebalancer moves the local keys whose replicas changed to the nodes that now hold them,
+// expiration included, and drops the keys this node no longer replicates
+type Rebalancer struct {
+	nodeRouter     *NodeRouter
+	cache          core.CacheInterface
+	batchSize      int
+	rate           int // keys sent per second
+	fallbackWindow time.Duration
+	pending        []*ringChange
+	wake           chan struct{}
+	mu             sync.Mutex
+}
+
+func NewRebalancer(nodeRouter *NodeRouter, cache core.CacheInterface, config *config.Config) *Rebalancer {
+	rebalanceConfig := config.Cluster.Rebalance
+	rebalancer := &Rebalancer{
+		nodeRouter:     nodeRouter,
+		cache:          cache,
+		batchSize:      rebalanceConfig.BatchSize,
+		rate:           rebalanceConfig.Rate,
+		fallbackWindow: durationOrDefault(rebalanceConfig.FallbackWindow, defaultRebalanceFallbackWindow),
+		wake:           make(chan struct{}, 1),
+	}
+	if rebalancer.batchSize <= 0 {
+		rebalancer.batchSize = defaultRebalanceBatchSize
+	}
+	if rebalancer.rate <= 0 {
+		rebalancer.rate = defaultRebalanceRate
+	}
+	return rebalancer
+}
+
+// Start follows the ring changes of the node router until ctx is done
+func (rb *Rebalancer) Start(ctx context.Context) {
+	rb.nodeRouter.onRingChange(rb.enqueue)
+	go func() {
+		fallbackTimer := time.NewTimer(rb.fallbackWindow)
+		fallbackTimer.Stop()
+		defer fallbackTimer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-rb.wake:
+				for change := rb.next(); change != nil; change = rb.next() {
+					rb.rebalance(ctx, change)
+				}
+				// peers may still be sending, keep reading from the old owners a little longer
+				fallbackTimer.Reset(rb.fallbackWindow)
+			case <-fallbackTimer.C:
+				rb.nodeRouter.clearPreviousRing()
+			}
+		}
+	}()
+}
+
+func (rb *Rebalancer) enqueue(change *ringChange) {
+	rb.mu.Lock()
+	rb.pending = append(rb.pending, change)
+	rb.mu.Unlock()
+	select {
+	case rb.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (rb *Rebalancer) next() *ringChange {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if len(rb.pending) == 0 {
+		return nil
+	}
+	change := rb.pending[0]
+	rb.pending = rb.pending[1:]
+	return change
+}
+
+// rebalance sends every local key whose replicas changed to the nodes that did not hold it before
+func (rb *Rebalancer) rebalance(ctx context.Context, change *ringChange) {
+	localName := rb.nodeRouter.GetLocalName()
+	transfers := make(map[string][]string) // node name to keys it needs
+	var drops []string
+	for key := range rb.cache.Versions() {
+		previous, current := change.replicas(key)
+		if slices.Equal(previous, current) {
+			continue
+		}
+		for _, name := range current {
+			if name != localName && !slices.Contains(previous, name) {
+				transfers[name] = append(transfers[name], key)
+			}
+		}
+		if !slices.Contains(current, localName) {
+			drops = append(drops, key)
+		}
+	}
+	if len(transfers) == 0 && len(drops) == 0 {
+		return
+	}
+	log.Printf("Rebalancing: sending keys to %d nodes, %d keys leave this node", len(transfers), len(drops))
+
+	failed := make(map[string]struct{})
+	moved := 0
+	for name, keys := range transfers {
+		target, ok := rb.nodeRouter.GetNodeAdapter(name)
+		if !ok {
+			continue // the node left again
+		}
+		for start := 0; start < len(keys); start += rb.batchSize {
+			batch := keys[start:min(start+rb.batchSize, len(keys))]
+			entries := rb.cache.GetEntries(ctx, batch)
+			if err := target.SetEntries(ctx, entries); err != nil {
+				log.Printf("Error moving %d keys to %s: %v", len(entries), name, err)
+				for _, key := range batch {
+					failed[key] = struct{}{}
+				}
+				continue
+			}
+			moved += len(entries)
+			// throttle so a join does not starve regular traffic
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(len(batch)) * time.Second / time.Duration(rb.rate)):
+			}
+		}
+	}
+
+	var dropped []string
+	localAdapter := rb.nodeRouter.GetLocalAdapter()
+	for _, key := range drops {
+		if _, ok := failed[key]; ok {
+			continue // keep the only copy we know of
+		}
+		// the ring may have changed again since, only drop keys that are still not ours
+		if adapters, err := rb.nodeRouter.GetAdapters(key); err != nil || slices.Contains(adapters, localAdapter) {
+			continue
+		}
+		dropped = append(dropped, key)
+	}
+	// the keys live on elsewhere, dropping them must not read as a delete
+	if err := rb.cache.DropEntries(ctx, dropped); err != nil {
+		log.Printf("Error dropping %d keys: %v", len(dropped), err)
+	}
+	log.Printf("Rebalance done: %d keys sent, %d keys dropped, %d keys failed", moved, len(dropped), len(failed))
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/core"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+// newSingleNodeRouter builds a router holding only the local node, so every key starts there
+func newSingleNodeRouter(t *testing.T, testConfig *config.Config) (*NodeRouter, *Distributor, *core.Cache) {
+	t.Helper()
+	localCache := newTestNodeCache(t)
+	nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(localCache))
+	return nodeRouter, NewDistributor(nodeRouter, testConfig), localCache
+}
+
+func TestRebalancerMovesKeysToJoiningNode(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Rebalance.Rate = 100000
+	testConfig.Cluster.Rebalance.BatchSize = 10
+	nodeRouter, distributor, localCache := newSingleNodeRouter(t, testConfig)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	NewRebalancer(nodeRouter, localCache, testConfig).Start(ctx)
+
+	for i := 0; i < 100; i++ {
+		if err := distributor.Set(t.Context(), fmt.Sprintf("key-%d", i), []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+
+	peerCache := newTestNodeCache(t)
+	if err := nodeRouter.AddAdapter("peer-node", adapter.NewLocalAdapter(peerCache)); err != nil {
+		t.Fatalf("AddAdapter returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(keysOf(t, localCache))+len(keysOf(t, peerCache)) != 100 || len(keysOf(t, peerCache)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("keys were not moved, local=%d peer=%d", len(keysOf(t, localCache)), len(keysOf(t, peerCache)))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, key := range keysOf(t, peerCache) {
+		owner, err := nodeRouter.GetOwner(key)
+		if err != nil || owner == nodeRouter.GetLocalAdapter() {
+			t.Fatalf("key %s moved to a node that does not own it", key)
+		}
+		if ttl, ok := peerCache.TTL(t.Context(), key); !ok || ttl <= 0 || ttl > time.Minute {
+			t.Fatalf("expected moved key %s to keep its TTL, got %v", key, ttl)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if value, ok, err := distributor.Get(t.Context(), key); err != nil || !ok || string(value) != "1" {
+			t.Fatalf("Get(%s) returned value=%s ok=%v err=%v", key, value, ok, err)
+		}
+	}
+}
+
+func TestDistributorReadsFromPreviousOwnerDuringRebalance(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	nodeRouter, distributor, _ := newSingleNodeRouter(t, testConfig)
+	nodeRouter.onRingChange(func(change *ringChange) {}) // keep the previous ring, move nothing
+
+	for i := 0; i < 50; i++ {
+		if err := distributor.Set(t.Context(), fmt.Sprintf("key-%d", i), []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+	if err := nodeRouter.AddAdapter("peer-node", adapter.NewLocalAdapter(newTestNodeCache(t))); err != nil {
+		t.Fatalf("AddAdapter returned error: %v", err)
+	}
+
+	var moved []string
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if owner, _ := nodeRouter.GetOwner(key); owner != nodeRouter.GetLocalAdapter() {
+			moved = append(moved, key)
+		}
+	}
+	if len(moved) == 0 {
+		t.Fatal("expected some keys to change owner")
+	}
+	values, err := distributor.MGet(t.Context(), moved)
+	if err != nil || len(values) != len(moved) {
+		t.Fatalf("MGet returned %d values err=%v, want %d", len(values), err, len(moved))
+	}
+	for _, key := range moved {
+		if value, ok, err := distributor.Get(t.Context(), key); err != nil || !ok || string(value) != "1" {
+			t.Fatalf("Get(%s) returned value=%s ok=%v err=%v", key, value, ok, err)
+		}
+		if ttl, ok, err := distributor.TTL(t.Context(), key); err != nil || !ok || ttl <= 0 {
+			t.Fatalf("TTL(%s) returned ttl=%v ok=%v err=%v", key, ttl, ok, err)
+		}
+	}
+
+	nodeRouter.clearPreviousRing()
+	if _, ok, _ := distributor.Get(t.Context(), moved[0]); ok {
+		t.Fatalf("expected %s to be read from its new owner only once the move is done", moved[0])
+	}
+}
@@ -0,0 +1,30 @@
+package router
+
+import "go-cache-server-mini/internal/util"
+
+// ringSnapshot is the placement of the ring at one point in time, used to compare the ring before and after a change
+type ringSnapshot struct {
+	placement   Placement
+	backupNodes int
+}
+
+// replicas returns the names of the nodes holding hash, the primary first, as NodeRouter.GetAdapters does
+func (r *ringSnapshot) replicas(hash uint32) []string {
+	return r.placement.Replicas(hash, r.backupNodes+1)
+}
+
+// ringChange is a membership change of the ring, told to the NodeRouter listeners
+type ringChange struct {
+	previous *ringSnapshot
+	current  *ringSnapshot
+}
+
+func newRingChange(previous, current *ringSnapshot) *ringChange {
+	return &ringChange{previous: previous, current: current}
+}
+
+// replicas returns the nodes holding key before and after the change
+func (rc *ringChange) replicas(key string) (previous []string, current []string) {
+	hash := util.Fnv32aHash(key)
+	return rc.previous.replicas(hash), rc.current.replicas(hash)
+}
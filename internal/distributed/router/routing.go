@@ -0,0 +1,80 @@
+package router
+
+import (
+	"fmt"
+	"go-cache-server-mini/internal"
+	"math"
+	"slices"
+)
+
+const (
+	RoutingProxy    = "proxy"    // forward calls for keys owned by another node and relay the answer
+	RoutingRedirect = "redirect" // answer with the owner so the client calls it directly
+)
+
+// MovedError names the node owning a key, returned instead of forwarding in redirect mode
+type MovedError struct {
+	Key     string
+	Node    string
+	Address string // base URL of the owner
+	Epoch   uint64 // ring version the answer is based on, see ClusterSlots
+}
+
+func (e *MovedError) Error() string {
+	return fmt.Sprintf("MOVED %s %s (%s)", e.Key, e.Node, e.Address)
+}
+
+func (e *MovedError) Unwrap() error {
+	return internal.ErrMoved
+}
+
+type SlotNode struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Weight  int    `json:"weight"`
+}
+
+// SlotRange is a range of key hashes, bounds included, and the nodes holding it, the primary first
+type SlotRange struct {
+	Start uint32   `json:"start"`
+	End   uint32   `json:"end"`
+	Nodes []string `json:"nodes"`
+}
+
+// ClusterSlots publishes the ring so clients can send each key to its owner.
+// Keys are hashed with 32-bit FNV-1a; ranges are only listed for consistent_hash,
+// other placements are computed from the nodes and weights.
+type ClusterSlots struct {
+	Epoch        uint64      `json:"epoch"` // increases on every ring change
+	Hash         string      `json:"hash"`
+	Placement    string      `json:"placement"`
+	VirtualNodes int         `json:"virtual_nodes"`
+	BackupNodes  int         `json:"backup_nodes"`
+	Nodes        []SlotNode  `json:"nodes"`
+	Ranges       []SlotRange `json:"ranges,omitempty"`
+}
+
+// ranges lists the hash ranges of the ring, merging neighbours held by the same nodes
+func (p *consistentHashPlacement) ranges(count int) []SlotRange {
+	if len(p.hashes) == 0 {
+		return nil
+	}
+	var ranges []SlotRange
+	add := func(start, end uint32, nodes []string) {
+		if last := len(ranges) - 1; last >= 0 && ranges[last].End+1 == start && slices.Equal(ranges[last].Nodes, nodes) {
+			ranges[last].End = end
+			return
+		}
+		ranges = append(ranges, SlotRange{Start: start, End: end, Nodes: nodes})
+	}
+	start := uint32(0)
+	for _, hash := range p.hashes {
+		add(start, hash, p.Replicas(hash, count))
+		start = hash + 1
+	}
+	if last := p.hashes[len(p.hashes)-1]; last != math.MaxUint32 {
+		// hashes past the last virtual node wrap around to the first one
+		add(last+1, math.MaxUint32, p.Replicas(p.hashes[0], count))
+	}
+	return ranges
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"go-cache-server-mini/internal"
+	"go-cache-server-mini/internal/config"
+	"go-cache-server-mini/internal/distributed/adapter"
+)
+
+func TestDistributorRedirectsKeysOwnedByPeer(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Routing = RoutingRedirect
+	distributor, localCache, peerCache, _ := newTestClusterWithConfig(t, testConfig)
+
+	key := localKey(t, distributor)
+	if err := distributor.Set(t.Context(), key, []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set of a local key returned error: %v", err)
+	}
+	peerKey := ""
+	for _, candidate := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		if distributor.nodeRouter.Moved(candidate) != nil {
+			peerKey = candidate
+			break
+		}
+	}
+	if peerKey == "" {
+		t.Fatal("expected a key owned by peer-node")
+	}
+
+	err := distributor.Set(t.Context(), peerKey, []byte("1"), time.Minute)
+	var moved *MovedError
+	if !errors.As(err, &moved) || !errors.Is(err, internal.ErrMoved) || moved.Node != "peer-node" {
+		t.Fatalf("expected MovedError naming peer-node, got %v", err)
+	}
+	if _, _, err := distributor.Get(t.Context(), peerKey); !errors.Is(err, internal.ErrMoved) {
+		t.Fatalf("expected Get to be redirected, got %v", err)
+	}
+	if peerCache.Exists(t.Context(), peerKey) || localCache.Exists(t.Context(), peerKey) {
+		t.Fatal("a redirected write must not be stored")
+	}
+
+	// peers calling each other and multi-key calls are still served
+	if err := distributor.Local().Set(t.Context(), peerKey, []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set through Local returned error: %v", err)
+	}
+	if err := distributor.MSet(t.Context(), map[string][]byte{key: []byte("2"), "k": []byte("2"), "z": []byte("2")}, time.Minute); err != nil {
+		t.Fatalf("MSet returned error: %v", err)
+	}
+}
+
+func TestNodeRouterSlotsCoverKeyspace(t *testing.T) {
+	testConfig := config.LoadTestConfig()
+	testConfig.Cluster.Replication.BackupNodes = 1
+	nodeRouter := NewNodeRouter(context.Background(), testConfig, adapter.NewLocalAdapter(newTestNodeCache(t)))
+	nodeRouter.AddAdapter("peer-node", adapter.NewRemoteAdapter("127.0.0.1:8081", adapter.RemotePolicy{Timeout: time.Second}))
+	nodeRouter.AddAdapter("third-node", adapter.NewRemoteAdapter("127.0.0.1:8082", adapter.RemotePolicy{Timeout: time.Second}))
+
+	slots := nodeRouter.Slots()
+	if len(slots.Nodes) != 3 || slots.Nodes[1].Address != "http://127.0.0.1:8081" {
+		t.Fatalf("unexpected nodes %+v", slots.Nodes)
+	}
+	if slots.Ranges[0].Start != 0 || slots.Ranges[len(slots.Ranges)-1].End != math.MaxUint32 {
+		t.Fatalf("expected ranges to cover every hash, got %+v ... %+v", slots.Ranges[0], slots.Ranges[len(slots.Ranges)-1])
+	}
+	for i, slot := range slots.Ranges {
+		if i > 0 && slots.Ranges[i-1].End+1 != slot.Start {
+			t.Fatalf("ranges %d and %d are not contiguous", i-1, i)
+		}
+		for _, hash := range []uint32{slot.Start, slot.End} {
+			if want := nodeRouter.placement.Replicas(hash, 2); !slices.Equal(slot.Nodes, want) {
+				t.Fatalf("range %+v lists %v, placement gives %v for %d", slot, slot.Nodes, want, hash)
+			}
+		}
+	}
+
+	epoch := slots.Epoch
+	nodeRouter.RemoveAdapter("third-node")
+	if nodeRouter.Slots().Epoch <= epoch {
+		t.Fatal("expected the epoch to increase on a ring change")
+	}
+}
@@ -3,7 +3,11 @@ package internal
 import "errors"
 
 var (
-	ErrBadRequest = errors.New("bad request")
-	ErrNotFound   = errors.New("key not found in cache")
-	ErrServer     = errors.New("internal server error")
+	ErrBadRequest  = errors.New("bad request")
+	ErrNotFound    = errors.New("key not found in cache")
+	ErrServer      = errors.New("internal server error")
+	ErrUnavailable = errors.New("node unavailable")
+	ErrQuorum      = errors.New("consistency level not met")
+	ErrMoved       = errors.New("key owned by another node")
+	ErrWrongType   = errors.New("operation against a key holding the wrong kind of value")
 )